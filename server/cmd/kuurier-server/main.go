@@ -83,6 +83,11 @@ func main() {
 		log.Printf("Warning: Failed to connect to MinIO: %v (media uploads disabled)", err)
 		minio = nil
 	}
+	exports, err := storage.NewPrivateMinIO(cfg.MinIOEndpoint, cfg.MinIOAccessKey, cfg.MinIOSecretKey, cfg.MinIOExportBucket, cfg.MinIOUseSSL)
+	if err != nil {
+		log.Printf("Warning: Failed to set up export bucket: %v (data exports disabled)", err)
+		exports = nil
+	}
 
 	// Initialize APNs (push notifications)
 	apnsCfg := storage.APNsConfig{
@@ -99,7 +104,7 @@ func main() {
 	}
 
 	// Create router and WebSocket hub
-	router, wsHub := api.NewRouter(cfg, db, redis, minio, apns, api.BuildInfo{
+	router, wsHub := api.NewRouter(cfg, db, redis, minio, exports, apns, api.BuildInfo{
 		Version:   Version,
 		SHA:       GitSHA,
 		BuildDate: BuildDate,
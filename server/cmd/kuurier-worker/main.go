@@ -13,6 +13,14 @@
 //     advisory lock race does the work).
 //   - Start NewsBot and ProtestBot schedulers.
 //   - Consume Redis-backed admin triggers.
+//   - Build queued personal data exports (needs MinIO).
+//   - Send daily/weekly subscription digests.
+//   - Remind RSVPs before events and when timed locations are revealed.
+//   - Fold event check-ins into anonymous counts once events end.
+//   - Expire stale SOS alerts and escalate those no one responds to.
+//   - End timed-out live responder location shares.
+//   - Alert contacts of users who miss a safety timer check-in.
+//   - Cluster related posts into incidents.
 //   - Emit a heartbeat key every 30 seconds so the API can surface
 //     worker liveness.
 //
@@ -27,12 +35,17 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/kuurier/server/internal/alerts"
 	"github.com/kuurier/server/internal/bot"
 	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/events"
+	"github.com/kuurier/server/internal/export"
 	"github.com/kuurier/server/internal/feed"
 	"github.com/kuurier/server/internal/logger"
 	"github.com/kuurier/server/internal/metrics"
 	"github.com/kuurier/server/internal/migrations"
+	"github.com/kuurier/server/internal/push"
+	"github.com/kuurier/server/internal/safety"
 	"github.com/kuurier/server/internal/storage"
 )
 
@@ -104,12 +117,67 @@ func main() {
 	// Heartbeat loop: write every 30s so the API can check worker health.
 	go runHeartbeat(ctx, redis)
 
-	// Feed materialization: precompute ranked feeds for active users.
-	// Runs every 5 minutes in a loop, with a panic-recovering wrapper
-	// similar to the bot scheduler.
+	// Feed materialization: full rebuild of active users' ranked feeds.
+	// New posts are inserted incrementally by the API; this pass is the
+	// periodic consistency check. Panic-recovering wrapper similar to
+	// the bot scheduler.
 	materializer := feed.NewMaterializer(cfg, db, redis)
 	go runMaterializer(ctx, materializer)
 
+	// Incident clustering: group nearby posts about the same thing
+	// from different members. Cheap enough to run often, so incidents
+	// show up while they're still happening.
+	go runJob(ctx, "incident clustering", 2*time.Minute, 2*time.Minute, feed.NewIncidentClusterer(db).RunOnce)
+
+	// Push notifications for worker-originated messages (digests).
+	// Same APNs setup as the API; without a key it logs instead of sending.
+	apns, err := storage.NewAPNs(storage.APNsConfig{
+		KeyPath:    cfg.APNsKeyPath,
+		KeyID:      cfg.APNsKeyID,
+		TeamID:     cfg.APNsTeamID,
+		BundleID:   cfg.APNsBundleID,
+		Production: cfg.APNsProduction,
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to initialize APNs: %v (push notifications disabled)", err)
+		apns = nil
+	}
+	pushService := push.NewService(cfg, db, redis, apns)
+
+	// Subscription digests. The job itself decides who is due, so the
+	// tick only bounds how late a digest can be.
+	go runJob(ctx, "digest", 15*time.Minute, 10*time.Minute, feed.NewDigester(cfg, db, redis, pushService).RunOnce)
+
+	// Event reminders. Runs every minute so hour-before reminders and
+	// location reveals go out on time; event_reminders dedupes sends.
+	go runJob(ctx, "event reminders", time.Minute, time.Minute, events.NewReminder(cfg, db, pushService).RunOnce)
+
+	// Event check-in: fold finished occurrences' check-ins into
+	// k-anonymous counts and delete them, so attendance can't be traced
+	// back to anyone after the event.
+	go runJob(ctx, "event check-in", 10*time.Minute, 2*time.Minute, events.NewCheckinCloser(db).RunOnce)
+
+	// SOS alert lifecycle: resolve alerts past their TTL, end live
+	// location shares, and widen or hand to trusted contacts those no
+	// one has responded to. Runs every minute so escalation isn't late
+	// when it matters.
+	go runJob(ctx, "alert lifecycle", time.Minute, time.Minute, alerts.NewLifecycle(db, redis, pushService).RunOnce)
+
+	// Safety timers: alert the contacts of anyone who missed their
+	// check-in. Every 30 seconds, so they hear within a minute of the
+	// deadline.
+	go runJob(ctx, "safety timers", 30*time.Second, time.Minute, safety.NewWatcher(db, pushService).RunOnce)
+
+	// Personal data exports: build queued archives and sweep expired
+	// ones. Needs object storage; skipped (requests stay pending) if
+	// MinIO isn't reachable.
+	exports, err := storage.NewPrivateMinIO(cfg.MinIOEndpoint, cfg.MinIOAccessKey, cfg.MinIOSecretKey, cfg.MinIOExportBucket, cfg.MinIOUseSSL)
+	if err != nil {
+		log.Printf("Warning: Failed to set up export bucket: %v (data exports disabled)", err)
+	} else {
+		go runJob(ctx, "data export", time.Minute, 5*time.Minute, export.NewExporter(cfg, db, exports).RunOnce)
+	}
+
 	// Consume Redis-backed admin triggers and dispatch to the right bot.
 	go bot.RunTriggerConsumer(ctx, redis, func(queue string) {
 		triggerCtx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
@@ -143,7 +211,7 @@ func runMaterializer(ctx context.Context, m *feed.Materializer) {
 				log.Printf("feed materializer panic recovered: %v", r)
 			}
 		}()
-		runCtx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+		runCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 		defer cancel()
 		if err := m.RunOnce(runCtx); err != nil {
 			log.Printf("feed materializer error: %v", err)
@@ -151,7 +219,37 @@ func runMaterializer(ctx context.Context, m *feed.Materializer) {
 	}
 
 	runOnce()
-	ticker := time.NewTicker(5 * time.Minute)
+	ticker := time.NewTicker(feed.FullRebuildInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			runOnce()
+		}
+	}
+}
+
+// runJob runs fn immediately and then every interval until ctx is
+// cancelled. Each run gets its own timeout and a panic-recovering
+// wrapper so one bad pass doesn't take the worker down.
+func runJob(ctx context.Context, name string, interval, timeout time.Duration, fn func(context.Context) error) {
+	runOnce := func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("%s job panic recovered: %v", name, r)
+			}
+		}()
+		runCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if err := fn(runCtx); err != nil {
+			log.Printf("%s job error: %v", name, err)
+		}
+	}
+
+	runOnce()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for {
 		select {
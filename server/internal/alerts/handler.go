@@ -1,6 +1,8 @@
 package alerts
 
 import (
+	"context"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -8,8 +10,10 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/counts"
 	"github.com/kuurier/server/internal/push"
 	"github.com/kuurier/server/internal/storage"
+	"github.com/kuurier/server/internal/websocket"
 )
 
 // Handler handles SOS alert endpoints
@@ -18,11 +22,12 @@ type Handler struct {
 	db    *storage.Postgres
 	redis *storage.Redis
 	push  *push.Service
+	hub   *websocket.Hub
 }
 
 // NewHandler creates a new alerts handler
-func NewHandler(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, pushService *push.Service) *Handler {
-	return &Handler{cfg: cfg, db: db, redis: redis, push: pushService}
+func NewHandler(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, pushService *push.Service, hub *websocket.Hub) *Handler {
+	return &Handler{cfg: cfg, db: db, redis: redis, push: pushService, hub: hub}
 }
 
 // CreateAlertRequest represents a new SOS alert
@@ -34,10 +39,14 @@ type CreateAlertRequest struct {
 	Longitude    float64 `json:"longitude" binding:"required"`
 	LocationName string  `json:"location_name"`
 	RadiusMeters int     `json:"radius_meters"` // Broadcast radius
+
+	// Skills to route the alert to first (medic, legal_observer, ...)
+	RequiredSkills []string `json:"required_skills" binding:"max=4"`
 }
 
 // ListAlerts returns active alerts
 func (h *Handler) ListAlerts(c *gin.Context) {
+	userID := c.GetString("user_id")
 	ctx := c.Request.Context()
 
 	// Parse filters
@@ -52,7 +61,8 @@ func (h *Handler) ListAlerts(c *gin.Context) {
 		SELECT a.id, a.author_id, a.title, a.description, a.severity,
 			   ST_Y(a.location::geometry) as lat, ST_X(a.location::geometry) as lon,
 			   a.location_name, a.radius_meters, a.status, a.created_at,
-			   (SELECT COUNT(*) FROM alert_responses WHERE alert_id = a.id) as response_count
+			   (SELECT COUNT(*) FROM alert_responses WHERE alert_id = a.id) as response_count,
+			   a.required_skills
 		FROM alerts a
 		WHERE a.status = $1
 		ORDER BY a.severity DESC, a.created_at DESC
@@ -65,6 +75,7 @@ func (h *Handler) ListAlerts(c *gin.Context) {
 	}
 	defer rows.Close()
 
+	policy := counts.FromConfig(h.cfg)
 	var alerts []gin.H
 	for rows.Next() {
 		var id, authorID, title, status string
@@ -72,22 +83,26 @@ func (h *Handler) ListAlerts(c *gin.Context) {
 		var severity, radiusMeters, responseCount int
 		var lat, lon float64
 		var createdAt time.Time
+		var requiredSkills []string
 
-		if err := rows.Scan(&id, &authorID, &title, &description, &severity, &lat, &lon, &locationName, &radiusMeters, &status, &createdAt, &responseCount); err != nil {
+		if err := rows.Scan(&id, &authorID, &title, &description, &severity, &lat, &lon, &locationName, &radiusMeters, &status, &createdAt, &responseCount,
+			&requiredSkills); err != nil {
 			continue
 		}
 
 		alert := gin.H{
-			"id":             id,
-			"author_id":      authorID,
-			"title":          title,
-			"severity":       severity,
-			"location":       gin.H{"latitude": lat, "longitude": lon},
-			"radius_meters":  radiusMeters,
-			"status":         status,
-			"created_at":     createdAt,
-			"response_count": responseCount,
+			"id":              id,
+			"author_id":       authorID,
+			"title":           title,
+			"severity":        severity,
+			"location":        gin.H{"latitude": lat, "longitude": lon},
+			"radius_meters":   radiusMeters,
+			"status":          status,
+			"created_at":      createdAt,
+			"required_skills": requiredSkills,
 		}
+		// Only the author sees how many exactly are coming
+		policy.Set(alert, "response_count", responseCount, authorID == userID)
 
 		if description != nil {
 			alert["description"] = *description
@@ -145,32 +160,77 @@ func (h *Handler) CreateAlert(c *gin.Context) {
 		return
 	}
 
+	requiredSkills, err := normalizeSkills(req.RequiredSkills)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	alertID := uuid.New().String()
 
 	// Default radius if not specified
 	if req.RadiusMeters == 0 {
-		req.RadiusMeters = 5000 // 5km default
+		req.RadiusMeters = defaultAlertRadius
 	}
-	if req.RadiusMeters > 50000 {
-		req.RadiusMeters = 50000 // Max 50km
+	if req.RadiusMeters > maxAlertRadius {
+		req.RadiusMeters = maxAlertRadius
 	}
 
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create alert"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
 	// Use parameterized ST_MakePoint to prevent SQL injection
 	// ST_SetSRID sets the coordinate system to WGS 84 (GPS)
-	_, err = h.db.Pool().Exec(ctx, `
-		INSERT INTO alerts (id, author_id, title, description, severity, location, location_name, radius_meters)
-		VALUES ($1, $2, $3, $4, $5, ST_SetSRID(ST_MakePoint($6, $7), 4326)::geography, $8, $9)
-	`, alertID, userID, req.Title, req.Description, req.Severity, req.Longitude, req.Latitude, req.LocationName, req.RadiusMeters)
+	now := time.Now()
+	_, err = tx.Exec(ctx, `
+		INSERT INTO alerts (id, author_id, title, description, severity, location, location_name, radius_meters,
+		                    expires_at, next_escalation_at, required_skills)
+		VALUES ($1, $2, $3, $4, $5, ST_SetSRID(ST_MakePoint($6, $7), 4326)::geography, $8, $9, $10, $11, $12)
+	`, alertID, userID, req.Title, req.Description, req.Severity, req.Longitude, req.Latitude, req.LocationName, req.RadiusMeters,
+		now.Add(alertTTL(req.Severity)), firstEscalation(req.Severity, now), requiredSkills)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create alert"})
 		return
 	}
+	if err := recordTransition(ctx, tx, alertID, timelineCreated, &req.RadiusMeters); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create alert"})
+		return
+	}
 
-	// Send push notifications to users within radius
-	if h.push != nil {
-		go h.push.SendAlertToNearbyUsers(ctx, alertID, userID)
+	// People nearby with a skill the alert asks for get it first; the
+	// worker broadcasts it to everyone else after skillPriorityWindow
+	var skilled []string
+	if len(requiredSkills) > 0 {
+		skilled, err = skilledUsers(ctx, tx, alertID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create alert"})
+			return
+		}
 	}
+	if len(skilled) > 0 {
+		if _, err := tx.Exec(ctx, "UPDATE alerts SET broadcast_at = $2 WHERE id = $1",
+			alertID, now.Add(skillPriorityWindow)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create alert"})
+			return
+		}
+		if err := recordTransition(ctx, tx, alertID, timelineSkillsNotified, nil); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create alert"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create alert"})
+		return
+	}
+
+	// Send push notifications to users within radius
+	go h.fanOut(alertID, userID, skilled, neededLabel(requiredSkills))
 
 	// Publish alert to Redis for real-time notification (WebSocket)
 	h.redis.Publish(ctx, "alerts:new", alertID)
@@ -181,7 +241,8 @@ func (h *Handler) CreateAlert(c *gin.Context) {
 	})
 }
 
-// GetAlert returns a single alert with responses
+// GetAlert returns a single alert. Its author sees who is responding;
+// everyone else only a count blurred like the listings'.
 func (h *Handler) GetAlert(c *gin.Context) {
 	alertID := c.Param("id")
 	userID := c.GetString("user_id")
@@ -192,51 +253,69 @@ func (h *Handler) GetAlert(c *gin.Context) {
 	var severity, radiusMeters int
 	var lat, lon float64
 	var createdAt time.Time
-	var resolvedAt *time.Time
+	var resolvedAt, expiresAt *time.Time
+	var requiredSkills []string
 
 	err := h.db.Pool().QueryRow(ctx, `
 		SELECT a.id, a.author_id, a.title, a.description, a.severity,
 			   ST_Y(a.location::geometry), ST_X(a.location::geometry),
-			   a.location_name, a.radius_meters, a.status, a.created_at, a.resolved_at
+			   a.location_name, a.radius_meters, a.status, a.created_at, a.resolved_at, a.expires_at,
+			   a.required_skills
 		FROM alerts a
 		WHERE a.id = $1
-	`, alertID).Scan(&id, &authorID, &title, &description, &severity, &lat, &lon, &locationName, &radiusMeters, &status, &createdAt, &resolvedAt)
+	`, alertID).Scan(&id, &authorID, &title, &description, &severity, &lat, &lon, &locationName, &radiusMeters, &status, &createdAt, &resolvedAt, &expiresAt,
+		&requiredSkills)
 
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "alert not found"})
 		return
 	}
 
-	// Get responses
+	// Get responses, with the skills coming for the author
 	rows, err := h.db.Pool().Query(ctx, `
-		SELECT ar.user_id, ar.status, ar.eta_minutes, ar.created_at
+		SELECT ar.user_id, ar.status, ar.eta_minutes, ar.created_at, u.skills
 		FROM alert_responses ar
+		JOIN users u ON u.id = ar.user_id
 		WHERE ar.alert_id = $1
 		ORDER BY ar.created_at ASC
 	`, alertID)
 
 	var responses []gin.H
+	skillsEnRoute := map[string]int{}
 	if err == nil {
 		defer rows.Close()
 		for rows.Next() {
 			var respUserID, respStatus string
 			var eta *int
 			var respCreatedAt time.Time
+			var respSkills []string
 
-			if err := rows.Scan(&respUserID, &respStatus, &eta, &respCreatedAt); err == nil {
+			if err := rows.Scan(&respUserID, &respStatus, &eta, &respCreatedAt, &respSkills); err == nil {
 				resp := gin.H{
 					"user_id":    respUserID,
 					"status":     respStatus,
 					"created_at": respCreatedAt,
+					"skills":     respSkills,
 				}
 				if eta != nil {
 					resp["eta_minutes"] = *eta
 				}
+				if respStatus == "en_route" || respStatus == "arrived" {
+					for _, s := range respSkills {
+						skillsEnRoute[s]++
+					}
+				}
 				responses = append(responses, resp)
 			}
 		}
 	}
 
+	timeline, err := h.alertTimeline(ctx, alertID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch alert"})
+		return
+	}
+
 	// Check if current user has responded
 	var userResponse *string
 	h.db.Pool().QueryRow(ctx,
@@ -245,15 +324,23 @@ func (h *Handler) GetAlert(c *gin.Context) {
 	).Scan(&userResponse)
 
 	alert := gin.H{
-		"id":            id,
-		"author_id":     authorID,
-		"title":         title,
-		"severity":      severity,
-		"location":      gin.H{"latitude": lat, "longitude": lon},
-		"radius_meters": radiusMeters,
-		"status":        status,
-		"created_at":    createdAt,
-		"responses":     responses,
+		"id":              id,
+		"author_id":       authorID,
+		"title":           title,
+		"severity":        severity,
+		"location":        gin.H{"latitude": lat, "longitude": lon},
+		"radius_meters":   radiusMeters,
+		"status":          status,
+		"created_at":      createdAt,
+		"timeline":        timeline,
+		"required_skills": requiredSkills,
+	}
+	if authorID == userID {
+		alert["responses"] = responses
+		alert["response_count"] = len(responses)
+		alert["skills_en_route"] = skillsEnRoute
+	} else {
+		counts.FromConfig(h.cfg).Set(alert, "response_count", len(responses), false)
 	}
 
 	if description != nil {
@@ -265,6 +352,9 @@ func (h *Handler) GetAlert(c *gin.Context) {
 	if resolvedAt != nil {
 		alert["resolved_at"] = *resolvedAt
 	}
+	if status == "active" && expiresAt != nil {
+		alert["expires_at"] = *expiresAt
+	}
 	if userResponse != nil {
 		alert["user_response"] = *userResponse
 	}
@@ -287,9 +377,18 @@ func (h *Handler) UpdateAlertStatus(c *gin.Context) {
 		return
 	}
 
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update alert"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
 	// Verify ownership
-	var authorID string
-	err := h.db.Pool().QueryRow(ctx, "SELECT author_id FROM alerts WHERE id = $1", alertID).Scan(&authorID)
+	var authorID, status string
+	var severity int
+	err = tx.QueryRow(ctx, "SELECT author_id, status, severity FROM alerts WHERE id = $1 FOR UPDATE", alertID).
+		Scan(&authorID, &status, &severity)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "alert not found"})
 		return
@@ -298,24 +397,46 @@ func (h *Handler) UpdateAlertStatus(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "only the author can update alert status"})
 		return
 	}
-
-	var resolvedAt interface{}
-	if req.Status == "resolved" || req.Status == "false_alarm" {
-		resolvedAt = time.Now().UTC()
+	if status == req.Status {
+		c.JSON(http.StatusOK, gin.H{"message": "alert status updated", "status": req.Status})
+		return
 	}
 
-	_, err = h.db.Pool().Exec(ctx,
-		"UPDATE alerts SET status = $2, resolved_at = $3 WHERE id = $1",
-		alertID, req.Status, resolvedAt,
-	)
-
+	// Reactivating restarts the TTL and escalation, as for a new alert
+	now := time.Now().UTC()
+	kind := timelineReactivated
+	if req.Status == "active" {
+		_, err = tx.Exec(ctx, `
+			UPDATE alerts SET status = 'active', resolved_at = NULL, expires_at = $2, next_escalation_at = $3
+			WHERE id = $1
+		`, alertID, now.Add(alertTTL(severity)), firstEscalation(severity, now))
+	} else {
+		kind = req.Status // resolved or false_alarm
+		_, err = tx.Exec(ctx,
+			"UPDATE alerts SET status = $2, resolved_at = $3, next_escalation_at = NULL, broadcast_at = NULL WHERE id = $1",
+			alertID, req.Status, now,
+		)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update alert"})
 		return
 	}
+	if err := recordTransition(ctx, tx, alertID, kind, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update alert"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update alert"})
+		return
+	}
 
 	// Notify responders of status change
 	h.redis.Publish(ctx, "alerts:updated", alertID)
+	if req.Status != "active" {
+		if err := h.endLocationShares(ctx, alertID, "", req.Status); err != nil {
+			log.Printf("alerts: %v", err)
+		}
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "alert status updated", "status": req.Status})
 }
@@ -340,7 +461,12 @@ func (h *Handler) RespondToAlert(c *gin.Context) {
 
 	// Verify alert exists and is active
 	var alertStatus string
-	err := h.db.Pool().QueryRow(ctx, "SELECT status FROM alerts WHERE id = $1", alertID).Scan(&alertStatus)
+	var requiredSkills, responderSkills []string
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT a.status, a.required_skills, u.skills
+		FROM alerts a, users u
+		WHERE a.id = $1 AND u.id = $2
+	`, alertID, userID).Scan(&alertStatus, &requiredSkills, &responderSkills)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "alert not found"})
 		return
@@ -377,9 +503,16 @@ func (h *Handler) RespondToAlert(c *gin.Context) {
 		return
 	}
 
+	if endsLocationShare(req.Status) {
+		if err := h.endLocationShares(ctx, alertID, userID, req.Status); err != nil {
+			log.Printf("alerts: %v", err)
+		}
+	}
+
 	// Send push notification to alert author
 	if h.push != nil {
-		go h.push.SendAlertResponseNotification(ctx, alertID, userID)
+		go h.push.SendAlertResponseNotification(context.Background(), alertID, userID,
+			responderLabel(responderSkills, requiredSkills))
 	}
 
 	// Notify via WebSocket too
@@ -390,6 +523,7 @@ func (h *Handler) RespondToAlert(c *gin.Context) {
 
 // GetNearbyAlerts returns active alerts near a location
 func (h *Handler) GetNearbyAlerts(c *gin.Context) {
+	userID := c.GetString("user_id")
 	ctx := c.Request.Context()
 
 	lat, err := strconv.ParseFloat(c.Query("latitude"), 64)
@@ -406,11 +540,12 @@ func (h *Handler) GetNearbyAlerts(c *gin.Context) {
 
 	// Get alerts where user is within broadcast radius
 	rows, err := h.db.Pool().Query(ctx, `
-		SELECT a.id, a.title, a.severity,
+		SELECT a.id, a.author_id, a.title, a.severity,
 			   ST_Y(a.location::geometry) as lat, ST_X(a.location::geometry) as lon,
 			   a.location_name, a.radius_meters, a.created_at,
 			   ST_Distance(a.location, ST_MakePoint($2, $1)::geography) as distance_meters,
-			   (SELECT COUNT(*) FROM alert_responses WHERE alert_id = a.id) as response_count
+			   (SELECT COUNT(*) FROM alert_responses WHERE alert_id = a.id) as response_count,
+			   a.required_skills
 		FROM alerts a
 		WHERE a.status = 'active'
 		  AND ST_DWithin(a.location, ST_MakePoint($2, $1)::geography, a.radius_meters)
@@ -424,15 +559,18 @@ func (h *Handler) GetNearbyAlerts(c *gin.Context) {
 	}
 	defer rows.Close()
 
+	policy := counts.FromConfig(h.cfg)
 	var alerts []gin.H
 	for rows.Next() {
-		var id, title string
+		var id, authorID, title string
 		var locationName *string
 		var severity, radiusMeters, responseCount int
 		var alertLat, alertLon, distance float64
 		var createdAt time.Time
+		var requiredSkills []string
 
-		if err := rows.Scan(&id, &title, &severity, &alertLat, &alertLon, &locationName, &radiusMeters, &createdAt, &distance, &responseCount); err != nil {
+		if err := rows.Scan(&id, &authorID, &title, &severity, &alertLat, &alertLon, &locationName, &radiusMeters, &createdAt, &distance, &responseCount,
+			&requiredSkills); err != nil {
 			continue
 		}
 
@@ -444,8 +582,9 @@ func (h *Handler) GetNearbyAlerts(c *gin.Context) {
 			"radius_meters":   radiusMeters,
 			"distance_meters": int(distance),
 			"created_at":      createdAt,
-			"response_count":  responseCount,
+			"required_skills": requiredSkills,
 		}
+		policy.Set(alert, "response_count", responseCount, authorID == userID)
 
 		if locationName != nil {
 			alert["location_name"] = *locationName
@@ -0,0 +1,400 @@
+package alerts
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kuurier/server/internal/push"
+	"github.com/kuurier/server/internal/storage"
+)
+
+const (
+	// defaultAlertRadius and maxAlertRadius bound an alert's broadcast
+	// radius, in meters. Escalation widens it up to the cap.
+	defaultAlertRadius = 5000
+	maxAlertRadius     = 50000
+
+	// escalateAfter is how long an alert that needs help waits for a
+	// responder before each escalation step.
+	escalateAfter = 10 * time.Minute
+)
+
+// Kinds of alert_timeline entries.
+const (
+	timelineCreated          = "created"
+	timelineResolved         = "resolved"
+	timelineFalseAlarm       = "false_alarm"
+	timelineReactivated      = "reactivated"
+	timelineRadiusWidened    = "radius_widened"
+	timelineContactsNotified = "contacts_notified"
+	timelineExpired          = "expired"
+	timelineSkillsNotified   = "skills_notified"
+	timelineBroadcast        = "broadcast"
+)
+
+// alertTTL is how long an alert of severity stays active without its
+// author resolving it. Emergencies may take longest to play out.
+func alertTTL(severity int) time.Duration {
+	switch severity {
+	case 1:
+		return 6 * time.Hour
+	case 2:
+		return 12 * time.Hour
+	default:
+		return 24 * time.Hour
+	}
+}
+
+// firstEscalation returns when an alert of severity activated at now
+// is first escalated, or nil if it never is: awareness alerts aren't
+// asking for anyone to come.
+func firstEscalation(severity int, now time.Time) *time.Time {
+	if severity < 2 {
+		return nil
+	}
+	at := now.Add(escalateAfter)
+	return &at
+}
+
+// escalationStep returns the next step for an alert of radius no one
+// has responded to: the wider radius, or 0 if it is already at the cap
+// and it's the trusted contacts' turn.
+func escalationStep(radius int) int {
+	if radius >= maxAlertRadius {
+		return 0
+	}
+	return min(radius*2, maxAlertRadius)
+}
+
+// recordTransition adds an entry to alertID's timeline.
+func recordTransition(ctx context.Context, tx pgx.Tx, alertID, kind string, radius *int) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO alert_timeline (alert_id, kind, radius_meters) VALUES ($1, $2, $3)
+	`, alertID, kind, radius)
+	return err
+}
+
+// alertTimeline returns alertID's transitions, oldest first.
+func (h *Handler) alertTimeline(ctx context.Context, alertID string) ([]gin.H, error) {
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT kind, radius_meters, created_at FROM alert_timeline
+		WHERE alert_id = $1
+		ORDER BY created_at, id
+	`, alertID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (gin.H, error) {
+		var kind string
+		var radius *int
+		var at time.Time
+		if err := row.Scan(&kind, &radius, &at); err != nil {
+			return nil, err
+		}
+		entry := gin.H{"kind": kind, "at": at}
+		if radius != nil {
+			entry["radius_meters"] = *radius
+		}
+		return entry, nil
+	})
+}
+
+// Lifecycle is the worker job that moves active alerts along without
+// their author: it resolves alerts past their TTL, ends live location
+// shares past their timeout or for alerts no longer active, broadcasts
+// those skilled responders had first, and escalates those no one has
+// responded to.
+type Lifecycle struct {
+	db    *storage.Postgres
+	redis *storage.Redis
+	push  *push.Service
+}
+
+// NewLifecycle returns a Lifecycle.
+func NewLifecycle(db *storage.Postgres, redis *storage.Redis, pushService *push.Service) *Lifecycle {
+	return &Lifecycle{db: db, redis: redis, push: pushService}
+}
+
+// RunOnce expires, broadcasts and escalates every alert that is due.
+func (j *Lifecycle) RunOnce(ctx context.Context) error {
+	if err := j.expire(ctx); err != nil {
+		return err
+	}
+	if err := j.endLocationShares(ctx); err != nil {
+		return err
+	}
+	if err := j.broadcast(ctx); err != nil {
+		return err
+	}
+	return j.escalate(ctx)
+}
+
+// expire resolves active alerts past their TTL and tells their authors.
+func (j *Lifecycle) expire(ctx context.Context) error {
+	rows, err := j.db.Pool().Query(ctx, `
+		WITH expired AS (
+			UPDATE alerts SET status = 'resolved', resolved_at = NOW(), next_escalation_at = NULL, broadcast_at = NULL
+			WHERE status = 'active' AND expires_at <= NOW()
+			RETURNING id, author_id, title
+		), logged AS (
+			INSERT INTO alert_timeline (alert_id, kind)
+			SELECT id, '`+timelineExpired+`' FROM expired
+		)
+		SELECT id, author_id, title FROM expired
+	`)
+	if err != nil {
+		return fmt.Errorf("expire alerts: %w", err)
+	}
+	type expiredAlert struct{ id, authorID, title string }
+	expired, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (expiredAlert, error) {
+		var a expiredAlert
+		err := row.Scan(&a.id, &a.authorID, &a.title)
+		return a, err
+	})
+	if err != nil {
+		return fmt.Errorf("expire alerts: %w", err)
+	}
+
+	for _, a := range expired {
+		j.redis.Publish(ctx, "alerts:updated", a.id)
+		j.notify(ctx, []string{a.authorID}, a.id, "Alert closed: "+a.title,
+			"Your alert was resolved after going without an update. Reactivate it if you still need help.", "alert_expired")
+	}
+	if len(expired) > 0 {
+		log.Printf("alerts: expired %d alerts", len(expired))
+	}
+	return nil
+}
+
+// endLocationShares deletes live location shares, with their trails,
+// that timed out or whose alert is no longer active. Sharers' clients
+// stop at expires_at on their own; the worker has no connections to
+// tell them.
+func (j *Lifecycle) endLocationShares(ctx context.Context) error {
+	tag, err := j.db.Pool().Exec(ctx, `
+		DELETE FROM alert_location_shares s
+		USING alerts a
+		WHERE a.id = s.alert_id AND (s.expires_at <= NOW() OR a.status <> 'active')
+	`)
+	if err != nil {
+		return fmt.Errorf("end location shares: %w", err)
+	}
+	if n := tag.RowsAffected(); n > 0 {
+		log.Printf("alerts: ended %d location shares", n)
+	}
+	return nil
+}
+
+// broadcast sends alerts whose skilled responders have had them to
+// themselves for skillPriorityWindow to everyone else nearby.
+func (j *Lifecycle) broadcast(ctx context.Context) error {
+	rows, err := j.db.Pool().Query(ctx, `
+		SELECT id, author_id FROM alerts WHERE status = 'active' AND broadcast_at <= NOW()
+	`)
+	if err != nil {
+		return fmt.Errorf("list alerts to broadcast: %w", err)
+	}
+	type dueAlert struct{ id, authorID string }
+	due, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (dueAlert, error) {
+		var a dueAlert
+		err := row.Scan(&a.id, &a.authorID)
+		return a, err
+	})
+	if err != nil {
+		return fmt.Errorf("list alerts to broadcast: %w", err)
+	}
+
+	for _, a := range due {
+		if err := j.broadcastAlert(ctx, a.id, a.authorID); err != nil {
+			log.Printf("alerts: broadcast %s: %v", a.id, err)
+		}
+	}
+	return nil
+}
+
+func (j *Lifecycle) broadcastAlert(ctx context.Context, alertID, authorID string) error {
+	tx, err := j.db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE alerts SET broadcast_at = NULL
+		WHERE id = $1 AND status = 'active' AND broadcast_at <= NOW()
+	`, alertID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	// They were sent it already
+	skilled, err := skilledUsers(ctx, tx, alertID)
+	if err != nil {
+		return err
+	}
+	if err := recordTransition(ctx, tx, alertID, timelineBroadcast, nil); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	if j.push != nil {
+		return j.push.SendAlertToNearbyUsers(ctx, alertID, authorID, skilled...)
+	}
+	return nil
+}
+
+// escalate takes the next escalation step for every active alert
+// that is due for one and has no responders, other than those unable
+// to come.
+func (j *Lifecycle) escalate(ctx context.Context) error {
+	rows, err := j.db.Pool().Query(ctx, `
+		SELECT a.id, a.author_id, a.title, a.radius_meters
+		FROM alerts a
+		WHERE a.status = 'active' AND a.next_escalation_at <= NOW()
+		  AND NOT EXISTS(SELECT 1 FROM alert_responses r
+		                 WHERE r.alert_id = a.id AND r.status <> 'unable')
+	`)
+	if err != nil {
+		return fmt.Errorf("list alerts to escalate: %w", err)
+	}
+	type dueAlert struct {
+		id, authorID, title string
+		radius              int
+	}
+	due, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (dueAlert, error) {
+		var a dueAlert
+		err := row.Scan(&a.id, &a.authorID, &a.title, &a.radius)
+		return a, err
+	})
+	if err != nil {
+		return fmt.Errorf("list alerts to escalate: %w", err)
+	}
+
+	for _, a := range due {
+		var err error
+		if radius := escalationStep(a.radius); radius > 0 {
+			err = j.widen(ctx, a.id, a.authorID, a.title, a.radius, radius)
+		} else {
+			err = j.notifyContacts(ctx, a.id, a.authorID, a.title)
+		}
+		if err != nil {
+			log.Printf("alerts: escalate %s: %v", a.id, err)
+		}
+	}
+	return nil
+}
+
+// widen extends alertID from previousRadius to radius, and broadcasts
+// it to the users in the ring it newly covers.
+func (j *Lifecycle) widen(ctx context.Context, alertID, authorID, title string, previousRadius, radius int) error {
+	tx, err := j.db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// The author may have resolved it since it was listed
+	tag, err := tx.Exec(ctx, `
+		UPDATE alerts SET radius_meters = $2, next_escalation_at = $3
+		WHERE id = $1 AND status = 'active' AND next_escalation_at <= NOW()
+	`, alertID, radius, time.Now().Add(escalateAfter))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	if err := recordTransition(ctx, tx, alertID, timelineRadiusWidened, &radius); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	j.redis.Publish(ctx, "alerts:updated", alertID)
+	if j.push != nil {
+		if err := j.push.SendAlertToWidenedArea(ctx, alertID, authorID, previousRadius); err != nil {
+			log.Printf("alerts: re-broadcast %s: %v", alertID, err)
+		}
+	}
+	j.notify(ctx, []string{authorID}, alertID, "No response yet: "+title,
+		"Your alert now reaches "+strconv.Itoa(radius/1000)+" km around you.", "alert_escalated")
+	return nil
+}
+
+// notifyContacts is the last escalation step: it alerts the author's
+// trusted contacts, the people they vouched for who vouched for them.
+func (j *Lifecycle) notifyContacts(ctx context.Context, alertID, authorID, title string) error {
+	tx, err := j.db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, `
+		UPDATE alerts SET next_escalation_at = NULL
+		WHERE id = $1 AND status = 'active' AND next_escalation_at <= NOW()
+	`, alertID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT v.vouchee_id FROM vouches v
+		JOIN vouches back ON back.voucher_id = v.vouchee_id AND back.vouchee_id = v.voucher_id
+		WHERE v.voucher_id = $1
+	`, authorID)
+	if err != nil {
+		return err
+	}
+	contacts, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	if len(contacts) > 0 {
+		if err := recordTransition(ctx, tx, alertID, timelineContactsNotified, nil); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	if len(contacts) == 0 {
+		return nil
+	}
+
+	j.redis.Publish(ctx, "alerts:updated", alertID)
+	j.notify(ctx, contacts, alertID, "🚨 "+title,
+		"Someone you trust sent an SOS and no one nearby has responded. Tap to help.", "alert")
+	j.notify(ctx, []string{authorID}, alertID, "No response yet: "+title,
+		"Your trusted contacts have been alerted.", "alert_escalated")
+	return nil
+}
+
+func (j *Lifecycle) notify(ctx context.Context, userIDs []string, alertID, title, body, kind string) {
+	if j.push == nil {
+		return
+	}
+	j.push.SendToUsers(ctx, userIDs, push.Notification{
+		Title:    title,
+		Body:     body,
+		Priority: "high",
+		Category: "ALERT",
+		ThreadID: "alert-" + alertID,
+		Data: map[string]string{
+			"type":     kind,
+			"alert_id": alertID,
+		},
+	})
+}
@@ -0,0 +1,38 @@
+package alerts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlertTTL(t *testing.T) {
+	assert.Less(t, alertTTL(1), alertTTL(2))
+	assert.Less(t, alertTTL(2), alertTTL(3))
+}
+
+func TestFirstEscalation(t *testing.T) {
+	now := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+
+	assert.Nil(t, firstEscalation(1, now), "awareness alerts don't escalate")
+	for _, severity := range []int{2, 3} {
+		at := firstEscalation(severity, now)
+		require.NotNil(t, at)
+		assert.Equal(t, now.Add(escalateAfter), *at)
+	}
+}
+
+func TestEscalationStep(t *testing.T) {
+	assert.Equal(t, 10000, escalationStep(defaultAlertRadius))
+	assert.Equal(t, maxAlertRadius, escalationStep(40000), "capped")
+	assert.Equal(t, 0, escalationStep(maxAlertRadius), "contacts once at the cap")
+
+	// From the default, the radius reaches the cap in a few steps
+	steps := 0
+	for r := defaultAlertRadius; r > 0; r = escalationStep(r) {
+		steps++
+	}
+	assert.Equal(t, 5, steps)
+}
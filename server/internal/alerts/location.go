@@ -0,0 +1,329 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kuurier/server/internal/websocket"
+)
+
+const (
+	// liveShareTimeout is how long a responder's live location share
+	// lasts at most. Starting it again once it has ended begins a new
+	// session, with an empty trail.
+	liveShareTimeout = 2 * time.Hour
+
+	// minPointInterval is how often a responder can send their location.
+	minPointInterval = 3 * time.Second
+
+	// shareEndStopped is the reason sent when a responder stops sharing.
+	// Otherwise it's their response status, or the alert's.
+	shareEndStopped = "stopped"
+)
+
+// endsLocationShare reports whether a responder moving to a response
+// status ends their live location share: they're there, or not coming.
+func endsLocationShare(responseStatus string) bool {
+	return responseStatus == "arrived" || responseStatus == "unable"
+}
+
+// StartLocationShareRequest is the request body for starting to share
+// a live location with an alert
+type StartLocationShareRequest struct {
+	ShareWithResponders bool `json:"share_with_responders"`
+}
+
+// StartLocationShare starts sharing the current user's live location
+// with the author of an alert they are responding to and, if they
+// choose, its other responders. Starting an ongoing share only changes
+// who it is shared with.
+// POST /alerts/:id/location-share
+func (h *Handler) StartLocationShare(c *gin.Context) {
+	userID := c.GetString("user_id")
+	alertID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req StartLocationShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var alertStatus string
+	var responseStatus *string
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT a.status, r.status
+		FROM alerts a
+		LEFT JOIN alert_responses r ON r.alert_id = a.id AND r.user_id = $2
+		WHERE a.id = $1
+	`, alertID, userID).Scan(&alertStatus, &responseStatus)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "alert not found"})
+		return
+	}
+	if alertStatus != "active" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "alert is no longer active"})
+		return
+	}
+	if responseStatus == nil || (*responseStatus != "acknowledged" && *responseStatus != "en_route") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only responders on their way can share their location"})
+		return
+	}
+
+	// An ended share that the worker hasn't swept yet is gone for good
+	_, err = h.db.Pool().Exec(ctx, `
+		DELETE FROM alert_location_shares WHERE alert_id = $1 AND user_id = $2 AND expires_at <= NOW()
+	`, alertID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start location share"})
+		return
+	}
+
+	var startedAt, expiresAt time.Time
+	err = h.db.Pool().QueryRow(ctx, `
+		INSERT INTO alert_location_shares (alert_id, user_id, share_with_responders, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (alert_id, user_id) DO UPDATE SET share_with_responders = EXCLUDED.share_with_responders
+		RETURNING started_at, expires_at
+	`, alertID, userID, req.ShareWithResponders, time.Now().Add(liveShareTimeout)).Scan(&startedAt, &expiresAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start location share"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"share_with_responders": req.ShareWithResponders,
+		"started_at":            startedAt,
+		"expires_at":            expiresAt,
+	})
+}
+
+// LocationPointRequest is the request body for a live location update
+type LocationPointRequest struct {
+	Latitude  *float64 `json:"latitude" binding:"required,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"required,min=-180,max=180"`
+}
+
+// UpdateLocationShare records the current user's location in their
+// live share and streams it to whoever it is shared with.
+// POST /alerts/:id/location-share/points
+func (h *Handler) UpdateLocationShare(c *gin.Context) {
+	userID := c.GetString("user_id")
+	alertID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req LocationPointRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var withResponders bool
+	var lastAt *time.Time
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT s.share_with_responders,
+		       (SELECT MAX(p.recorded_at) FROM alert_location_points p
+		        WHERE p.alert_id = s.alert_id AND p.user_id = s.user_id)
+		FROM alert_location_shares s
+		JOIN alerts a ON a.id = s.alert_id
+		WHERE s.alert_id = $1 AND s.user_id = $2 AND s.expires_at > NOW() AND a.status = 'active'
+	`, alertID, userID).Scan(&withResponders, &lastAt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no active location share for this alert"})
+		return
+	}
+	if lastAt != nil && time.Since(*lastAt) < minPointInterval {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "location updated too recently; try again shortly"})
+		return
+	}
+
+	var recordedAt time.Time
+	err = h.db.Pool().QueryRow(ctx, `
+		INSERT INTO alert_location_points (alert_id, user_id, location)
+		VALUES ($1, $2, ST_SetSRID(ST_MakePoint($3, $4), 4326)::geography)
+		RETURNING recorded_at
+	`, alertID, userID, *req.Longitude, *req.Latitude).Scan(&recordedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record location"})
+		return
+	}
+
+	recipients, err := h.locationRecipients(ctx, alertID, userID, withResponders)
+	if err != nil {
+		log.Printf("alerts: location recipients for %s: %v", alertID, err)
+	}
+	h.sendLocationMessage(recipients, websocket.TypeAlertLocation, gin.H{
+		"alert_id":    alertID,
+		"user_id":     userID,
+		"latitude":    *req.Latitude,
+		"longitude":   *req.Longitude,
+		"recorded_at": recordedAt,
+	})
+
+	c.JSON(http.StatusOK, gin.H{"recorded_at": recordedAt})
+}
+
+// StopLocationShare ends the current user's live share, deleting the
+// trail they shared.
+// DELETE /alerts/:id/location-share
+func (h *Handler) StopLocationShare(c *gin.Context) {
+	userID := c.GetString("user_id")
+	alertID := c.Param("id")
+
+	if err := h.endLocationShares(c.Request.Context(), alertID, userID, shareEndStopped); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to stop location share"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "location share stopped"})
+}
+
+// ListLocationShares returns the live shares for an alert the current
+// user can see, with their trails: all of them for its author, and
+// for a responder their own and those shared with responders.
+// GET /alerts/:id/location-shares
+func (h *Handler) ListLocationShares(c *gin.Context) {
+	userID := c.GetString("user_id")
+	alertID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var authorID string
+	var responseStatus *string
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT a.author_id, r.status
+		FROM alerts a
+		LEFT JOIN alert_responses r ON r.alert_id = a.id AND r.user_id = $2
+		WHERE a.id = $1
+	`, alertID, userID).Scan(&authorID, &responseStatus)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "alert not found"})
+		return
+	}
+	isAuthor := authorID == userID
+	if !isAuthor && (responseStatus == nil || *responseStatus == "unable") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the author and responders can see location shares"})
+		return
+	}
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT s.user_id, s.started_at, s.expires_at,
+		       ST_Y(p.location::geometry), ST_X(p.location::geometry), p.recorded_at
+		FROM alert_location_shares s
+		LEFT JOIN alert_location_points p ON p.alert_id = s.alert_id AND p.user_id = s.user_id
+		WHERE s.alert_id = $1 AND s.expires_at > NOW()
+		  AND ($3 OR s.user_id = $2 OR s.share_with_responders)
+		ORDER BY s.started_at, s.user_id, p.recorded_at
+	`, alertID, userID, isAuthor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch location shares"})
+		return
+	}
+	defer rows.Close()
+
+	shares := []gin.H{}
+	var trail []gin.H
+	lastUserID := ""
+	for rows.Next() {
+		var sharerID string
+		var startedAt, expiresAt time.Time
+		var lat, lon *float64
+		var recordedAt *time.Time
+		if err := rows.Scan(&sharerID, &startedAt, &expiresAt, &lat, &lon, &recordedAt); err != nil {
+			continue
+		}
+		if sharerID != lastUserID {
+			trail = []gin.H{}
+			shares = append(shares, gin.H{
+				"user_id":    sharerID,
+				"started_at": startedAt,
+				"expires_at": expiresAt,
+				"trail":      trail,
+			})
+			lastUserID = sharerID
+		}
+		if recordedAt != nil {
+			trail = append(trail, gin.H{"latitude": *lat, "longitude": *lon, "recorded_at": *recordedAt})
+			shares[len(shares)-1]["trail"] = trail
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"shares": shares})
+}
+
+// endLocationShares ends the live shares for alertID, only userID's
+// unless it is "", deleting their trails, and tells everyone who was
+// following them, and the sharers themselves, why.
+func (h *Handler) endLocationShares(ctx context.Context, alertID, userID, reason string) error {
+	rows, err := h.db.Pool().Query(ctx, `
+		DELETE FROM alert_location_shares
+		WHERE alert_id = $1 AND ($2 = '' OR user_id::text = $2)
+		RETURNING user_id, share_with_responders
+	`, alertID, userID)
+	if err != nil {
+		return fmt.Errorf("end location shares: %w", err)
+	}
+	type endedShare struct {
+		userID         string
+		withResponders bool
+	}
+	ended, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (endedShare, error) {
+		var s endedShare
+		err := row.Scan(&s.userID, &s.withResponders)
+		return s, err
+	})
+	if err != nil {
+		return fmt.Errorf("end location shares: %w", err)
+	}
+
+	for _, s := range ended {
+		recipients, err := h.locationRecipients(ctx, alertID, s.userID, s.withResponders)
+		if err != nil {
+			log.Printf("alerts: location recipients for %s: %v", alertID, err)
+		}
+		h.sendLocationMessage(append(recipients, s.userID), websocket.TypeAlertLocationEnd, gin.H{
+			"alert_id": alertID,
+			"user_id":  s.userID,
+			"reason":   reason,
+		})
+	}
+	return nil
+}
+
+// locationRecipients returns who sharerID's live location for alertID
+// goes to: its author, and with withResponders the other responders
+// who haven't said they can't come.
+func (h *Handler) locationRecipients(ctx context.Context, alertID, sharerID string, withResponders bool) ([]string, error) {
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT author_id FROM alerts WHERE id = $1
+		UNION
+		SELECT user_id FROM alert_responses
+		WHERE $3::boolean AND alert_id = $1 AND user_id::text <> $2::text AND status <> 'unable'
+	`, alertID, sharerID, withResponders)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// sendLocationMessage sends a live location message to userIDs over
+// their WebSocket connections. Nothing is queued for those offline:
+// the location is only of use as it happens.
+func (h *Handler) sendLocationMessage(userIDs []string, msgType string, payload gin.H) {
+	if h.hub == nil || len(userIDs) == 0 {
+		return
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	msg := &websocket.Message{Type: msgType, Payload: data, Timestamp: time.Now().UTC()}
+	for _, userID := range userIDs {
+		h.hub.BroadcastToUser(userID, msg)
+	}
+}
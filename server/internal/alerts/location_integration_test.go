@@ -0,0 +1,229 @@
+//go:build integration
+
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/storage"
+	"github.com/kuurier/server/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// locationFixture is an active alert with responders in each status,
+// served by an alerts Handler over a migrated test database.
+type locationFixture struct {
+	t      *testing.T
+	db     *storage.Postgres
+	pool   *pgxpool.Pool
+	h      *Handler
+	router *gin.Engine
+
+	alertID                                                   string
+	author, acknowledged, enRoute, arrived, unable, bystander string
+}
+
+func newLocationFixture(t *testing.T) *locationFixture {
+	t.Helper()
+	db := testutil.NewTestPostgres(t)
+	f := &locationFixture{t: t, db: db, pool: db.Pool()}
+	f.h = NewHandler(&config.Config{}, db, testutil.NewTestRedis(t), nil, nil)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-User-ID"))
+		c.Next()
+	})
+	r.PUT("/alerts/:id/status", f.h.UpdateAlertStatus)
+	r.POST("/alerts/:id/location-share", f.h.StartLocationShare)
+	r.POST("/alerts/:id/location-share/points", f.h.UpdateLocationShare)
+	r.DELETE("/alerts/:id/location-share", f.h.StopLocationShare)
+	r.GET("/alerts/:id/location-shares", f.h.ListLocationShares)
+	f.router = r
+
+	f.author = testutil.CreateUser(t, f.pool, "Author")
+	f.acknowledged = testutil.CreateUser(t, f.pool, "Acknowledged")
+	f.enRoute = testutil.CreateUser(t, f.pool, "En route")
+	f.arrived = testutil.CreateUser(t, f.pool, "Arrived")
+	f.unable = testutil.CreateUser(t, f.pool, "Unable")
+	f.bystander = testutil.CreateUser(t, f.pool, "Bystander")
+	f.alertID = f.alert(f.author)
+	for userID, status := range map[string]string{
+		f.acknowledged: "acknowledged", f.enRoute: "en_route", f.arrived: "arrived", f.unable: "unable",
+	} {
+		f.exec(`INSERT INTO alert_responses (alert_id, user_id, status) VALUES ($1, $2, $3)`, f.alertID, userID, status)
+	}
+	return f
+}
+
+// alert creates an active alert by authorID.
+func (f *locationFixture) alert(authorID string) string {
+	f.t.Helper()
+	var id string
+	require.NoError(f.t, f.pool.QueryRow(context.Background(), `
+		INSERT INTO alerts (author_id, title, severity, location, expires_at)
+		VALUES ($1, 'Help', 3, ST_SetSRID(ST_MakePoint(13.4, 52.5), 4326)::geography, NOW() + INTERVAL '1 hour')
+		RETURNING id
+	`, authorID).Scan(&id))
+	return id
+}
+
+func (f *locationFixture) exec(sql string, args ...any) {
+	f.t.Helper()
+	_, err := f.pool.Exec(context.Background(), sql, args...)
+	require.NoError(f.t, err)
+}
+
+// call makes a request as userID and returns the status and decoded
+// JSON body.
+func (f *locationFixture) call(method, path, userID string, body any) (int, map[string]any) {
+	f.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(f.t, json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", userID)
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+
+	var resp map[string]any
+	if w.Body.Len() > 0 {
+		require.NoError(f.t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+	}
+	return w.Code, resp
+}
+
+// share starts userID's share of alertID, with one point on its trail.
+func (f *locationFixture) share(alertID, userID string, withResponders bool) {
+	f.t.Helper()
+	code, resp := f.call(http.MethodPost, "/alerts/"+alertID+"/location-share", userID,
+		gin.H{"share_with_responders": withResponders})
+	require.Equal(f.t, http.StatusOK, code, resp)
+	code, resp = f.call(http.MethodPost, "/alerts/"+alertID+"/location-share/points", userID,
+		gin.H{"latitude": 52.51, "longitude": 13.41})
+	require.Equal(f.t, http.StatusOK, code, resp)
+}
+
+// count returns how many rows of table belong to alertID.
+func (f *locationFixture) count(table, alertID string) int {
+	f.t.Helper()
+	var n int
+	require.NoError(f.t, f.pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM `+table+` WHERE alert_id = $1`, alertID).Scan(&n))
+	return n
+}
+
+func TestLocationRecipients_AuthorAndRespondersOnly(t *testing.T) {
+	f := newLocationFixture(t)
+	ctx := context.Background()
+
+	recipients, err := f.h.locationRecipients(ctx, f.alertID, f.enRoute, false)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{f.author}, recipients)
+
+	recipients, err = f.h.locationRecipients(ctx, f.alertID, f.enRoute, true)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{f.author, f.acknowledged, f.arrived}, recipients,
+		"not the sharer, those unable to come, or anyone who isn't responding")
+}
+
+func TestLocationShares_OnlyRespondersShareAndSee(t *testing.T) {
+	f := newLocationFixture(t)
+	shares := "/alerts/" + f.alertID + "/location-shares"
+
+	for _, u := range []string{f.bystander, f.unable, f.arrived, f.author} {
+		code, _ := f.call(http.MethodPost, "/alerts/"+f.alertID+"/location-share", u, gin.H{})
+		assert.Equal(t, http.StatusForbidden, code, "only responders on their way share")
+	}
+	f.share(f.alertID, f.enRoute, false)
+	f.share(f.alertID, f.acknowledged, true)
+
+	for _, u := range []string{f.bystander, f.unable} {
+		code, _ := f.call(http.MethodGet, shares, u, nil)
+		assert.Equal(t, http.StatusForbidden, code)
+	}
+
+	sharers := func(userID string) []string {
+		t.Helper()
+		code, resp := f.call(http.MethodGet, shares, userID, nil)
+		require.Equal(t, http.StatusOK, code, resp)
+		var ids []string
+		for _, s := range resp["shares"].([]any) {
+			share := s.(map[string]any)
+			ids = append(ids, share["user_id"].(string))
+			assert.Len(t, share["trail"], 1)
+		}
+		return ids
+	}
+	assert.ElementsMatch(t, []string{f.enRoute, f.acknowledged}, sharers(f.author))
+	assert.ElementsMatch(t, []string{f.acknowledged}, sharers(f.arrived), "shared with responders only")
+	assert.ElementsMatch(t, []string{f.enRoute, f.acknowledged}, sharers(f.enRoute), "and their own")
+}
+
+func TestUpdateAlertStatus_EndsLocationShares(t *testing.T) {
+	f := newLocationFixture(t)
+	f.share(f.alertID, f.enRoute, true)
+	f.share(f.alertID, f.acknowledged, false)
+
+	code, resp := f.call(http.MethodDelete, "/alerts/"+f.alertID+"/location-share", f.acknowledged, nil)
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, 1, f.count("alert_location_shares", f.alertID))
+	assert.Equal(t, 1, f.count("alert_location_points", f.alertID), "stopping deletes the trail")
+
+	code, resp = f.call(http.MethodPut, "/alerts/"+f.alertID+"/status", f.author, gin.H{"status": "resolved"})
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, 0, f.count("alert_location_shares", f.alertID))
+	assert.Equal(t, 0, f.count("alert_location_points", f.alertID))
+
+	code, _ = f.call(http.MethodPost, "/alerts/"+f.alertID+"/location-share/points", f.enRoute,
+		gin.H{"latitude": 52.52, "longitude": 13.42})
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestLifecycle_EndsTimedOutAndInactiveShares(t *testing.T) {
+	f := newLocationFixture(t)
+	resolved := f.alert(f.author)
+	f.exec(`INSERT INTO alert_responses (alert_id, user_id, status) VALUES ($1, $2, 'en_route')`, resolved, f.enRoute)
+
+	f.share(f.alertID, f.enRoute, false)
+	f.share(f.alertID, f.acknowledged, false)
+	f.share(resolved, f.enRoute, false)
+	f.exec(`UPDATE alert_location_shares SET expires_at = NOW() WHERE alert_id = $1 AND user_id = $2`,
+		f.alertID, f.acknowledged)
+	f.exec(`UPDATE alerts SET status = 'resolved', resolved_at = NOW() WHERE id = $1`, resolved)
+
+	require.NoError(t, NewLifecycle(f.db, nil, nil).endLocationShares(context.Background()))
+
+	var remaining []string
+	rows, err := f.pool.Query(context.Background(), `
+		SELECT s.user_id::text FROM alert_location_shares s
+		JOIN alert_location_points p ON p.alert_id = s.alert_id AND p.user_id = s.user_id
+		WHERE s.alert_id = $1
+	`, f.alertID)
+	require.NoError(t, err)
+	for rows.Next() {
+		var id string
+		require.NoError(t, rows.Scan(&id))
+		remaining = append(remaining, id)
+	}
+	require.NoError(t, rows.Err())
+	assert.Equal(t, []string{f.enRoute}, remaining, "the live share keeps its trail")
+	assert.Equal(t, 1, f.count("alert_location_points", f.alertID), "the timed-out trail is gone")
+	assert.Equal(t, 0, f.count("alert_location_shares", resolved))
+	assert.Equal(t, 0, f.count("alert_location_points", resolved))
+}
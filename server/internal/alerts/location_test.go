@@ -0,0 +1,14 @@
+package alerts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEndsLocationShare(t *testing.T) {
+	assert.True(t, endsLocationShare("arrived"))
+	assert.True(t, endsLocationShare("unable"))
+	assert.False(t, endsLocationShare("acknowledged"))
+	assert.False(t, endsLocationShare("en_route"))
+}
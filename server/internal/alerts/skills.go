@@ -0,0 +1,152 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// skills are what users can declare they help with in an emergency,
+// and alerts can ask for, with how they're named in notifications.
+var skills = map[string]string{
+	"medic":          "medic",
+	"legal_observer": "legal observer",
+	"translator":     "translator",
+	"driver":         "driver",
+}
+
+// skillPriorityWindow is how long users with a skill an alert asks for
+// have it to themselves before it is broadcast to everyone nearby.
+const skillPriorityWindow = 2 * time.Minute
+
+// normalizeSkills validates skills, dropping duplicates and sorting
+// them. It never returns nil, so the result always stores as an array.
+func normalizeSkills(in []string) ([]string, error) {
+	normalized := []string{}
+	for _, s := range in {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if _, ok := skills[s]; !ok {
+			return nil, errors.New("unknown skill: " + s)
+		}
+		if !slices.Contains(normalized, s) {
+			normalized = append(normalized, s)
+		}
+	}
+	slices.Sort(normalized)
+	return normalized, nil
+}
+
+// neededLabel names who an alert asking for required is looking for,
+// e.g. "A medic or driver".
+func neededLabel(required []string) string {
+	names := make([]string, len(required))
+	for i, s := range required {
+		names[i] = skills[s]
+	}
+	return "A " + strings.Join(names, " or ")
+}
+
+// responderLabel names a responder by one of their skills, preferring
+// one the alert asks for, e.g. "A medic". It is "" if they have none.
+func responderLabel(responderSkills, required []string) string {
+	for _, s := range responderSkills {
+		if slices.Contains(required, s) {
+			return "A " + skills[s]
+		}
+	}
+	if len(responderSkills) > 0 {
+		return "A " + skills[responderSkills[0]]
+	}
+	return ""
+}
+
+// skilledUsers returns the users who have a skill alertID asks for and
+// follow an area that takes the alert in, other than its author.
+func skilledUsers(ctx context.Context, tx pgx.Tx, alertID string) ([]string, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT u.id FROM users u
+		JOIN alerts a ON a.id = $1
+		WHERE u.id <> a.author_id AND u.skills && a.required_skills
+		  AND EXISTS(SELECT 1 FROM subscriptions sub
+		             WHERE sub.user_id = u.id AND sub.is_active AND sub.location IS NOT NULL
+		               AND ST_DWithin(sub.location, a.location, a.radius_meters))
+	`, alertID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// fanOut pushes a new alert: to skilled, named by label, first if any
+// were found, and otherwise to everyone nearby at once.
+func (h *Handler) fanOut(alertID, authorID string, skilled []string, label string) {
+	if h.push == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var err error
+	if len(skilled) > 0 {
+		err = h.push.SendAlertToSkilledUsers(ctx, alertID, skilled, label)
+	} else {
+		err = h.push.SendAlertToNearbyUsers(ctx, alertID, authorID)
+	}
+	if err != nil {
+		log.Printf("alerts: push %s: %v", alertID, err)
+	}
+}
+
+// GetSkills returns the current user's skills
+// GET /me/skills
+func (h *Handler) GetSkills(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var userSkills []string
+	err := h.db.Pool().QueryRow(c.Request.Context(), "SELECT skills FROM users WHERE id = $1", userID).Scan(&userSkills)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"skills": userSkills})
+}
+
+// SetSkillsRequest is the request body for declaring skills
+type SetSkillsRequest struct {
+	Skills []string `json:"skills" binding:"max=10"`
+}
+
+// SetSkills replaces the current user's skills. They are private:
+// alerts asking for one are routed to the user first, and the author
+// of an alert they respond to sees them.
+// PUT /me/skills
+func (h *Handler) SetSkills(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req SetSkillsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userSkills, err := normalizeSkills(req.Skills)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	_, err = h.db.Pool().Exec(c.Request.Context(), "UPDATE users SET skills = $2 WHERE id = $1", userID, userSkills)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update skills"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"skills": userSkills})
+}
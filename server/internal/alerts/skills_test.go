@@ -0,0 +1,34 @@
+package alerts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeSkills(t *testing.T) {
+	got, err := normalizeSkills([]string{" Medic", "driver", "medic"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"driver", "medic"}, got)
+
+	got, err = normalizeSkills(nil)
+	require.NoError(t, err)
+	assert.NotNil(t, got)
+	assert.Empty(t, got)
+
+	_, err = normalizeSkills([]string{"medic", "astronaut"})
+	assert.Error(t, err)
+}
+
+func TestNeededLabel(t *testing.T) {
+	assert.Equal(t, "A medic", neededLabel([]string{"medic"}))
+	assert.Equal(t, "A legal observer or translator", neededLabel([]string{"legal_observer", "translator"}))
+}
+
+func TestResponderLabel(t *testing.T) {
+	assert.Equal(t, "", responderLabel(nil, []string{"medic"}))
+	assert.Equal(t, "A driver", responderLabel([]string{"driver"}, nil))
+	// A skill the alert asks for names them first
+	assert.Equal(t, "A medic", responderLabel([]string{"driver", "medic"}, []string{"medic"}))
+}
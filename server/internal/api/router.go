@@ -11,6 +11,7 @@ import (
 	"github.com/kuurier/server/internal/config"
 	"github.com/kuurier/server/internal/devices"
 	"github.com/kuurier/server/internal/events"
+	"github.com/kuurier/server/internal/export"
 	"github.com/kuurier/server/internal/feed"
 	"github.com/kuurier/server/internal/geo"
 	"github.com/kuurier/server/internal/invites"
@@ -18,8 +19,12 @@ import (
 	"github.com/kuurier/server/internal/media"
 	"github.com/kuurier/server/internal/messaging"
 	"github.com/kuurier/server/internal/middleware"
+	"github.com/kuurier/server/internal/mutes"
 	"github.com/kuurier/server/internal/push"
+	"github.com/kuurier/server/internal/safety"
+	"github.com/kuurier/server/internal/search"
 	"github.com/kuurier/server/internal/storage"
+	"github.com/kuurier/server/internal/topics"
 	"github.com/kuurier/server/internal/websocket"
 )
 
@@ -33,7 +38,7 @@ type BuildInfo = types.VersionResponse
 // Bot instances are no longer held here — the API process does not run
 // bots. Admin-triggered bot runs are forwarded to the worker process
 // via Redis (see internal/bot/trigger.go).
-func NewRouter(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, minio *storage.MinIO, apns *storage.APNs, build BuildInfo) (*gin.Engine, *websocket.Hub) {
+func NewRouter(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, minio, exports *storage.MinIO, apns *storage.APNs, build BuildInfo) (*gin.Engine, *websocket.Hub) {
 	// Set Gin mode based on environment
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -73,17 +78,25 @@ func NewRouter(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, m
 	messageHandler := messaging.NewMessageHandler(cfg, db)
 	groupHandler := messaging.NewGroupHandler(cfg, db)
 	governanceHandler := messaging.NewGovernanceHandler(cfg, db)
-	feedHandler := feed.NewHandler(cfg, db, redis)
+	feedHandler := feed.NewHandler(cfg, db, redis, pushService)
 	geoHandler := geo.NewHandler(cfg, db, redis)
-	eventsHandler := events.NewHandler(cfg, db, redis)
-	alertsHandler := alerts.NewHandler(cfg, db, redis, pushService)
+	eventsHandler := events.NewHandler(cfg, db, redis, pushService, wsHub)
+	alertsHandler := alerts.NewHandler(cfg, db, redis, pushService, wsHub)
 	devicesHandler := devices.NewHandler(cfg, db)
+	searchHandler := search.NewHandler(cfg, db)
+	mutesHandler := mutes.NewHandler(cfg, db)
+	topicsHandler := topics.NewHandler(cfg, db)
+	safetyHandler := safety.NewHandler(cfg, db, pushService, wsHub)
 
-	// Media handler (optional - requires MinIO)
+	// Media and data export handlers (optional - require MinIO)
 	var mediaHandler *media.Handler
+	var exportHandler *export.Handler
 	if minio != nil {
 		mediaHandler = media.NewHandler(cfg, db, minio)
 	}
+	if exports != nil {
+		exportHandler = export.NewHandler(cfg, db, exports)
+	}
 
 	// API v1 routes
 	v1 := router.Group("/api/v1")
@@ -105,6 +118,10 @@ func NewRouter(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, m
 		// Invite validation (public - needed before registration)
 		v1.GET("/invites/validate/:code", invitesHandler.ValidateInvite)
 
+		// Calendar feeds (public - calendar apps can't send a JWT; the
+		// token in the URL authenticates)
+		v1.GET("/calendar/:token", eventsHandler.ServeCalendarFeed)
+
 		// Protected routes
 		protected := v1.Group("")
 		protected.Use(middleware.Auth(cfg))
@@ -112,8 +129,46 @@ func NewRouter(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, m
 			// User routes
 			protected.GET("/me", authHandler.GetCurrentUser)
 			protected.PUT("/me/display-name", authHandler.SetDisplayName)
+			protected.GET("/me/skills", alertsHandler.GetSkills)
+			protected.PUT("/me/skills", alertsHandler.SetSkills)
 			protected.DELETE("/me", authHandler.DeleteAccount)
 
+			// Personal safety timer
+			safetyRoutes := protected.Group("/me/safety-timer")
+			{
+				safetyRoutes.GET("", safetyHandler.GetTimer)
+				safetyRoutes.POST("", safetyHandler.StartTimer)
+				safetyRoutes.DELETE("", safetyHandler.CancelTimer)
+				safetyRoutes.POST("/extend", safetyHandler.ExtendTimer)
+				safetyRoutes.PUT("/location", safetyHandler.SetTimerLocation)
+				safetyRoutes.DELETE("/location", safetyHandler.ClearTimerLocation)
+			}
+
+			// Personal data export (only if MinIO is configured)
+			if exportHandler != nil {
+				exportRoutes := protected.Group("/me/export")
+				{
+					exportRoutes.GET("", exportHandler.ListExports)                  // List recent exports
+					exportRoutes.POST("", exportHandler.RequestExport)               // Queue a new export
+					exportRoutes.GET("/:id", exportHandler.GetExport)                // Export status
+					exportRoutes.POST("/:id/download", exportHandler.DownloadExport) // One-time download
+				}
+			}
+
+			// Mutes and blocks
+			muteRoutes := protected.Group("/me/mutes")
+			{
+				muteRoutes.GET("", mutesHandler.ListMutes)
+				muteRoutes.POST("", mutesHandler.CreateMute)
+				muteRoutes.DELETE("/:id", mutesHandler.DeleteMute)
+			}
+			blockRoutes := protected.Group("/me/blocks")
+			{
+				blockRoutes.GET("", mutesHandler.ListBlocks)
+				blockRoutes.PUT("/:user_id", mutesHandler.BlockUser)
+				blockRoutes.DELETE("/:user_id", mutesHandler.UnblockUser)
+			}
+
 			// Vouch system (web of trust)
 			protected.POST("/vouch/:user_id", authHandler.Vouch)
 			protected.GET("/vouches", authHandler.GetVouches)
@@ -152,6 +207,7 @@ func NewRouter(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, m
 				orgRoutes.DELETE("/:id", governanceHandler.SafeDeleteOrganization) // Delete organization (with safeguards)
 				orgRoutes.POST("/:id/join", orgHandler.JoinOrganization)           // Join public org
 				orgRoutes.POST("/:id/leave", orgHandler.LeaveOrganization)         // Leave organization
+				orgRoutes.GET("/:id/events", eventsHandler.ListOrgEvents)          // Upcoming events of the org
 
 				// Governance routes
 				orgRoutes.GET("/:id/governance", governanceHandler.GetOrgGovernanceInfo)    // Get governance info
@@ -210,11 +266,21 @@ func NewRouter(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, m
 			{
 				feedRoutes.GET("", feedHandler.GetFeed)
 				feedRoutes.GET("/v2", feedHandler.GetFeedV2)
+				feedRoutes.GET("/v2/new", feedHandler.GetFeedV2New)
 				feedRoutes.POST("/posts", feedHandler.CreatePost)
 				feedRoutes.GET("/posts/:id", feedHandler.GetPost)
+				feedRoutes.PUT("/posts/:id", feedHandler.UpdatePost)
 				feedRoutes.DELETE("/posts/:id", feedHandler.DeletePost)
+				feedRoutes.GET("/posts/:id/revisions", feedHandler.ListPostRevisions)
 				feedRoutes.POST("/posts/:id/verify", feedHandler.VerifyPost)
 				feedRoutes.POST("/posts/:id/flag", feedHandler.FlagPost)
+				feedRoutes.GET("/posts/:id/replies", feedHandler.ListReplies)
+				feedRoutes.POST("/posts/:id/replies", feedHandler.CreateReply)
+				feedRoutes.DELETE("/posts/:id/replies/:reply_id", feedHandler.DeleteReply)
+				feedRoutes.POST("/posts/:id/replies/:reply_id/verify", feedHandler.VerifyReply)
+				feedRoutes.POST("/posts/:id/replies/:reply_id/flag", feedHandler.FlagReply)
+				feedRoutes.GET("/digests", feedHandler.ListDigests)
+				feedRoutes.GET("/digests/:id", feedHandler.GetDigest)
 			}
 
 			// /news is gone — clients should use /feed/v2?type=news.
@@ -244,13 +310,25 @@ func NewRouter(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, m
 			}
 
 			// Topic routes
-			protected.GET("/topics", feedHandler.GetTopics)
+			topicRoutes := protected.Group("/topics")
+			{
+				topicRoutes.GET("", topicsHandler.ListTopics)
+				topicRoutes.GET("/proposals", topicsHandler.ListProposals)
+				topicRoutes.POST("/proposals", topicsHandler.ProposeTopic)
+				topicRoutes.POST("/proposals/:id/endorse", topicsHandler.EndorseProposal)
+				topicRoutes.DELETE("/proposals/:id/endorse", topicsHandler.WithdrawEndorsement)
+				topicRoutes.GET("/:id", topicsHandler.GetTopic)
+			}
+
+			// Full-text search over posts, events, public orgs and topics
+			protected.GET("/search", searchHandler.Search)
 
 			// Map/Geo routes
 			geoRoutes := protected.Group("/map")
 			{
 				geoRoutes.GET("/heatmap", geoHandler.GetHeatmap)
 				geoRoutes.GET("/clusters", geoHandler.GetClusters)
+				geoRoutes.GET("/incidents", geoHandler.GetIncidents)
 				geoRoutes.GET("/nearby", geoHandler.GetNearby)
 			}
 
@@ -261,13 +339,48 @@ func NewRouter(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, m
 				eventRoutes.POST("", eventsHandler.CreateEvent)
 				eventRoutes.GET("/map", eventsHandler.GetPublicEventsForMap) // Public events for map display
 				eventRoutes.GET("/nearby", eventsHandler.GetNearbyEvents)
+				eventRoutes.POST("/import/preview", eventsHandler.PreviewImport) // .ics or CSV, nothing written
+				eventRoutes.POST("/import", eventsHandler.ImportEvents)
 				eventRoutes.GET("/:id", eventsHandler.GetEvent)
+				eventRoutes.GET("/:id/ics", eventsHandler.ExportEventICS)
 				eventRoutes.PUT("/:id", eventsHandler.UpdateEvent)
 				eventRoutes.DELETE("/:id", eventsHandler.DeleteEvent)
 				eventRoutes.POST("/:id/rsvp", eventsHandler.RSVP)
 				eventRoutes.DELETE("/:id/rsvp", eventsHandler.CancelRSVP)
+				eventRoutes.GET("/:id/rsvps", eventsHandler.ListRSVPRequests) // Organizer review of approval-gated RSVPs
+				eventRoutes.PUT("/:id/rsvps/:userId", eventsHandler.DecideRSVP)
+				eventRoutes.GET("/:id/announcements", eventsHandler.ListAnnouncements)
+				eventRoutes.POST("/:id/announcements", eventsHandler.Announce) // Organizer broadcast to attendees
+				eventRoutes.GET("/:id/roles", eventsHandler.ListRoles)
+				eventRoutes.POST("/:id/roles", eventsHandler.CreateRole)
+				eventRoutes.PUT("/:id/roles/:roleId", eventsHandler.UpdateRole)
+				eventRoutes.DELETE("/:id/roles/:roleId", eventsHandler.DeleteRole)
+				eventRoutes.POST("/:id/roles/:roleId/signup", eventsHandler.SignUp)
+				eventRoutes.DELETE("/:id/roles/:roleId/signup", eventsHandler.Withdraw)
+				eventRoutes.PUT("/:id/roles/:roleId/signups/:userId", eventsHandler.DecideSignup) // Organizer approve/decline
+				eventRoutes.GET("/:id/roster", eventsHandler.GetRoster)
+				eventRoutes.GET("/:id/checkin/token", eventsHandler.GetCheckinToken) // Rotating QR code for organizer and role holders
+				eventRoutes.POST("/:id/checkin", eventsHandler.CheckIn)
+				eventRoutes.GET("/:id/crowd", eventsHandler.GetCrowdSize) // k-anonymous headcount
+				eventRoutes.GET("/:id/co-organizers", eventsHandler.ListCoOrganizers)
+				eventRoutes.POST("/:id/co-organizers", eventsHandler.InviteCoOrganizer)
+				eventRoutes.POST("/:id/co-organizers/respond", eventsHandler.RespondToCoOrganizerInvite) // Invitee accepts or declines
+				eventRoutes.DELETE("/:id/co-organizers/:userId", eventsHandler.RemoveCoOrganizer)
+				eventRoutes.POST("/:id/transfer", eventsHandler.RequestEventTransfer) // Hand over as organizer
 			}
 
+			// Calendar feed management (see /calendar/:token above)
+			calendarRoutes := protected.Group("/calendar/feeds")
+			{
+				calendarRoutes.GET("", eventsHandler.ListCalendarFeeds)
+				calendarRoutes.POST("", eventsHandler.CreateCalendarFeed)
+				calendarRoutes.DELETE("/:id", eventsHandler.RevokeCalendarFeed)
+			}
+
+			// Event transfer requests (recipient side)
+			protected.GET("/event-transfers", eventsHandler.ListEventTransfers)
+			protected.POST("/event-transfers/:request_id/respond", eventsHandler.RespondToEventTransfer)
+
 			// Alert routes (SOS system)
 			alertRoutes := protected.Group("/alerts")
 			{
@@ -276,6 +389,10 @@ func NewRouter(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, m
 				alertRoutes.GET("/:id", alertsHandler.GetAlert)
 				alertRoutes.PUT("/:id/status", alertsHandler.UpdateAlertStatus)
 				alertRoutes.POST("/:id/respond", alertsHandler.RespondToAlert)
+				alertRoutes.POST("/:id/location-share", alertsHandler.StartLocationShare)
+				alertRoutes.POST("/:id/location-share/points", alertsHandler.UpdateLocationShare)
+				alertRoutes.DELETE("/:id/location-share", alertsHandler.StopLocationShare)
+				alertRoutes.GET("/:id/location-shares", alertsHandler.ListLocationShares)
 				alertRoutes.GET("/nearby", alertsHandler.GetNearbyAlerts)
 			}
 
@@ -310,6 +427,14 @@ func NewRouter(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, m
 				adminRoutes.GET("/bot/worker-status", botHandler.WorkerStatus)
 				adminRoutes.GET("/bot/runs", botHandler.GetRunHistory)
 				adminRoutes.GET("/bot/articles", botHandler.GetPostedArticles)
+
+				adminRoutes.POST("/topics", topicsHandler.CreateTopic)
+				adminRoutes.PUT("/topics/:id", topicsHandler.UpdateTopic)
+				adminRoutes.DELETE("/topics/:id", topicsHandler.ArchiveTopic)
+				adminRoutes.PUT("/topics/:id/names/:lang", topicsHandler.SetTopicName)
+				adminRoutes.DELETE("/topics/:id/names/:lang", topicsHandler.DeleteTopicName)
+				adminRoutes.POST("/topics/proposals/:id/approve", topicsHandler.ApproveProposal)
+				adminRoutes.POST("/topics/proposals/:id/reject", topicsHandler.RejectProposal)
 			}
 
 			// WebSocket endpoint for real-time messaging
@@ -33,23 +33,45 @@ type FeedV2Response struct {
 	Limit      int          `json:"limit"`
 	Offset     int          `json:"offset"`
 	NextOffset int          `json:"next_offset"` // -1 when no more pages
+	// NextCursor continues this listing on the same ranking epoch;
+	// pass it back as ?cursor=. Empty when there are no more pages.
+	// Preferred over NextOffset, which can repeat or skip items.
+	NextCursor string `json:"next_cursor,omitempty"`
 	// Source identifies whether the result came from the precomputed
 	// materialized_feeds table or the live ranking path. Optional —
 	// only set when FEED_MATERIALIZED is on and a hit occurred.
 	Source string `json:"source,omitempty"`
 }
 
-// FeedV2Item is one entry in a feed response. All items are posts;
-// news articles appear as posts with source_type='mainstream' since
-// Phase 4.
+// FeedV2NewResponse is the body returned by GET /api/v1/feed/v2/new,
+// the pull-to-refresh endpoint: items that arrived after the listing
+// behind ?cursor= started, ranked for the same feed.
+type FeedV2NewResponse struct {
+	Items   []FeedV2Item `json:"items"`
+	Count   int          `json:"count"` // total new items, may exceed len(Items)
+	HasMore bool         `json:"has_more"`
+	// Cursor marks "now"; pass it on the next refresh. It is only
+	// valid for /feed/v2/new, not for paging /feed/v2.
+	Cursor string `json:"cursor"`
+}
+
+// FeedV2Item is one entry in a feed response. Almost all items are
+// posts; news articles appear as posts with source_type='mainstream'
+// since Phase 4. Posts that belong to an incident are collapsed into
+// one "incident" item. The first page of for_you/following may start
+// with one "digest" item summarising the newest unread subscription
+// digest.
 type FeedV2Item struct {
-	ID   string     `json:"id"`
-	Type string     `json:"type"` // currently always "post"
-	Post *PostBody  `json:"post,omitempty"`
-	Why  []string   `json:"why,omitempty"`
+	ID       string        `json:"id"`
+	Type     string        `json:"type"` // post | incident | digest
+	Post     *PostBody     `json:"post,omitempty"`
+	Incident *IncidentBody `json:"incident,omitempty"`
+	Digest   *DigestBody   `json:"digest,omitempty"`
+	Why      []string      `json:"why,omitempty"`
 }
 
-// PostBody is a post in a feed response.
+// PostBody is a post in a feed response. EditedAt is set once the
+// author has edited it; the versions are at .../posts/:id/revisions.
 type PostBody struct {
 	ID                string     `json:"id"`
 	AuthorID          string     `json:"author_id"`
@@ -58,11 +80,48 @@ type PostBody struct {
 	Urgency           int        `json:"urgency"`
 	CreatedAt         time.Time  `json:"created_at"`
 	VerificationScore int        `json:"verification_score"`
+	ReplyCount        int        `json:"reply_count"`
+	Corroborations    int        `json:"corroborations"` // distinct users confirming it from the scene
+	EditedAt          *time.Time `json:"edited_at,omitempty"`
 	Location          *LatLng    `json:"location,omitempty"`
 	LocationName      string     `json:"location_name,omitempty"`
 	Media             []Media    `json:"media,omitempty"`
 }
 
+// IncidentBody is a group of posts by different members about the
+// same thing at the same place and time, e.g. a kettle reported five
+// times. LeadPost is the member ranked highest on this page; PostIDs
+// lists every member, so clients can skip them on later pages.
+type IncidentBody struct {
+	ID             string    `json:"id"`
+	PostCount      int       `json:"post_count"`
+	Corroborations int       `json:"corroborations"` // distinct authors
+	Confidence     float64   `json:"confidence"`     // combined, 0-1
+	Urgency        int       `json:"urgency"`        // highest among members
+	Location       LatLng    `json:"location"`       // centroid
+	RadiusMeters   int       `json:"radius_meters"`
+	LocationName   string    `json:"location_name,omitempty"`
+	TopicIDs       []string  `json:"topic_ids"`
+	PostIDs        []string  `json:"post_ids"`
+	FirstSeenAt    time.Time `json:"first_seen_at"`
+	LastSeenAt     time.Time `json:"last_seen_at"`
+	LeadPost       *PostBody `json:"lead_post"`
+}
+
+// DigestBody summarises a daily/weekly subscription digest. The full
+// list of items is at GET /api/v1/feed/digests/:id.
+type DigestBody struct {
+	ID          string    `json:"id"`
+	DigestMode  string    `json:"digest_mode"` // daily | weekly
+	Title       string    `json:"title"`
+	Summary     string    `json:"summary"`
+	PostCount   int       `json:"post_count"`
+	EventCount  int       `json:"event_count"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
 type LatLng struct {
 	Latitude  float64 `json:"latitude"`
 	Longitude float64 `json:"longitude"`
@@ -74,3 +133,446 @@ type Media struct {
 	Type      string    `json:"type"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// DataExportStatus is the body returned by POST /api/v1/me/export and
+// GET /api/v1/me/export/:id. POST /me/export/:id/download returns the
+// archive itself, once.
+type DataExportStatus struct {
+	ID           string     `json:"id"`
+	Status       string     `json:"status"` // pending | processing | ready | failed | expired
+	CreatedAt    time.Time  `json:"created_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	DownloadedAt *time.Time `json:"downloaded_at,omitempty"`
+	SizeBytes    int64      `json:"size_bytes,omitempty"`
+	SHA256       string     `json:"sha256,omitempty"`
+}
+
+// DataExportArchive is data.json inside a personal data export zip.
+// The zip also carries signature.json (DataExportSignature) so the
+// server can later prove the archive is unmodified.
+//
+// Every list is present (possibly empty) so clients can rely on the
+// keys. Message bodies are exported as the ciphertext the server
+// stores; the server has never seen the plaintext.
+type DataExportArchive struct {
+	SchemaVersion   int                   `json:"schema_version"`
+	ExportID        string                `json:"export_id"`
+	GeneratedAt     time.Time             `json:"generated_at"`
+	Account         ExportAccount         `json:"account"`
+	VouchesGiven    []ExportVouch         `json:"vouches_given"`
+	VouchesReceived []ExportVouch         `json:"vouches_received"`
+	Invites         []ExportInvite        `json:"invites"`
+	Subscriptions   []ExportSubscription  `json:"subscriptions"`
+	Digests         []ExportDigest        `json:"digests"`
+	DigestRuns      []ExportDigestRun     `json:"digest_runs"`
+	Mutes           []ExportMute          `json:"mutes"`
+	Blocks          []ExportBlock         `json:"blocks"`
+	TopicProposals  []ExportTopicProposal `json:"topic_proposals"`
+	Posts           []ExportPost          `json:"posts"`
+	Replies         []ExportReply         `json:"replies"`
+	Events          []ExportEvent         `json:"events"`
+	RSVPs           []ExportRSVP          `json:"rsvps"`
+	Announcements   []ExportAnnouncement  `json:"event_announcements"`
+	RoleSignups     []ExportRoleSignup    `json:"event_role_signups"`
+	CoOrganizing    []ExportCoOrganizer   `json:"event_co_organizing"`
+	Alerts          []ExportAlert         `json:"alerts"`
+	AlertResponses  []ExportAlertResponse `json:"alert_responses"`
+	SafetyTimers    []ExportSafetyTimer   `json:"safety_timers"`
+	Devices         []ExportDevice        `json:"devices"`
+	PushTokens      []ExportPushToken     `json:"push_tokens"`
+	CalendarFeeds   []ExportCalendarFeed  `json:"calendar_feeds"`
+	Messages        []ExportMessage       `json:"messages"`
+}
+
+// DataExportSignature is signature.json inside a personal data export.
+// Signature is HMAC-SHA256 over the exact bytes of data.json, keyed
+// with a server secret; SHA256 lets anyone check integrity offline.
+type DataExportSignature struct {
+	Algorithm   string    `json:"algorithm"` // "HMAC-SHA256"
+	ExportID    string    `json:"export_id"`
+	UserID      string    `json:"user_id"`
+	GeneratedAt time.Time `json:"generated_at"`
+	SHA256      string    `json:"sha256"`
+	Signature   string    `json:"signature"`
+}
+
+// ExportAccount is the user's own row from the users table.
+type ExportAccount struct {
+	ID             string     `json:"id"`
+	PublicKey      string     `json:"public_key"` // base64
+	DisplayName    *string    `json:"display_name"`
+	TrustScore     int        `json:"trust_score"`
+	IsVerified     bool       `json:"is_verified"`
+	IsAdmin        bool       `json:"is_admin"`
+	InvitedBy      *string    `json:"invited_by"`
+	InviteCodeUsed *string    `json:"invite_code_used"`
+	Skills         []string   `json:"skills"`
+	CreatedAt      time.Time  `json:"created_at"`
+	LastActiveAt   *time.Time `json:"last_active_at"`
+}
+
+// ExportVouch is one edge of the web of trust. UserID is the other
+// party: the vouchee for vouches_given, the voucher for vouches_received.
+type ExportVouch struct {
+	UserID    string    `json:"user_id"`
+	VouchType string    `json:"vouch_type"` // invite | manual
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportInvite struct {
+	Code      string     `json:"code"`
+	InviteeID *string    `json:"invitee_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+}
+
+type ExportSubscription struct {
+	ID           string    `json:"id"`
+	TopicSlug    *string   `json:"topic_slug"`
+	Location     *LatLng   `json:"location"`
+	RadiusMeters *int      `json:"radius_meters"`
+	MinUrgency   int       `json:"min_urgency"`
+	DigestMode   string    `json:"digest_mode"`
+	IsActive     bool      `json:"is_active"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ExportDigest is a daily or weekly digest delivered to the user.
+// Items are referenced by ID, posts ranked best first and events
+// soonest first.
+type ExportDigest struct {
+	ID          string     `json:"id"`
+	DigestMode  string     `json:"digest_mode"` // daily | weekly
+	PeriodStart time.Time  `json:"period_start"`
+	PeriodEnd   time.Time  `json:"period_end"`
+	PostIDs     []string   `json:"post_ids"`
+	EventIDs    []string   `json:"event_ids"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ReadAt      *time.Time `json:"read_at"`
+}
+
+// ExportDigestRun is when the user's digest of a mode was last put
+// together, whether or not it found anything to deliver.
+type ExportDigestRun struct {
+	DigestMode string    `json:"digest_mode"`
+	LastRunAt  time.Time `json:"last_run_at"`
+}
+
+// ExportMute is something the user muted. Value is a user ID, topic
+// ID, source type or keyword depending on Type.
+type ExportMute struct {
+	Type      string     `json:"type"`
+	Value     string     `json:"value"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// ExportBlock is a user the user blocked.
+type ExportBlock struct {
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ExportTopicProposal is a topic proposal the user made or endorsed.
+type ExportTopicProposal struct {
+	ID         string     `json:"id"`
+	Slug       string     `json:"slug"`
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	Proposed   bool       `json:"proposed"`
+	EndorsedAt *time.Time `json:"endorsed_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type ExportPost struct {
+	ID                string     `json:"id"`
+	Content           string     `json:"content"`
+	SourceType        string     `json:"source_type"`
+	Urgency           int        `json:"urgency"`
+	Location          *LatLng    `json:"location"`
+	LocationName      *string    `json:"location_name"`
+	Topics            []string   `json:"topics"` // slugs
+	VerificationScore int        `json:"verification_score"`
+	IsFlagged         bool       `json:"is_flagged"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ExpiresAt         *time.Time `json:"expires_at"`
+	EditedAt          *time.Time `json:"edited_at"`
+	Media             []Media    `json:"media"`
+	// Revisions are the versions replaced by edits, oldest first.
+	Revisions []ExportPostRevision `json:"revisions"`
+}
+
+// ExportPostRevision is an earlier version of one of the user's posts.
+type ExportPostRevision struct {
+	Revision     int       `json:"revision"`
+	Content      string    `json:"content"`
+	Location     *LatLng   `json:"location"`
+	LocationName *string   `json:"location_name"`
+	Urgency      int       `json:"urgency"`
+	CreatedAt    time.Time `json:"created_at"`
+	ReplacedAt   time.Time `json:"replaced_at"`
+}
+
+// ExportReply is a reply the user wrote. Deleted replies that were
+// kept as placeholders appear with their blanked content.
+type ExportReply struct {
+	ID                string     `json:"id"`
+	PostID            string     `json:"post_id"`
+	ParentID          *string    `json:"parent_id"`
+	Content           string     `json:"content"`
+	IsFirsthand       bool       `json:"is_firsthand"`
+	Corroborates      bool       `json:"corroborates"`
+	VerificationScore int        `json:"verification_score"`
+	IsFlagged         bool       `json:"is_flagged"`
+	CreatedAt         time.Time  `json:"created_at"`
+	DeletedAt         *time.Time `json:"deleted_at"`
+}
+
+// ExportEvent is an event the user organizes. Location is always
+// included — the organizer is entitled to their own event's location.
+type ExportEvent struct {
+	ID                 string     `json:"id"`
+	Title              string     `json:"title"`
+	Description        *string    `json:"description"`
+	EventType          string     `json:"event_type"`
+	Location           LatLng     `json:"location"`
+	LocationName       *string    `json:"location_name"`
+	LocationArea       *string    `json:"location_area"`
+	LocationVisibility string     `json:"location_visibility"`
+	LocationRevealAt   *time.Time `json:"location_reveal_at"`
+	StartsAt           time.Time  `json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at"`
+	IsCancelled        bool       `json:"is_cancelled"`
+	Recurrence         *string    `json:"recurrence"`
+	Timezone           string     `json:"timezone"`
+	OrgID              *string    `json:"org_id"`
+	Accessibility      []string   `json:"accessibility"`
+	RiskLevel          *string    `json:"risk_level"`
+	LegalHotline       *string    `json:"legal_hotline"`
+	BustCard           *string    `json:"bust_card"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+// ExportRSVP references the event by ID and title only; the event's
+// location is not included unless the user organizes it.
+type ExportRSVP struct {
+	EventID      string     `json:"event_id"`
+	EventTitle   string     `json:"event_title"`
+	OccurrenceAt *time.Time `json:"occurrence_at"`
+	Status       string     `json:"status"`
+	Approval     string     `json:"approval"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ExportRoleSignup is the user's sign-up for a volunteer role at an event.
+type ExportRoleSignup struct {
+	EventID      string     `json:"event_id"`
+	EventTitle   string     `json:"event_title"`
+	Role         string     `json:"role"`
+	OccurrenceAt *time.Time `json:"occurrence_at"`
+	Status       string     `json:"status"`
+	Note         *string    `json:"note"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// ExportCoOrganizer is an event the user co-organizes or was invited
+// to co-organize.
+type ExportCoOrganizer struct {
+	EventID    string     `json:"event_id"`
+	EventTitle string     `json:"event_title"`
+	Status     string     `json:"status"`
+	InvitedAt  time.Time  `json:"invited_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+}
+
+// ExportAnnouncement is an announcement the user posted to attendees
+// of an event they organize.
+type ExportAnnouncement struct {
+	ID           string     `json:"id"`
+	EventID      string     `json:"event_id"`
+	OccurrenceAt *time.Time `json:"occurrence_at"`
+	Body         string     `json:"body"`
+	IsUrgent     bool       `json:"is_urgent"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type ExportAlert struct {
+	ID             string     `json:"id"`
+	Title          string     `json:"title"`
+	Description    *string    `json:"description"`
+	Severity       int        `json:"severity"`
+	Location       LatLng     `json:"location"`
+	LocationName   *string    `json:"location_name"`
+	RadiusMeters   int        `json:"radius_meters"`
+	RequiredSkills []string   `json:"required_skills"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ResolvedAt     *time.Time `json:"resolved_at"`
+}
+
+type ExportAlertResponse struct {
+	AlertID    string    `json:"alert_id"`
+	Status     string    `json:"status"`
+	ETAMinutes *int      `json:"eta_minutes"`
+	Location   *LatLng   `json:"location"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ExportSafetyTimer is the user's running or fired safety timer.
+// LastLocation is the location they last chose to share with it.
+type ExportSafetyTimer struct {
+	ID             string                     `json:"id"`
+	Deadline       time.Time                  `json:"deadline"`
+	Status         string                     `json:"status"` // active | fired
+	LastLocation   *LatLng                    `json:"last_location"`
+	LastLocationAt *time.Time                 `json:"last_location_at"`
+	Contacts       []ExportSafetyTimerContact `json:"contacts"`
+	CreatedAt      time.Time                  `json:"created_at"`
+	FiredAt        *time.Time                 `json:"fired_at"`
+}
+
+// ExportSafetyTimerContact is someone the timer alerts. EncryptedNote
+// is base64 of the note as the client encrypted it for that contact,
+// and is null once the timer has fired.
+type ExportSafetyTimerContact struct {
+	UserID        string `json:"user_id"`
+	EncryptedNote []byte `json:"encrypted_note"`
+}
+
+type ExportDevice struct {
+	ID           string     `json:"id"`
+	DeviceType   string     `json:"device_type"`
+	DeviceName   *string    `json:"device_name"`
+	IsActive     bool       `json:"is_active"`
+	CreatedAt    time.Time  `json:"created_at"`
+	LastActiveAt *time.Time `json:"last_active_at"`
+}
+
+// ExportPushToken describes a registered push token without the
+// token itself: a leaked export must not let anyone push to the device.
+type ExportPushToken struct {
+	ID          string    `json:"id"`
+	Platform    string    `json:"platform"`
+	TokenSuffix string    `json:"token_suffix"` // last 6 characters
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ExportCalendarFeed is a calendar feed URL the user created. The
+// token itself is only stored hashed.
+type ExportCalendarFeed struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	TargetID   *string    `json:"target_id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// ExportMessage is a message the user sent. Ciphertext is base64 of
+// the stored Signal Protocol payload.
+type ExportMessage struct {
+	ID          string     `json:"id"`
+	ChannelID   string     `json:"channel_id"`
+	MessageType string     `json:"message_type"`
+	Ciphertext  []byte     `json:"ciphertext"`
+	ReplyToID   *string    `json:"reply_to_id"`
+	CreatedAt   time.Time  `json:"created_at"`
+	EditedAt    *time.Time `json:"edited_at"`
+	DeletedAt   *time.Time `json:"deleted_at"`
+}
+
+// SearchResponse is the body returned by GET /api/v1/search. Each
+// list is present (possibly empty) and ranked best first; types that
+// weren't requested, or that can't satisfy a filter (organizations
+// have no location, for example), come back empty.
+type SearchResponse struct {
+	Query         string               `json:"query"`
+	Posts         []SearchPost         `json:"posts"`
+	Events        []SearchEvent        `json:"events"`
+	Organizations []SearchOrganization `json:"organizations"`
+	Topics        []SearchTopic        `json:"topics"`
+	Limit         int                  `json:"limit"`
+	Offset        int                  `json:"offset"`
+}
+
+// SearchPost is a post search hit. Score combines text relevance
+// with the same confidence score the feed uses.
+type SearchPost struct {
+	ID           string    `json:"id"`
+	AuthorID     string    `json:"author_id"`
+	Content      string    `json:"content"`
+	SourceType   string    `json:"source_type"`
+	Location     *LatLng   `json:"location,omitempty"`
+	LocationName *string   `json:"location_name,omitempty"`
+	Urgency      int       `json:"urgency"`
+	CreatedAt    time.Time `json:"created_at"`
+	Headline     string    `json:"headline"` // content excerpt with matches wrapped in <b></b>
+	Score        float64   `json:"score"`
+}
+
+// SearchEvent is an event search hit. Location follows the same
+// visibility rules as GET /events/:id; LocationArea is shown instead
+// while the exact location is hidden.
+type SearchEvent struct {
+	ID                 string     `json:"id"`
+	Title              string     `json:"title"`
+	EventType          string     `json:"event_type"`
+	StartsAt           time.Time  `json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at,omitempty"`
+	LocationVisibility string     `json:"location_visibility"`
+	LocationRevealed   bool       `json:"location_revealed"`
+	Location           *LatLng    `json:"location,omitempty"`
+	LocationName       *string    `json:"location_name,omitempty"`
+	LocationArea       *string    `json:"location_area,omitempty"`
+	Headline           string     `json:"headline"`
+	Score              float64    `json:"score"`
+}
+
+type SearchOrganization struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	AvatarURL   *string `json:"avatar_url,omitempty"`
+	MemberCount int     `json:"member_count"`
+	Score       float64 `json:"score"`
+}
+
+type SearchTopic struct {
+	ID    string  `json:"id"`
+	Slug  string  `json:"slug"`
+	Name  string  `json:"name"`
+	Icon  *string `json:"icon,omitempty"`
+	Score float64 `json:"score"`
+}
+
+// PostRevisionsResponse is the body returned by
+// GET /api/v1/feed/posts/:id/revisions: every version of the post,
+// oldest first, the last one being the current post.
+type PostRevisionsResponse struct {
+	PostID    string         `json:"post_id"`
+	Revisions []PostRevision `json:"revisions"`
+}
+
+// PostRevision is one version of an edited post. Changes and
+// ContentDiff compare it with the version before; both are empty for
+// the original. ReplacedAt is nil for the current version.
+type PostRevision struct {
+	Revision          int        `json:"revision"`
+	Content           string     `json:"content"`
+	Location          *LatLng    `json:"location,omitempty"`
+	LocationName      *string    `json:"location_name,omitempty"`
+	Urgency           int        `json:"urgency"`
+	VerificationScore int        `json:"verification_score"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ReplacedAt        *time.Time `json:"replaced_at,omitempty"`
+	Changes           []string   `json:"changes"` // content | location | location_name | urgency
+	ContentDiff       []DiffSpan `json:"content_diff"`
+}
+
+// DiffSpan is a run of words in a content diff.
+type DiffSpan struct {
+	Op   string `json:"op"` // equal | insert | delete
+	Text string `json:"text"`
+}
@@ -374,11 +374,12 @@ func (h *Handler) GetCurrentUser(c *gin.Context) {
 	var isVerified bool
 	var createdAt time.Time
 	var displayName *string
+	var skills []string
 
 	err := h.db.Pool().QueryRow(ctx,
-		"SELECT trust_score, is_verified, created_at, display_name FROM users WHERE id = $1",
+		"SELECT trust_score, is_verified, created_at, display_name, skills FROM users WHERE id = $1",
 		userID,
-	).Scan(&trustScore, &isVerified, &createdAt, &displayName)
+	).Scan(&trustScore, &isVerified, &createdAt, &displayName, &skills)
 
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
@@ -399,6 +400,7 @@ func (h *Handler) GetCurrentUser(c *gin.Context) {
 		"created_at":   createdAt,
 		"vouch_count":  vouchCount,
 		"display_name": displayName,
+		"skills":       skills,
 	})
 }
 
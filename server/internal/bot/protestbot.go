@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/kuurier/server/internal/recurrence"
 	"github.com/kuurier/server/internal/storage"
 )
 
@@ -402,25 +403,29 @@ func resolveStartTime(p scrapedProtest, now time.Time) time.Time {
 		}
 	}
 
-	// Recurring: find the next occurrence of the given weekday
-	if p.Recurrent && p.RecurDay != "" {
-		targetDay := parseDayOfWeek(p.RecurDay)
-		if targetDay < 0 {
-			return time.Time{}
-		}
-
-		// Find the next occurrence (today or later)
-		candidate := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, time.UTC)
-		daysUntil := (int(targetDay) - int(candidate.Weekday()) + 7) % 7
-		if daysUntil == 0 && candidate.Before(now) {
-			daysUntil = 7
-		}
-		return candidate.Add(time.Duration(daysUntil) * 24 * time.Hour)
+	// Recurring: the next occurrence of the weekly rule
+	if rule, ok := recurrenceRule(p); ok {
+		anchor := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, time.UTC)
+		next, _ := rule.Next(anchor, now)
+		return next
 	}
 
 	return time.Time{}
 }
 
+// recurrenceRule is the weekly rule of a recurring protest. The event
+// is created once with this rule instead of once per week.
+func recurrenceRule(p scrapedProtest) (recurrence.Rule, bool) {
+	if !p.Recurrent || p.RecurDay == "" {
+		return recurrence.Rule{}, false
+	}
+	day := parseDayOfWeek(p.RecurDay)
+	if day < 0 {
+		return recurrence.Rule{}, false
+	}
+	return recurrence.WeeklyOn(day), true
+}
+
 func parseTime(s string) (int, int) {
 	var h, m int
 	if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil {
@@ -430,24 +435,11 @@ func parseTime(s string) (int, int) {
 }
 
 func parseDayOfWeek(s string) time.Weekday {
-	switch strings.ToLower(strings.TrimSpace(s)) {
-	case "sunday":
-		return time.Sunday
-	case "monday":
-		return time.Monday
-	case "tuesday":
-		return time.Tuesday
-	case "wednesday":
-		return time.Wednesday
-	case "thursday":
-		return time.Thursday
-	case "friday":
-		return time.Friday
-	case "saturday":
-		return time.Saturday
-	default:
+	day, ok := recurrence.ParseWeekday(s)
+	if !ok {
 		return -1
 	}
+	return day
 }
 
 // ── Deduplication ──────────────────────────────────────────────────────
@@ -537,13 +529,19 @@ func (b *ProtestBot) createEvent(ctx context.Context, p scrapedProtest) error {
 
 	locationSQL := fmt.Sprintf("SRID=4326;POINT(%f %f)", p.Lng, p.Lat)
 
+	var rrule *string
+	if rule, ok := recurrenceRule(p); ok {
+		s := rule.String()
+		rrule = &s
+	}
+
 	// Insert event — bot user is the organizer, location is always public
 	_, err := b.db.Pool().Exec(ctx,
 		`INSERT INTO events (id, organizer_id, title, description, event_type, location, location_name,
-		                     location_area, location_visibility, starts_at)
-		 VALUES ($1, $2, $3, $4, 'protest', ST_GeogFromText($5), $6, $7, 'public', $8)`,
+		                     location_area, location_visibility, starts_at, recurrence_rule)
+		 VALUES ($1, $2, $3, $4, 'protest', ST_GeogFromText($5), $6, $7, 'public', $8, $9)`,
 		eventID, BotUserID, p.Title, desc.String(), locationSQL, locationName,
-		formatLocationArea(p.City, p.State, p.Country), p.StartsAt,
+		formatLocationArea(p.City, p.State, p.Country), p.StartsAt, rrule,
 	)
 	if err != nil {
 		return fmt.Errorf("insert event: %w", err)
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Config holds all configuration for the server
@@ -29,11 +30,12 @@ type Config struct {
 	RedisURL string
 
 	// MinIO (object storage)
-	MinIOEndpoint  string
-	MinIOAccessKey string
-	MinIOSecretKey string
-	MinIOBucket    string
-	MinIOUseSSL    bool
+	MinIOEndpoint     string
+	MinIOAccessKey    string
+	MinIOSecretKey    string
+	MinIOBucket       string
+	MinIOExportBucket string // Private; personal data exports
+	MinIOUseSSL       bool
 
 	// Security
 	JWTSecret      []byte
@@ -50,8 +52,17 @@ type Config struct {
 	APNsBundleID   string
 	APNsProduction bool
 
+	// Event reminders: how long before an event RSVPs are reminded
+	EventReminderOffsets []time.Duration
+
+	// Public counts: RSVP and alert response counts shown to anyone but
+	// the organizers are "fewer than" the threshold, or rounded down to
+	// the bucket (see internal/counts)
+	PublicCountThreshold int
+	PublicCountBucket    int
+
 	// Feature flags
-	FeedMaterialized bool // Serve the for_you feed from materialized_feeds when available
+	FeedMaterialized bool // Serve feeds from materialized_feeds when available
 }
 
 // Load reads configuration from environment variables
@@ -85,6 +96,15 @@ func Load() (*Config, error) {
 		APNsBundleID:   getEnv("APNS_BUNDLE_ID", "com.kuurier.app"),
 		APNsProduction: getEnv("APNS_PRODUCTION", "false") == "true",
 
+		// Personal data exports are kept apart from media, in a bucket
+		// with no public access
+		MinIOExportBucket: getEnv("MINIO_EXPORT_BUCKET", "kuurier-exports"),
+
+		EventReminderOffsets: getEnvDurations("EVENT_REMINDER_OFFSETS", []time.Duration{24 * time.Hour, time.Hour}),
+
+		PublicCountThreshold: getEnvInt("PUBLIC_COUNT_THRESHOLD", 10),
+		PublicCountBucket:    getEnvInt("PUBLIC_COUNT_BUCKET", 5),
+
 		// Feature flags. Default off until Phase 5 rollout is verified.
 		FeedMaterialized: getEnv("FEED_MATERIALIZED", "false") == "true",
 	}
@@ -152,3 +172,21 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvDurations reads a comma-separated list of Go durations such as
+// "24h,1h". An unparseable list falls back to the default.
+func getEnvDurations(key string, defaultValue []time.Duration) []time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	var durations []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil || d <= 0 {
+			return defaultValue
+		}
+		durations = append(durations, d)
+	}
+	return durations
+}
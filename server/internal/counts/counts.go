@@ -0,0 +1,56 @@
+// Package counts blurs headcounts shown to the public. An exact RSVP
+// count of 5 on a small meeting, put next to who was seen walking in,
+// all but names the people who came; blurred counts still say how big
+// a thing is without that.
+package counts
+
+import (
+	"strconv"
+
+	"github.com/kuurier/server/internal/config"
+)
+
+// Policy says how counts are blurred for people not entitled to exact
+// numbers. Counts below Threshold are shown only as "fewer than
+// Threshold"; larger ones are rounded down to a multiple of Bucket.
+type Policy struct {
+	Threshold int
+	Bucket    int
+}
+
+// FromConfig returns the configured policy.
+func FromConfig(cfg *config.Config) Policy {
+	return Policy{Threshold: cfg.PublicCountThreshold, Bucket: cfg.PublicCountBucket}
+}
+
+// Blur returns n as it may be shown under p, and a label saying how it
+// was blurred. The label is empty when n is shown as it is.
+func (p Policy) Blur(n int) (int, string) {
+	if p.Threshold > 1 && n < p.Threshold {
+		return 0, "fewer than " + strconv.Itoa(p.Threshold)
+	}
+	if p.Bucket <= 1 {
+		return n, ""
+	}
+	rounded := n - n%p.Bucket
+	if rounded < p.Threshold {
+		rounded = p.Threshold
+	}
+	return rounded, strconv.Itoa(rounded) + "+"
+}
+
+// Set puts n into m under key, blurred unless exact is true. A blurred
+// count also gets key+"_label" saying what it means, e.g. "fewer than
+// 10" or "25+", for clients to show in its place.
+func (p Policy) Set(m map[string]interface{}, key string, n int, exact bool) {
+	label := ""
+	if !exact {
+		n, label = p.Blur(n)
+	}
+	m[key] = n
+	if label != "" {
+		m[key+"_label"] = label
+	} else {
+		delete(m, key+"_label")
+	}
+}
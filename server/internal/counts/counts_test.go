@@ -0,0 +1,58 @@
+package counts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlur(t *testing.T) {
+	p := Policy{Threshold: 10, Bucket: 5}
+	for _, tc := range []struct {
+		n     int
+		want  int
+		label string
+	}{
+		{0, 0, "fewer than 10"},
+		{9, 0, "fewer than 10"},
+		{10, 10, "10+"},
+		{14, 10, "10+"},
+		{25, 25, "25+"},
+		{128, 125, "125+"},
+	} {
+		got, label := p.Blur(tc.n)
+		assert.Equal(t, tc.want, got, tc.n)
+		assert.Equal(t, tc.label, label, tc.n)
+	}
+}
+
+func TestBlur_BucketBelowThreshold(t *testing.T) {
+	// Rounding never goes back under the threshold
+	n, label := Policy{Threshold: 10, Bucket: 25}.Blur(12)
+	assert.Equal(t, 10, n)
+	assert.Equal(t, "10+", label)
+}
+
+func TestBlur_Disabled(t *testing.T) {
+	for _, p := range []Policy{{}, {Threshold: 1, Bucket: 1}} {
+		n, label := p.Blur(3)
+		assert.Equal(t, 3, n)
+		assert.Empty(t, label)
+	}
+
+	n, label := Policy{Threshold: 5}.Blur(7)
+	assert.Equal(t, 7, n)
+	assert.Empty(t, label)
+}
+
+func TestSet(t *testing.T) {
+	p := Policy{Threshold: 10, Bucket: 5}
+
+	m := map[string]interface{}{}
+	p.Set(m, "rsvp_count", 4, false)
+	assert.Equal(t, map[string]interface{}{"rsvp_count": 0, "rsvp_count_label": "fewer than 10"}, m)
+
+	// A copy re-set exactly drops the stale label
+	p.Set(m, "rsvp_count", 4, true)
+	assert.Equal(t, map[string]interface{}{"rsvp_count": 4}, m)
+}
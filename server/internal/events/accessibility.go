@@ -0,0 +1,115 @@
+package events
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// accessibilityFeatures are what an event can list as accessibility
+// provisions.
+var accessibilityFeatures = map[string]bool{
+	"wheelchair":         true, // step-free route and space for wheelchairs
+	"asl":                true, // sign language interpretation
+	"captioning":         true,
+	"seating":            true,
+	"quiet_space":        true,
+	"accessible_toilets": true,
+	"family_friendly":    true,
+	"masks_required":     true,
+}
+
+// riskLevels orders the risk levels from least to most likely to end
+// in arrests.
+var riskLevels = []string{"low", "elevated", "arrestable"}
+
+// riskRank returns the position of level in riskLevels, or -1 if it
+// isn't one.
+func riskRank(level string) int {
+	for i, l := range riskLevels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
+
+// normalizeAccessibility validates accessibility features, dropping
+// duplicates and sorting them. It never returns nil, so the result
+// always stores as an array.
+func normalizeAccessibility(features []string) ([]string, error) {
+	seen := make(map[string]bool, len(features))
+	normalized := []string{}
+	for _, f := range features {
+		f = strings.ToLower(strings.TrimSpace(f))
+		if !accessibilityFeatures[f] {
+			return nil, errors.New("unknown accessibility feature: " + f)
+		}
+		if !seen[f] {
+			seen[f] = true
+			normalized = append(normalized, f)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// eventFilters are the accessibility and risk filters of an event
+// listing: ?accessibility=wheelchair,asl keeps events offering all of
+// them, and ?max_risk=elevated those whose organizer rated them at
+// most that risky.
+type eventFilters struct {
+	accessibility []string
+	risks         []string // acceptable risk levels; nil for any
+}
+
+func parseEventFilters(c *gin.Context) (eventFilters, error) {
+	var f eventFilters
+	if s := c.Query("accessibility"); s != "" {
+		features, err := normalizeAccessibility(strings.Split(s, ","))
+		if err != nil {
+			return f, err
+		}
+		f.accessibility = features
+	}
+	if s := c.Query("max_risk"); s != "" {
+		rank := riskRank(s)
+		if rank < 0 {
+			return f, errors.New("max_risk must be low, elevated or arrestable")
+		}
+		f.risks = riskLevels[:rank+1]
+	}
+	return f, nil
+}
+
+// sql returns the filters as conditions on e, with parameters numbered
+// from next, and their arguments.
+func (f eventFilters) sql(next int) (string, []interface{}) {
+	var query string
+	var args []interface{}
+	if len(f.accessibility) > 0 {
+		query += " AND e.accessibility @> $" + strconv.Itoa(next+len(args))
+		args = append(args, f.accessibility)
+	}
+	if f.risks != nil {
+		// Events of unknown risk can't be vouched for
+		query += " AND e.risk_level = ANY($" + strconv.Itoa(next+len(args)) + ")"
+		args = append(args, f.risks)
+	}
+	return query, args
+}
+
+// setAccessibilityFields adds an event's accessibility and risk to its
+// listing.
+func setAccessibilityFields(event gin.H, accessibility []string, riskLevel *string) {
+	if accessibility == nil {
+		accessibility = []string{}
+	}
+	event["accessibility"] = accessibility
+	if riskLevel != nil {
+		event["risk_level"] = *riskLevel
+	}
+}
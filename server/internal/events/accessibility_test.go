@@ -0,0 +1,54 @@
+package events
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeAccessibility(t *testing.T) {
+	features, err := normalizeAccessibility([]string{" ASL", "wheelchair", "asl"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"asl", "wheelchair"}, features)
+
+	features, err = normalizeAccessibility(nil)
+	require.NoError(t, err)
+	assert.NotNil(t, features, "stores as an empty array")
+
+	_, err = normalizeAccessibility([]string{"wheelchair", "ramp"})
+	assert.Error(t, err)
+}
+
+func TestRiskRank(t *testing.T) {
+	assert.Less(t, riskRank("low"), riskRank("elevated"))
+	assert.Less(t, riskRank("elevated"), riskRank("arrestable"))
+	assert.Equal(t, -1, riskRank("extreme"))
+}
+
+func TestEventFilters(t *testing.T) {
+	parse := func(query string) (eventFilters, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/events?"+query, nil)
+		return parseEventFilters(c)
+	}
+
+	f, err := parse("")
+	require.NoError(t, err)
+	query, args := f.sql(4)
+	assert.Empty(t, query)
+	assert.Empty(t, args)
+
+	f, err = parse("accessibility=wheelchair,asl&max_risk=elevated")
+	require.NoError(t, err)
+	query, args = f.sql(4)
+	assert.Equal(t, " AND e.accessibility @> $4 AND e.risk_level = ANY($5)", query)
+	assert.Equal(t, []interface{}{[]string{"asl", "wheelchair"}, []string{"low", "elevated"}}, args)
+
+	_, err = parse("max_risk=extreme")
+	assert.Error(t, err)
+	_, err = parse("accessibility=ramp")
+	assert.Error(t, err)
+}
@@ -0,0 +1,195 @@
+package events
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kuurier/server/internal/websocket"
+)
+
+const (
+	// maxAnnouncementsPerHour caps announcements per event, so
+	// attendees don't mute the event's pushes.
+	maxAnnouncementsPerHour = 10
+
+	maxListedAnnouncements = 50
+)
+
+// AnnounceRequest broadcasts a short update to an event's attendees.
+// Occurrence (Unix start) targets one occurrence of a series; without
+// it every attendee still to come is reached.
+type AnnounceRequest struct {
+	Message    string `json:"message" binding:"required,max=500"`
+	Occurrence *int64 `json:"occurrence"`
+	Urgent     bool   `json:"urgent"` // push even during quiet hours
+}
+
+// Announce sends an organizer's update to the event's going and
+// interested RSVPs by push and WebSocket, and posts it to the event
+// channel if there is one.
+func (h *Handler) Announce(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req AnnounceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Message = strings.TrimSpace(req.Message)
+	if req.Message == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "message is required"})
+		return
+	}
+
+	st, err := h.eventState(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	manager, err := h.eventManager(ctx, eventID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if manager == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the organizers can post announcements"})
+		return
+	}
+
+	var occurrenceAt *time.Time
+	if req.Occurrence != nil {
+		at := time.Unix(*req.Occurrence, 0)
+		var ok bool
+		occurrenceAt, ok = h.rsvpOccurrence(c, eventID, newSeries(st.recurrence, st.timezone, st.startsAt, st.endsAt), &at, true)
+		if !ok {
+			return
+		}
+	}
+
+	var recent int
+	if err := h.db.Pool().QueryRow(ctx, `
+		SELECT COUNT(*) FROM event_announcements
+		WHERE event_id = $1 AND created_at > NOW() - INTERVAL '1 hour'
+	`, eventID).Scan(&recent); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if recent >= maxAnnouncementsPerHour {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "too many announcements for this event; try again later"})
+		return
+	}
+
+	var id string
+	var createdAt time.Time
+	if err := h.db.Pool().QueryRow(ctx, `
+		INSERT INTO event_announcements (event_id, occurrence_at, author_id, body, is_urgent)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, eventID, occurrenceAt, userID, req.Message, req.Urgent).Scan(&id, &createdAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to post announcement"})
+		return
+	}
+
+	go h.notifyAttendees(eventID, userID, st.channelID, eventNotice{
+		wsType:       websocket.TypeEventAnnouncement,
+		title:        "📣 " + st.title,
+		body:         req.Message,
+		channelBody:  "Announcement: " + req.Message,
+		urgent:       req.Urgent,
+		occurrenceAt: occurrenceAt,
+		extra:        map[string]interface{}{"announcement_id": id},
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":            id,
+		"event_id":      eventID,
+		"occurrence_at": occurrenceAt,
+		"message":       req.Message,
+		"urgent":        req.Urgent,
+		"created_at":    createdAt,
+	})
+}
+
+// ListAnnouncements returns an event's announcements, newest first, to
+// its organizers and anyone who has RSVP'd. ?occurrence= limits a
+// series to those for all attendees and for that occurrence.
+func (h *Handler) ListAnnouncements(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	occ, err := parseOccurrence(c.Query("occurrence"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	manager, err := h.eventManager(ctx, eventID, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	allowed := manager != ""
+	if !allowed {
+		h.db.Pool().QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM event_rsvps WHERE event_id = $1 AND user_id = $2 AND approval = 'approved')
+		`, eventID, userID).Scan(&allowed)
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "RSVP to see announcements"})
+		return
+	}
+
+	query := `
+		SELECT id, occurrence_at, body, is_urgent, created_at
+		FROM event_announcements
+		WHERE event_id = $1`
+	args := []interface{}{eventID}
+	if occ != nil {
+		query += ` AND (occurrence_at IS NULL OR occurrence_at = $2)`
+		args = append(args, *occ)
+	}
+	query += ` ORDER BY created_at DESC LIMIT ` + strconv.Itoa(maxListedAnnouncements)
+
+	rows, err := h.db.Pool().Query(ctx, query, args...)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch announcements"})
+		return
+	}
+	announcements, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (gin.H, error) {
+		var id, body string
+		var occurrenceAt *time.Time
+		var urgent bool
+		var createdAt time.Time
+		if err := row.Scan(&id, &occurrenceAt, &body, &urgent, &createdAt); err != nil {
+			return nil, err
+		}
+		return gin.H{
+			"id":            id,
+			"occurrence_at": occurrenceAt,
+			"message":       body,
+			"urgent":        urgent,
+			"created_at":    createdAt,
+		}, nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch announcements"})
+		return
+	}
+
+	if announcements == nil {
+		announcements = []gin.H{}
+	}
+	c.JSON(http.StatusOK, gin.H{"announcements": announcements})
+}
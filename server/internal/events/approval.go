@@ -0,0 +1,288 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// RSVP approval states. Only approved RSVPs grant access to an event's
+// location, channel and updates.
+const (
+	approvalPending  = "pending"
+	approvalApproved = "approved"
+	approvalDenied   = "denied"
+)
+
+// rsvpApproval decides a new RSVP to an approval-gated event. A
+// requester denied before stays denied, one approved before (for
+// another occurrence of a series) stays approved, and the organizer's
+// auto-approve rules let the rest straight in or leave them pending.
+func rsvpApproval(minTrust *int, trust int, orgMember, approvedBefore, deniedBefore bool) string {
+	switch {
+	case deniedBefore:
+		return approvalDenied
+	case approvedBefore, orgMember, minTrust != nil && trust >= *minTrust:
+		return approvalApproved
+	default:
+		return approvalPending
+	}
+}
+
+// newRSVPApproval decides the approval of userID's new RSVP to an
+// event with location_visibility 'approval'.
+func (h *Handler) newRSVPApproval(ctx context.Context, eventID, userID string) (string, error) {
+	var minTrust *int
+	var trust int
+	var orgMember, approvedBefore, deniedBefore bool
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT e.approval_min_trust, u.trust_score,
+		       EXISTS(SELECT 1 FROM organization_members m WHERE m.org_id = e.approval_org_id AND m.user_id = u.id),
+		       EXISTS(SELECT 1 FROM event_rsvps r WHERE r.event_id = e.id AND r.user_id = u.id AND r.approval = 'approved'),
+		       EXISTS(SELECT 1 FROM event_rsvps r WHERE r.event_id = e.id AND r.user_id = u.id AND r.approval = 'denied')
+		FROM events e, users u
+		WHERE e.id = $1 AND u.id = $2
+	`, eventID, userID).Scan(&minTrust, &trust, &orgMember, &approvedBefore, &deniedBefore)
+	if err != nil {
+		return "", err
+	}
+	// The people running the event are let in to their own event
+	if manager, err := h.eventManager(ctx, eventID, userID); err != nil || manager != "" {
+		return approvalApproved, err
+	}
+	return rsvpApproval(minTrust, trust, orgMember, approvedBefore, deniedBefore), nil
+}
+
+// isOrgMember reports whether userID belongs to orgID, for checking an
+// organizer's choice of auto-approve organization.
+func (h *Handler) isOrgMember(ctx context.Context, orgID, userID string) (bool, error) {
+	var member bool
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM organization_members WHERE org_id::text = $1 AND user_id = $2)
+	`, orgID, userID).Scan(&member)
+	return member, err
+}
+
+// ListRSVPRequests returns an approval-gated event's RSVPs awaiting a
+// decision (?approval= pending, the default, or denied or approved),
+// oldest first, with what the organizer needs to judge each requester:
+// trust score, whether the organizer vouched for them, how many of
+// their vouchers the organizer is connected to by a vouch either way,
+// and the organizations both belong to (the event's organizers only).
+func (h *Handler) ListRSVPRequests(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	approval := c.DefaultQuery("approval", approvalPending)
+	if approval != approvalPending && approval != approvalApproved && approval != approvalDenied {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "approval must be pending, approved or denied"})
+		return
+	}
+	occ, err := parseOccurrence(c.Query("occurrence"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	manager, err := h.eventManager(ctx, eventID, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if manager == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the organizers can review RSVPs"})
+		return
+	}
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT r.user_id, u.display_name, u.trust_score, r.status, r.occurrence_at, r.created_at,
+		       EXISTS(SELECT 1 FROM vouches WHERE voucher_id = $2 AND vouchee_id = r.user_id),
+		       (SELECT COUNT(*) FROM vouches v
+		        WHERE v.vouchee_id = r.user_id
+		          AND (v.voucher_id IN (SELECT vouchee_id FROM vouches WHERE voucher_id = $2)
+		               OR v.voucher_id IN (SELECT voucher_id FROM vouches WHERE vouchee_id = $2))),
+		       ARRAY(SELECT o.name FROM organization_members a
+		             JOIN organization_members b ON b.org_id = a.org_id AND b.user_id = $2
+		             JOIN organizations o ON o.id = a.org_id
+		             WHERE a.user_id = r.user_id
+		             ORDER BY o.name)
+		FROM event_rsvps r
+		JOIN users u ON u.id = r.user_id
+		WHERE r.event_id = $1 AND r.approval = $3 AND ($4::timestamptz IS NULL OR r.occurrence_at = $4)
+		ORDER BY r.created_at
+		LIMIT 200
+	`, eventID, userID, approval, occ)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch RSVPs"})
+		return
+	}
+	requests, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (gin.H, error) {
+		var requesterID, status string
+		var displayName *string
+		var trust int
+		var occurrenceAt *time.Time
+		var requestedAt time.Time
+		var vouchedByYou bool
+		var mutualVouches int
+		var sharedOrgs []string
+		if err := row.Scan(&requesterID, &displayName, &trust, &status, &occurrenceAt, &requestedAt,
+			&vouchedByYou, &mutualVouches, &sharedOrgs); err != nil {
+			return nil, err
+		}
+		request := gin.H{
+			"user_id":        requesterID,
+			"trust_score":    trust,
+			"status":         status,
+			"requested_at":   requestedAt,
+			"vouched_by_you": vouchedByYou,
+			"mutual_vouches": mutualVouches,
+			"shared_orgs":    nonNil(sharedOrgs),
+		}
+		if displayName != nil {
+			request["display_name"] = *displayName
+		}
+		if occurrenceAt != nil {
+			request["occurrence_at"] = *occurrenceAt
+		}
+		return request, nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch RSVPs"})
+		return
+	}
+	if requests == nil {
+		requests = []gin.H{}
+	}
+	c.JSON(http.StatusOK, gin.H{"rsvps": requests})
+}
+
+// DecideRSVPRequest approves or denies a user's RSVPs to an event;
+// Occurrence limits it to one occurrence of a series.
+type DecideRSVPRequest struct {
+	Approval   string `json:"approval" binding:"required,oneof=approved denied"`
+	Occurrence *int64 `json:"occurrence"`
+}
+
+// DecideRSVP approves or denies a user's RSVPs (the event's organizers
+// only). Approved attendees join the event channel; denying someone
+// approved before takes them out of it and off any roles.
+func (h *Handler) DecideRSVP(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	attendeeID := c.Param("userId")
+	ctx := c.Request.Context()
+
+	var req DecideRSVPRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	st, err := h.eventState(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	manager, err := h.eventManager(ctx, eventID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if manager == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the organizers can review RSVPs"})
+		return
+	}
+	if attendeeID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot decide your own RSVP"})
+		return
+	}
+	var occurrenceAt *time.Time
+	if req.Occurrence != nil {
+		at := time.Unix(*req.Occurrence, 0)
+		var ok bool
+		occurrenceAt, ok = h.rsvpOccurrence(c, eventID, newSeries(st.recurrence, st.timezone, st.startsAt, st.endsAt), &at, false)
+		if !ok {
+			return
+		}
+	}
+
+	rows, err := h.db.Pool().Query(ctx, `
+		UPDATE event_rsvps SET approval = $4, decided_at = NOW()
+		WHERE event_id = $1 AND user_id::text = $2 AND ($3::timestamptz IS NULL OR occurrence_at = $3)
+		  AND approval <> $4
+		RETURNING occurrence_at, status
+	`, eventID, attendeeID, occurrenceAt, req.Approval)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update RSVP"})
+		return
+	}
+	type decided struct {
+		occurrenceAt *time.Time
+		status       string
+	}
+	changed, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (decided, error) {
+		var d decided
+		err := row.Scan(&d.occurrenceAt, &d.status)
+		return d, err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update RSVP"})
+		return
+	}
+	if len(changed) == 0 {
+		var exists bool
+		h.db.Pool().QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM event_rsvps
+			              WHERE event_id = $1 AND user_id::text = $2 AND ($3::timestamptz IS NULL OR occurrence_at = $3))
+		`, eventID, attendeeID, occurrenceAt).Scan(&exists)
+		if !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": "RSVP not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"approval": req.Approval, "updated": 0})
+		return
+	}
+
+	if req.Approval == approvalApproved {
+		attending := false
+		for _, d := range changed {
+			attending = attending || d.status == "going" || d.status == "interested"
+		}
+		if attending && st.channelID != nil {
+			_, _ = h.db.Pool().Exec(ctx, `
+				INSERT INTO channel_members (channel_id, user_id, role, joined_at)
+				VALUES ($1, $2, 'member', NOW())
+				ON CONFLICT (channel_id, user_id) DO NOTHING
+			`, *st.channelID, attendeeID)
+		}
+		go h.notifyUsers(eventID, st.title, map[string]string{attendeeID: "Your RSVP was approved"})
+	} else {
+		// Without an approved RSVP left, they lose the event channel too
+		var stillApproved bool
+		h.db.Pool().QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM event_rsvps WHERE event_id = $1 AND user_id::text = $2 AND approval = 'approved')
+		`, eventID, attendeeID).Scan(&stillApproved)
+		if !stillApproved && st.channelID != nil {
+			_, _ = h.db.Pool().Exec(ctx, `
+				DELETE FROM channel_members WHERE channel_id = $1 AND user_id::text = $2 AND role <> 'admin'
+			`, *st.channelID, attendeeID)
+		}
+		for _, d := range changed {
+			if err := h.withdrawFromRoles(ctx, eventID, attendeeID, d.occurrenceAt); err != nil {
+				log.Printf("events: withdraw %s from roles of %s: %v", attendeeID, eventID, err)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"approval": req.Approval, "updated": len(changed)})
+}
@@ -0,0 +1,24 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRSVPApproval(t *testing.T) {
+	threshold := 40
+
+	assert.Equal(t, approvalPending, rsvpApproval(nil, 90, false, false, false), "no auto-approve rules")
+	assert.Equal(t, approvalPending, rsvpApproval(&threshold, 39, false, false, false))
+	assert.Equal(t, approvalApproved, rsvpApproval(&threshold, 40, false, false, false))
+	assert.Equal(t, approvalApproved, rsvpApproval(nil, 0, true, false, false), "member of the organizing org")
+	assert.Equal(t, approvalApproved, rsvpApproval(nil, 0, false, true, false), "approved for another occurrence")
+	assert.Equal(t, approvalDenied, rsvpApproval(&threshold, 90, true, false, true), "a denial sticks")
+}
+
+func TestShouldRevealLocation_Approval(t *testing.T) {
+	assert.False(t, shouldRevealLocation("approval", nil, "u1", "org", false), "pending or no RSVP")
+	assert.True(t, shouldRevealLocation("approval", nil, "u1", "org", true))
+	assert.True(t, shouldRevealLocation("approval", nil, "org", "org", false))
+}
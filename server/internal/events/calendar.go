@@ -0,0 +1,464 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kuurier/server/internal/mutes"
+)
+
+const (
+	// maxCalendarFeeds caps feed URLs per user.
+	maxCalendarFeeds = 20
+
+	// maxCalendarEvents caps how many events one feed renders.
+	maxCalendarEvents = 500
+
+	// calendarLookback is how far back feeds include past events, so
+	// recent events don't vanish from calendars the moment they end.
+	calendarLookback = 30 * 24 * time.Hour
+
+	feedPersonal     = "personal"
+	feedTopic        = "topic"
+	feedOrganization = "organization"
+)
+
+// CreateCalendarFeedRequest creates a calendar feed URL. TargetID is
+// the topic or organization ID; omit it for the personal feed.
+type CreateCalendarFeedRequest struct {
+	Type     string `json:"type" binding:"required,oneof=personal topic organization"`
+	TargetID string `json:"target_id"`
+}
+
+// calendarRow is an event as stored, before the viewer's location
+// rights are applied.
+type calendarRow struct {
+	id, organizerID, title, description, eventType string
+	lat, lon                                       float64
+	locationName, locationArea                     *string
+	locationVisibility                             string
+	locationRevealAt                               *time.Time
+	startsAt                                       time.Time
+	endsAt                                         *time.Time
+	updatedAt                                      time.Time
+	cancelled, hasRSVP, isManager                  bool
+	recurrenceRule                                 *string
+	timezone                                       string
+}
+
+// calendarEntry applies shouldRevealLocation for viewerID. A hidden
+// location shows only the general area, with a note on how to see it.
+func calendarEntry(r calendarRow, viewerID string) icalEvent {
+	e := icalEvent{
+		id:          r.id,
+		title:       r.title,
+		description: r.description,
+		eventType:   r.eventType,
+		startsAt:    r.startsAt,
+		endsAt:      r.endsAt,
+		updatedAt:   r.updatedAt,
+		cancelled:   r.cancelled,
+	}
+	if shouldRevealLocation(r.locationVisibility, r.locationRevealAt, viewerID, r.organizerID, r.hasRSVP || r.isManager) {
+		lat, lon := r.lat, r.lon
+		e.latitude, e.longitude = &lat, &lon
+		if r.locationName != nil && *r.locationName != "" {
+			e.location = *r.locationName
+		} else {
+			e.location = fmt.Sprintf("%.6f, %.6f", lat, lon)
+		}
+		return e
+	}
+
+	if r.locationArea != nil {
+		e.location = *r.locationArea
+	}
+	var note string
+	switch {
+	case r.locationVisibility == "timed" && r.locationRevealAt != nil:
+		note = "Exact location will be shared at " + r.locationRevealAt.UTC().Format("2006-01-02 15:04 UTC") + "."
+	default:
+		note = "RSVP to see the exact location."
+	}
+	if e.description != "" {
+		e.description += "\n\n"
+	}
+	e.description += note
+	return e
+}
+
+// newFeedToken returns a random URL-safe token and its SHA-256.
+func newFeedToken() (string, []byte, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashFeedToken(token), nil
+}
+
+func hashFeedToken(token string) []byte {
+	sum := sha256.Sum256([]byte(token))
+	return sum[:]
+}
+
+// calendarFeedPath is the URL path a calendar app subscribes to.
+func calendarFeedPath(token string) string {
+	return "/api/v1/calendar/" + token + ".ics"
+}
+
+// ListCalendarFeeds returns the user's calendar feeds. Tokens are only
+// shown when a feed is created.
+func (h *Handler) ListCalendarFeeds(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	rows, err := h.db.Pool().Query(c.Request.Context(), `
+		SELECT f.id, f.feed_type, f.target_id, COALESCE(t.name, o.name), f.created_at, f.last_used_at
+		FROM calendar_feeds f
+		LEFT JOIN topics t ON f.feed_type = 'topic' AND t.id = f.target_id
+		LEFT JOIN organizations o ON f.feed_type = 'organization' AND o.id = f.target_id
+		WHERE f.user_id = $1
+		ORDER BY f.created_at DESC
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch calendar feeds"})
+		return
+	}
+	defer rows.Close()
+
+	feeds := []gin.H{}
+	for rows.Next() {
+		var id, feedType string
+		var targetID, targetName *string
+		var createdAt time.Time
+		var lastUsedAt *time.Time
+		if err := rows.Scan(&id, &feedType, &targetID, &targetName, &createdAt, &lastUsedAt); err != nil {
+			continue
+		}
+		feed := gin.H{"id": id, "type": feedType, "created_at": createdAt}
+		if targetID != nil {
+			feed["target_id"] = *targetID
+		}
+		if targetName != nil {
+			feed["target_name"] = *targetName
+		}
+		if lastUsedAt != nil {
+			feed["last_used_at"] = *lastUsedAt
+		}
+		feeds = append(feeds, feed)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"feeds": feeds})
+}
+
+// CreateCalendarFeed creates a calendar feed URL. The response holds
+// the only copy of the token.
+func (h *Handler) CreateCalendarFeed(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ctx := c.Request.Context()
+
+	var req CreateCalendarFeedRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var targetID *string
+	switch req.Type {
+	case feedTopic, feedOrganization:
+		if _, err := uuid.Parse(req.TargetID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "target_id is required for " + req.Type + " feeds"})
+			return
+		}
+		targetID = &req.TargetID
+		if ok, err := h.canSubscribeCalendar(ctx, userID, req.Type, req.TargetID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create calendar feed"})
+			return
+		} else if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": req.Type + " not found"})
+			return
+		}
+	}
+
+	var count int
+	if err := h.db.Pool().QueryRow(ctx, `SELECT COUNT(*) FROM calendar_feeds WHERE user_id = $1`, userID).Scan(&count); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create calendar feed"})
+		return
+	}
+	if count >= maxCalendarFeeds {
+		c.JSON(http.StatusConflict, gin.H{"error": "calendar feed limit reached"})
+		return
+	}
+
+	token, tokenHash, err := newFeedToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create calendar feed"})
+		return
+	}
+	var id string
+	err = h.db.Pool().QueryRow(ctx, `
+		INSERT INTO calendar_feeds (user_id, token_hash, feed_type, target_id)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, userID, tokenHash, req.Type, targetID).Scan(&id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create calendar feed"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":    id,
+		"type":  req.Type,
+		"token": token,
+		"path":  calendarFeedPath(token),
+	})
+}
+
+// RevokeCalendarFeed deletes a calendar feed; its URL stops working
+func (h *Handler) RevokeCalendarFeed(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	result, err := h.db.Pool().Exec(c.Request.Context(),
+		"DELETE FROM calendar_feeds WHERE id = $1 AND user_id = $2",
+		c.Param("id"), userID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke calendar feed"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "calendar feed not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "calendar feed revoked"})
+}
+
+// ServeCalendarFeed renders a feed as text/calendar (public; the token
+// in the URL authenticates). Events are shown as the feed's owner
+// would see them.
+func (h *Handler) ServeCalendarFeed(c *gin.Context) {
+	ctx := c.Request.Context()
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	var feedID, userID, feedType string
+	var targetID *string
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT id, user_id, feed_type, target_id FROM calendar_feeds WHERE token_hash = $1
+	`, hashFeedToken(token)).Scan(&feedID, &userID, &feedType, &targetID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "calendar feed not found"})
+		return
+	}
+
+	var name, filter string
+	args := []interface{}{userID}
+	switch feedType {
+	case feedPersonal:
+		name = "Kuurier: My events"
+		filter = `(e.organizer_id = $1 OR EXISTS (
+			SELECT 1 FROM event_co_organizers co
+			WHERE co.event_id = e.id AND co.user_id = $1 AND co.status = 'accepted') OR EXISTS (
+			SELECT 1 FROM event_rsvps r
+			WHERE r.event_id = e.id AND r.user_id = $1 AND r.status IN ('going', 'interested')))`
+	case feedTopic, feedOrganization:
+		// Access is re-checked on every fetch: leaving a private org
+		// or the topic being archived ends the feed.
+		ok, err := h.canSubscribeCalendar(ctx, userID, feedType, *targetID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch calendar feed"})
+			return
+		}
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "calendar feed not found"})
+			return
+		}
+		args = append(args, *targetID)
+		if feedType == feedTopic {
+			h.db.Pool().QueryRow(ctx, `SELECT name FROM topics WHERE id = $1`, *targetID).Scan(&name)
+			filter = `EXISTS (
+				SELECT 1 FROM event_topics et JOIN topic_lineage tl ON tl.topic_id = et.topic_id
+				WHERE et.event_id = e.id AND tl.ancestor_id = $2)`
+		} else {
+			h.db.Pool().QueryRow(ctx, `SELECT name FROM organizations WHERE id = $1`, *targetID).Scan(&name)
+			filter = `e.org_id = $2`
+		}
+		filter += ` AND NOT ` + mutes.HiddenSQL("$1", "e.organizer_id", "NULL", "e.title || ' ' || COALESCE(e.description, '')",
+			"ARRAY(SELECT topic_id::text FROM event_topics WHERE event_id = e.id)")
+		name = "Kuurier: " + name
+	}
+
+	events, err := h.calendarEvents(ctx, userID, filter, args...)
+	if err != nil {
+		log.Printf("events: calendar feed %s: %v", feedID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch calendar feed"})
+		return
+	}
+
+	if _, err := h.db.Pool().Exec(ctx, `UPDATE calendar_feeds SET last_used_at = NOW() WHERE id = $1`, feedID); err != nil {
+		log.Printf("events: calendar feed %s: touch: %v", feedID, err)
+	}
+
+	writeCalendar(c, "kuurier.ics", renderCalendar(name, events, time.Now()))
+}
+
+// ExportEventICS downloads a single event as an .ics file
+func (h *Handler) ExportEventICS(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+
+	if _, err := uuid.Parse(eventID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	events, err := h.calendarEvents(c.Request.Context(), userID, "e.id = $2", userID, eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch event"})
+		return
+	}
+	if len(events) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+
+	writeCalendar(c, "event-"+eventID+".ics", renderCalendar(events[0].title, events, time.Now()))
+}
+
+// canSubscribeCalendar reports whether userID may follow a topic or
+// organization calendar: the topic must be active, and the
+// organization public or joined by the user.
+func (h *Handler) canSubscribeCalendar(ctx context.Context, userID, feedType, targetID string) (bool, error) {
+	query := `SELECT EXISTS(SELECT 1 FROM topics WHERE id = $1 AND archived_at IS NULL)`
+	args := []interface{}{targetID}
+	if feedType == feedOrganization {
+		query = `
+			SELECT EXISTS(
+				SELECT 1 FROM organizations o
+				WHERE o.id = $1 AND (o.is_public
+				   OR EXISTS(SELECT 1 FROM organization_members WHERE org_id = o.id AND user_id = $2))
+			)`
+		args = append(args, userID)
+	}
+	var ok bool
+	err := h.db.Pool().QueryRow(ctx, query, args...).Scan(&ok)
+	return ok, err
+}
+
+// calendarEvents loads events matching filter (SQL over e, with the
+// viewer as $1) from calendarLookback onwards, as viewerID may see
+// them (the people running an event see it as its organizer does).
+// Cancelled events are included so calendars drop them. Series
+// that haven't ended are included whenever they started, and are
+// rendered with their rule and edited occurrences.
+func (h *Handler) calendarEvents(ctx context.Context, viewerID, filter string, args ...interface{}) ([]icalEvent, error) {
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT e.id, e.organizer_id, e.title, COALESCE(e.description, ''), e.event_type,
+		       ST_Y(e.location::geometry), ST_X(e.location::geometry),
+		       e.location_name, e.location_area, e.location_visibility, e.location_reveal_at,
+		       e.starts_at, e.ends_at, e.updated_at, e.is_cancelled,
+		       EXISTS(SELECT 1 FROM event_rsvps r
+		              WHERE r.event_id = e.id AND r.user_id = $1 AND r.status IN ('going', 'interested')
+		                AND r.approval = 'approved'),
+		       `+managesEventSQL+`,
+		       e.recurrence_rule, e.timezone
+		FROM events e
+		WHERE COALESCE(e.recurrence_ends_at, CASE WHEN e.recurrence_rule IS NULL THEN e.starts_at ELSE 'infinity' END)
+		      > NOW() - INTERVAL '`+strconv.Itoa(int(calendarLookback.Hours()))+` hours'
+		  AND `+filter+`
+		ORDER BY e.starts_at ASC
+		LIMIT `+strconv.Itoa(maxCalendarEvents), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var found []calendarRow
+	var seriesIDs []string
+	for rows.Next() {
+		var r calendarRow
+		if err := rows.Scan(&r.id, &r.organizerID, &r.title, &r.description, &r.eventType, &r.lat, &r.lon,
+			&r.locationName, &r.locationArea, &r.locationVisibility, &r.locationRevealAt,
+			&r.startsAt, &r.endsAt, &r.updatedAt, &r.cancelled, &r.hasRSVP, &r.isManager,
+			&r.recurrenceRule, &r.timezone); err != nil {
+			return nil, err
+		}
+		found = append(found, r)
+		if r.recurrenceRule != nil {
+			seriesIDs = append(seriesIDs, r.id)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	overrides, err := h.occurrenceOverrides(ctx, seriesIDs)
+	if err != nil {
+		return nil, err
+	}
+	var events []icalEvent
+	for _, r := range found {
+		events = append(events, calendarSeries(r, viewerID, overrides[r.id])...)
+	}
+	return events, nil
+}
+
+// calendarSeries renders a series as its master VEVENT plus one per
+// edited occurrence; cancelled occurrences become EXDATEs. One-off
+// events render as a single calendarEntry.
+func calendarSeries(r calendarRow, viewerID string, overrides map[int64]occurrenceOverride) []icalEvent {
+	s := newSeries(r.recurrenceRule, r.timezone, r.startsAt, r.endsAt)
+	if s == nil {
+		return []icalEvent{calendarEntry(r, viewerID)}
+	}
+
+	master := calendarEntry(r, viewerID)
+	master.rrule = s.rule.String()
+	master.timezone = r.timezone
+	events := []icalEvent{master}
+	for _, at := range slices.Sorted(maps.Keys(overrides)) {
+		o := s.instance(time.Unix(at, 0), overrides[at])
+		if o.override.cancelled {
+			events[0].exdates = append(events[0].exdates, o.at)
+			continue
+		}
+		edited := r
+		edited.startsAt, edited.endsAt = o.startsAt, o.endsAt
+		edited.locationRevealAt = s.revealAt(o, r.locationRevealAt)
+		if o.override.title != nil {
+			edited.title = *o.override.title
+		}
+		if o.override.description != nil {
+			edited.description = *o.override.description
+		}
+		if o.override.locationName != nil {
+			edited.locationName = o.override.locationName
+		}
+		if o.override.locationArea != nil {
+			edited.locationArea = o.override.locationArea
+		}
+		e := calendarEntry(edited, viewerID)
+		e.timezone = r.timezone
+		e.recurrenceID = &o.at
+		events = append(events, e)
+	}
+	return events
+}
+
+func writeCalendar(c *gin.Context, filename, body string) {
+	c.Header("Content-Disposition", `inline; filename="`+filename+`"`)
+	// Feeds can hold revealed locations; keep them out of shared caches.
+	c.Header("Cache-Control", "private, no-store")
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(body))
+}
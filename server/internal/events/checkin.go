@@ -0,0 +1,534 @@
+package events
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kuurier/server/internal/storage"
+)
+
+const (
+	// checkinTokenRotation is how often the QR token changes. The
+	// previous token is still accepted, so a scan just before the
+	// change doesn't fail.
+	checkinTokenRotation = time.Minute
+
+	// checkinBucket is the resolution check-ins are counted at.
+	checkinBucket = 15 * time.Minute
+
+	// checkinK is the smallest count ever shown or kept: fewer
+	// check-ins than this are merged into a neighbouring bucket, or
+	// reported only as "fewer than k".
+	checkinK = 5
+
+	// Check-in opens this long before an occurrence starts, and closes
+	// at its end or, without one, this long after it starts.
+	checkinOpensBefore      = 2 * time.Hour
+	checkinDefaultDuration  = 12 * time.Hour
+	checkinFinalizeAfterEnd = time.Hour
+)
+
+// checkinBucketCount is the number of check-ins in the bucket starting
+// at start.
+type checkinBucketCount struct {
+	start time.Time
+	count int
+}
+
+// mergeBuckets makes every bucket hold at least k check-ins by merging
+// each run of small buckets into one that starts where the run did; a
+// small run at the end joins the bucket before it. buckets must be in
+// order. Nothing is left if all of them together hold fewer than k.
+func mergeBuckets(buckets []checkinBucketCount, k int) []checkinBucketCount {
+	var out []checkinBucketCount
+	var run checkinBucketCount
+	for _, b := range buckets {
+		if run.count == 0 {
+			run.start = b.start
+		}
+		run.count += b.count
+		if run.count >= k {
+			out = append(out, run)
+			run = checkinBucketCount{}
+		}
+	}
+	if run.count > 0 && len(out) > 0 {
+		out[len(out)-1].count += run.count
+	}
+	return out
+}
+
+// checkinToken is the QR token for one rotation window of an
+// occurrence (0 for one-off events): "<occurrence>.<window>.<mac>".
+func checkinToken(secret []byte, occurrence, window int64) string {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "token|%d|%d", occurrence, window)
+	return strconv.FormatInt(occurrence, 10) + "." + strconv.FormatInt(window, 10) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:12])
+}
+
+// verifyCheckinToken checks token against secret at now and returns
+// the occurrence it is for. Tokens of the current and the previous
+// window are valid.
+func verifyCheckinToken(secret []byte, token string, now time.Time) (int64, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	occurrence, err1 := strconv.ParseInt(parts[0], 10, 64)
+	window, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, false
+	}
+	current := now.Unix() / int64(checkinTokenRotation.Seconds())
+	if window != current && window != current-1 {
+		return 0, false
+	}
+	if !hmac.Equal([]byte(token), []byte(checkinToken(secret, occurrence, window))) {
+		return 0, false
+	}
+	return occurrence, true
+}
+
+// checkinTag identifies an attendee's check-in to an occurrence, so a
+// second scan isn't counted, without naming them.
+func checkinTag(secret []byte, occurrence int64, userID string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "attendee|%d|%s", occurrence, userID)
+	return mac.Sum(nil)
+}
+
+// checkinOccurrence is the occurrence check-in is for, and when
+// check-in to it opens and closes.
+type checkinOccurrence struct {
+	at            *time.Time // nil for one-off events
+	opens, closes time.Time
+	cancelled     bool
+}
+
+func (o checkinOccurrence) unix() int64 {
+	if o.at == nil {
+		return 0
+	}
+	return o.at.Unix()
+}
+
+func newCheckinOccurrence(at *time.Time, startsAt time.Time, endsAt *time.Time, cancelled bool) checkinOccurrence {
+	closes := startsAt.Add(checkinDefaultDuration)
+	if endsAt != nil {
+		closes = *endsAt
+	}
+	return checkinOccurrence{at: at, opens: startsAt.Add(-checkinOpensBefore), closes: closes, cancelled: cancelled}
+}
+
+// findCheckinOccurrence resolves the occurrence check-in is for: the
+// event itself, the given occurrence of a series (Unix start, 0 for
+// none), or without one, the occurrence open for check-in at now.
+func (h *Handler) findCheckinOccurrence(ctx context.Context, eventID string, st eventState, occurrence int64, now time.Time) (checkinOccurrence, bool, error) {
+	s := newSeries(st.recurrence, st.timezone, st.startsAt, st.endsAt)
+	if s == nil {
+		return newCheckinOccurrence(nil, st.startsAt, st.endsAt, st.cancelled), occurrence == 0, nil
+	}
+	all, err := h.occurrenceOverrides(ctx, []string{eventID})
+	if err != nil {
+		return checkinOccurrence{}, false, err
+	}
+	if occurrence != 0 {
+		o, ok := s.occurrence(time.Unix(occurrence, 0), all[eventID])
+		if !ok {
+			return checkinOccurrence{}, false, nil
+		}
+		return newCheckinOccurrence(&o.at, o.startsAt, o.endsAt, st.cancelled || o.override.cancelled), true, nil
+	}
+	for _, o := range s.occurrences(now.Add(-checkinDefaultDuration), now.Add(checkinOpensBefore), 10, all[eventID]) {
+		c := newCheckinOccurrence(&o.at, o.startsAt, o.endsAt, st.cancelled)
+		if !now.Before(c.opens) && now.Before(c.closes) {
+			return c, true, nil
+		}
+	}
+	return checkinOccurrence{}, false, nil
+}
+
+// isEventStaff reports whether userID runs eventID: one of its
+// organizers (see eventManager) or a confirmed role holder, such as a
+// marshal.
+func (h *Handler) isEventStaff(ctx context.Context, eventID, userID string) (bool, error) {
+	if manager, err := h.eventManager(ctx, eventID, userID); err != nil || manager != "" {
+		return manager != "", err
+	}
+	var staff bool
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM event_role_signups s
+		              JOIN event_roles r ON r.id = s.role_id
+		              WHERE r.event_id = $1 AND s.user_id = $2 AND s.status = 'confirmed')
+	`, eventID, userID).Scan(&staff)
+	return staff, err
+}
+
+// GetCheckinToken returns the event's current check-in QR token for
+// the organizers and role holders to display. A series takes
+// ?occurrence=, defaulting to the one open for check-in now.
+func (h *Handler) GetCheckinToken(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+	now := time.Now()
+
+	occ, err := parseOccurrence(c.Query("occurrence"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	st, err := h.eventState(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	staff, err := h.isEventStaff(ctx, eventID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !staff {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the organizers and role holders can show the check-in code"})
+		return
+	}
+
+	var occurrence int64
+	if occ != nil {
+		occurrence = occ.Unix()
+	}
+	o, ok, err := h.findCheckinOccurrence(ctx, eventID, st, occurrence, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no occurrence open for check-in"})
+		return
+	}
+	if o.cancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "event is cancelled"})
+		return
+	}
+	if now.Before(o.opens) || !now.Before(o.closes) {
+		c.JSON(http.StatusConflict, gin.H{"error": "check-in is not open", "opens_at": o.opens, "closes_at": o.closes})
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create check-in code"})
+		return
+	}
+	if err := h.db.Pool().QueryRow(ctx, `
+		UPDATE events SET checkin_secret = COALESCE(checkin_secret, $2) WHERE id = $1
+		RETURNING checkin_secret
+	`, eventID, secret).Scan(&secret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create check-in code"})
+		return
+	}
+
+	rotation := int64(checkinTokenRotation.Seconds())
+	window := now.Unix() / rotation
+	response := gin.H{
+		"token":         checkinToken(secret, o.unix(), window),
+		"expires_at":    time.Unix((window+1)*rotation, 0).UTC(),
+		"rotates_every": rotation,
+		"closes_at":     o.closes,
+	}
+	if o.at != nil {
+		response["occurrence_at"] = *o.at
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CheckinRequest records presence at an event.
+type CheckinRequest struct {
+	Token string `json:"token" binding:"required,max=100"`
+}
+
+// CheckIn records that the user is at the event, given the QR token
+// shown there. Only an opaque tag and the time bucket are stored, and
+// scanning again changes nothing.
+func (h *Handler) CheckIn(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+	now := time.Now()
+
+	var req CheckinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	st, err := h.eventState(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	var secret []byte
+	if err := h.db.Pool().QueryRow(ctx, `SELECT checkin_secret FROM events WHERE id = $1`, eventID).Scan(&secret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if secret == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired check-in code"})
+		return
+	}
+	occurrence, ok := verifyCheckinToken(secret, req.Token, now)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid or expired check-in code"})
+		return
+	}
+	o, ok, err := h.findCheckinOccurrence(ctx, eventID, st, occurrence, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !ok || o.cancelled || now.Before(o.opens) || !now.Before(o.closes) {
+		c.JSON(http.StatusConflict, gin.H{"error": "check-in is not open"})
+		return
+	}
+
+	if _, err := h.db.Pool().Exec(ctx, `
+		INSERT INTO event_checkins (event_id, occurrence_at, tag, bucket)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+	`, eventID, o.at, checkinTag(secret, o.unix(), userID), now.Truncate(checkinBucket)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check in"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "checked in"})
+}
+
+// GetCrowdSize returns how many people have checked in, for the
+// organizers and role holders. Counts are k-anonymous: per-bucket
+// arrivals are merged until each holds at least k, and a total below
+// k is reported only as such.
+func (h *Handler) GetCrowdSize(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	occ, err := parseOccurrence(c.Query("occurrence"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	st, err := h.eventState(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	staff, err := h.isEventStaff(ctx, eventID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !staff {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the organizers and role holders can see the crowd size"})
+		return
+	}
+	var occurrence int64
+	if occ != nil {
+		occurrence = occ.Unix()
+	}
+	o, ok, err := h.findCheckinOccurrence(ctx, eventID, st, occurrence, time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "occurrence not found"})
+		return
+	}
+
+	// Live check-ins until the worker folds them into counts
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT bucket, SUM(n)::int FROM (
+			SELECT bucket, COUNT(*) AS n FROM event_checkins
+			WHERE event_id = $1 AND occurrence_at IS NOT DISTINCT FROM $2
+			GROUP BY bucket
+			UNION ALL
+			SELECT bucket, count FROM event_checkin_counts
+			WHERE event_id = $1 AND occurrence_at IS NOT DISTINCT FROM $2
+		) b
+		GROUP BY bucket
+		ORDER BY bucket
+	`, eventID, o.at)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count check-ins"})
+		return
+	}
+	buckets, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (checkinBucketCount, error) {
+		var b checkinBucketCount
+		err := row.Scan(&b.start, &b.count)
+		return b, err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count check-ins"})
+		return
+	}
+
+	merged := mergeBuckets(buckets, checkinK)
+	arrivals := make([]gin.H, 0, len(merged))
+	total := 0
+	for _, b := range merged {
+		arrivals = append(arrivals, gin.H{"from": b.start, "count": b.count})
+		total += b.count
+	}
+	response := gin.H{
+		"arrivals":       arrivals,
+		"bucket_minutes": int(checkinBucket.Minutes()),
+		"k":              checkinK,
+	}
+	if total > 0 {
+		response["checked_in"] = total
+	} else {
+		response["checked_in"] = nil
+		response["fewer_than"] = checkinK
+	}
+	if o.at != nil {
+		response["occurrence_at"] = *o.at
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// CheckinCloser is the worker job that, once an occurrence is over,
+// folds its check-ins into k-anonymous counts and deletes them.
+type CheckinCloser struct {
+	db *storage.Postgres
+}
+
+// NewCheckinCloser returns a CheckinCloser.
+func NewCheckinCloser(db *storage.Postgres) *CheckinCloser {
+	return &CheckinCloser{db: db}
+}
+
+// RunOnce closes check-in for every occurrence that has ended, and
+// for any check-ins left over two days.
+func (j *CheckinCloser) RunOnce(ctx context.Context) error {
+	rows, err := j.db.Pool().Query(ctx, `
+		SELECT DISTINCT c.event_id, c.occurrence_at
+		FROM event_checkins c
+		JOIN events e ON e.id = c.event_id
+		WHERE COALESCE(c.occurrence_at, e.starts_at)
+		      + COALESCE(e.ends_at - e.starts_at, make_interval(secs => $1))
+		      + make_interval(secs => $2) < NOW()
+		   OR c.bucket < NOW() - INTERVAL '2 days'
+	`, checkinDefaultDuration.Seconds(), checkinFinalizeAfterEnd.Seconds())
+	if err != nil {
+		return fmt.Errorf("list finished check-ins: %w", err)
+	}
+	type due struct {
+		eventID      string
+		occurrenceAt *time.Time
+	}
+	finished, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (due, error) {
+		var d due
+		err := row.Scan(&d.eventID, &d.occurrenceAt)
+		return d, err
+	})
+	if err != nil {
+		return fmt.Errorf("list finished check-ins: %w", err)
+	}
+
+	for _, d := range finished {
+		if err := j.close(ctx, d.eventID, d.occurrenceAt); err != nil {
+			log.Printf("events: close check-in for %s: %v", d.eventID, err)
+		}
+	}
+	if len(finished) > 0 {
+		log.Printf("events: closed check-in for %d occurrences", len(finished))
+	}
+	return nil
+}
+
+func (j *CheckinCloser) close(ctx context.Context, eventID string, occurrenceAt *time.Time) error {
+	tx, err := j.db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rows, err := tx.Query(ctx, `
+		WITH gone AS (
+			DELETE FROM event_checkins
+			WHERE event_id = $1 AND occurrence_at IS NOT DISTINCT FROM $2
+			RETURNING bucket
+		)
+		SELECT bucket, COUNT(*)::int FROM gone GROUP BY bucket ORDER BY bucket
+	`, eventID, occurrenceAt)
+	if err != nil {
+		return err
+	}
+	buckets, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (checkinBucketCount, error) {
+		var b checkinBucketCount
+		err := row.Scan(&b.start, &b.count)
+		return b, err
+	})
+	if err != nil {
+		return err
+	}
+
+	// Counts kept from an earlier close (check-ins after it) merge in
+	rows, err = tx.Query(ctx, `
+		DELETE FROM event_checkin_counts
+		WHERE event_id = $1 AND occurrence_at IS NOT DISTINCT FROM $2
+		RETURNING bucket, count
+	`, eventID, occurrenceAt)
+	if err != nil {
+		return err
+	}
+	kept, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (checkinBucketCount, error) {
+		var b checkinBucketCount
+		err := row.Scan(&b.start, &b.count)
+		return b, err
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, b := range mergeBuckets(combineBuckets(buckets, kept), checkinK) {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO event_checkin_counts (event_id, occurrence_at, bucket, count)
+			VALUES ($1, $2, $3, $4)
+		`, eventID, occurrenceAt, b.start, b.count); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// combineBuckets adds two ordered bucket lists together, in order.
+func combineBuckets(a, b []checkinBucketCount) []checkinBucketCount {
+	out := make([]checkinBucketCount, 0, len(a)+len(b))
+	for len(a) > 0 || len(b) > 0 {
+		switch {
+		case len(b) == 0 || (len(a) > 0 && a[0].start.Before(b[0].start)):
+			out = append(out, a[0])
+			a = a[1:]
+		case len(a) == 0 || b[0].start.Before(a[0].start):
+			out = append(out, b[0])
+			b = b[1:]
+		default:
+			out = append(out, checkinBucketCount{start: a[0].start, count: a[0].count + b[0].count})
+			a, b = a[1:], b[1:]
+		}
+	}
+	return out
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckinToken(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	now := time.Date(2026, 5, 1, 14, 0, 30, 0, time.UTC)
+	window := now.Unix() / 60
+	occurrence := time.Date(2026, 5, 1, 14, 0, 0, 0, time.UTC).Unix()
+
+	token := checkinToken(secret, occurrence, window)
+	got, ok := verifyCheckinToken(secret, token, now)
+	require.True(t, ok)
+	assert.Equal(t, occurrence, got)
+
+	_, ok = verifyCheckinToken(secret, token, now.Add(time.Minute))
+	assert.True(t, ok, "the previous token is still accepted")
+	_, ok = verifyCheckinToken(secret, token, now.Add(2*time.Minute))
+	assert.False(t, ok, "older tokens have expired")
+
+	_, ok = verifyCheckinToken([]byte("another event's secret"), token, now)
+	assert.False(t, ok)
+	_, ok = verifyCheckinToken(secret, "0"+token[strings.Index(token, "."):], now)
+	assert.False(t, ok, "bound to its occurrence")
+	_, ok = verifyCheckinToken(secret, "garbage", now)
+	assert.False(t, ok)
+
+	assert.Equal(t, checkinTag(secret, occurrence, "u1"), checkinTag(secret, occurrence, "u1"))
+	assert.NotEqual(t, checkinTag(secret, occurrence, "u1"), checkinTag(secret, occurrence, "u2"))
+}
+
+func TestMergeBuckets(t *testing.T) {
+	at := func(m int) time.Time { return time.Date(2026, 5, 1, 14, m, 0, 0, time.UTC) }
+	b := func(m, n int) checkinBucketCount { return checkinBucketCount{start: at(m), count: n} }
+
+	assert.Empty(t, mergeBuckets([]checkinBucketCount{b(0, 2), b(15, 2)}, 5), "fewer than k in total")
+	assert.Equal(t, []checkinBucketCount{b(0, 12), b(15, 5)},
+		mergeBuckets([]checkinBucketCount{b(0, 12), b(15, 5)}, 5))
+	assert.Equal(t, []checkinBucketCount{b(0, 5), b(30, 9)},
+		mergeBuckets([]checkinBucketCount{b(0, 2), b(15, 3), b(30, 7), b(45, 2)}, 5),
+		"small runs merge forward; a small tail joins the last bucket")
+}
+
+func TestCombineBuckets(t *testing.T) {
+	at := func(m int) time.Time { return time.Date(2026, 5, 1, 14, m, 0, 0, time.UTC) }
+	b := func(m, n int) checkinBucketCount { return checkinBucketCount{start: at(m), count: n} }
+
+	assert.Equal(t, []checkinBucketCount{b(0, 5), b(15, 8), b(30, 1)},
+		combineBuckets([]checkinBucketCount{b(15, 3), b(30, 1)}, []checkinBucketCount{b(0, 5), b(15, 5)}))
+}
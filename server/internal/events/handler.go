@@ -1,14 +1,22 @@
 package events
 
 import (
+	"context"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/counts"
+	"github.com/kuurier/server/internal/mutes"
+	"github.com/kuurier/server/internal/push"
 	"github.com/kuurier/server/internal/storage"
+	"github.com/kuurier/server/internal/websocket"
 )
 
 // Handler handles event-related endpoints
@@ -16,11 +24,13 @@ type Handler struct {
 	cfg   *config.Config
 	db    *storage.Postgres
 	redis *storage.Redis
+	push  *push.Service  // optional; nil disables attendee pushes
+	hub   *websocket.Hub // optional; nil disables live attendee updates
 }
 
 // NewHandler creates a new events handler
-func NewHandler(cfg *config.Config, db *storage.Postgres, redis *storage.Redis) *Handler {
-	return &Handler{cfg: cfg, db: db, redis: redis}
+func NewHandler(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, pushService *push.Service, hub *websocket.Hub) *Handler {
+	return &Handler{cfg: cfg, db: db, redis: redis, push: pushService, hub: hub}
 }
 
 // CreateEventRequest represents a new event
@@ -32,20 +42,48 @@ type CreateEventRequest struct {
 	Longitude          float64  `json:"longitude" binding:"required"`
 	LocationName       string   `json:"location_name"`
 	LocationArea       string   `json:"location_area"`        // General area shown when exact location hidden
-	LocationVisibility string   `json:"location_visibility"`  // public, rsvp, timed
+	LocationVisibility string   `json:"location_visibility"`  // public, rsvp, timed, approval
 	LocationRevealAt   *int64   `json:"location_reveal_at"`   // Unix timestamp for timed visibility
 	StartsAt           int64    `json:"starts_at" binding:"required"` // Unix timestamp
 	EndsAt             *int64   `json:"ends_at"`
 	TopicIDs           []string `json:"topic_ids"`
 	EnableChat         *bool    `json:"enable_chat"`          // Whether to create event discussion channel
+	// Language is the ISO 639-1 code the event is written in; search
+	// stems it with the matching dictionary.
+	Language string `json:"language" binding:"omitempty,len=2,alpha"`
+	// Recurrence is an RFC 5545 RRULE such as "FREQ=WEEKLY;BYDAY=FR".
+	// starts_at is the first occurrence; later ones keep its wall-clock
+	// time in Timezone (IANA, default UTC).
+	Recurrence           string  `json:"recurrence"`
+	RecurrenceExceptions []int64 `json:"recurrence_exceptions"` // Occurrences to skip (Unix timestamps)
+	Timezone             string  `json:"timezone"`
+	// With approval visibility, RSVPs wait for the organizer unless the
+	// requester has at least AutoApproveTrust trust or is a member of
+	// AutoApproveOrgID, which the organizer must belong to.
+	AutoApproveTrust *int    `json:"auto_approve_trust" binding:"omitempty,min=1"`
+	AutoApproveOrgID *string `json:"auto_approve_org_id" binding:"omitempty,uuid"`
+	// OrgID puts the event under an organization the creator is an
+	// admin or moderator of; its admins and moderators can run it too.
+	OrgID *string `json:"org_id" binding:"omitempty,uuid"`
+	// Accessibility lists what the event offers (wheelchair, asl,
+	// family_friendly, ...) and RiskLevel what to expect from police:
+	// low, elevated or arrestable.
+	Accessibility []string `json:"accessibility"`
+	RiskLevel     string   `json:"risk_level" binding:"omitempty,oneof=low elevated arrestable"`
+	// Legal support, shown only to RSVPs: who to call on arrest and
+	// the bust card, what to know before going.
+	LegalHotline string `json:"legal_hotline" binding:"max=50"`
+	BustCard     string `json:"bust_card" binding:"max=2000"`
 }
 
-// shouldRevealLocation determines if location should be shown based on visibility settings
+// shouldRevealLocation determines if location should be shown based on visibility settings.
+// hasRSVP means an approved RSVP; for approval-gated events only the
+// organizer approves them.
 func shouldRevealLocation(visibility string, revealAt *time.Time, userID, organizerID string, hasRSVP bool) bool {
 	switch visibility {
 	case "public":
 		return true
-	case "rsvp":
+	case "rsvp", "approval":
 		return hasRSVP || userID == organizerID
 	case "timed":
 		if userID == organizerID {
@@ -61,7 +99,10 @@ func shouldRevealLocation(visibility string, revealAt *time.Time, userID, organi
 	}
 }
 
-// ListEvents returns upcoming events
+// ListEvents returns upcoming events. Recurring events are expanded
+// into their occurrences between ?from= and ?to= (Unix seconds; by
+// default from a day ago for the next 90 days). ?accessibility= and
+// ?max_risk= filter them (see eventFilters).
 func (h *Handler) ListEvents(c *gin.Context) {
 	ctx := c.Request.Context()
 	userID := c.GetString("user_id")
@@ -75,23 +116,50 @@ func (h *Handler) ListEvents(c *gin.Context) {
 	if limit > 100 {
 		limit = 100
 	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	from, to, bounded, err := occurrenceWindow(c, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filters, err := parseEventFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Base query - includes location privacy fields and channel info
+	// Base query - includes location privacy fields and channel info.
+	// Series whose rule still runs inside the window are included
+	// whenever they started.
 	query := `
 		SELECT e.id, e.organizer_id, e.title, e.description, e.event_type,
 			   ST_Y(e.location::geometry) as lat, ST_X(e.location::geometry) as lon,
 			   e.location_name, e.location_area, e.location_visibility, e.location_reveal_at,
 			   e.starts_at, e.ends_at, e.is_cancelled, e.channel_id,
-			   (SELECT COUNT(*) FROM event_rsvps WHERE event_id = e.id AND status = 'going') as rsvp_count,
-			   EXISTS(SELECT 1 FROM event_rsvps WHERE event_id = e.id AND user_id = $1) as has_rsvp,
-			   EXISTS(SELECT 1 FROM channel_members WHERE channel_id = e.channel_id AND user_id = $1) as is_channel_member
+			   e.recurrence_rule, e.timezone,
+			   (SELECT COUNT(*) FROM event_rsvps WHERE event_id = e.id AND status = 'going' AND approval = 'approved') as rsvp_count,
+			   EXISTS(SELECT 1 FROM event_rsvps WHERE event_id = e.id AND user_id = $1 AND approval = 'approved') as has_rsvp,
+			   EXISTS(SELECT 1 FROM channel_members WHERE channel_id = e.channel_id AND user_id = $1) as is_channel_member,
+			   e.accessibility, e.risk_level,
+			   ` + managesEventSQL + ` as is_manager
 		FROM events e
-		WHERE e.starts_at > NOW() - INTERVAL '1 day'
+		WHERE (e.starts_at > $2
+		       OR (e.recurrence_rule IS NOT NULL AND e.starts_at < $3
+		           AND (e.recurrence_ends_at IS NULL OR e.recurrence_ends_at > $2)))
 		  AND e.is_cancelled = false
+		  AND NOT ` + mutes.HiddenSQL("$1", "e.organizer_id", "NULL", "e.title || ' ' || COALESCE(e.description, '')",
+		"ARRAY(SELECT topic_id::text FROM event_topics WHERE event_id = e.id)") + `
 	`
 
-	args := []interface{}{userID}
-	argCount := 1
+	args := []interface{}{userID, from, to}
+	argCount := 3
+
+	if bounded {
+		query += " AND (e.recurrence_rule IS NOT NULL OR e.starts_at < $3)"
+	}
 
 	if eventType != "" {
 		argCount++
@@ -101,93 +169,171 @@ func (h *Handler) ListEvents(c *gin.Context) {
 
 	if topicID != "" {
 		argCount++
-		query += " AND EXISTS (SELECT 1 FROM event_topics WHERE event_id = e.id AND topic_id = $" + strconv.Itoa(argCount) + ")"
+		query += " AND EXISTS (SELECT 1 FROM event_topics et JOIN topic_lineage tl ON tl.topic_id = et.topic_id WHERE et.event_id = e.id AND tl.ancestor_id = $" + strconv.Itoa(argCount) + ")"
 		args = append(args, topicID)
 	}
 
-	query += " ORDER BY e.starts_at ASC"
+	filterSQL, filterArgs := filters.sql(argCount + 1)
+	query += filterSQL
+	args = append(args, filterArgs...)
+	argCount += len(filterArgs)
+
+	// Series sort first so they aren't crowded out; they're expanded
+	// and merged with one-off events below, so the page is cut in Go.
+	query += " ORDER BY (e.recurrence_rule IS NOT NULL) DESC, e.starts_at ASC"
 	argCount++
 	query += " LIMIT $" + strconv.Itoa(argCount)
-	args = append(args, limit)
-	argCount++
-	query += " OFFSET $" + strconv.Itoa(argCount)
-	args = append(args, offset)
+	args = append(args, offset+limit+maxListedSeries)
 
 	rows, err := h.db.Pool().Query(ctx, query, args...)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch events"})
 		return
 	}
+	listings, err := h.listEventRows(ctx, rows, userID, from, to, offset+limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": sortListings(listings, offset, limit),
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+// listEventRows scans rows of ListEvents' columns and expands series
+// into their occurrences between from and to, at most n per series.
+func (h *Handler) listEventRows(ctx context.Context, rows pgx.Rows, userID string, from, to time.Time, n int) ([]eventListing, error) {
 	defer rows.Close()
 
-	var events []gin.H
+	var listed []listedEvent
+	var seriesIDs []string
 	for rows.Next() {
-		var id, organizerID, title, eventType string
-		var description, locationName, locationArea, channelID *string
-		var locationVisibility string
-		var locationRevealAt *time.Time
-		var lat, lon float64
-		var startsAt time.Time
-		var endsAt *time.Time
-		var isCancelled, hasRSVP, isChannelMember bool
-		var rsvpCount int
-
-		if err := rows.Scan(&id, &organizerID, &title, &description, &eventType, &lat, &lon,
-			&locationName, &locationArea, &locationVisibility, &locationRevealAt,
-			&startsAt, &endsAt, &isCancelled, &channelID, &rsvpCount, &hasRSVP, &isChannelMember); err != nil {
+		var r listedEvent
+		if err := rows.Scan(&r.id, &r.organizerID, &r.title, &r.description, &r.eventType, &r.lat, &r.lon,
+			&r.locationName, &r.locationArea, &r.locationVisibility, &r.locationRevealAt,
+			&r.startsAt, &r.endsAt, &r.isCancelled, &r.channelID, &r.recurrenceRule, &r.timezone,
+			&r.rsvpCount, &r.hasRSVP, &r.isChannelMember, &r.accessibility, &r.riskLevel, &r.isManager); err != nil {
 			continue
 		}
-
-		event := gin.H{
-			"id":                  id,
-			"organizer_id":        organizerID,
-			"title":               title,
-			"event_type":          eventType,
-			"starts_at":           startsAt,
-			"rsvp_count":          rsvpCount,
-			"location_visibility": locationVisibility,
+		if r.recurrenceRule != nil {
+			seriesIDs = append(seriesIDs, r.id)
 		}
+		listed = append(listed, r)
+	}
+	rows.Close()
 
-		// Include channel info if available
-		if channelID != nil {
-			event["channel_id"] = *channelID
-			event["is_channel_member"] = isChannelMember
-		}
+	overrides, err := h.occurrenceOverrides(ctx, seriesIDs)
+	if err != nil {
+		return nil, err
+	}
+	rsvps, err := h.occurrenceRSVPs(ctx, seriesIDs, userID)
+	if err != nil {
+		return nil, err
+	}
 
-		// Conditionally include exact location
-		if shouldRevealLocation(locationVisibility, locationRevealAt, userID, organizerID, hasRSVP) {
-			event["location"] = gin.H{"latitude": lat, "longitude": lon}
-			if locationName != nil {
-				event["location_name"] = *locationName
-			}
-			event["location_revealed"] = true
-		} else {
-			event["location_revealed"] = false
-			// Show general area if provided
-			if locationArea != nil {
-				event["location_area"] = *locationArea
-			}
-			// Show when location will be revealed for timed events
-			if locationVisibility == "timed" && locationRevealAt != nil {
-				event["location_reveal_at"] = *locationRevealAt
-			}
+	policy := counts.FromConfig(h.cfg)
+	var listings []eventListing
+	for _, r := range listed {
+		s := newSeries(r.recurrenceRule, r.timezone, r.startsAt, r.endsAt)
+		if s == nil {
+			listings = append(listings, eventListing{r.startsAt, r.listing(userID, policy)})
+			continue
+		}
+		for _, o := range s.occurrences(from, to, n, overrides[r.id]) {
+			occ := r.forOccurrence(s, o)
+			occ.rsvpCount = rsvps[r.id][o.at.Unix()].going
+			event := occ.listing(userID, policy)
+			setOccurrenceFields(event, o)
+			event["recurrence"] = s.rule.String()
+			listings = append(listings, eventListing{o.startsAt, event})
 		}
+	}
+	return listings, nil
+}
 
-		if description != nil {
-			event["description"] = *description
+// listedEvent is a row of ListEvents.
+type listedEvent struct {
+	id, organizerID, title, eventType       string
+	description, locationName, locationArea *string
+	channelID                               *string
+	locationVisibility                      string
+	locationRevealAt                        *time.Time
+	lat, lon                                float64
+	startsAt                                time.Time
+	endsAt                                  *time.Time
+	recurrenceRule                          *string
+	timezone                                string
+	isCancelled, hasRSVP, isChannelMember   bool
+	isManager                               bool
+	rsvpCount                               int
+	accessibility                           []string
+	riskLevel                               *string
+}
+
+// forOccurrence returns the row as one occurrence of its series.
+func (r listedEvent) forOccurrence(s *series, o occurrence) listedEvent {
+	r.startsAt, r.endsAt = o.startsAt, o.endsAt
+	if r.locationVisibility == "timed" {
+		r.locationRevealAt = s.revealAt(o, r.locationRevealAt)
+	}
+	if o.override.locationName != nil {
+		r.locationName = o.override.locationName
+	}
+	if o.override.locationArea != nil {
+		r.locationArea = o.override.locationArea
+	}
+	return r
+}
+
+// listing renders the row as userID may see it, with its RSVP count
+// blurred by p unless they help run the event.
+func (r listedEvent) listing(userID string, p counts.Policy) gin.H {
+	event := gin.H{
+		"id":                  r.id,
+		"organizer_id":        r.organizerID,
+		"title":               r.title,
+		"event_type":          r.eventType,
+		"starts_at":           r.startsAt,
+		"location_visibility": r.locationVisibility,
+	}
+	p.Set(event, "rsvp_count", r.rsvpCount, r.isManager)
+	setAccessibilityFields(event, r.accessibility, r.riskLevel)
+
+	// Include channel info if available
+	if r.channelID != nil {
+		event["channel_id"] = *r.channelID
+		event["is_channel_member"] = r.isChannelMember
+	}
+
+	// Conditionally include exact location
+	if shouldRevealLocation(r.locationVisibility, r.locationRevealAt, userID, r.organizerID, r.hasRSVP || r.isManager) {
+		event["location"] = gin.H{"latitude": r.lat, "longitude": r.lon}
+		if r.locationName != nil {
+			event["location_name"] = *r.locationName
+		}
+		event["location_revealed"] = true
+	} else {
+		event["location_revealed"] = false
+		// Show general area if provided
+		if r.locationArea != nil {
+			event["location_area"] = *r.locationArea
 		}
-		if endsAt != nil {
-			event["ends_at"] = *endsAt
+		// Show when location will be revealed for timed events
+		if r.locationVisibility == "timed" && r.locationRevealAt != nil {
+			event["location_reveal_at"] = *r.locationRevealAt
 		}
-
-		events = append(events, event)
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"events": events,
-		"limit":  limit,
-		"offset": offset,
-	})
+	if r.description != nil {
+		event["description"] = *r.description
+	}
+	if r.endsAt != nil {
+		event["ends_at"] = *r.endsAt
+	}
+	return event
 }
 
 // CreateEvent creates a new event
@@ -210,10 +356,67 @@ func (h *Handler) CreateEvent(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if _, err := parseRecurrence(req.Recurrence, req.Timezone, req.StartsAt, req.RecurrenceExceptions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, err := normalizeAccessibility(req.Accessibility); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	ctx := c.Request.Context()
+
+	if req.AutoApproveOrgID != nil {
+		member, err := h.isOrgMember(ctx, *req.AutoApproveOrgID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if !member {
+			c.JSON(http.StatusForbidden, gin.H{"error": "you must be a member of the auto-approve organization"})
+			return
+		}
+	}
+	if req.OrgID != nil && !h.checkEventOrg(c, *req.OrgID, userID) {
+		return
+	}
+
+	// Start transaction
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	eventID, channelID, err := insertEvent(ctx, tx, userID, req, time.Now().UTC())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Commit transaction
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	response := gin.H{
+		"id":      eventID,
+		"message": "event created",
+	}
+	if channelID != "" {
+		response["channel_id"] = channelID
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// insertEvent creates an event, its topic associations and, if
+// requested, its chat channel inside tx. Shared by CreateEvent and
+// the importer. The returned error's message is safe to show.
+func insertEvent(ctx context.Context, tx pgx.Tx, userID string, req CreateEventRequest, now time.Time) (string, string, error) {
 	eventID := uuid.New().String()
-	now := time.Now().UTC()
 
 	// Default enableChat to false if not specified
 	enableChat := req.EnableChat != nil && *req.EnableChat
@@ -230,7 +433,7 @@ func (h *Handler) CreateEvent(c *gin.Context) {
 	if visibility == "" {
 		visibility = "public"
 	}
-	if visibility != "public" && visibility != "rsvp" && visibility != "timed" {
+	if visibility != "public" && visibility != "rsvp" && visibility != "timed" && visibility != "approval" {
 		visibility = "public"
 	}
 
@@ -247,27 +450,45 @@ func (h *Handler) CreateEvent(c *gin.Context) {
 		}
 	}
 
-	locationSQL := "POINT(" + strconv.FormatFloat(req.Longitude, 'f', 6, 64) + " " + strconv.FormatFloat(req.Latitude, 'f', 6, 64) + ")"
-
-	// Start transaction
-	tx, err := h.db.Pool().Begin(ctx)
+	recur, err := parseRecurrence(req.Recurrence, req.Timezone, req.StartsAt, req.RecurrenceExceptions)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
-		return
+		return "", "", err
 	}
-	defer tx.Rollback(ctx)
+	accessibility, err := normalizeAccessibility(req.Accessibility)
+	if err != nil {
+		return "", "", err
+	}
+	if req.RiskLevel != "" && riskRank(req.RiskLevel) < 0 {
+		return "", "", errors.New("risk_level must be low, elevated or arrestable")
+	}
+
+	locationSQL := "POINT(" + strconv.FormatFloat(req.Longitude, 'f', 6, 64) + " " + strconv.FormatFloat(req.Latitude, 'f', 6, 64) + ")"
 
 	// Create the event first (without channel_id to avoid FK violation)
 	_, err = tx.Exec(ctx, `
 		INSERT INTO events (id, organizer_id, title, description, event_type, location, location_name,
-		                    location_area, location_visibility, location_reveal_at, starts_at, ends_at)
-		VALUES ($1, $2, $3, $4, $5, ST_GeogFromText($6), $7, $8, $9, $10, $11, $12)
+		                    location_area, location_visibility, location_reveal_at, starts_at, ends_at, language,
+		                    recurrence_rule, recurrence_ends_at, timezone, approval_min_trust, approval_org_id,
+		                    org_id, accessibility, risk_level, legal_hotline, bust_card)
+		VALUES ($1, $2, $3, $4, $5, ST_GeogFromText($6), $7, $8, $9, $10, $11, $12, NULLIF(LOWER($13), ''),
+		        $14, $15, $16, $17, $18, $19, $20, NULLIF($21, ''), NULLIF($22, ''), NULLIF($23, ''))
 	`, eventID, userID, req.Title, req.Description, req.EventType, locationSQL,
-		req.LocationName, req.LocationArea, visibility, revealAt, startsAt, endsAt)
+		req.LocationName, req.LocationArea, visibility, revealAt, startsAt, endsAt, req.Language,
+		recur.rule, recur.endsAt, recur.timezone, req.AutoApproveTrust, req.AutoApproveOrgID,
+		req.OrgID, accessibility, req.RiskLevel, req.LegalHotline, req.BustCard)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create event"})
-		return
+		return "", "", errors.New("failed to create event")
+	}
+
+	for _, ex := range recur.exceptions {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO event_occurrences (event_id, occurrence_at, is_cancelled)
+			VALUES ($1, $2, true)
+			ON CONFLICT DO NOTHING
+		`, eventID, ex); err != nil {
+			return "", "", errors.New("failed to add recurrence exceptions")
+		}
 	}
 
 	// Optionally create the event channel
@@ -281,15 +502,13 @@ func (h *Handler) CreateEvent(c *gin.Context) {
 		`, channelID, channelName, req.Description, eventID, userID, now)
 
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create event channel"})
-			return
+			return "", "", errors.New("failed to create event channel")
 		}
 
 		// Link the channel back to the event
 		_, err = tx.Exec(ctx, `UPDATE events SET channel_id = $1 WHERE id = $2`, channelID, eventID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to link event channel"})
-			return
+			return "", "", errors.New("failed to link event channel")
 		}
 
 		// Add organizer as channel admin
@@ -299,33 +518,22 @@ func (h *Handler) CreateEvent(c *gin.Context) {
 		`, channelID, userID, now)
 
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add organizer to channel"})
-			return
+			return "", "", errors.New("failed to add organizer to channel")
 		}
 	}
 
-	// Commit transaction
-	if err := tx.Commit(ctx); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
-		return
-	}
-
-	// Add topic associations (non-critical, after commit)
+	// Add topic associations. Unknown and archived topics are skipped.
 	for _, topicID := range req.TopicIDs {
-		h.db.Pool().Exec(ctx, `
-			INSERT INTO event_topics (event_id, topic_id) VALUES ($1, $2)
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO event_topics (event_id, topic_id)
+			SELECT $1, id FROM topics WHERE id::text = $2 AND archived_at IS NULL
 			ON CONFLICT DO NOTHING
-		`, eventID, topicID)
+		`, eventID, topicID); err != nil {
+			return "", "", errors.New("failed to add event topics")
+		}
 	}
 
-	response := gin.H{
-		"id":      eventID,
-		"message": "event created",
-	}
-	if channelID != "" {
-		response["channel_id"] = channelID
-	}
-	c.JSON(http.StatusCreated, response)
+	return eventID, channelID, nil
 }
 
 // GetEvent returns a single event
@@ -335,38 +543,114 @@ func (h *Handler) GetEvent(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	var id, organizerID, title, eventType, locationVisibility string
-	var description, locationName, locationArea, channelID *string
+	var description, locationName, locationArea, channelID, rolesChannelID, orgID *string
+	var accessibility []string
+	var riskLevel, legalHotline, bustCard *string
 	var locationRevealAt *time.Time
 	var lat, lon float64
 	var startsAt time.Time
 	var endsAt *time.Time
 	var isCancelled bool
+	var recurrenceRule *string
+	var timezone string
 
-	err := h.db.Pool().QueryRow(ctx, `
+	occ, err := parseOccurrence(c.Query("occurrence"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = h.db.Pool().QueryRow(ctx, `
 		SELECT e.id, e.organizer_id, e.title, e.description, e.event_type,
 			   ST_Y(e.location::geometry) as lat, ST_X(e.location::geometry) as lon,
 			   e.location_name, e.location_area, e.location_visibility, e.location_reveal_at,
-			   e.starts_at, e.ends_at, e.is_cancelled, e.channel_id
+			   e.starts_at, e.ends_at, e.is_cancelled, e.channel_id, e.recurrence_rule, e.timezone,
+			   e.roles_channel_id, e.org_id, e.accessibility, e.risk_level, e.legal_hotline, e.bust_card
 		FROM events e
 		WHERE e.id = $1
 	`, eventID).Scan(&id, &organizerID, &title, &description, &eventType, &lat, &lon,
 		&locationName, &locationArea, &locationVisibility, &locationRevealAt,
-		&startsAt, &endsAt, &isCancelled, &channelID)
+		&startsAt, &endsAt, &isCancelled, &channelID, &recurrenceRule, &timezone,
+		&rolesChannelID, &orgID, &accessibility, &riskLevel, &legalHotline, &bustCard)
 
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
 		return
 	}
 
+	// A recurring event can be viewed as a series or, with
+	// ?occurrence=, as one occurrence with its exceptions applied.
+	s := newSeries(recurrenceRule, timezone, startsAt, endsAt)
+	if occ != nil && s == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "event is not recurring"})
+		return
+	}
+	var overrides map[int64]occurrenceOverride
+	if s != nil {
+		all, err := h.occurrenceOverrides(ctx, []string{id})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch event"})
+			return
+		}
+		overrides = all[id]
+	}
+	var o occurrence
+	var occurrenceAt *time.Time
+	if occ != nil {
+		var ok bool
+		if o, ok = s.occurrence(*occ, overrides); !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "occurrence not found"})
+			return
+		}
+		occurrenceAt = &o.at
+		startsAt, endsAt = o.startsAt, o.endsAt
+		isCancelled = isCancelled || o.override.cancelled
+		if o.override.title != nil {
+			title = *o.override.title
+		}
+		if o.override.description != nil {
+			description = o.override.description
+		}
+		if o.override.locationName != nil {
+			locationName = o.override.locationName
+		}
+		if o.override.locationArea != nil {
+			locationArea = o.override.locationArea
+		}
+		if locationVisibility == "timed" {
+			locationRevealAt = s.revealAt(o, locationRevealAt)
+		}
+	}
+
 	// Get RSVP count
 	var rsvpCount int
-	h.db.Pool().QueryRow(ctx, "SELECT COUNT(*) FROM event_rsvps WHERE event_id = $1 AND status = 'going'", eventID).Scan(&rsvpCount)
-
-	// Check if current user has RSVP'd
-	var userRSVP *string
+	h.db.Pool().QueryRow(ctx, `
+		SELECT COUNT(*) FROM event_rsvps
+		WHERE event_id = $1 AND status = 'going' AND approval = 'approved'
+		  AND ($2::timestamptz IS NULL OR occurrence_at = $2)
+	`, eventID, occurrenceAt).Scan(&rsvpCount)
+
+	// Check if current user has RSVP'd. An approved RSVP to any
+	// occurrence of a series reveals the series' location.
+	var userRSVP, userApproval *string
 	var hasRSVP bool
-	err = h.db.Pool().QueryRow(ctx, "SELECT status FROM event_rsvps WHERE event_id = $1 AND user_id = $2", eventID, userID).Scan(&userRSVP)
-	hasRSVP = err == nil && userRSVP != nil
+	err = h.db.Pool().QueryRow(ctx, `
+		SELECT status, approval FROM event_rsvps
+		WHERE event_id = $1 AND user_id = $2 AND occurrence_at IS NOT DISTINCT FROM $3
+	`, eventID, userID, occurrenceAt).Scan(&userRSVP, &userApproval)
+	hasRSVP = err == nil && userApproval != nil && *userApproval == approvalApproved
+	if s != nil && !hasRSVP {
+		h.db.Pool().QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM event_rsvps WHERE event_id = $1 AND user_id = $2 AND approval = 'approved')
+		`, eventID, userID).Scan(&hasRSVP)
+	}
+
+	// Whoever runs the event sees it as the organizer does
+	manager, err := h.eventManager(ctx, eventID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch event"})
+		return
+	}
 
 	event := gin.H{
 		"id":                  id,
@@ -375,10 +659,34 @@ func (h *Handler) GetEvent(c *gin.Context) {
 		"event_type":          eventType,
 		"starts_at":           startsAt,
 		"is_cancelled":        isCancelled,
-		"rsvp_count":          rsvpCount,
 		"location_visibility": locationVisibility,
 	}
 
+	counts.FromConfig(h.cfg).Set(event, "rsvp_count", rsvpCount, manager != "")
+	setAccessibilityFields(event, accessibility, riskLevel)
+	if orgID != nil {
+		event["org_id"] = *orgID
+	}
+	if manager != "" {
+		event["manager_role"] = manager
+	}
+
+	// Legal support is for those going, like an RSVP-only location
+	if legalHotline != nil || bustCard != nil {
+		if hasRSVP || manager != "" {
+			legal := gin.H{}
+			if legalHotline != nil {
+				legal["hotline"] = *legalHotline
+			}
+			if bustCard != nil {
+				legal["bust_card"] = *bustCard
+			}
+			event["legal_support"] = legal
+		} else {
+			event["has_legal_support"] = true
+		}
+	}
+
 	// Include channel_id if available
 	if channelID != nil {
 		event["channel_id"] = *channelID
@@ -391,8 +699,19 @@ func (h *Handler) GetEvent(c *gin.Context) {
 		event["is_channel_member"] = isChannelMember
 	}
 
+	// The roles channel is only shown to its members
+	if rolesChannelID != nil {
+		var isRolesMember bool
+		h.db.Pool().QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM channel_members WHERE channel_id = $1 AND user_id = $2)
+		`, *rolesChannelID, userID).Scan(&isRolesMember)
+		if isRolesMember {
+			event["roles_channel_id"] = *rolesChannelID
+		}
+	}
+
 	// Conditionally include exact location
-	if shouldRevealLocation(locationVisibility, locationRevealAt, userID, organizerID, hasRSVP) {
+	if shouldRevealLocation(locationVisibility, locationRevealAt, userID, organizerID, hasRSVP || manager != "") {
 		event["location"] = gin.H{"latitude": lat, "longitude": lon}
 		if locationName != nil {
 			event["location_name"] = *locationName
@@ -409,6 +728,9 @@ func (h *Handler) GetEvent(c *gin.Context) {
 		if locationVisibility == "rsvp" {
 			event["location_hint"] = "RSVP to see exact location"
 		}
+		if locationVisibility == "approval" {
+			event["location_hint"] = "RSVP and be approved by the organizer to see exact location"
+		}
 	}
 
 	if description != nil {
@@ -419,86 +741,240 @@ func (h *Handler) GetEvent(c *gin.Context) {
 	}
 	if userRSVP != nil {
 		event["user_rsvp"] = *userRSVP
+		if locationVisibility == "approval" {
+			event["user_rsvp_approval"] = *userApproval
+		}
+	}
+	if locationVisibility == "approval" && manager != "" {
+		var pending int
+		var minTrust *int
+		var approvalOrgID *string
+		h.db.Pool().QueryRow(ctx, `
+			SELECT (SELECT COUNT(*) FROM event_rsvps WHERE event_id = e.id AND approval = 'pending'),
+			       e.approval_min_trust, e.approval_org_id
+			FROM events e WHERE e.id = $1
+		`, eventID).Scan(&pending, &minTrust, &approvalOrgID)
+		event["pending_rsvps"] = pending
+		if minTrust != nil {
+			event["auto_approve_trust"] = *minTrust
+		}
+		if approvalOrgID != nil {
+			event["auto_approve_org_id"] = *approvalOrgID
+		}
+	}
+
+	if s != nil {
+		event["recurrence"] = s.rule.String()
+		event["timezone"] = timezone
+		if occurrenceAt != nil {
+			event["occurrence_at"] = *occurrenceAt
+		} else {
+			upcoming, err := h.upcomingOccurrences(ctx, id, userID, s, overrides, manager != "")
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch event"})
+				return
+			}
+			event["next_occurrences"] = upcoming
+		}
 	}
 
 	c.JSON(http.StatusOK, event)
 }
 
-// UpdateEvent updates an event (organizer only)
+// UpdateEventRequest is a partial update of an event. For a recurring
+// event, Scope picks what it applies to:
+//
+//	occurrence  only Occurrence
+//	future      Occurrence and every later one (the series is split there)
+//	all         the whole series (the default without Occurrence)
+type UpdateEventRequest struct {
+	Title              *string  `json:"title"`
+	Description        *string  `json:"description"`
+	LocationName       *string  `json:"location_name"`
+	LocationArea       *string  `json:"location_area"`
+	LocationVisibility *string  `json:"location_visibility" binding:"omitempty,oneof=public rsvp timed approval"`
+	LocationRevealAt   *int64   `json:"location_reveal_at"`
+	Latitude           *float64 `json:"latitude"`
+	Longitude          *float64 `json:"longitude"`
+	StartsAt           *int64   `json:"starts_at"`
+	EndsAt             *int64   `json:"ends_at"`
+	IsCancelled        *bool    `json:"is_cancelled"`
+	Language           *string  `json:"language" binding:"omitempty,len=2,alpha"`
+	Occurrence         *int64   `json:"occurrence"` // Unix start of the occurrence as the rule generates it
+	Scope              string   `json:"scope" binding:"omitempty,oneof=occurrence future all"`
+	Recurrence         *string  `json:"recurrence"` // New RRULE; "" makes the event one-off
+	Timezone           *string  `json:"timezone"`
+	// Auto-approve rules for approval visibility; 0 and "" turn them off
+	AutoApproveTrust *int    `json:"auto_approve_trust" binding:"omitempty,min=0"`
+	AutoApproveOrgID *string `json:"auto_approve_org_id" binding:"omitempty,uuid|len=0"`
+	// Owning organization; "" takes the event out of its organization
+	OrgID *string `json:"org_id" binding:"omitempty,uuid|len=0"`
+	// Accessibility replaces the list; "" clears the other three
+	Accessibility *[]string `json:"accessibility"`
+	RiskLevel     *string   `json:"risk_level" binding:"omitempty,oneof=low elevated arrestable|len=0"`
+	LegalHotline  *string   `json:"legal_hotline" binding:"omitempty,max=50"`
+	BustCard      *string   `json:"bust_card" binding:"omitempty,max=2000"`
+}
+
+// UpdateEvent updates an event (organizer, co-organizers and the
+// owning organization's admins and moderators)
 func (h *Handler) UpdateEvent(c *gin.Context) {
 	userID := c.GetString("user_id")
 	eventID := c.Param("id")
 	ctx := c.Request.Context()
 
 	// Verify ownership
-	var organizerID string
-	err := h.db.Pool().QueryRow(ctx, "SELECT organizer_id FROM events WHERE id = $1", eventID).Scan(&organizerID)
-	if err != nil {
+	role, err := h.eventManager(ctx, eventID, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
 		return
 	}
-	if organizerID != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "only the organizer can update this event"})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 		return
 	}
-
-	var req struct {
-		Title              *string `json:"title"`
-		Description        *string `json:"description"`
-		LocationName       *string `json:"location_name"`
-		LocationArea       *string `json:"location_area"`
-		LocationVisibility *string `json:"location_visibility"`
-		LocationRevealAt   *int64  `json:"location_reveal_at"`
-		StartsAt           *int64  `json:"starts_at"`
-		EndsAt             *int64  `json:"ends_at"`
-		IsCancelled        *bool   `json:"is_cancelled"`
+	if role == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the organizers can update this event"})
+		return
 	}
 
+	var req UpdateEventRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Convert reveal timestamp if provided
-	var revealAt *time.Time
+	if (req.Latitude == nil) != (req.Longitude == nil) ||
+		(req.Latitude != nil && !validCoordinates(*req.Latitude, *req.Longitude)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "latitude and longitude must be valid and given together"})
+		return
+	}
+	if req.AutoApproveOrgID != nil && *req.AutoApproveOrgID != "" {
+		member, err := h.isOrgMember(ctx, *req.AutoApproveOrgID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if !member {
+			c.JSON(http.StatusForbidden, gin.H{"error": "you must be a member of the auto-approve organization"})
+			return
+		}
+	}
+	if req.Accessibility != nil {
+		features, err := normalizeAccessibility(*req.Accessibility)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		req.Accessibility = &features
+	}
+	if req.OrgID != nil {
+		if !ownsEvent(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "co-organizers cannot change the event's organization"})
+			return
+		}
+		if *req.OrgID != "" && !h.checkEventOrg(c, *req.OrgID, userID) {
+			return
+		}
+	}
+
+	// Occurrence edits, series splits and rule changes
+	if req.Occurrence != nil || req.Scope != "" || req.Recurrence != nil || req.Timezone != nil {
+		h.updateSeries(c, eventID, req)
+		return
+	}
+
+	// What attendees knew, to tell them what changed
+	before, err := h.eventState(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+
+	// Convert timestamps if provided
+	var revealAt, startsAt, endsAt *time.Time
 	if req.LocationRevealAt != nil {
 		t := time.Unix(*req.LocationRevealAt, 0)
 		revealAt = &t
 	}
+	if req.StartsAt != nil {
+		t := time.Unix(*req.StartsAt, 0)
+		startsAt = &t
+	}
+	if req.EndsAt != nil {
+		t := time.Unix(*req.EndsAt, 0)
+		endsAt = &t
+	}
+	newStart, newEnd := before.startsAt, before.endsAt
+	if startsAt != nil {
+		newStart = *startsAt
+	}
+	if endsAt != nil {
+		newEnd = endsAt
+	}
+	if newEnd != nil && !newEnd.After(newStart) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be after starts_at"})
+		return
+	}
 
 	// Build update query
 	_, err = h.db.Pool().Exec(ctx, `
 		UPDATE events SET
-			title = COALESCE($3, title),
-			description = COALESCE($4, description),
-			location_name = COALESCE($5, location_name),
-			location_area = COALESCE($6, location_area),
-			location_visibility = COALESCE($7, location_visibility),
-			location_reveal_at = COALESCE($8, location_reveal_at),
-			is_cancelled = COALESCE($9, is_cancelled),
+			title = COALESCE($2, title),
+			description = COALESCE($3, description),
+			location_name = COALESCE($4, location_name),
+			location_area = COALESCE($5, location_area),
+			location_visibility = COALESCE($6, location_visibility),
+			location_reveal_at = COALESCE($7, location_reveal_at),
+			is_cancelled = COALESCE($8, is_cancelled),
+			language = COALESCE(LOWER($9), language),
+			starts_at = COALESCE($10, starts_at),
+			ends_at = COALESCE($11, ends_at),
+			location = COALESCE(ST_SetSRID(ST_MakePoint($13, $12), 4326)::geography, location),
+			approval_min_trust = CASE WHEN $14::int IS NULL THEN approval_min_trust ELSE NULLIF($14, 0) END,
+			approval_org_id = CASE WHEN $15::text IS NULL THEN approval_org_id ELSE NULLIF($15, '')::uuid END,
+			org_id = CASE WHEN $16::text IS NULL THEN org_id ELSE NULLIF($16, '')::uuid END,
+			accessibility = COALESCE($17, accessibility),
+			risk_level = CASE WHEN $18::text IS NULL THEN risk_level ELSE NULLIF($18, '') END,
+			legal_hotline = CASE WHEN $19::text IS NULL THEN legal_hotline ELSE NULLIF($19, '') END,
+			bust_card = CASE WHEN $20::text IS NULL THEN bust_card ELSE NULLIF($20, '') END,
 			updated_at = NOW()
-		WHERE id = $1 AND organizer_id = $2
-	`, eventID, userID, req.Title, req.Description, req.LocationName, req.LocationArea,
-		req.LocationVisibility, revealAt, req.IsCancelled)
+		WHERE id = $1
+	`, eventID, req.Title, req.Description, req.LocationName, req.LocationArea,
+		req.LocationVisibility, revealAt, req.IsCancelled, req.Language,
+		startsAt, endsAt, req.Latitude, req.Longitude, req.AutoApproveTrust, req.AutoApproveOrgID, req.OrgID,
+		req.Accessibility, req.RiskLevel, req.LegalHotline, req.BustCard)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update event"})
 		return
 	}
 
+	if after, err := h.eventState(ctx, eventID); err == nil {
+		go h.notifyChanges(eventID, nil, before, after)
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "event updated"})
 }
 
-// DeleteEvent deletes an event (organizer only)
+// DeleteEvent deletes an event (organizer or the owning organization's
+// admins and moderators)
 func (h *Handler) DeleteEvent(c *gin.Context) {
 	userID := c.GetString("user_id")
 	eventID := c.Param("id")
 	ctx := c.Request.Context()
 
-	result, err := h.db.Pool().Exec(ctx,
-		"DELETE FROM events WHERE id = $1 AND organizer_id = $2",
-		eventID, userID,
-	)
+	role, err := h.eventManager(ctx, eventID, userID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete event"})
+		return
+	}
+	if !ownsEvent(role) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found or unauthorized"})
+		return
+	}
+
+	result, err := h.db.Pool().Exec(ctx, "DELETE FROM events WHERE id = $1", eventID)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete event"})
@@ -521,6 +997,9 @@ func (h *Handler) RSVP(c *gin.Context) {
 
 	var req struct {
 		Status string `json:"status" binding:"required,oneof=going interested not_going"`
+		// Occurrence picks one occurrence of a recurring event (Unix
+		// timestamp of its start as the rule generates it).
+		Occurrence *int64 `json:"occurrence"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -534,33 +1013,76 @@ func (h *Handler) RSVP(c *gin.Context) {
 	var locationRevealAt *time.Time
 	var lat, lon float64
 	var locationName, channelID *string
+	var recurrenceRule *string
+	var timezone, title, organizerID string
+	var startsAt time.Time
+	var endsAt *time.Time
 
 	err := h.db.Pool().QueryRow(ctx, `
 		SELECT true, location_visibility, location_reveal_at,
-		       ST_Y(location::geometry) as lat, ST_X(location::geometry) as lon, location_name, channel_id
+		       ST_Y(location::geometry) as lat, ST_X(location::geometry) as lon, location_name, channel_id,
+		       recurrence_rule, timezone, starts_at, ends_at, title, organizer_id
 		FROM events WHERE id = $1
-	`, eventID).Scan(&exists, &locationVisibility, &locationRevealAt, &lat, &lon, &locationName, &channelID)
+	`, eventID).Scan(&exists, &locationVisibility, &locationRevealAt, &lat, &lon, &locationName, &channelID,
+		&recurrenceRule, &timezone, &startsAt, &endsAt, &title, &organizerID)
 
 	if err != nil || !exists {
 		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
 		return
 	}
 
-	_, err = h.db.Pool().Exec(ctx, `
-		INSERT INTO event_rsvps (event_id, user_id, status)
-		VALUES ($1, $2, $3)
-		ON CONFLICT (event_id, user_id) DO UPDATE SET status = $3
-	`, eventID, userID, req.Status)
+	var occ *time.Time
+	if req.Occurrence != nil {
+		t := time.Unix(*req.Occurrence, 0)
+		occ = &t
+	}
+	occurrenceAt, ok := h.rsvpOccurrence(c, eventID, newSeries(recurrenceRule, timezone, startsAt, endsAt), occ, true)
+	if !ok {
+		return
+	}
+
+	// Approval-gated events decide new RSVPs; changing an RSVP's
+	// status keeps its approval.
+	approval := approvalApproved
+	if locationVisibility == "approval" {
+		if approval, err = h.newRSVPApproval(ctx, eventID, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record RSVP"})
+			return
+		}
+	}
+	var created bool
+	err = h.db.Pool().QueryRow(ctx, `
+		INSERT INTO event_rsvps (event_id, user_id, status, occurrence_at, approval)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (event_id, user_id, occurrence_at) DO UPDATE SET status = $3
+		RETURNING approval, xmax = 0
+	`, eventID, userID, req.Status, occurrenceAt, approval).Scan(&approval, &created)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record RSVP"})
 		return
 	}
+	approved := approval == approvalApproved
+
+	if req.Status == "not_going" {
+		if err := h.withdrawFromRoles(ctx, eventID, userID, occurrenceAt); err != nil {
+			log.Printf("events: withdraw %s from roles of %s: %v", userID, eventID, err)
+		}
+	}
 
 	response := gin.H{"message": "RSVP recorded", "status": req.Status}
+	if occurrenceAt != nil {
+		response["occurrence_at"] = *occurrenceAt
+	}
+	if locationVisibility == "approval" {
+		response["approval"] = approval
+		if created && approval == approvalPending {
+			go h.notifyManagers(eventID, title, "New RSVP awaits your approval")
+		}
+	}
 
-	// If this is a going/interested RSVP, add user to the event channel
-	if (req.Status == "going" || req.Status == "interested") && channelID != nil {
+	// If this is an approved going/interested RSVP, add user to the event channel
+	if (req.Status == "going" || req.Status == "interested") && approved && channelID != nil {
 		// Add user to channel (ignore if already member)
 		_, _ = h.db.Pool().Exec(ctx, `
 			INSERT INTO channel_members (channel_id, user_id, role, joined_at)
@@ -572,8 +1094,10 @@ func (h *Handler) RSVP(c *gin.Context) {
 		response["joined_channel"] = true
 	}
 
-	// If this is a going/interested RSVP for an rsvp-only event, reveal the location
-	if (req.Status == "going" || req.Status == "interested") && locationVisibility == "rsvp" {
+	// If this is an approved going/interested RSVP for an rsvp-only or
+	// approval-gated event, reveal the location
+	if (req.Status == "going" || req.Status == "interested") && approved &&
+		(locationVisibility == "rsvp" || locationVisibility == "approval") {
 		response["location"] = gin.H{"latitude": lat, "longitude": lon}
 		if locationName != nil {
 			response["location_name"] = *locationName
@@ -590,20 +1114,48 @@ func (h *Handler) CancelRSVP(c *gin.Context) {
 	eventID := c.Param("id")
 	ctx := c.Request.Context()
 
-	_, err := h.db.Pool().Exec(ctx,
-		"DELETE FROM event_rsvps WHERE event_id = $1 AND user_id = $2",
-		eventID, userID,
+	occ, err := parseOccurrence(c.Query("occurrence"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var recurrenceRule *string
+	var timezone string
+	var startsAt time.Time
+	var endsAt *time.Time
+	err = h.db.Pool().QueryRow(ctx, `
+		SELECT recurrence_rule, timezone, starts_at, ends_at FROM events WHERE id = $1
+	`, eventID).Scan(&recurrenceRule, &timezone, &startsAt, &endsAt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	occurrenceAt, ok := h.rsvpOccurrence(c, eventID, newSeries(recurrenceRule, timezone, startsAt, endsAt), occ, false)
+	if !ok {
+		return
+	}
+
+	_, err = h.db.Pool().Exec(ctx,
+		// Denied RSVPs stay, so cancelling doesn't clear a denial
+		"DELETE FROM event_rsvps WHERE event_id = $1 AND user_id = $2 AND occurrence_at IS NOT DISTINCT FROM $3 AND approval <> 'denied'",
+		eventID, userID, occurrenceAt,
 	)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel RSVP"})
 		return
 	}
+	if err := h.withdrawFromRoles(ctx, eventID, userID, occurrenceAt); err != nil {
+		log.Printf("events: withdraw %s from roles of %s: %v", userID, eventID, err)
+	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "RSVP cancelled"})
 }
 
-// GetNearbyEvents returns events near a location (PUBLIC events only for map display)
+// GetNearbyEvents returns events near a location (PUBLIC events only for map display).
+// Recurring events appear once per occurrence in the next 90 days.
+// ?accessibility= and ?max_risk= filter them as in ListEvents.
 func (h *Handler) GetNearbyEvents(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -624,21 +1176,32 @@ func (h *Handler) GetNearbyEvents(c *gin.Context) {
 		radiusMeters = 100000 // Max 100km
 	}
 
+	from, to, bounded, err := occurrenceWindow(c, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filters, err := parseEventFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	filterSQL, filterArgs := filters.sql(7)
+
 	// Only return PUBLIC events for map/nearby display
 	rows, err := h.db.Pool().Query(ctx, `
 		SELECT e.id, e.title, e.event_type,
 			   ST_Y(e.location::geometry) as lat, ST_X(e.location::geometry) as lon,
-			   e.location_name, e.starts_at,
+			   e.location_name, e.starts_at, e.ends_at, e.recurrence_rule, e.timezone,
 			   ST_Distance(e.location, ST_MakePoint($2, $1)::geography) as distance_meters,
-			   (SELECT COUNT(*) FROM event_rsvps WHERE event_id = e.id AND status = 'going') as rsvp_count
+			   (SELECT COUNT(*) FROM event_rsvps WHERE event_id = e.id AND status = 'going' AND approval = 'approved') as rsvp_count,
+			   e.accessibility, e.risk_level
 		FROM events e
-		WHERE e.starts_at > NOW()
-		  AND e.is_cancelled = false
-		  AND e.location_visibility = 'public'
-		  AND ST_DWithin(e.location, ST_MakePoint($2, $1)::geography, $3)
-		ORDER BY e.starts_at ASC
-		LIMIT 50
-	`, lat, lon, radiusMeters)
+		WHERE `+publicWindowSQL("$4", "$5")+`
+		  AND ST_DWithin(e.location, ST_MakePoint($2, $1)::geography, $3)`+filterSQL+`
+		ORDER BY (e.recurrence_rule IS NOT NULL) DESC, e.starts_at ASC
+		LIMIT $6
+	`, append([]interface{}{lat, lon, radiusMeters, from, to, maxNearbyEvents + maxListedSeries}, filterArgs...)...)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch nearby events"})
@@ -646,33 +1209,44 @@ func (h *Handler) GetNearbyEvents(c *gin.Context) {
 	}
 	defer rows.Close()
 
-	var events []gin.H
+	// Public listings are blurred for everyone, organizers included
+	policy := counts.FromConfig(h.cfg)
+	var found []publicEvent
 	for rows.Next() {
-		var id, title, eventType string
-		var eventLat, eventLon, distance float64
-		var locationName *string
-		var startsAt time.Time
-		var rsvpCount int
-
-		if err := rows.Scan(&id, &title, &eventType, &eventLat, &eventLon, &locationName, &startsAt, &distance, &rsvpCount); err != nil {
+		var e publicEvent
+		var distance float64
+		var accessibility []string
+		var riskLevel *string
+
+		if err := rows.Scan(&e.id, &e.title, &e.eventType, &e.lat, &e.lon, &e.locationName,
+			&e.startsAt, &e.endsAt, &e.recurrenceRule, &e.timezone, &distance, &e.rsvpCount,
+			&accessibility, &riskLevel); err != nil {
 			continue
 		}
 
-		event := gin.H{
-			"id":              id,
-			"title":           title,
-			"event_type":      eventType,
-			"location":        gin.H{"latitude": eventLat, "longitude": eventLon},
-			"starts_at":       startsAt,
+		e.event = gin.H{
+			"id":              e.id,
+			"title":           e.title,
+			"event_type":      e.eventType,
+			"location":        gin.H{"latitude": e.lat, "longitude": e.lon},
+			"starts_at":       e.startsAt,
 			"distance_meters": int(distance),
-			"rsvp_count":      rsvpCount,
 		}
+		policy.Set(e.event, "rsvp_count", e.rsvpCount, false)
+		setAccessibilityFields(e.event, accessibility, riskLevel)
 
-		if locationName != nil {
-			event["location_name"] = *locationName
+		if e.locationName != nil {
+			e.event["location_name"] = *e.locationName
 		}
 
-		events = append(events, event)
+		found = append(found, e)
+	}
+	rows.Close()
+
+	events, err := h.expandPublicEvents(ctx, found, from, to, bounded, maxNearbyEvents)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch nearby events"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -682,7 +1256,8 @@ func (h *Handler) GetNearbyEvents(c *gin.Context) {
 	})
 }
 
-// GetPublicEventsForMap returns all public events with locations for map display
+// GetPublicEventsForMap returns all public events with locations for map display.
+// Recurring events appear once per occurrence in the next 90 days.
 func (h *Handler) GetPublicEventsForMap(c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -692,39 +1267,40 @@ func (h *Handler) GetPublicEventsForMap(c *gin.Context) {
 	minLon, _ := strconv.ParseFloat(c.Query("min_lon"), 64)
 	maxLon, _ := strconv.ParseFloat(c.Query("max_lon"), 64)
 
+	from, to, bounded, err := occurrenceWindow(c, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var rows interface{ Close(); Next() bool; Scan(...interface{}) error }
-	var err error
 
 	if minLat != 0 || maxLat != 0 {
 		// Query with bounding box
 		rows, err = h.db.Pool().Query(ctx, `
 			SELECT e.id, e.title, e.event_type,
 				   ST_Y(e.location::geometry) as lat, ST_X(e.location::geometry) as lon,
-				   e.location_name, e.starts_at,
-				   (SELECT COUNT(*) FROM event_rsvps WHERE event_id = e.id AND status = 'going') as rsvp_count
+				   e.location_name, e.starts_at, e.ends_at, e.recurrence_rule, e.timezone,
+				   (SELECT COUNT(*) FROM event_rsvps WHERE event_id = e.id AND status = 'going' AND approval = 'approved') as rsvp_count
 			FROM events e
-			WHERE e.starts_at > NOW()
-			  AND e.is_cancelled = false
-			  AND e.location_visibility = 'public'
+			WHERE `+publicWindowSQL("$5", "$6")+`
 			  AND ST_Y(e.location::geometry) BETWEEN $1 AND $2
 			  AND ST_X(e.location::geometry) BETWEEN $3 AND $4
-			ORDER BY e.starts_at ASC
-			LIMIT 100
-		`, minLat, maxLat, minLon, maxLon)
+			ORDER BY (e.recurrence_rule IS NOT NULL) DESC, e.starts_at ASC
+			LIMIT $7
+		`, minLat, maxLat, minLon, maxLon, from, to, maxMapEvents+maxListedSeries)
 	} else {
 		// Query all upcoming public events
 		rows, err = h.db.Pool().Query(ctx, `
 			SELECT e.id, e.title, e.event_type,
 				   ST_Y(e.location::geometry) as lat, ST_X(e.location::geometry) as lon,
-				   e.location_name, e.starts_at,
-				   (SELECT COUNT(*) FROM event_rsvps WHERE event_id = e.id AND status = 'going') as rsvp_count
+				   e.location_name, e.starts_at, e.ends_at, e.recurrence_rule, e.timezone,
+				   (SELECT COUNT(*) FROM event_rsvps WHERE event_id = e.id AND status = 'going' AND approval = 'approved') as rsvp_count
 			FROM events e
-			WHERE e.starts_at > NOW()
-			  AND e.is_cancelled = false
-			  AND e.location_visibility = 'public'
-			ORDER BY e.starts_at ASC
-			LIMIT 100
-		`)
+			WHERE `+publicWindowSQL("$1", "$2")+`
+			ORDER BY (e.recurrence_rule IS NOT NULL) DESC, e.starts_at ASC
+			LIMIT $3
+		`, from, to, maxMapEvents+maxListedSeries)
 	}
 
 	if err != nil {
@@ -733,32 +1309,37 @@ func (h *Handler) GetPublicEventsForMap(c *gin.Context) {
 	}
 	defer rows.Close()
 
-	var events []gin.H
+	policy := counts.FromConfig(h.cfg)
+	var found []publicEvent
 	for rows.Next() {
-		var id, title, eventType string
-		var lat, lon float64
-		var locationName *string
-		var startsAt time.Time
-		var rsvpCount int
+		var e publicEvent
 
-		if err := rows.Scan(&id, &title, &eventType, &lat, &lon, &locationName, &startsAt, &rsvpCount); err != nil {
+		if err := rows.Scan(&e.id, &e.title, &e.eventType, &e.lat, &e.lon, &e.locationName,
+			&e.startsAt, &e.endsAt, &e.recurrenceRule, &e.timezone, &e.rsvpCount); err != nil {
 			continue
 		}
 
-		event := gin.H{
-			"id":         id,
-			"title":      title,
-			"event_type": eventType,
-			"location":   gin.H{"latitude": lat, "longitude": lon},
-			"starts_at":  startsAt,
-			"rsvp_count": rsvpCount,
+		e.event = gin.H{
+			"id":         e.id,
+			"title":      e.title,
+			"event_type": e.eventType,
+			"location":   gin.H{"latitude": e.lat, "longitude": e.lon},
+			"starts_at":  e.startsAt,
 		}
+		policy.Set(e.event, "rsvp_count", e.rsvpCount, false)
 
-		if locationName != nil {
-			event["location_name"] = *locationName
+		if e.locationName != nil {
+			e.event["location_name"] = *e.locationName
 		}
 
-		events = append(events, event)
+		found = append(found, e)
+	}
+	rows.Close()
+
+	events, err := h.expandPublicEvents(ctx, found, from, to, bounded, maxMapEvents)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch events"})
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"events": events})
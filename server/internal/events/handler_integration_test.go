@@ -0,0 +1,136 @@
+//go:build integration
+
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// testServer serves an events Handler over a migrated test database.
+// Requests are made as the user in their X-User-ID header, standing in
+// for the auth middleware.
+type testServer struct {
+	t      *testing.T
+	cfg    *config.Config
+	pool   *pgxpool.Pool
+	router *gin.Engine
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	db := testutil.NewTestPostgres(t)
+	cfg := &config.Config{}
+	h := NewHandler(cfg, db, nil, nil, nil)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-User-ID"))
+		c.Next()
+	})
+	r.GET("/events/nearby", h.GetNearbyEvents)
+	r.GET("/events/map", h.GetPublicEventsForMap)
+	r.GET("/events/:id/roles", h.ListRoles)
+	r.POST("/events/:id/roles", h.CreateRole)
+	r.PUT("/events/:id/roles/:roleId", h.UpdateRole)
+	r.DELETE("/events/:id/roles/:roleId", h.DeleteRole)
+	r.POST("/events/:id/roles/:roleId/signup", h.SignUp)
+	r.DELETE("/events/:id/roles/:roleId/signup", h.Withdraw)
+	r.PUT("/events/:id/roles/:roleId/signups/:userId", h.DecideSignup)
+	r.GET("/events/:id/roster", h.GetRoster)
+	r.GET("/events/:id/co-organizers", h.ListCoOrganizers)
+	r.POST("/events/:id/co-organizers", h.InviteCoOrganizer)
+	r.POST("/events/:id/co-organizers/respond", h.RespondToCoOrganizerInvite)
+	r.DELETE("/events/:id/co-organizers/:userId", h.RemoveCoOrganizer)
+	r.POST("/events/:id/transfer", h.RequestEventTransfer)
+	r.GET("/event-transfers", h.ListEventTransfers)
+	r.POST("/event-transfers/:request_id/respond", h.RespondToEventTransfer)
+	r.GET("/organizations/:id/events", h.ListOrgEvents)
+
+	return &testServer{t: t, cfg: cfg, pool: db.Pool(), router: r}
+}
+
+// call makes a request as userID and returns the status and decoded
+// JSON body.
+func (s *testServer) call(method, path, userID string, body any) (int, map[string]any) {
+	s.t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(s.t, json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", userID)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	var resp map[string]any
+	if w.Body.Len() > 0 {
+		require.NoError(s.t, json.Unmarshal(w.Body.Bytes(), &resp), w.Body.String())
+	}
+	return w.Code, resp
+}
+
+// user creates a user.
+func (s *testServer) user(displayName string) string {
+	s.t.Helper()
+	return testutil.CreateUser(s.t, s.pool, displayName)
+}
+
+// event creates a one-off public event tomorrow, organized by
+// organizerID.
+func (s *testServer) event(organizerID string) string {
+	s.t.Helper()
+	var id string
+	err := s.pool.QueryRow(context.Background(), `
+		INSERT INTO events (organizer_id, title, event_type, location, starts_at)
+		VALUES ($1, 'Rally', 'protest', ST_SetSRID(ST_MakePoint(13.4, 52.5), 4326)::geography, NOW() + INTERVAL '1 day')
+		RETURNING id
+	`, organizerID).Scan(&id)
+	require.NoError(s.t, err)
+	return id
+}
+
+// exec runs a fixture statement.
+func (s *testServer) exec(sql string, args ...any) {
+	s.t.Helper()
+	_, err := s.pool.Exec(context.Background(), sql, args...)
+	require.NoError(s.t, err)
+}
+
+func TestPublicListings_CountOnlyApprovedRSVPs(t *testing.T) {
+	s := newTestServer(t)
+	eventID := s.event(s.user("Organizer"))
+	// An event opened up after asking for approval keeps its requests
+	s.exec(`
+		INSERT INTO event_rsvps (event_id, user_id, status, approval)
+		VALUES ($1, $2, 'going', 'approved'), ($1, $3, 'going', 'pending'), ($1, $4, 'going', 'denied')
+	`, eventID, s.user("Approved"), s.user("Pending"), s.user("Denied"))
+
+	for _, path := range []string{
+		"/events/nearby?latitude=52.5&longitude=13.4",
+		"/events/map",
+		"/events/map?min_lat=52&max_lat=53&min_lon=13&max_lon=14",
+	} {
+		code, resp := s.call(http.MethodGet, path, s.user("Viewer"), nil)
+		require.Equal(t, http.StatusOK, code, resp)
+		events := resp["events"].([]any)
+		require.Len(t, events, 1, path)
+		assert.EqualValues(t, 1, events[0].(map[string]any)["rsvp_count"], path)
+	}
+}
@@ -0,0 +1,146 @@
+package events
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// icalTimeFormat is an RFC 5545 DATE-TIME in UTC.
+const icalTimeFormat = "20060102T150405Z"
+
+// icalRefresh is how often calendar clients are asked to re-fetch a
+// feed. Timed locations appear in a feed at the first refresh after
+// location_reveal_at.
+const icalRefresh = "PT1H"
+
+// icalEvent is one VEVENT, already filtered for what the feed's viewer
+// may see. latitude/longitude are nil when the location is hidden.
+type icalEvent struct {
+	id          string
+	title       string
+	description string
+	eventType   string
+	location    string
+	latitude    *float64
+	longitude   *float64
+	startsAt    time.Time
+	endsAt      *time.Time
+	updatedAt   time.Time
+	cancelled   bool
+
+	// Series carry their rule and timezone; an edited occurrence is a
+	// separate VEVENT with the same UID and a RECURRENCE-ID.
+	timezone     string
+	rrule        string
+	exdates      []time.Time
+	recurrenceID *time.Time
+}
+
+// renderCalendar renders events as an RFC 5545 VCALENDAR.
+func renderCalendar(name string, events []icalEvent, now time.Time) string {
+	var b strings.Builder
+	line := func(s string) {
+		b.WriteString(foldICalLine(s))
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//Kuurier//Events//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-CALNAME:" + escapeICalText(name))
+	line("REFRESH-INTERVAL;VALUE=DURATION:" + icalRefresh)
+	line("X-PUBLISHED-TTL:" + icalRefresh)
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		line("UID:" + e.id + "@kuurier")
+		line("DTSTAMP:" + now.UTC().Format(icalTimeFormat))
+		line("LAST-MODIFIED:" + e.updatedAt.UTC().Format(icalTimeFormat))
+		if e.recurrenceID != nil {
+			line(icalDateTime("RECURRENCE-ID", *e.recurrenceID, e.timezone))
+		}
+		line(icalDateTime("DTSTART", e.startsAt, e.timezone))
+		if e.endsAt != nil {
+			line(icalDateTime("DTEND", *e.endsAt, e.timezone))
+		}
+		if e.rrule != "" {
+			line("RRULE:" + e.rrule)
+		}
+		for _, t := range e.exdates {
+			line(icalDateTime("EXDATE", t, e.timezone))
+		}
+		line("SUMMARY:" + escapeICalText(e.title))
+		if e.description != "" {
+			line("DESCRIPTION:" + escapeICalText(e.description))
+		}
+		if e.eventType != "" {
+			line("CATEGORIES:" + escapeICalText(strings.ToUpper(e.eventType)))
+		}
+		if e.location != "" {
+			line("LOCATION:" + escapeICalText(e.location))
+		}
+		if e.latitude != nil && e.longitude != nil {
+			line(fmt.Sprintf("GEO:%.6f;%.6f", *e.latitude, *e.longitude))
+		}
+		if e.cancelled {
+			line("STATUS:CANCELLED")
+		} else {
+			line("STATUS:CONFIRMED")
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// icalDateTime renders a DATE-TIME property in UTC, or as local time
+// with a TZID so a series keeps its wall-clock time across DST.
+func icalDateTime(name string, t time.Time, timezone string) string {
+	if timezone == "" || timezone == "UTC" {
+		return name + ":" + t.UTC().Format(icalTimeFormat)
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return name + ":" + t.UTC().Format(icalTimeFormat)
+	}
+	return name + ";TZID=" + timezone + ":" + t.In(loc).Format("20060102T150405")
+}
+
+// escapeICalText escapes a TEXT value (RFC 5545 section 3.3.11).
+func escapeICalText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", `\n`,
+	).Replace(s)
+}
+
+// foldICalLine splits a content line into lines of at most 75 octets,
+// continuation lines starting with a space (RFC 5545 section 3.1).
+// It never splits a UTF-8 sequence.
+func foldICalLine(s string) string {
+	const maxOctets = 75
+	if len(s) <= maxOctets {
+		return s
+	}
+	var b strings.Builder
+	limit := maxOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		limit = maxOctets - 1 // the leading space counts
+	}
+	b.WriteString(s)
+	return b.String()
+}
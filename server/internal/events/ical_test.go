@@ -0,0 +1,87 @@
+package events
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeICalText(t *testing.T) {
+	assert.Equal(t, `March\, rally\; speeches\nBring water \\ snacks`,
+		escapeICalText("March, rally; speeches\r\nBring water \\ snacks"))
+}
+
+func TestFoldICalLine(t *testing.T) {
+	short := "SUMMARY:General meeting"
+	assert.Equal(t, short, foldICalLine(short))
+
+	long := "DESCRIPTION:" + strings.Repeat("Räumung verhindern! ", 10)
+	folded := foldICalLine(long)
+	lines := strings.Split(folded, "\r\n")
+	assert.Greater(t, len(lines), 1)
+	for i, l := range lines {
+		assert.LessOrEqual(t, len(l), 75, "line %d is %d octets", i, len(l))
+		if i > 0 {
+			assert.True(t, strings.HasPrefix(l, " "), "continuation lines start with a space")
+		}
+	}
+	// Unfolding restores the original without splitting any rune.
+	assert.Equal(t, long, strings.ReplaceAll(folded, "\r\n ", ""))
+}
+
+func TestCalendarEntry_Location(t *testing.T) {
+	name, area := "Union Hall, 5 Main St", "Downtown"
+	revealAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	row := calendarRow{
+		id: "e1", organizerID: "org", title: "Strike", lat: 52.52, lon: 13.405,
+		locationName: &name, locationArea: &area, locationVisibility: "rsvp",
+		startsAt: revealAt.Add(time.Hour),
+	}
+
+	hidden := calendarEntry(row, "someone")
+	assert.Nil(t, hidden.latitude)
+	assert.Equal(t, "Downtown", hidden.location)
+	assert.Contains(t, hidden.description, "RSVP to see the exact location")
+
+	row.hasRSVP = true
+	shown := calendarEntry(row, "someone")
+	assert.Equal(t, "Union Hall, 5 Main St", shown.location)
+	if assert.NotNil(t, shown.latitude) {
+		assert.Equal(t, 52.52, *shown.latitude)
+	}
+
+	row.hasRSVP = false
+	assert.NotNil(t, calendarEntry(row, "org").latitude, "organizer always sees the location")
+
+	row.locationVisibility = "timed"
+	row.locationRevealAt = &revealAt
+	timed := calendarEntry(row, "someone")
+	if time.Now().Before(revealAt) {
+		assert.Contains(t, timed.description, "2026-03-01 09:00 UTC")
+	} else {
+		assert.NotNil(t, timed.latitude)
+	}
+}
+
+func TestRenderCalendar(t *testing.T) {
+	start := time.Date(2026, 5, 1, 14, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	end := start.Add(2 * time.Hour)
+	lat, lon := 52.52, 13.405
+	out := renderCalendar("Kuurier: My events", []icalEvent{
+		{id: "e1", title: "May Day", startsAt: start, endsAt: &end, updatedAt: start,
+			location: "Alexanderplatz", latitude: &lat, longitude: &lon, eventType: "protest"},
+		{id: "e2", title: "Cancelled meeting", startsAt: start, updatedAt: start, cancelled: true},
+	}, start)
+
+	assert.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(out, "END:VCALENDAR\r\n"))
+	assert.Equal(t, 2, strings.Count(out, "BEGIN:VEVENT"))
+	assert.Contains(t, out, "DTSTART:20260501T120000Z\r\n", "times are written in UTC")
+	assert.Contains(t, out, "DTEND:20260501T140000Z\r\n")
+	assert.Contains(t, out, "GEO:52.520000;13.405000\r\n")
+	assert.Contains(t, out, "UID:e2@kuurier\r\n")
+	assert.Contains(t, out, "STATUS:CANCELLED\r\n")
+	assert.NotContains(t, strings.ReplaceAll(out, "\r\n", ""), "\n", "every line ends in CRLF")
+}
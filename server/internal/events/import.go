@@ -0,0 +1,459 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxImportBytes caps the size of an uploaded .ics or CSV file.
+	maxImportBytes = 1 << 20
+
+	// maxImportEvents caps how many events one import may create.
+	maxImportEvents = 200
+
+	// duplicateRadiusMeters and duplicateWindow define "the same event":
+	// same title, starting within the window, within the radius.
+	duplicateRadiusMeters = 200
+	duplicateWindow       = 15 * time.Minute
+)
+
+// importRequest is what PreviewImport and ImportEvents read from the
+// request before any database work.
+type importRequest struct {
+	rows        []importRow
+	skipInvalid bool
+}
+
+// PreviewImport parses an .ics or CSV upload and reports, per event,
+// what would be created, what's wrong with it and whether it
+// duplicates an existing event. Nothing is written.
+func (h *Handler) PreviewImport(c *gin.Context) {
+	if !checkImportTrust(c) {
+		return
+	}
+	req, ok := readImport(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.resolveImport(ctx, req.rows); err != nil {
+		log.Printf("import: resolve rows: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, importPreview(req.rows))
+}
+
+// ImportEvents creates every valid, non-duplicate event from an .ics
+// or CSV upload in one transaction. If any event is invalid nothing is
+// created and the preview is returned, unless skip_invalid=true.
+func (h *Handler) ImportEvents(c *gin.Context) {
+	userID := c.GetString("user_id")
+	if !checkImportTrust(c) {
+		return
+	}
+	req, ok := readImport(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.resolveImport(ctx, req.rows); err != nil {
+		log.Printf("import: resolve rows: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check events"})
+		return
+	}
+
+	preview := importPreview(req.rows)
+	if preview["invalid"].(int) > 0 && !req.skipInvalid {
+		preview["error"] = "some events are invalid; fix them or retry with skip_invalid=true"
+		c.JSON(http.StatusUnprocessableEntity, preview)
+		return
+	}
+
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+	created := make([]gin.H, 0, len(req.rows))
+	skipped := 0
+	for _, row := range req.rows {
+		if len(row.errors) > 0 || row.duplicate != "" {
+			skipped++
+			continue
+		}
+		eventID, channelID, err := insertEvent(ctx, tx, userID, row.event, now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+				"line":  row.line,
+			})
+			return
+		}
+		entry := gin.H{"line": row.line, "id": eventID, "title": row.event.Title}
+		if channelID != "" {
+			entry["channel_id"] = channelID
+		}
+		created = append(created, entry)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"created": created,
+		"skipped": skipped,
+	})
+}
+
+// checkImportTrust applies CreateEvent's trust requirement.
+func checkImportTrust(c *gin.Context) bool {
+	trustScore := c.GetFloat64("trust_score")
+	if trustScore < 50 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":    "insufficient trust level to create events",
+			"required": 50,
+			"current":  int(trustScore),
+		})
+		return false
+	}
+	return true
+}
+
+// readImport reads the upload (a multipart "file" field or the raw
+// body) and parses it. Query parameters:
+//
+//	format       csv or ics; detected from the file name, content type or content if omitted
+//	timezone     IANA zone for times without one (default UTC)
+//	visibility   default location_visibility (default public)
+//	enable_chat  create a chat channel for events that don't say
+//	skip_invalid import the valid events even if others are invalid
+func readImport(c *gin.Context) (importRequest, bool) {
+	opts := importOptions{
+		loc:        time.UTC,
+		visibility: "public",
+		enableChat: c.Query("enable_chat") == "true",
+	}
+	if tz := c.Query("timezone"); tz != "" {
+		loc, err := time.LoadLocation(tz)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown timezone"})
+			return importRequest{}, false
+		}
+		opts.loc = loc
+	}
+	if v := c.Query("visibility"); v != "" {
+		opts.visibility = v
+	}
+
+	data, filename, contentType, err := readImportFile(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return importRequest{}, false
+	}
+
+	var rows []importRow
+	switch format := detectImportFormat(c.Query("format"), filename, contentType, data); format {
+	case "ics":
+		rows, err = parseICSImport(string(data), opts)
+	case "csv":
+		rows, err = parseCSVImport(bytes.NewReader(data), opts)
+	default:
+		err = errors.New("unrecognized file format; use format=csv or format=ics")
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return importRequest{}, false
+	}
+	if len(rows) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file contains no events"})
+		return importRequest{}, false
+	}
+	if len(rows) > maxImportEvents {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("file contains %d events; at most %d can be imported at once", len(rows), maxImportEvents),
+		})
+		return importRequest{}, false
+	}
+
+	return importRequest{rows: rows, skipInvalid: c.Query("skip_invalid") == "true"}, true
+}
+
+// readImportFile returns the uploaded bytes with whatever name and
+// content type the client gave.
+func readImportFile(c *gin.Context) ([]byte, string, string, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes+64<<10)
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		fh, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", "", errors.New("missing file upload")
+		}
+		if fh.Size > maxImportBytes {
+			return nil, "", "", errors.New("file is too large")
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return nil, "", "", errors.New("could not read upload")
+		}
+		defer f.Close()
+		data, err := io.ReadAll(io.LimitReader(f, maxImportBytes+1))
+		if err != nil {
+			return nil, "", "", errors.New("could not read upload")
+		}
+		return data, fh.Filename, fh.Header.Get("Content-Type"), nil
+	}
+
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxImportBytes+1))
+	if err != nil {
+		return nil, "", "", errors.New("could not read request body")
+	}
+	if len(data) > maxImportBytes {
+		return nil, "", "", errors.New("file is too large")
+	}
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, "", "", errors.New("missing file upload")
+	}
+	return data, "", c.ContentType(), nil
+}
+
+// detectImportFormat picks csv or ics from an explicit format, then the
+// file name, then the content type, then the content itself.
+func detectImportFormat(format, filename, contentType string, data []byte) string {
+	if format != "" {
+		return strings.ToLower(format)
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".ics", ".ical", ".ifb":
+		return "ics"
+	case ".csv":
+		return "csv"
+	}
+	contentType, _, _ = strings.Cut(contentType, ";")
+	switch strings.ToLower(strings.TrimSpace(contentType)) {
+	case "text/calendar":
+		return "ics"
+	case "text/csv", "application/csv":
+		return "csv"
+	}
+	trimmed := bytes.TrimPrefix(bytes.TrimSpace(data), []byte("\xef\xbb\xbf"))
+	if len(trimmed) >= 15 && strings.EqualFold(string(trimmed[:15]), "BEGIN:VCALENDAR") {
+		return "ics"
+	}
+	return "csv"
+}
+
+// resolveImport does the checks that need the database: topic names to
+// IDs, location names to coordinates, and duplicates of existing
+// events or of earlier rows in the same file.
+func (h *Handler) resolveImport(ctx context.Context, rows []importRow) error {
+	topicIDs, err := h.importTopics(ctx)
+	if err != nil {
+		return err
+	}
+
+	places := make(map[string]*[2]float64)
+	seen := make(map[string]int)
+	for i := range rows {
+		row := &rows[i]
+
+		for _, name := range row.topicNames {
+			if id, ok := topicIDs[strings.ToLower(name)]; ok {
+				row.event.TopicIDs = append(row.event.TopicIDs, id)
+			} else {
+				row.warnf("unknown topic %q ignored", name)
+			}
+		}
+
+		if !row.hasCoords {
+			if err := h.geocodeImportRow(ctx, row, places); err != nil {
+				return err
+			}
+		}
+		if row.hasCoords && !validCoordinates(row.event.Latitude, row.event.Longitude) {
+			row.errorf("coordinates %.6f, %.6f are out of range", row.event.Latitude, row.event.Longitude)
+		}
+		if len(row.errors) > 0 {
+			continue
+		}
+
+		key := fmt.Sprintf("%s|%d|%.3f|%.3f", strings.ToLower(row.event.Title), row.event.StartsAt,
+			row.event.Latitude, row.event.Longitude)
+		if first, ok := seen[key]; ok {
+			row.errorf("duplicates line %d of this file", first)
+			continue
+		}
+		seen[key] = row.line
+
+		startsAt := time.Unix(row.event.StartsAt, 0)
+		err := h.db.Pool().QueryRow(ctx, `
+			SELECT id FROM events
+			WHERE LOWER(title) = LOWER($1)
+			  AND starts_at BETWEEN $2 AND $3
+			  AND ST_DWithin(location, ST_SetSRID(ST_MakePoint($4, $5), 4326)::geography, $6)
+			  AND is_cancelled = false
+			LIMIT 1
+		`, row.event.Title, startsAt.Add(-duplicateWindow), startsAt.Add(duplicateWindow),
+			row.event.Longitude, row.event.Latitude, duplicateRadiusMeters).Scan(&row.duplicate)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+	}
+	return nil
+}
+
+// importTopics maps lowercased slugs and names of active topics to IDs.
+func (h *Handler) importTopics(ctx context.Context) (map[string]string, error) {
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT id, slug, name FROM topics WHERE archived_at IS NULL
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := make(map[string]string)
+	for rows.Next() {
+		var id, slug, name string
+		if err := rows.Scan(&id, &slug, &name); err != nil {
+			return nil, err
+		}
+		ids[strings.ToLower(slug)] = id
+		if _, taken := ids[strings.ToLower(name)]; !taken {
+			ids[strings.ToLower(name)] = id
+		}
+	}
+	return ids, rows.Err()
+}
+
+// geocodeImportRow fills in coordinates for a row that only has a
+// location name. There is no external geocoder - sending organizers'
+// planned locations to a third party would leak them - so a name only
+// resolves if a public event has already been held at a place with
+// that exact name. places caches lookups for the file.
+func (h *Handler) geocodeImportRow(ctx context.Context, row *importRow, places map[string]*[2]float64) error {
+	name := strings.ToLower(strings.TrimSpace(row.location))
+	if name == "" {
+		row.errorf("latitude and longitude are required")
+		return nil
+	}
+
+	coords, cached := places[name]
+	if !cached {
+		var lat, lon float64
+		err := h.db.Pool().QueryRow(ctx, `
+			SELECT ST_Y(location::geometry), ST_X(location::geometry)
+			FROM events
+			WHERE LOWER(location_name) = $1 AND location_visibility = 'public'
+			ORDER BY created_at DESC
+			LIMIT 1
+		`, name).Scan(&lat, &lon)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+		case err != nil:
+			return err
+		default:
+			coords = &[2]float64{lat, lon}
+		}
+		places[name] = coords
+	}
+
+	if coords == nil {
+		row.errorf("could not find coordinates for %q; add latitude and longitude", row.location)
+		return nil
+	}
+	row.event.Latitude, row.event.Longitude, row.hasCoords = coords[0], coords[1], true
+	row.warnf("coordinates for %q taken from an earlier public event there", row.location)
+	return nil
+}
+
+// importPreview renders parsed rows for the client.
+func importPreview(rows []importRow) gin.H {
+	events := make([]gin.H, 0, len(rows))
+	valid, invalid, duplicates := 0, 0, 0
+	for _, row := range rows {
+		status := "ok"
+		switch {
+		case len(row.errors) > 0:
+			status = "invalid"
+			invalid++
+		case row.duplicate != "":
+			status = "duplicate"
+			duplicates++
+		default:
+			valid++
+		}
+
+		ev := row.event
+		entry := gin.H{
+			"line":                row.line,
+			"status":              status,
+			"title":               ev.Title,
+			"description":         ev.Description,
+			"event_type":          ev.EventType,
+			"location_name":       ev.LocationName,
+			"location_area":       ev.LocationArea,
+			"location_visibility": ev.LocationVisibility,
+			"topic_ids":           ev.TopicIDs,
+			"enable_chat":         ev.EnableChat != nil && *ev.EnableChat,
+			"errors":              nonNil(row.errors),
+			"warnings":            nonNil(row.warnings),
+		}
+		if row.hasCoords {
+			entry["latitude"] = math.Round(ev.Latitude*1e6) / 1e6
+			entry["longitude"] = math.Round(ev.Longitude*1e6) / 1e6
+		}
+		if ev.StartsAt != 0 {
+			entry["starts_at"] = time.Unix(ev.StartsAt, 0).UTC()
+		}
+		if ev.EndsAt != nil {
+			entry["ends_at"] = time.Unix(*ev.EndsAt, 0).UTC()
+		}
+		if ev.LocationRevealAt != nil {
+			entry["location_reveal_at"] = time.Unix(*ev.LocationRevealAt, 0).UTC()
+		}
+		if ev.Language != "" {
+			entry["language"] = ev.Language
+		}
+		if row.duplicate != "" {
+			entry["duplicate_of"] = row.duplicate
+		}
+		events = append(events, entry)
+	}
+
+	return gin.H{
+		"events":     events,
+		"total":      len(rows),
+		"valid":      valid,
+		"invalid":    invalid,
+		"duplicates": duplicates,
+	}
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
@@ -0,0 +1,553 @@
+package events
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importOptions are the defaults an import applies where a row
+// doesn't say otherwise.
+type importOptions struct {
+	loc        *time.Location // for times without a zone
+	visibility string
+	enableChat bool
+}
+
+// importRow is one parsed event plus what's wrong with it. Rows with
+// errors are never created; warnings are informational.
+type importRow struct {
+	line       int // CSV line or VEVENT number
+	event      CreateEventRequest
+	location   string   // free-text location used to geocode when there are no coordinates
+	hasCoords  bool     // event.Latitude/Longitude were given or resolved
+	topicNames []string // slugs or names, resolved to IDs against the topics table
+	errors     []string
+	warnings   []string
+	duplicate  string // ID of an existing event this one duplicates
+}
+
+func (r *importRow) errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// hasError reports whether an error about one of fields was recorded.
+func (r *importRow) hasError(fields ...string) bool {
+	for _, e := range r.errors {
+		for _, f := range fields {
+			if strings.HasPrefix(e, f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r *importRow) warnf(format string, args ...interface{}) {
+	r.warnings = append(r.warnings, fmt.Sprintf(format, args...))
+}
+
+var validEventTypes = map[string]bool{
+	"protest": true, "strike": true, "fundraiser": true, "mutual_aid": true, "meeting": true, "other": true,
+}
+
+// csvTimeLayouts are the accepted time formats for CSV cells besides
+// Unix seconds. Layouts without a zone use importOptions.loc.
+var csvTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// parseImportTime parses a CSV time cell.
+func parseImportTime(s string, loc *time.Location) (time.Time, error) {
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0), nil
+	}
+	for _, layout := range csvTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q (use RFC 3339 or YYYY-MM-DD HH:MM)", s)
+}
+
+// latLonPattern matches a "lat, lon" pair typed into a location field.
+var latLonPattern = regexp.MustCompile(`^\s*(-?\d{1,2}(?:\.\d+)?)\s*[,;]\s*(-?\d{1,3}(?:\.\d+)?)\s*$`)
+
+// parseLatLon reads a "lat, lon" pair.
+func parseLatLon(s string) (float64, float64, bool) {
+	m := latLonPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0, false
+	}
+	lat, _ := strconv.ParseFloat(m[1], 64)
+	lon, _ := strconv.ParseFloat(m[2], 64)
+	return lat, lon, true
+}
+
+// validCoordinates rejects out-of-range pairs and 0,0, which in
+// spreadsheets almost always means "missing".
+func validCoordinates(lat, lon float64) bool {
+	if math.IsNaN(lat) || math.IsNaN(lon) {
+		return false
+	}
+	return lat >= -90 && lat <= 90 && lon >= -180 && lon <= 180 && (lat != 0 || lon != 0)
+}
+
+// csvColumns maps accepted header names to canonical ones.
+var csvColumns = map[string]string{
+	"title": "title", "name": "title", "summary": "title",
+	"description": "description",
+	"event_type":  "event_type", "type": "event_type",
+	"starts_at": "starts_at", "start": "starts_at",
+	"ends_at": "ends_at", "end": "ends_at",
+	"latitude": "latitude", "lat": "latitude",
+	"longitude": "longitude", "lon": "longitude", "lng": "longitude",
+	"location": "location_name", "location_name": "location_name",
+	"location_area":       "location_area",
+	"location_visibility": "location_visibility", "visibility": "location_visibility",
+	"location_reveal_at": "location_reveal_at",
+	"topics":             "topics",
+	"enable_chat":        "enable_chat", "chat": "enable_chat",
+	"language":   "language",
+	"recurrence": "recurrence", "rrule": "recurrence",
+	"timezone": "timezone", "tz": "timezone",
+}
+
+// parseCSVImport parses a CSV file with a header row. Only title and
+// starts_at columns are required; the error is for files that can't
+// be read as an event sheet at all.
+func parseCSVImport(r io.Reader, opts importOptions) ([]importRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, errors.New("could not read CSV header")
+	}
+	cols := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if canonical, ok := csvColumns[name]; ok {
+			if _, dup := cols[canonical]; !dup {
+				cols[canonical] = i
+			}
+		}
+	}
+	for _, required := range []string{"title", "starts_at"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("CSV is missing a %s column", required)
+		}
+	}
+
+	var rows []importRow
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CSV line %d: %v", line, err)
+		}
+		get := func(col string) string {
+			if i, ok := cols[col]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+
+		row := importRow{line: line}
+		ev := &row.event
+		ev.Title = get("title")
+		ev.Description = get("description")
+		ev.EventType = strings.ToLower(strings.ReplaceAll(get("event_type"), " ", "_"))
+		ev.LocationName = get("location_name")
+		ev.LocationArea = get("location_area")
+		ev.LocationVisibility = strings.ToLower(get("location_visibility"))
+		ev.Language = strings.ToLower(get("language"))
+		ev.Recurrence = strings.TrimPrefix(strings.ToUpper(get("recurrence")), "RRULE:")
+		row.location = ev.LocationName
+
+		// A timezone column overrides the import's for the row's times.
+		loc := opts.loc
+		if s := get("timezone"); s != "" {
+			if l, err := time.LoadLocation(s); err != nil {
+				row.errorf("timezone %q is unknown", s)
+			} else {
+				loc, ev.Timezone = l, l.String()
+			}
+		}
+
+		if s := get("starts_at"); s != "" {
+			if t, err := parseImportTime(s, loc); err != nil {
+				row.errorf("starts_at: %v", err)
+			} else {
+				ev.StartsAt = t.Unix()
+			}
+		}
+		if s := get("ends_at"); s != "" {
+			if t, err := parseImportTime(s, loc); err != nil {
+				row.errorf("ends_at: %v", err)
+			} else {
+				end := t.Unix()
+				ev.EndsAt = &end
+			}
+		}
+		if s := get("location_reveal_at"); s != "" {
+			if t, err := parseImportTime(s, loc); err != nil {
+				row.errorf("location_reveal_at: %v", err)
+			} else {
+				reveal := t.Unix()
+				ev.LocationRevealAt = &reveal
+			}
+		}
+
+		latS, lonS := get("latitude"), get("longitude")
+		if latS != "" || lonS != "" {
+			lat, errLat := strconv.ParseFloat(latS, 64)
+			lon, errLon := strconv.ParseFloat(lonS, 64)
+			if errLat != nil || errLon != nil {
+				row.errorf("latitude and longitude must both be numbers")
+			} else {
+				ev.Latitude, ev.Longitude, row.hasCoords = lat, lon, true
+			}
+		}
+
+		if s := get("enable_chat"); s != "" {
+			s = strings.ToLower(s)
+			chat, err := strconv.ParseBool(s)
+			if err != nil {
+				chat = s == "yes" || s == "y"
+			}
+			ev.EnableChat = &chat
+		}
+		for _, t := range strings.FieldsFunc(get("topics"), func(r rune) bool { return r == ';' || r == '|' || r == ',' }) {
+			if t = strings.TrimSpace(t); t != "" {
+				row.topicNames = append(row.topicNames, t)
+			}
+		}
+
+		applyImportDefaults(&row, opts)
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// icalProperty is one unfolded content line.
+type icalProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// unfoldICal splits an iCalendar stream into unfolded content lines.
+func unfoldICal(data string) []string {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	var lines []string
+	for _, l := range strings.Split(data, "\n") {
+		if (strings.HasPrefix(l, " ") || strings.HasPrefix(l, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += l[1:]
+			continue
+		}
+		lines = append(lines, strings.TrimRight(l, "\r"))
+	}
+	return lines
+}
+
+// parseICalLine splits NAME;PARAM=V;PARAM="V":VALUE.
+func parseICalLine(line string) (icalProperty, bool) {
+	inQuote := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuote = !inQuote
+		} else if r == ':' && !inQuote {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return icalProperty{}, false
+	}
+	parts := strings.Split(line[:colon], ";")
+	p := icalProperty{name: strings.ToUpper(parts[0]), params: map[string]string{}, value: line[colon+1:]}
+	for _, param := range parts[1:] {
+		if k, v, ok := strings.Cut(param, "="); ok {
+			p.params[strings.ToUpper(k)] = strings.Trim(v, `"`)
+		}
+	}
+	return p, true
+}
+
+// unescapeICalText reverses escapeICalText.
+func unescapeICalText(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// parseICalTime parses a DATE or DATE-TIME value. allDay reports a
+// DATE value.
+func parseICalTime(p icalProperty, loc *time.Location) (t time.Time, allDay bool, err error) {
+	v := p.value
+	if p.params["VALUE"] == "DATE" || len(v) == 8 {
+		t, err = time.ParseInLocation("20060102", v, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(v, "Z") {
+		t, err = time.Parse(icalTimeFormat, v)
+		return t, false, err
+	}
+	if tzid := p.params["TZID"]; tzid != "" {
+		if l, lerr := time.LoadLocation(tzid); lerr == nil {
+			loc = l
+		}
+	}
+	t, err = time.ParseInLocation("20060102T150405", v, loc)
+	return t, false, err
+}
+
+// icalDurationPattern matches the RFC 5545 dur-value subset calendar
+// apps actually emit: P1W, P1D, PT2H30M, P1DT2H.
+var icalDurationPattern = regexp.MustCompile(`^\+?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+func parseICalDuration(s string) (time.Duration, bool) {
+	m := icalDurationPattern.FindStringSubmatch(s)
+	if m == nil || s == "P" || s == "PT" {
+		return 0, false
+	}
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var d time.Duration
+	for i, u := range units {
+		if m[i+1] != "" {
+			n, _ := strconv.Atoi(m[i+1])
+			d += time.Duration(n) * u
+		}
+	}
+	return d, true
+}
+
+// parseICSImport parses the VEVENTs of an iCalendar file.
+// CATEGORIES are matched against event types and topics.
+func parseICSImport(data string, opts importOptions) ([]importRow, error) {
+	lines := unfoldICal(data)
+	if len(lines) == 0 || !strings.EqualFold(strings.TrimSpace(lines[0]), "BEGIN:VCALENDAR") {
+		return nil, errors.New("not an iCalendar file")
+	}
+
+	var rows []importRow
+	var row *importRow
+	var duration time.Duration
+	var allDay bool
+	depth := 0 // nesting inside the VEVENT (VALARM etc.)
+	for _, line := range lines {
+		p, ok := parseICalLine(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case p.name == "BEGIN" && strings.EqualFold(p.value, "VEVENT") && row == nil:
+			row = &importRow{line: len(rows) + 1}
+			duration, allDay, depth = 0, false, 0
+			continue
+		case row == nil:
+			continue
+		case p.name == "BEGIN":
+			depth++
+			continue
+		case p.name == "END" && depth > 0:
+			depth--
+			continue
+		case p.name == "END" && strings.EqualFold(p.value, "VEVENT"):
+			if row.event.EndsAt == nil && duration > 0 && row.event.StartsAt != 0 {
+				end := row.event.StartsAt + int64(duration/time.Second)
+				row.event.EndsAt = &end
+			}
+			if allDay {
+				row.warnf("all-day event; imported as starting at midnight")
+			}
+			applyImportDefaults(row, opts)
+			rows = append(rows, *row)
+			row = nil
+			continue
+		case depth > 0:
+			continue
+		}
+
+		ev := &row.event
+		switch p.name {
+		case "SUMMARY":
+			ev.Title = strings.TrimSpace(unescapeICalText(p.value))
+		case "DESCRIPTION":
+			ev.Description = strings.TrimSpace(unescapeICalText(p.value))
+		case "LOCATION":
+			row.location = strings.TrimSpace(unescapeICalText(p.value))
+			ev.LocationName = row.location
+		case "GEO":
+			lat, lon, ok := parseLatLon(p.value)
+			if !ok {
+				row.errorf("GEO %q is not a latitude;longitude pair", p.value)
+			} else {
+				ev.Latitude, ev.Longitude, row.hasCoords = lat, lon, true
+			}
+		case "DTSTART":
+			t, day, err := parseICalTime(p, opts.loc)
+			if err != nil {
+				row.errorf("DTSTART %q is not a valid date", p.value)
+			} else {
+				ev.StartsAt, allDay = t.Unix(), day
+				if tzid := p.params["TZID"]; tzid != "" && t.Location().String() == tzid {
+					ev.Timezone = tzid
+				}
+			}
+		case "DTEND":
+			if t, _, err := parseICalTime(p, opts.loc); err != nil {
+				row.errorf("DTEND %q is not a valid date", p.value)
+			} else {
+				end := t.Unix()
+				ev.EndsAt = &end
+			}
+		case "DURATION":
+			if d, ok := parseICalDuration(p.value); ok {
+				duration = d
+			}
+		case "CATEGORIES":
+			for _, cat := range strings.Split(unescapeICalCategories(p.value), "\x00") {
+				cat = strings.TrimSpace(cat)
+				if t := strings.ToLower(strings.ReplaceAll(cat, " ", "_")); validEventTypes[t] && ev.EventType == "" {
+					ev.EventType = t
+				} else if cat != "" {
+					row.topicNames = append(row.topicNames, cat)
+				}
+			}
+		case "STATUS":
+			if strings.EqualFold(p.value, "CANCELLED") {
+				row.errorf("event is cancelled")
+			}
+		case "RRULE":
+			ev.Recurrence = p.value
+		case "EXDATE":
+			for _, v := range strings.Split(p.value, ",") {
+				if t, _, err := parseICalTime(icalProperty{value: v, params: p.params}, opts.loc); err != nil {
+					row.errorf("EXDATE %q is not a valid date", v)
+				} else {
+					ev.RecurrenceExceptions = append(ev.RecurrenceExceptions, t.Unix())
+				}
+			}
+		case "RDATE":
+			row.warnf("RDATE is not imported; only the RRULE occurrences are created")
+		}
+	}
+	if row != nil {
+		return nil, errors.New("iCalendar file ends inside a VEVENT")
+	}
+	return rows, nil
+}
+
+// unescapeICalCategories splits a CATEGORIES value on unescaped commas
+// (returned as NUL separators) and unescapes each item.
+func unescapeICalCategories(s string) string {
+	var items []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == ',' {
+			items = append(items, unescapeICalText(s[start:i]))
+			start = i + 1
+		}
+	}
+	items = append(items, unescapeICalText(s[start:]))
+	return strings.Join(items, "\x00")
+}
+
+// applyImportDefaults fills in import-wide defaults and checks what
+// can be checked without the database. Coordinates are checked after
+// geocoding.
+func applyImportDefaults(row *importRow, opts importOptions) {
+	ev := &row.event
+	if ev.EventType == "" {
+		ev.EventType = "other"
+	}
+	if ev.LocationVisibility == "" {
+		ev.LocationVisibility = opts.visibility
+	}
+	if ev.EnableChat == nil && opts.enableChat {
+		chat := true
+		ev.EnableChat = &chat
+	}
+
+	switch {
+	case ev.Title == "":
+		row.errorf("title is required")
+	case len([]rune(ev.Title)) > 200:
+		row.errorf("title is longer than 200 characters")
+	}
+	if !validEventTypes[ev.EventType] {
+		row.errorf("event_type %q must be one of protest, strike, fundraiser, mutual_aid, meeting, other", ev.EventType)
+	}
+	if ev.StartsAt == 0 {
+		if !row.hasError("starts_at", "DTSTART") {
+			row.errorf("starts_at is required")
+		}
+	} else if time.Unix(ev.StartsAt, 0).Before(time.Now()) {
+		row.errorf("starts_at is in the past")
+	}
+	if ev.EndsAt != nil && ev.StartsAt != 0 && *ev.EndsAt <= ev.StartsAt {
+		row.errorf("ends_at must be after starts_at")
+	}
+	switch ev.LocationVisibility {
+	case "public", "rsvp", "approval":
+	case "timed":
+		if ev.LocationRevealAt != nil && ev.StartsAt != 0 && *ev.LocationRevealAt > ev.StartsAt {
+			row.errorf("location_reveal_at must be before starts_at")
+		}
+	default:
+		row.errorf("location_visibility %q must be public, rsvp or timed", ev.LocationVisibility)
+	}
+	if ev.Language != "" && !langCode.MatchString(ev.Language) {
+		row.errorf("language %q must be a two-letter code", ev.Language)
+	}
+	if ev.Recurrence != "" || len(ev.RecurrenceExceptions) > 0 {
+		if ev.Timezone == "" {
+			ev.Timezone = opts.loc.String()
+		}
+		if _, err := parseRecurrence(ev.Recurrence, ev.Timezone, ev.StartsAt, ev.RecurrenceExceptions); err != nil && ev.StartsAt != 0 {
+			row.errorf("%v", err)
+		}
+	}
+	if !row.hasCoords {
+		if lat, lon, ok := parseLatLon(row.location); ok {
+			ev.Latitude, ev.Longitude, row.hasCoords = lat, lon, true
+			ev.LocationName = ""
+		}
+	}
+}
+
+var langCode = regexp.MustCompile(`^[a-z]{2}$`)
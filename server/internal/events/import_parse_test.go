@@ -0,0 +1,182 @@
+package events
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testImportOptions() importOptions {
+	return importOptions{loc: time.UTC, visibility: "public"}
+}
+
+func TestParseCSVImport(t *testing.T) {
+	year := time.Now().Year() + 1
+	csv := strings.Join([]string{
+		"Title,Type,Start,End,Lat,Lon,Location,Topics,Chat",
+		`March for Housing,protest,` + strconv.Itoa(year) + `-05-01 14:00,` + strconv.Itoa(year) + `-05-01 16:00,52.52,13.405,City Hall,"housing;tenants",yes`,
+		`No Date,meeting,,,,,,,`,
+		`Coordinates In Name,,` + strconv.Itoa(year) + `-05-02T10:00,,,,"48.8566, 2.3522",,`,
+		``,
+		`Bad Order,strike,` + strconv.Itoa(year) + `-05-03 10:00,` + strconv.Itoa(year) + `-05-03 09:00,1,1,,,`,
+	}, "\n")
+
+	rows, err := parseCSVImport(strings.NewReader(csv), testImportOptions())
+	require.NoError(t, err)
+	require.Len(t, rows, 4, "blank lines are skipped")
+
+	march := rows[0]
+	assert.Equal(t, 2, march.line)
+	assert.Empty(t, march.errors)
+	assert.Equal(t, "protest", march.event.EventType)
+	assert.Equal(t, time.Date(year, 5, 1, 14, 0, 0, 0, time.UTC).Unix(), march.event.StartsAt)
+	assert.True(t, march.hasCoords)
+	assert.Equal(t, []string{"housing", "tenants"}, march.topicNames)
+	require.NotNil(t, march.event.EnableChat)
+	assert.True(t, *march.event.EnableChat)
+
+	assert.Equal(t, []string{"starts_at is required"}, rows[1].errors)
+
+	named := rows[2]
+	assert.Empty(t, named.errors)
+	assert.Equal(t, "other", named.event.EventType)
+	assert.True(t, named.hasCoords, "lat, lon in the location column")
+	assert.InDelta(t, 48.8566, named.event.Latitude, 1e-9)
+
+	assert.Contains(t, rows[3].errors, "ends_at must be after starts_at")
+}
+
+func TestParseCSVImport_Recurrence(t *testing.T) {
+	year := time.Now().Year() + 1
+	start := strconv.Itoa(year) + "-05-01 18:00"
+	otherDay := strings.ToUpper(time.Date(year, 5, 2, 0, 0, 0, 0, time.UTC).Weekday().String()[:2])
+	csv := strings.Join([]string{
+		"title,starts_at,rrule,timezone",
+		"Weekly assembly," + start + ",RRULE:FREQ=WEEKLY,America/New_York",
+		"Wrong day," + start + ",FREQ=WEEKLY;BYDAY=" + otherDay + ",",
+		"Bad zone," + start + ",,Mars/Olympus",
+	}, "\n")
+
+	rows, err := parseCSVImport(strings.NewReader(csv), testImportOptions())
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	weekly := rows[0]
+	assert.Empty(t, weekly.errors)
+	assert.Equal(t, "FREQ=WEEKLY", weekly.event.Recurrence)
+	assert.Equal(t, "America/New_York", weekly.event.Timezone)
+	ny, _ := time.LoadLocation("America/New_York")
+	assert.Equal(t, time.Date(year, 5, 1, 18, 0, 0, 0, ny).Unix(), weekly.event.StartsAt, "times are read in the row's timezone")
+
+	assert.Equal(t, []string{"starts_at must be an occurrence of the recurrence rule"}, rows[1].errors)
+	assert.Equal(t, "UTC", rows[1].event.Timezone)
+	assert.Equal(t, []string{`timezone "Mars/Olympus" is unknown`}, rows[2].errors)
+}
+
+func TestParseCSVImport_Header(t *testing.T) {
+	_, err := parseCSVImport(strings.NewReader("title,location\nx,y\n"), testImportOptions())
+	assert.EqualError(t, err, "CSV is missing a starts_at column")
+
+	rows, err := parseCSVImport(strings.NewReader("\ufeffTITLE,STARTS_AT\nx,nope\n"), testImportOptions())
+	require.NoError(t, err, "header is case-insensitive and may start with a BOM")
+	require.Len(t, rows, 1)
+	assert.Len(t, rows[0].errors, 1, "an unparseable time isn't also reported as missing")
+}
+
+func TestParseICSImport(t *testing.T) {
+	year := strconv.Itoa(time.Now().Year() + 1)
+	ics := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"SUMMARY:Rally\\, then march",
+		"DESCRIPTION:Bring water\\nand signs",
+		"DTSTART;TZID=Europe/Berlin:" + year + "0501T140000",
+		"DURATION:PT2H30M",
+		"LOCATION:Alexanderplatz",
+		"GEO:52.5219;13.4132",
+		"CATEGORIES:PROTEST,Climate Action",
+		"RRULE:FREQ=WEEKLY",
+		"EXDATE;TZID=Europe/Berlin:" + year + "0508T140000",
+		"BEGIN:VALARM",
+		"DESCRIPTION:ignored",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"SUMMARY:Assembly",
+		"DTSTART;VALUE=DATE:" + year + "0601",
+		"STATUS:CANCELLED",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	rows, err := parseICSImport(ics, testImportOptions())
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	rally := rows[0]
+	assert.Empty(t, rally.errors)
+	assert.Equal(t, "Rally, then march", rally.event.Title)
+	assert.Equal(t, "Bring water\nand signs", rally.event.Description)
+	assert.Equal(t, "protest", rally.event.EventType)
+	assert.Equal(t, []string{"Climate Action"}, rally.topicNames)
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	start, _ := time.ParseInLocation("20060102T150405", year+"0501T140000", berlin)
+	assert.Equal(t, start.Unix(), rally.event.StartsAt)
+	require.NotNil(t, rally.event.EndsAt)
+	assert.Equal(t, start.Add(150*time.Minute).Unix(), *rally.event.EndsAt)
+	assert.True(t, rally.hasCoords)
+	assert.Empty(t, rally.warnings)
+	assert.Equal(t, "FREQ=WEEKLY", rally.event.Recurrence)
+	assert.Equal(t, "Europe/Berlin", rally.event.Timezone)
+	assert.Equal(t, []int64{start.AddDate(0, 0, 7).Unix()}, rally.event.RecurrenceExceptions)
+
+	assembly := rows[1]
+	assert.Equal(t, []string{"event is cancelled"}, assembly.errors)
+	assert.Contains(t, assembly.warnings[0], "all-day")
+
+	_, err = parseICSImport("title,starts_at\n", testImportOptions())
+	assert.Error(t, err)
+	_, err = parseICSImport("BEGIN:VCALENDAR\nBEGIN:VEVENT\nSUMMARY:x\n", testImportOptions())
+	assert.Error(t, err, "truncated file")
+}
+
+func TestUnfoldICal_RoundTrip(t *testing.T) {
+	long := strings.Repeat("Långt namn, ", 12)
+	folded := foldICalLine("SUMMARY:" + escapeICalText(long))
+	lines := unfoldICal(folded + "\r\n")
+	require.NotEmpty(t, lines)
+	p, ok := parseICalLine(lines[0])
+	require.True(t, ok)
+	assert.Equal(t, long, unescapeICalText(p.value))
+}
+
+func TestParseICalDuration(t *testing.T) {
+	d, ok := parseICalDuration("P1DT2H")
+	assert.True(t, ok)
+	assert.Equal(t, 26*time.Hour, d)
+	_, ok = parseICalDuration("PT")
+	assert.False(t, ok)
+	_, ok = parseICalDuration("2 hours")
+	assert.False(t, ok)
+}
+
+func TestValidCoordinates(t *testing.T) {
+	assert.True(t, validCoordinates(52.52, 13.405))
+	assert.False(t, validCoordinates(0, 0), "null island means missing")
+	assert.False(t, validCoordinates(91, 0))
+	assert.False(t, validCoordinates(0, 181))
+}
+
+func TestDetectImportFormat(t *testing.T) {
+	assert.Equal(t, "ics", detectImportFormat("", "events.ICS", "", nil))
+	assert.Equal(t, "csv", detectImportFormat("", "", "text/csv", nil))
+	assert.Equal(t, "ics", detectImportFormat("", "", "", []byte("\n begin:vcalendar\r\n")))
+	assert.Equal(t, "csv", detectImportFormat("", "", "", []byte("title,starts_at")))
+	assert.Equal(t, "xml", detectImportFormat("XML", "a.csv", "", nil), "explicit format wins, then gets rejected")
+}
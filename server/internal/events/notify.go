@@ -0,0 +1,299 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kuurier/server/internal/push"
+	"github.com/kuurier/server/internal/websocket"
+)
+
+// eventState is what attendees are told about when it changes.
+type eventState struct {
+	title, organizerID         string
+	channelID                  *string
+	visibility                 string
+	startsAt                   time.Time
+	endsAt                     *time.Time
+	locationName, locationArea *string
+	lat, lon                   float64
+	cancelled                  bool
+	recurrence                 *string
+	timezone                   string
+	riskLevel                  *string
+}
+
+func (h *Handler) eventState(ctx context.Context, eventID string) (eventState, error) {
+	var st eventState
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT title, organizer_id, channel_id, location_visibility, starts_at, ends_at,
+		       location_name, location_area, ST_Y(location::geometry), ST_X(location::geometry),
+		       is_cancelled, recurrence_rule, timezone, risk_level
+		FROM events WHERE id = $1
+	`, eventID).Scan(&st.title, &st.organizerID, &st.channelID, &st.visibility, &st.startsAt, &st.endsAt,
+		&st.locationName, &st.locationArea, &st.lat, &st.lon,
+		&st.cancelled, &st.recurrence, &st.timezone, &st.riskLevel)
+	return st, err
+}
+
+// forOccurrence is the state of one occurrence of a series.
+func (st eventState) forOccurrence(o occurrence) eventState {
+	st.startsAt, st.endsAt = o.startsAt, o.endsAt
+	st.recurrence = nil
+	st.cancelled = st.cancelled || o.override.cancelled
+	if o.override.title != nil {
+		st.title = *o.override.title
+	}
+	if o.override.locationName != nil {
+		st.locationName = o.override.locationName
+	}
+	if o.override.locationArea != nil {
+		st.locationArea = o.override.locationArea
+	}
+	return st
+}
+
+// eventChanges summarizes how the time, location, cancellation and
+// risk level of an event changed, one sentence each. With exactLocation false a move
+// names only the general area, for readers who may not see the
+// exact location.
+func eventChanges(before, after eventState, exactLocation bool, now time.Time) []string {
+	var changes []string
+	switch {
+	case !before.cancelled && after.cancelled:
+		changes = append(changes, "Cancelled")
+	case before.cancelled && !after.cancelled:
+		changes = append(changes, "No longer cancelled")
+	}
+
+	startMoved := !before.startsAt.Equal(after.startsAt)
+	endMoved := (before.endsAt == nil) != (after.endsAt == nil) ||
+		(before.endsAt != nil && after.endsAt != nil && !before.endsAt.Equal(*after.endsAt))
+	ruleChanged := !equalPtr(before.recurrence, after.recurrence) || before.timezone != after.timezone
+	if s := newSeries(after.recurrence, after.timezone, after.startsAt, after.endsAt); s != nil {
+		if startMoved || endMoved || ruleChanged {
+			summary := "Schedule changed"
+			if next, ok := s.rule.Next(s.start, now); ok {
+				summary += "; next on " + formatEventTime(next, after.timezone)
+			}
+			changes = append(changes, summary)
+		}
+	} else {
+		switch {
+		case startMoved || ruleChanged:
+			changes = append(changes, "Now starts "+formatEventTime(after.startsAt, after.timezone))
+		case endMoved && after.endsAt != nil:
+			changes = append(changes, "Now ends "+formatEventTime(*after.endsAt, after.timezone))
+		case endMoved:
+			changes = append(changes, "No longer has an end time")
+		}
+	}
+
+	moved := before.lat != after.lat || before.lon != after.lon || !equalPtr(before.locationName, after.locationName)
+	areaChanged := !equalPtr(before.locationArea, after.locationArea)
+	switch {
+	case moved && exactLocation:
+		place := fmt.Sprintf("%.6f, %.6f", after.lat, after.lon)
+		if after.locationName != nil && *after.locationName != "" {
+			place = *after.locationName
+		}
+		changes = append(changes, "Moved to "+place)
+	case (moved || areaChanged) && after.locationArea != nil && *after.locationArea != "":
+		changes = append(changes, "Location changed (now in "+*after.locationArea+")")
+	case moved || areaChanged:
+		changes = append(changes, "Location changed")
+	}
+
+	if !equalPtr(before.riskLevel, after.riskLevel) && after.riskLevel != nil {
+		if before.riskLevel == nil || riskRank(*after.riskLevel) > riskRank(*before.riskLevel) {
+			changes = append(changes, "Risk level raised to "+*after.riskLevel)
+		} else {
+			changes = append(changes, "Risk level lowered to "+*after.riskLevel)
+		}
+	}
+	return changes
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// formatEventTime renders t in the event's timezone, e.g.
+// "Fri 1 May 14:00 CEST".
+func formatEventTime(t time.Time, timezone string) string {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format("Mon 2 Jan 15:04 MST")
+}
+
+// eventNotice is an update for an event's attendees.
+type eventNotice struct {
+	wsType       string
+	title        string
+	body         string // for attendees, who may see the exact location
+	channelBody  string // for the event channel; empty posts nothing
+	urgent       bool   // bypasses quiet hours
+	occurrenceAt *time.Time
+	extra        map[string]interface{} // added to the WebSocket payload
+}
+
+// notifyChanges tells attendees what changed between before and after,
+// if anything they need to know about did. occ limits it to one
+// occurrence of a series.
+func (h *Handler) notifyChanges(eventID string, occ *time.Time, before, after eventState) {
+	now := time.Now()
+	changes := eventChanges(before, after, true, now)
+	if len(changes) == 0 {
+		return
+	}
+	n := eventNotice{
+		wsType:       websocket.TypeEventUpdated,
+		title:        "Update: " + after.title,
+		body:         strings.Join(changes, ". ") + ".",
+		urgent:       true, // a reroute or cancellation can't wait for morning
+		occurrenceAt: occ,
+		extra:        map[string]interface{}{"changes": changes},
+	}
+	if after.channelID != nil {
+		n.channelBody = n.title + ": " + strings.Join(eventChanges(before, after, after.visibility == "public", now), ". ") + "."
+	}
+	h.notifyAttendees(eventID, after.organizerID, after.channelID, n)
+}
+
+// notifyAttendees sends n to the approved going and interested RSVPs of eventID
+// (of one occurrence, or of those still to come) by push and to those
+// online over WebSocket, and posts it to the event channel if there is
+// one, for members without push. Runs after the response is written,
+// so it uses its own context.
+func (h *Handler) notifyAttendees(eventID, senderID string, channelID *string, n eventNotice) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT DISTINCT user_id FROM event_rsvps
+		WHERE event_id = $1 AND user_id <> $2
+		  AND status IN ('going', 'interested') AND approval = 'approved'
+		  AND (CASE WHEN $3::timestamptz IS NULL THEN occurrence_at IS NULL OR occurrence_at >= NOW()
+		            ELSE occurrence_at = $3 END)
+	`, eventID, senderID, n.occurrenceAt)
+	if err != nil {
+		log.Printf("events: notify attendees of %s: %v", eventID, err)
+		return
+	}
+	var userIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			userIDs = append(userIDs, id)
+		}
+	}
+	rows.Close()
+
+	if h.push != nil && len(userIDs) > 0 {
+		notification := push.Notification{
+			Title:    n.title,
+			Body:     n.body,
+			Priority: "normal",
+			Category: "EVENT",
+			ThreadID: "event-" + eventID,
+			Data: map[string]string{
+				"type":     string(push.NotificationTypeEvent),
+				"event_id": eventID,
+			},
+		}
+		if n.urgent {
+			notification.Priority = "high"
+		}
+		if n.occurrenceAt != nil {
+			notification.Data["occurrence_at"] = fmt.Sprint(n.occurrenceAt.Unix())
+		}
+		_ = h.push.SendToUsers(ctx, userIDs, notification)
+	}
+
+	if h.hub != nil && len(userIDs) > 0 {
+		payload := map[string]interface{}{"event_id": eventID, "title": n.title, "body": n.body}
+		if n.occurrenceAt != nil {
+			payload["occurrence_at"] = *n.occurrenceAt
+		}
+		for k, v := range n.extra {
+			payload[k] = v
+		}
+		if data, err := json.Marshal(payload); err == nil {
+			msg := &websocket.Message{Type: n.wsType, Payload: data, Timestamp: time.Now().UTC()}
+			for _, userID := range userIDs {
+				h.hub.BroadcastToUser(userID, msg)
+			}
+		}
+	}
+
+	if channelID != nil && n.channelBody != "" {
+		if err := h.postSystemMessage(ctx, *channelID, senderID, n.channelBody); err != nil {
+			log.Printf("events: post update to channel %s: %v", *channelID, err)
+		}
+	}
+}
+
+// postSystemMessage posts body to an event channel as a system message
+// from the organizer. System messages are server-generated notices,
+// stored as plain UTF-8 rather than ciphertext.
+func (h *Handler) postSystemMessage(ctx context.Context, channelID, senderID, body string) error {
+	messageID := uuid.New().String()
+	var createdAt time.Time
+	if err := h.db.Pool().QueryRow(ctx, `
+		INSERT INTO messages (id, channel_id, sender_id, ciphertext, message_type, created_at)
+		VALUES ($1, $2, $3, $4, 'system', NOW())
+		RETURNING created_at
+	`, messageID, channelID, senderID, []byte(body)).Scan(&createdAt); err != nil {
+		return err
+	}
+	if _, err := h.db.Pool().Exec(ctx, `UPDATE channels SET updated_at = NOW() WHERE id = $1`, channelID); err != nil {
+		log.Printf("events: failed to update channel timestamp for %s: %v", channelID, err)
+	}
+
+	if h.hub != nil {
+		data, err := json.Marshal(map[string]interface{}{
+			"id":           messageID,
+			"channel_id":   channelID,
+			"sender_id":    senderID,
+			"ciphertext":   []byte(body),
+			"message_type": "system",
+			"created_at":   createdAt,
+		})
+		if err == nil {
+			h.hub.BroadcastToChannel(channelID, &websocket.Message{
+				Type:      websocket.TypeMessageNew,
+				ChannelID: channelID,
+				UserID:    senderID,
+				Payload:   data,
+				Timestamp: time.Now().UTC(),
+			})
+		}
+	}
+	return nil
+}
+
+// notifyUsers pushes each user their own news about an event, such as
+// a decision on their RSVP or role sign-up. Runs after the response is
+// written, so it uses its own context.
+func (h *Handler) notifyUsers(eventID, title string, notices map[string]string) {
+	if h.push == nil || len(notices) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for userID, body := range notices {
+		if err := h.push.SendEventNotification(ctx, eventID, title, body, []string{userID}); err != nil {
+			log.Printf("events: notify %s about %s: %v", userID, eventID, err)
+		}
+	}
+}
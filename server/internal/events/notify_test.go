@@ -0,0 +1,82 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventChanges(t *testing.T) {
+	now := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2026, 5, 1, 14, 0, 0, 0, time.UTC)
+	hall, park, area := "Union Hall", "City Park", "Kreuzberg"
+	before := eventState{title: "Rally", startsAt: start, locationName: &hall, locationArea: &area,
+		lat: 52.5, lon: 13.4, timezone: "UTC"}
+
+	assert.Empty(t, eventChanges(before, before, true, now))
+
+	after := before
+	after.cancelled = true
+	assert.Equal(t, []string{"Cancelled"}, eventChanges(before, after, true, now))
+	assert.Equal(t, []string{"No longer cancelled"}, eventChanges(after, before, true, now))
+
+	after = before
+	after.startsAt = start.Add(2 * time.Hour)
+	assert.Equal(t, []string{"Now starts Fri 1 May 16:00 UTC"}, eventChanges(before, after, true, now))
+
+	after = before
+	ends := start.Add(3 * time.Hour)
+	after.endsAt = &ends
+	assert.Equal(t, []string{"Now ends Fri 1 May 17:00 UTC"}, eventChanges(before, after, true, now))
+
+	after = before
+	after.locationName, after.lat = &park, 52.51
+	assert.Equal(t, []string{"Moved to City Park"}, eventChanges(before, after, true, now))
+	assert.Equal(t, []string{"Location changed (now in Kreuzberg)"}, eventChanges(before, after, false, now),
+		"only the area for readers who may not see the location")
+
+	after.cancelled, after.startsAt = true, start.Add(time.Hour)
+	assert.Len(t, eventChanges(before, after, true, now), 3)
+}
+
+func TestEventChanges_Series(t *testing.T) {
+	now := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC) // a Wednesday
+	start := time.Date(2026, 3, 2, 18, 0, 0, 0, time.UTC)
+	weekly := "FREQ=WEEKLY;BYDAY=MO"
+	before := eventState{title: "Assembly", startsAt: start, recurrence: &weekly, timezone: "UTC"}
+
+	after := before
+	thursdays := "FREQ=WEEKLY;BYDAY=TH"
+	after.recurrence = &thursdays
+	assert.Equal(t, []string{"Schedule changed; next on Thu 2 Apr 18:00 UTC"}, eventChanges(before, after, true, now))
+
+	// Editing one occurrence reports it like a one-off event.
+	o := occurrence{at: start.AddDate(0, 0, 35), startsAt: start.AddDate(0, 0, 35)}
+	moved := o
+	moved.startsAt = o.startsAt.Add(time.Hour)
+	assert.Equal(t, []string{"Now starts Mon 6 Apr 19:00 UTC"},
+		eventChanges(before.forOccurrence(o), before.forOccurrence(moved), true, now))
+
+	cancelled := o
+	cancelled.override.cancelled = true
+	assert.Equal(t, []string{"Cancelled"},
+		eventChanges(before.forOccurrence(o), before.forOccurrence(cancelled), true, now))
+}
+
+func TestEventChanges_RiskLevel(t *testing.T) {
+	now := time.Date(2026, 4, 1, 12, 0, 0, 0, time.UTC)
+	low, arrestable := "low", "arrestable"
+	before := eventState{title: "Blockade", startsAt: now.Add(48 * time.Hour), timezone: "UTC"}
+
+	after := before
+	after.riskLevel = &arrestable
+	assert.Equal(t, []string{"Risk level raised to arrestable"}, eventChanges(before, after, true, now), "first rating")
+
+	before.riskLevel = &low
+	assert.Equal(t, []string{"Risk level raised to arrestable"}, eventChanges(before, after, true, now))
+	assert.Equal(t, []string{"Risk level lowered to low"}, eventChanges(after, before, true, now))
+
+	after.riskLevel = nil
+	assert.Empty(t, eventChanges(before, after, true, now), "clearing the rating isn't news")
+}
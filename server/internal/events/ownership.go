@@ -0,0 +1,768 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+// How a user is entitled to run an event. Any of them may edit it, post
+// announcements and review RSVPs and sign-ups; inviting co-organizers,
+// moving the event between organizations and deleting it are left to
+// the organizer and the owning organization's admins and moderators.
+const (
+	managerOrganizer    = "organizer"
+	managerOrgAdmin     = "org_admin"
+	managerOrgModerator = "org_moderator"
+	managerCoOrganizer  = "co_organizer"
+)
+
+// eventTransferTTL is how long the recipient of an event transfer has
+// to accept it, as for organization admin transfers.
+const eventTransferTTL = 7 * 24 * time.Hour
+
+// managerRole decides how userID may run an event, given its organizer,
+// whether userID is an accepted co-organizer and their role in the
+// organization owning the event ("" if none). It returns "" for users
+// who may not run it.
+func managerRole(organizerID, userID string, coOrganizer bool, orgRole string) string {
+	switch {
+	case userID == organizerID:
+		return managerOrganizer
+	case orgRole == "admin":
+		return managerOrgAdmin
+	case orgRole == "moderator":
+		return managerOrgModerator
+	case coOrganizer:
+		return managerCoOrganizer
+	default:
+		return ""
+	}
+}
+
+// ownsEvent reports whether a manager role answers for the event as a
+// whole rather than helping run it.
+func ownsEvent(role string) bool {
+	return role == managerOrganizer || role == managerOrgAdmin || role == managerOrgModerator
+}
+
+// eventManager returns how userID may run eventID (see managerRole).
+// It returns pgx.ErrNoRows if the event doesn't exist.
+func (h *Handler) eventManager(ctx context.Context, eventID, userID string) (string, error) {
+	var organizerID, orgRole string
+	var coOrganizer bool
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT e.organizer_id,
+		       EXISTS(SELECT 1 FROM event_co_organizers
+		              WHERE event_id = e.id AND user_id::text = $2 AND status = 'accepted'),
+		       COALESCE((SELECT role FROM organization_members
+		                 WHERE org_id = e.org_id AND user_id::text = $2), '')
+		FROM events e WHERE e.id = $1
+	`, eventID, userID).Scan(&organizerID, &coOrganizer, &orgRole)
+	if err != nil {
+		return "", err
+	}
+	return managerRole(organizerID, userID, coOrganizer, orgRole), nil
+}
+
+// managesEventSQL is whether the user $1 is among the people running
+// event e, for listings that can't ask eventManager row by row.
+const managesEventSQL = `(e.organizer_id = $1
+			   OR EXISTS(SELECT 1 FROM event_co_organizers co
+			             WHERE co.event_id = e.id AND co.user_id = $1 AND co.status = 'accepted')
+			   OR EXISTS(SELECT 1 FROM organization_members m
+			             WHERE m.org_id = e.org_id AND m.user_id = $1 AND m.role IN ('admin', 'moderator')))`
+
+// eventManagerIDs returns the organizer and accepted co-organizers of
+// eventID, who are told about requests awaiting a decision.
+func (h *Handler) eventManagerIDs(ctx context.Context, eventID string) ([]string, error) {
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT organizer_id FROM events WHERE id = $1
+		UNION
+		SELECT user_id FROM event_co_organizers WHERE event_id = $1 AND status = 'accepted'
+	`, eventID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
+
+// notifyManagers sends body to the people running eventID.
+func (h *Handler) notifyManagers(eventID, title, body string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	managers, err := h.eventManagerIDs(ctx, eventID)
+	cancel()
+	if err != nil {
+		return
+	}
+	notices := make(map[string]string, len(managers))
+	for _, id := range managers {
+		notices[id] = body
+	}
+	h.notifyUsers(eventID, title, notices)
+}
+
+// orgRole returns userID's role in orgID, or "" if they aren't a member.
+func (h *Handler) orgRole(ctx context.Context, orgID, userID string) (string, error) {
+	var role string
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT role FROM organization_members WHERE org_id::text = $1 AND user_id = $2
+	`, orgID, userID).Scan(&role)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
+}
+
+// checkEventOrg writes an error response and returns false unless
+// userID may put an event under orgID: only its admins and moderators,
+// who will be able to run the event, can.
+func (h *Handler) checkEventOrg(c *gin.Context, orgID, userID string) bool {
+	role, err := h.orgRole(c.Request.Context(), orgID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return false
+	}
+	if role != "admin" && role != "moderator" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only admins and moderators can add events to an organization"})
+		return false
+	}
+	return true
+}
+
+// grantEventChannels makes userID an admin of the event's channel and
+// roles channel, as the organizer is.
+func grantEventChannels(ctx context.Context, tx pgx.Tx, eventID, userID string) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO channel_members (channel_id, user_id, role, joined_at)
+		SELECT c.id, $2, 'admin', NOW()
+		FROM events e
+		JOIN channels c ON c.id IN (e.channel_id, e.roles_channel_id)
+		WHERE e.id = $1
+		ON CONFLICT (channel_id, user_id) DO UPDATE SET role = 'admin'
+	`, eventID, userID)
+	return err
+}
+
+// revokeEventChannels takes back what grantEventChannels gave: userID
+// stays in the event channel as a member, and in the roles channel only
+// while holding a role.
+func revokeEventChannels(ctx context.Context, tx pgx.Tx, eventID, userID string) error {
+	if _, err := tx.Exec(ctx, `
+		UPDATE channel_members m SET role = 'member'
+		FROM events e
+		WHERE e.id = $1 AND m.channel_id IN (e.channel_id, e.roles_channel_id) AND m.user_id::text = $2
+	`, eventID, userID); err != nil {
+		return err
+	}
+	return syncRolesChannel(ctx, tx, eventID)
+}
+
+// ============================================================================
+// CO-ORGANIZERS
+// ============================================================================
+
+// ListCoOrganizers returns an event's co-organizers and pending
+// invitations (people running the event only).
+func (h *Handler) ListCoOrganizers(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	role, err := h.eventManager(ctx, eventID, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if role == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the event's organizers can see co-organizers"})
+		return
+	}
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT co.user_id, u.display_name, co.status, co.invited_by, co.created_at, co.accepted_at
+		FROM event_co_organizers co
+		JOIN users u ON u.id = co.user_id
+		WHERE co.event_id = $1
+		ORDER BY co.created_at
+	`, eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch co-organizers"})
+		return
+	}
+	coOrganizers, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (gin.H, error) {
+		var id, status string
+		var displayName, invitedBy *string
+		var invitedAt time.Time
+		var acceptedAt *time.Time
+		if err := row.Scan(&id, &displayName, &status, &invitedBy, &invitedAt, &acceptedAt); err != nil {
+			return nil, err
+		}
+		co := gin.H{"user_id": id, "status": status, "invited_at": invitedAt}
+		if displayName != nil {
+			co["display_name"] = *displayName
+		}
+		if invitedBy != nil {
+			co["invited_by"] = *invitedBy
+		}
+		if acceptedAt != nil {
+			co["accepted_at"] = *acceptedAt
+		}
+		return co, nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch co-organizers"})
+		return
+	}
+	if coOrganizers == nil {
+		coOrganizers = []gin.H{}
+	}
+	c.JSON(http.StatusOK, gin.H{"co_organizers": coOrganizers})
+}
+
+// InviteCoOrganizerRequest names the user to invite.
+type InviteCoOrganizerRequest struct {
+	UserID string `json:"user_id" binding:"required,uuid"`
+}
+
+// InviteCoOrganizer invites a user to help run an event (organizer or
+// the owning organization's admins and moderators). They become a
+// co-organizer once they accept.
+func (h *Handler) InviteCoOrganizer(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req InviteCoOrganizerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	st, err := h.eventState(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	role, err := h.eventManager(ctx, eventID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !ownsEvent(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the organizer or the organization can invite co-organizers"})
+		return
+	}
+	if req.UserID == st.organizerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the organizer already runs this event"})
+		return
+	}
+
+	var exists bool
+	if err := h.db.Pool().QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, req.UserID).Scan(&exists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	result, err := h.db.Pool().Exec(ctx, `
+		INSERT INTO event_co_organizers (event_id, user_id, invited_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (event_id, user_id) DO NOTHING
+	`, eventID, req.UserID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to invite co-organizer"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "user is already invited"})
+		return
+	}
+
+	go h.notifyUsers(eventID, st.title, map[string]string{req.UserID: "You're invited to co-organize this event"})
+
+	c.JSON(http.StatusCreated, gin.H{"user_id": req.UserID, "status": "pending"})
+}
+
+// RespondToCoOrganizerInvite accepts or declines the user's invitation
+// to co-organize an event. Co-organizers become admins of the event's
+// channels.
+func (h *Handler) RespondToCoOrganizerInvite(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req struct {
+		Accept bool `json:"accept"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	err = tx.QueryRow(ctx, `
+		SELECT status FROM event_co_organizers WHERE event_id = $1 AND user_id = $2 FOR UPDATE
+	`, eventID, userID).Scan(&status)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "invitation not found"})
+		return
+	}
+	if status != "pending" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "you are already a co-organizer"})
+		return
+	}
+
+	if req.Accept {
+		if _, err := tx.Exec(ctx, `
+			UPDATE event_co_organizers SET status = 'accepted', accepted_at = NOW()
+			WHERE event_id = $1 AND user_id = $2
+		`, eventID, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to accept invitation"})
+			return
+		}
+		if err := grantEventChannels(ctx, tx, eventID, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to join event channels"})
+			return
+		}
+	} else if _, err := tx.Exec(ctx, `
+		DELETE FROM event_co_organizers WHERE event_id = $1 AND user_id = $2
+	`, eventID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to decline invitation"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	if req.Accept {
+		c.JSON(http.StatusOK, gin.H{"message": "you are now a co-organizer"})
+	} else {
+		c.JSON(http.StatusOK, gin.H{"message": "invitation declined"})
+	}
+}
+
+// RemoveCoOrganizer removes a co-organizer or withdraws an invitation
+// (organizer or the owning organization's admins and moderators).
+// Co-organizers can also step down themselves.
+func (h *Handler) RemoveCoOrganizer(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	coOrganizerID := c.Param("userId")
+	ctx := c.Request.Context()
+
+	if coOrganizerID != userID {
+		role, err := h.eventManager(ctx, eventID, userID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if !ownsEvent(role) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "only the organizer or the organization can remove co-organizers"})
+			return
+		}
+	}
+
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		DELETE FROM event_co_organizers WHERE event_id = $1 AND user_id::text = $2
+	`, eventID, coOrganizerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to remove co-organizer"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "co-organizer not found"})
+		return
+	}
+	if err := revokeEventChannels(ctx, tx, eventID, coOrganizerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update event channels"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "co-organizer removed"})
+}
+
+// ============================================================================
+// OWNERSHIP TRANSFER
+// ============================================================================
+
+// TransferEventRequest names the new organizer.
+type TransferEventRequest struct {
+	ToUserID string `json:"to_user_id" binding:"required,uuid"`
+}
+
+// RequestEventTransfer asks someone to take over as an event's
+// organizer. The organizer can hand the event on, and an admin of the
+// owning organization can when the organizer can't. The recipient must
+// be a co-organizer or a member of the owning organization.
+func (h *Handler) RequestEventTransfer(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req TransferEventRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	st, err := h.eventState(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	role, err := h.eventManager(ctx, eventID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if role != managerOrganizer && role != managerOrgAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the organizer or an organization admin can transfer this event"})
+		return
+	}
+	if req.ToUserID == st.organizerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user is already the organizer"})
+		return
+	}
+
+	// The recipient must already be trusted with the event
+	var eligible bool
+	err = h.db.Pool().QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM event_co_organizers
+		              WHERE event_id = e.id AND user_id::text = $2 AND status = 'accepted')
+		    OR EXISTS(SELECT 1 FROM organization_members WHERE org_id = e.org_id AND user_id::text = $2)
+		FROM events e WHERE e.id = $1
+	`, eventID, req.ToUserID).Scan(&eligible)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if !eligible {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user must be a co-organizer or a member of the organization"})
+		return
+	}
+
+	expiresAt := time.Now().Add(eventTransferTTL)
+	var requestID string
+	err = h.db.Pool().QueryRow(ctx, `
+		INSERT INTO event_transfer_requests (event_id, from_user_id, to_user_id, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (event_id, to_user_id) WHERE status = 'pending'
+		DO UPDATE SET from_user_id = $2, created_at = NOW(), expires_at = $4
+		RETURNING id
+	`, eventID, userID, req.ToUserID, expiresAt).Scan(&requestID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create transfer request"})
+		return
+	}
+
+	go h.notifyUsers(eventID, st.title, map[string]string{req.ToUserID: "You've been asked to take over this event"})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":         requestID,
+		"message":    "event transfer request created",
+		"expires_at": expiresAt,
+	})
+}
+
+// ListEventTransfers returns the user's pending event transfer requests.
+func (h *Handler) ListEventTransfers(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ctx := c.Request.Context()
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT t.id, t.event_id, e.title, t.from_user_id, t.created_at, t.expires_at
+		FROM event_transfer_requests t
+		JOIN events e ON e.id = t.event_id
+		WHERE t.to_user_id = $1 AND t.status = 'pending' AND t.expires_at > NOW()
+		ORDER BY t.created_at
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch transfer requests"})
+		return
+	}
+	transfers, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (gin.H, error) {
+		var id, eventID, title, fromUserID string
+		var createdAt, expiresAt time.Time
+		if err := row.Scan(&id, &eventID, &title, &fromUserID, &createdAt, &expiresAt); err != nil {
+			return nil, err
+		}
+		return gin.H{
+			"id":           id,
+			"event_id":     eventID,
+			"event_title":  title,
+			"from_user_id": fromUserID,
+			"created_at":   createdAt,
+			"expires_at":   expiresAt,
+		}, nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch transfer requests"})
+		return
+	}
+	if transfers == nil {
+		transfers = []gin.H{}
+	}
+	c.JSON(http.StatusOK, gin.H{"transfers": transfers})
+}
+
+// RespondToEventTransfer accepts or rejects an event transfer request
+// (recipient only). On accepting, the recipient becomes the organizer.
+// An organizer who handed the event on stays a co-organizer; one
+// replaced by the organization doesn't, and loses admin of the event's
+// channels.
+func (h *Handler) RespondToEventTransfer(c *gin.Context) {
+	userID := c.GetString("user_id")
+	requestID := c.Param("request_id")
+	ctx := c.Request.Context()
+
+	var req struct {
+		Accept bool `json:"accept"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request body"})
+		return
+	}
+
+	var eventID, fromUserID, toUserID, status string
+	var expiresAt time.Time
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT event_id, from_user_id, to_user_id, status, expires_at
+		FROM event_transfer_requests WHERE id = $1
+	`, requestID).Scan(&eventID, &fromUserID, &toUserID, &status, &expiresAt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "transfer request not found"})
+		return
+	}
+	if toUserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "you are not the recipient of this transfer request"})
+		return
+	}
+	if status != "pending" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "this request has already been " + status})
+		return
+	}
+	if time.Now().After(expiresAt) {
+		h.db.Pool().Exec(ctx, `UPDATE event_transfer_requests SET status = 'expired' WHERE id = $1`, requestID)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "this request has expired"})
+		return
+	}
+
+	if !req.Accept {
+		_, err = h.db.Pool().Exec(ctx, `
+			UPDATE event_transfer_requests SET status = 'rejected', responded_at = NOW() WHERE id = $1
+		`, requestID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reject request"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "event transfer rejected"})
+		return
+	}
+
+	// Whoever asked must still be entitled to hand the event over
+	role, err := h.eventManager(ctx, eventID, fromUserID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if role != managerOrganizer && role != managerOrgAdmin {
+		h.db.Pool().Exec(ctx, `UPDATE event_transfer_requests SET status = 'expired' WHERE id = $1`, requestID)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "this request is no longer valid"})
+		return
+	}
+
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var organizerID, title string
+	err = tx.QueryRow(ctx, `SELECT organizer_id, title FROM events WHERE id = $1 FOR UPDATE`, eventID).
+		Scan(&organizerID, &title)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	// Claimed only now the event is locked: another transfer of it
+	// accepted meanwhile has expired this one
+	claimed, err := tx.Exec(ctx, `
+		UPDATE event_transfer_requests SET status = 'accepted', responded_at = NOW()
+		WHERE id = $1 AND status = 'pending' AND expires_at > NOW()
+	`, requestID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update request"})
+		return
+	}
+	if claimed.RowsAffected() == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "this request is no longer pending"})
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE events SET organizer_id = $2, updated_at = NOW() WHERE id = $1
+	`, eventID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to transfer event"})
+		return
+	}
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM event_co_organizers WHERE event_id = $1 AND user_id = $2
+	`, eventID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to transfer event"})
+		return
+	}
+	if err := grantEventChannels(ctx, tx, eventID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update event channels"})
+		return
+	}
+	if fromUserID == organizerID {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO event_co_organizers (event_id, user_id, invited_by, status, accepted_at)
+			VALUES ($1, $2, $3, 'accepted', NOW())
+			ON CONFLICT (event_id, user_id) DO UPDATE SET status = 'accepted', accepted_at = NOW()
+		`, eventID, organizerID, userID)
+	} else {
+		err = revokeEventChannels(ctx, tx, eventID, organizerID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to transfer event"})
+		return
+	}
+
+	// Other requests for the event were made on the old organizer's watch
+	if _, err := tx.Exec(ctx, `
+		UPDATE event_transfer_requests SET status = 'expired' WHERE event_id = $1 AND status = 'pending'
+	`, eventID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update request"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit"})
+		return
+	}
+
+	go h.notifyUsers(eventID, title, map[string]string{fromUserID: "Your event transfer was accepted"})
+
+	c.JSON(http.StatusOK, gin.H{"message": "event transfer accepted, you are now the organizer"})
+}
+
+// ============================================================================
+// ORGANIZATION EVENTS
+// ============================================================================
+
+// ListOrgEvents returns an organization's upcoming events for its page,
+// series expanded as in ListEvents. Like the organization itself, they
+// are listed to everyone if it is public and to members otherwise.
+func (h *Handler) ListOrgEvents(c *gin.Context) {
+	userID := c.GetString("user_id")
+	orgID := c.Param("id")
+	ctx := c.Request.Context()
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	from, to, bounded, err := occurrenceWindow(c, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var visible bool
+	err = h.db.Pool().QueryRow(ctx, `
+		SELECT o.is_public OR EXISTS(SELECT 1 FROM organization_members WHERE org_id = o.id AND user_id = $2)
+		FROM organizations o WHERE o.id::text = $1
+	`, orgID, userID).Scan(&visible)
+	if err != nil || !visible {
+		c.JSON(http.StatusNotFound, gin.H{"error": "organization not found"})
+		return
+	}
+
+	query := `
+		SELECT e.id, e.organizer_id, e.title, e.description, e.event_type,
+			   ST_Y(e.location::geometry) as lat, ST_X(e.location::geometry) as lon,
+			   e.location_name, e.location_area, e.location_visibility, e.location_reveal_at,
+			   e.starts_at, e.ends_at, e.is_cancelled, e.channel_id,
+			   e.recurrence_rule, e.timezone,
+			   (SELECT COUNT(*) FROM event_rsvps WHERE event_id = e.id AND status = 'going' AND approval = 'approved') as rsvp_count,
+			   EXISTS(SELECT 1 FROM event_rsvps WHERE event_id = e.id AND user_id = $1 AND approval = 'approved') as has_rsvp,
+			   EXISTS(SELECT 1 FROM channel_members WHERE channel_id = e.channel_id AND user_id = $1) as is_channel_member,
+			   e.accessibility, e.risk_level,
+			   ` + managesEventSQL + ` as is_manager
+		FROM events e
+		WHERE e.org_id::text = $4
+		  AND (e.starts_at > $2
+		       OR (e.recurrence_rule IS NOT NULL AND e.starts_at < $3
+		           AND (e.recurrence_ends_at IS NULL OR e.recurrence_ends_at > $2)))
+		  AND e.is_cancelled = false
+	`
+	if bounded {
+		query += " AND (e.recurrence_rule IS NOT NULL OR e.starts_at < $3)"
+	}
+	query += " ORDER BY (e.recurrence_rule IS NOT NULL) DESC, e.starts_at ASC LIMIT $5"
+
+	rows, err := h.db.Pool().Query(ctx, query, userID, from, to, orgID, offset+limit+maxListedSeries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch events"})
+		return
+	}
+	listings, err := h.listEventRows(ctx, rows, userID, from, to, offset+limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch events"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"events": sortListings(listings, offset, limit),
+		"limit":  limit,
+		"offset": offset,
+	})
+}
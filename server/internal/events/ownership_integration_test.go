@@ -0,0 +1,221 @@
+//go:build integration
+
+package events
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// org creates an organization with members in the given roles, and
+// returns its ID.
+func (s *testServer) org(createdBy string, roles map[string]string) string {
+	s.t.Helper()
+	var id string
+	require.NoError(s.t, s.pool.QueryRow(context.Background(), `
+		INSERT INTO organizations (name, created_by) VALUES ('Org', $1) RETURNING id
+	`, createdBy).Scan(&id))
+	for userID, role := range roles {
+		s.exec(`INSERT INTO organization_members (org_id, user_id, role) VALUES ($1, $2, $3)`, id, userID, role)
+	}
+	return id
+}
+
+// coOrganizerStatus returns userID's co-organizer status on eventID,
+// or "" if they are neither invited nor a co-organizer.
+func (s *testServer) coOrganizerStatus(eventID, userID string) string {
+	s.t.Helper()
+	var status string
+	require.NoError(s.t, s.pool.QueryRow(context.Background(), `
+		SELECT COALESCE((SELECT status FROM event_co_organizers WHERE event_id = $1 AND user_id = $2), '')
+	`, eventID, userID).Scan(&status))
+	return status
+}
+
+func (s *testServer) organizerOf(eventID string) string {
+	s.t.Helper()
+	var id string
+	require.NoError(s.t, s.pool.QueryRow(context.Background(), `
+		SELECT organizer_id FROM events WHERE id = $1
+	`, eventID).Scan(&id))
+	return id
+}
+
+func TestCoOrganizers_InviteAndAccept(t *testing.T) {
+	s := newTestServer(t)
+	organizer := s.user("Organizer")
+	eventID := s.event(organizer)
+	helper, outsider := s.user("Helper"), s.user("Outsider")
+	coOrganizers := "/events/" + eventID + "/co-organizers"
+
+	code, _ := s.call(http.MethodPost, coOrganizers, outsider, gin.H{"user_id": helper})
+	assert.Equal(t, http.StatusForbidden, code)
+	code, _ = s.call(http.MethodPost, coOrganizers, organizer, gin.H{"user_id": "00000000-0000-0000-0000-000000000000"})
+	assert.Equal(t, http.StatusNotFound, code, "unknown user")
+
+	code, resp := s.call(http.MethodPost, coOrganizers, organizer, gin.H{"user_id": helper})
+	require.Equal(t, http.StatusCreated, code, resp)
+	assert.Equal(t, "pending", s.coOrganizerStatus(eventID, helper))
+
+	code, _ = s.call(http.MethodGet, coOrganizers, helper, nil)
+	assert.Equal(t, http.StatusForbidden, code, "a pending invitee doesn't run the event yet")
+	code, _ = s.call(http.MethodPost, coOrganizers+"/respond", outsider, gin.H{"accept": true})
+	assert.Equal(t, http.StatusNotFound, code, "only the invitee can accept")
+
+	code, resp = s.call(http.MethodPost, coOrganizers+"/respond", helper, gin.H{"accept": true})
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, "accepted", s.coOrganizerStatus(eventID, helper))
+	code, _ = s.call(http.MethodPost, coOrganizers+"/respond", helper, gin.H{"accept": true})
+	assert.Equal(t, http.StatusBadRequest, code, "accepting twice")
+
+	code, resp = s.call(http.MethodGet, coOrganizers, helper, nil)
+	require.Equal(t, http.StatusOK, code, resp)
+	listed := resp["co_organizers"].([]any)
+	require.Len(t, listed, 1)
+	assert.Equal(t, helper, listed[0].(map[string]any)["user_id"])
+	assert.Equal(t, "accepted", listed[0].(map[string]any)["status"])
+
+	code, _ = s.call(http.MethodPost, coOrganizers, helper, gin.H{"user_id": outsider})
+	assert.Equal(t, http.StatusForbidden, code, "co-organizers don't invite others")
+	code, _ = s.call(http.MethodDelete, coOrganizers+"/"+organizer, helper, nil)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, resp = s.call(http.MethodDelete, coOrganizers+"/"+helper, helper, nil)
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, "", s.coOrganizerStatus(eventID, helper), "co-organizers can step down")
+}
+
+func TestCoOrganizers_DeclineDropsInvitation(t *testing.T) {
+	s := newTestServer(t)
+	organizer := s.user("Organizer")
+	eventID := s.event(organizer)
+	helper := s.user("Helper")
+
+	code, resp := s.call(http.MethodPost, "/events/"+eventID+"/co-organizers", organizer, gin.H{"user_id": helper})
+	require.Equal(t, http.StatusCreated, code, resp)
+	code, resp = s.call(http.MethodPost, "/events/"+eventID+"/co-organizers/respond", helper, gin.H{"accept": false})
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, "", s.coOrganizerStatus(eventID, helper))
+}
+
+func TestTransfer_OrganizerHandsOver(t *testing.T) {
+	s := newTestServer(t)
+	organizer := s.user("Organizer")
+	eventID := s.event(organizer)
+	helper, outsider := s.user("Helper"), s.user("Outsider")
+	s.exec(`INSERT INTO event_co_organizers (event_id, user_id, status) VALUES ($1, $2, 'accepted')`, eventID, helper)
+	transfer := "/events/" + eventID + "/transfer"
+
+	code, _ := s.call(http.MethodPost, transfer, helper, gin.H{"to_user_id": helper})
+	assert.Equal(t, http.StatusForbidden, code, "co-organizers can't take the event")
+	code, _ = s.call(http.MethodPost, transfer, organizer, gin.H{"to_user_id": outsider})
+	assert.Equal(t, http.StatusBadRequest, code, "only co-organizers and org members can take over")
+
+	code, resp := s.call(http.MethodPost, transfer, organizer, gin.H{"to_user_id": helper})
+	require.Equal(t, http.StatusCreated, code, resp)
+	requestID := resp["id"].(string)
+
+	code, resp = s.call(http.MethodGet, "/event-transfers", helper, nil)
+	require.Equal(t, http.StatusOK, code, resp)
+	require.Len(t, resp["transfers"], 1)
+
+	respond := "/event-transfers/" + requestID + "/respond"
+	code, _ = s.call(http.MethodPost, respond, outsider, gin.H{"accept": true})
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Equal(t, organizer, s.organizerOf(eventID))
+
+	code, resp = s.call(http.MethodPost, respond, helper, gin.H{"accept": true})
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, helper, s.organizerOf(eventID))
+	assert.Equal(t, "", s.coOrganizerStatus(eventID, helper), "the new organizer isn't also a co-organizer")
+	assert.Equal(t, "accepted", s.coOrganizerStatus(eventID, organizer), "an organizer who hands over stays on")
+
+	code, _ = s.call(http.MethodPost, respond, helper, gin.H{"accept": true})
+	assert.Equal(t, http.StatusBadRequest, code, "accepting twice")
+}
+
+func TestTransfer_OrgAdminsRunOrgEvents(t *testing.T) {
+	s := newTestServer(t)
+	organizer, admin, moderator, member := s.user("Organizer"), s.user("Admin"), s.user("Moderator"), s.user("Member")
+	orgID := s.org(admin, map[string]string{admin: "admin", moderator: "moderator", member: "member"})
+	eventID := s.event(organizer)
+	s.exec(`UPDATE events SET org_id = $1 WHERE id = $2`, orgID, eventID)
+	helper := s.user("Helper")
+
+	code, _ := s.call(http.MethodPost, "/events/"+eventID+"/co-organizers", member, gin.H{"user_id": helper})
+	assert.Equal(t, http.StatusForbidden, code, "plain members don't run org events")
+	code, resp := s.call(http.MethodPost, "/events/"+eventID+"/co-organizers", moderator, gin.H{"user_id": helper})
+	require.Equal(t, http.StatusCreated, code, resp)
+	code, _ = s.call(http.MethodGet, "/events/"+eventID+"/co-organizers", admin, nil)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = s.call(http.MethodPost, "/events/"+eventID+"/transfer", moderator, gin.H{"to_user_id": member})
+	assert.Equal(t, http.StatusForbidden, code, "only org admins can transfer")
+	code, resp = s.call(http.MethodPost, "/events/"+eventID+"/transfer", admin, gin.H{"to_user_id": member})
+	require.Equal(t, http.StatusCreated, code, resp)
+
+	code, resp = s.call(http.MethodPost, "/event-transfers/"+resp["id"].(string)+"/respond", member, gin.H{"accept": true})
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, member, s.organizerOf(eventID))
+	assert.Equal(t, "", s.coOrganizerStatus(eventID, organizer), "an organizer replaced by the org doesn't stay on")
+}
+
+func TestListOrgEvents_RevealsLocationToManagers(t *testing.T) {
+	s := newTestServer(t)
+	organizer, admin, member := s.user("Organizer"), s.user("Admin"), s.user("Member")
+	orgID := s.org(admin, map[string]string{admin: "admin", member: "member"})
+	eventID := s.event(organizer)
+	s.exec(`UPDATE events SET org_id = $1, location_visibility = 'rsvp', location_area = 'Near the park' WHERE id = $2`,
+		orgID, eventID)
+
+	revealed := func(userID string) any {
+		t.Helper()
+		code, resp := s.call(http.MethodGet, "/organizations/"+orgID+"/events", userID, nil)
+		require.Equal(t, http.StatusOK, code, resp)
+		events := resp["events"].([]any)
+		require.Len(t, events, 1)
+		return events[0].(map[string]any)["location_revealed"]
+	}
+	assert.Equal(t, false, revealed(member))
+	assert.Equal(t, true, revealed(admin), "org admins see the location as the organizer does")
+}
+
+func TestTransfer_ConcurrentAcceptsHandOverOnce(t *testing.T) {
+	s := newTestServer(t)
+	organizer := s.user("Organizer")
+	eventID := s.event(organizer)
+	recipients := []string{s.user("First"), s.user("Second")}
+	requestIDs := make([]string, len(recipients))
+	for i, u := range recipients {
+		s.exec(`INSERT INTO event_co_organizers (event_id, user_id, status) VALUES ($1, $2, 'accepted')`, eventID, u)
+		code, resp := s.call(http.MethodPost, "/events/"+eventID+"/transfer", organizer, gin.H{"to_user_id": u})
+		require.Equal(t, http.StatusCreated, code, resp)
+		requestIDs[i] = resp["id"].(string)
+	}
+
+	codes := make([]int, len(recipients))
+	var wg sync.WaitGroup
+	for i, u := range recipients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i], _ = s.call(http.MethodPost, "/event-transfers/"+requestIDs[i]+"/respond", u, gin.H{"accept": true})
+		}()
+	}
+	wg.Wait()
+
+	var accepted []string
+	for i, code := range codes {
+		if code == http.StatusOK {
+			accepted = append(accepted, recipients[i])
+		}
+	}
+	require.Len(t, accepted, 1, "codes %v", codes)
+	assert.Equal(t, accepted[0], s.organizerOf(eventID))
+}
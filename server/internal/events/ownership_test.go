@@ -0,0 +1,44 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/kuurier/server/internal/counts"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManagerRole(t *testing.T) {
+	assert.Equal(t, managerOrganizer, managerRole("u1", "u1", false, ""))
+	assert.Equal(t, managerOrganizer, managerRole("u1", "u1", false, "member"), "organizer of an org event")
+	assert.Equal(t, managerOrgAdmin, managerRole("u1", "u2", false, "admin"))
+	assert.Equal(t, managerOrgModerator, managerRole("u1", "u2", false, "moderator"))
+	assert.Equal(t, managerOrgAdmin, managerRole("u1", "u2", true, "admin"), "org role wins over co-organizer")
+	assert.Equal(t, managerCoOrganizer, managerRole("u1", "u2", true, "member"))
+	assert.Equal(t, "", managerRole("u1", "u2", false, "member"), "plain org members don't run events")
+	assert.Equal(t, "", managerRole("u1", "u2", false, ""))
+}
+
+func TestOwnsEvent(t *testing.T) {
+	assert.True(t, ownsEvent(managerOrganizer))
+	assert.True(t, ownsEvent(managerOrgAdmin))
+	assert.True(t, ownsEvent(managerOrgModerator))
+	assert.False(t, ownsEvent(managerCoOrganizer))
+	assert.False(t, ownsEvent(""))
+}
+
+func TestListingRevealsLocationToManagers(t *testing.T) {
+	area := "Near the park"
+	r := listedEvent{id: "e1", organizerID: "u1", locationVisibility: "rsvp", locationArea: &area, lat: 52.5, lon: 13.4}
+
+	assert.Equal(t, false, r.listing("u2", counts.Policy{})["location_revealed"])
+
+	r.isManager = true
+	event := r.listing("u2", counts.Policy{})
+	assert.Equal(t, true, event["location_revealed"], "co-organizers and org admins see it as the organizer does")
+	assert.NotNil(t, event["location"])
+
+	row := calendarRow{id: "e1", organizerID: "u1", locationVisibility: "approval", locationArea: &area, lat: 52.5, lon: 13.4}
+	assert.Nil(t, calendarEntry(row, "u2").latitude)
+	row.isManager = true
+	assert.NotNil(t, calendarEntry(row, "u2").latitude)
+}
@@ -0,0 +1,472 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kuurier/server/internal/counts"
+	"github.com/kuurier/server/internal/recurrence"
+)
+
+const (
+	// recurrenceHorizon is how far ahead listings expand a series when
+	// the request doesn't give an end to its window.
+	recurrenceHorizon = 90 * 24 * time.Hour
+
+	// maxRecurrenceWindow caps a requested window.
+	maxRecurrenceWindow = 366 * 24 * time.Hour
+
+	// maxListedSeries caps how many series one listing expands.
+	maxListedSeries = 200
+)
+
+// recurrenceSpec is a validated recurrence, ready to store.
+type recurrenceSpec struct {
+	rule       *string    // canonical RRULE; nil for one-off events
+	endsAt     *time.Time // start of the last occurrence, for rules that end
+	timezone   string
+	exceptions []time.Time // occurrences cancelled up front
+}
+
+// parseRecurrence validates a rule against the event's first start.
+// starts_at must itself be an occurrence, so the series starts where
+// the organizer says it does.
+func parseRecurrence(rrule, timezone string, startsAt int64, exceptions []int64) (recurrenceSpec, error) {
+	if timezone == "" {
+		timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return recurrenceSpec{}, errors.New("unknown timezone")
+	}
+	spec := recurrenceSpec{timezone: loc.String()}
+	if rrule == "" {
+		if len(exceptions) > 0 {
+			return recurrenceSpec{}, errors.New("recurrence_exceptions need a recurrence rule")
+		}
+		return spec, nil
+	}
+
+	rule, err := recurrence.Parse(rrule)
+	if err != nil {
+		return recurrenceSpec{}, fmt.Errorf("invalid recurrence: %v", err)
+	}
+	start := time.Unix(startsAt, 0).In(loc)
+	if !rule.Includes(start, start) {
+		return recurrenceSpec{}, errors.New("starts_at must be an occurrence of the recurrence rule")
+	}
+	canonical := rule.String()
+	spec.rule = &canonical
+	if last, ok := rule.Last(start); ok {
+		spec.endsAt = &last
+	}
+	for _, ex := range exceptions {
+		t := time.Unix(ex, 0).In(loc)
+		if !rule.Includes(start, t) {
+			return recurrenceSpec{}, fmt.Errorf("recurrence exception %s is not an occurrence", t.UTC().Format(time.RFC3339))
+		}
+		spec.exceptions = append(spec.exceptions, t)
+	}
+	return spec, nil
+}
+
+// series is a stored recurring event, ready to expand.
+type series struct {
+	rule     recurrence.Rule
+	start    time.Time // first occurrence, in the event's timezone
+	duration *time.Duration
+}
+
+// newSeries returns nil for one-off events.
+func newSeries(rrule *string, timezone string, startsAt time.Time, endsAt *time.Time) *series {
+	if rrule == nil || *rrule == "" {
+		return nil
+	}
+	rule, err := recurrence.Parse(*rrule)
+	if err != nil {
+		return nil // rules are validated on write
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	s := &series{rule: rule, start: startsAt.In(loc)}
+	if endsAt != nil {
+		d := endsAt.Sub(startsAt)
+		s.duration = &d
+	}
+	return s
+}
+
+// occurrenceOverride is a row of event_occurrences. Nil fields fall
+// back to the series.
+type occurrenceOverride struct {
+	cancelled                                      bool
+	title, description, locationName, locationArea *string
+	startsAt, endsAt                               *time.Time
+}
+
+// occurrence is one instance of a series.
+type occurrence struct {
+	at       time.Time // the start the rule generates; identifies the occurrence
+	startsAt time.Time
+	endsAt   *time.Time
+	override occurrenceOverride
+}
+
+// occurrence builds the instance the rule generates at at, with its
+// override applied. ok is false if the rule doesn't generate at.
+func (s *series) occurrence(at time.Time, overrides map[int64]occurrenceOverride) (occurrence, bool) {
+	if !s.rule.Includes(s.start, at.In(s.start.Location())) {
+		return occurrence{}, false
+	}
+	return s.instance(at, overrides[at.Unix()]), true
+}
+
+func (s *series) instance(at time.Time, ov occurrenceOverride) occurrence {
+	o := occurrence{at: at, startsAt: at, override: ov}
+	if s.duration != nil {
+		end := at.Add(*s.duration)
+		o.endsAt = &end
+	}
+	if ov.startsAt != nil {
+		o.startsAt = *ov.startsAt
+		if s.duration != nil && ov.endsAt == nil {
+			end := o.startsAt.Add(*s.duration)
+			o.endsAt = &end
+		}
+	}
+	if ov.endsAt != nil {
+		o.endsAt = ov.endsAt
+	}
+	return o
+}
+
+// occurrences returns up to max uncancelled occurrences the rule
+// generates in [from, to).
+func (s *series) occurrences(from, to time.Time, max int, overrides map[int64]occurrenceOverride) []occurrence {
+	var out []occurrence
+	s.rule.Iterate(s.start, func(t time.Time) bool {
+		if !t.Before(to) || len(out) >= max {
+			return false
+		}
+		if t.Before(from) {
+			return true
+		}
+		ov := overrides[t.Unix()]
+		if !ov.cancelled {
+			out = append(out, s.instance(t, ov))
+		}
+		return true
+	})
+	return out
+}
+
+// revealAt shifts a timed event's reveal so each occurrence reveals
+// the same time before it starts as the first one does.
+func (s *series) revealAt(o occurrence, seriesReveal *time.Time) *time.Time {
+	if seriesReveal == nil {
+		return nil
+	}
+	t := o.startsAt.Add(seriesReveal.Sub(s.start))
+	return &t
+}
+
+// occurrenceWindow reads ?from= and ?to= (Unix seconds). from
+// defaults to defaultFrom and to to recurrenceHorizon after from;
+// explicit reports whether the client bounded the window.
+func occurrenceWindow(c *gin.Context, defaultFrom time.Time) (from, to time.Time, explicit bool, err error) {
+	from = defaultFrom
+	if s := c.Query("from"); s != "" {
+		secs, perr := strconv.ParseInt(s, 10, 64)
+		if perr != nil {
+			return from, to, false, errors.New("from must be a Unix timestamp")
+		}
+		from = time.Unix(secs, 0)
+	}
+	to = from.Add(recurrenceHorizon)
+	if s := c.Query("to"); s != "" {
+		secs, perr := strconv.ParseInt(s, 10, 64)
+		if perr != nil {
+			return from, to, false, errors.New("to must be a Unix timestamp")
+		}
+		to, explicit = time.Unix(secs, 0), true
+		if !to.After(from) {
+			return from, to, false, errors.New("to must be after from")
+		}
+	}
+	if to.Sub(from) > maxRecurrenceWindow {
+		to = from.Add(maxRecurrenceWindow)
+	}
+	return from, to, explicit, nil
+}
+
+// parseOccurrence reads an occurrence reference (Unix seconds of the
+// start the rule generates). Empty means none.
+func parseOccurrence(s string) (*time.Time, error) {
+	if s == "" {
+		return nil, nil
+	}
+	secs, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, errors.New("occurrence must be a Unix timestamp")
+	}
+	t := time.Unix(secs, 0)
+	return &t, nil
+}
+
+// occurrenceOverrides loads the exceptions of the given series, keyed
+// by event ID and then occurrence start (Unix seconds).
+func (h *Handler) occurrenceOverrides(ctx context.Context, eventIDs []string) (map[string]map[int64]occurrenceOverride, error) {
+	out := make(map[string]map[int64]occurrenceOverride)
+	if len(eventIDs) == 0 {
+		return out, nil
+	}
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT event_id, occurrence_at, is_cancelled, title, description,
+		       location_name, location_area, starts_at, ends_at
+		FROM event_occurrences
+		WHERE event_id::text = ANY($1)
+	`, eventIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventID string
+		var at time.Time
+		var ov occurrenceOverride
+		if err := rows.Scan(&eventID, &at, &ov.cancelled, &ov.title, &ov.description,
+			&ov.locationName, &ov.locationArea, &ov.startsAt, &ov.endsAt); err != nil {
+			return nil, err
+		}
+		if out[eventID] == nil {
+			out[eventID] = make(map[int64]occurrenceOverride)
+		}
+		out[eventID][at.Unix()] = ov
+	}
+	return out, rows.Err()
+}
+
+// occurrenceRSVP is the RSVP state of one occurrence.
+type occurrenceRSVP struct {
+	going  int
+	status *string // the viewer's RSVP
+}
+
+// occurrenceRSVPs loads per-occurrence going counts and the viewer's
+// RSVPs for the given series.
+func (h *Handler) occurrenceRSVPs(ctx context.Context, eventIDs []string, userID string) (map[string]map[int64]occurrenceRSVP, error) {
+	out := make(map[string]map[int64]occurrenceRSVP)
+	if len(eventIDs) == 0 {
+		return out, nil
+	}
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT event_id, occurrence_at,
+		       COUNT(*) FILTER (WHERE status = 'going' AND approval = 'approved'),
+		       MAX(status) FILTER (WHERE user_id::text = $2)
+		FROM event_rsvps
+		WHERE event_id::text = ANY($1) AND occurrence_at IS NOT NULL
+		GROUP BY event_id, occurrence_at
+	`, eventIDs, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventID string
+		var at time.Time
+		var r occurrenceRSVP
+		if err := rows.Scan(&eventID, &at, &r.going, &r.status); err != nil {
+			return nil, err
+		}
+		if out[eventID] == nil {
+			out[eventID] = make(map[int64]occurrenceRSVP)
+		}
+		out[eventID][at.Unix()] = r
+	}
+	return out, rows.Err()
+}
+
+// eventListing is one entry of a listing: a one-off event or one
+// occurrence of a series.
+type eventListing struct {
+	startsAt time.Time
+	event    gin.H
+}
+
+// sortListings orders listings by start and applies offset and limit.
+func sortListings(listings []eventListing, offset, limit int) []gin.H {
+	sort.SliceStable(listings, func(i, j int) bool {
+		return listings[i].startsAt.Before(listings[j].startsAt)
+	})
+	events := []gin.H{}
+	for i := offset; i < len(listings) && len(events) < limit; i++ {
+		events = append(events, listings[i].event)
+	}
+	return events
+}
+
+// occurrenceListing copies a public event's listing for one
+// occurrence, its RSVP count blurred by p. The location is the series'
+// unless overridden.
+func occurrenceListing(base gin.H, s *series, o occurrence, rsvp occurrenceRSVP, p counts.Policy) gin.H {
+	event := maps.Clone(base)
+	setOccurrenceFields(event, o)
+	event["recurrence"] = s.rule.String()
+	p.Set(event, "rsvp_count", rsvp.going, false)
+	if o.override.locationName != nil {
+		event["location_name"] = *o.override.locationName
+	}
+	return event
+}
+
+// setOccurrenceFields adds what identifies and times an occurrence.
+func setOccurrenceFields(event gin.H, o occurrence) {
+	event["starts_at"] = o.startsAt
+	if o.endsAt != nil {
+		event["ends_at"] = *o.endsAt
+	}
+	event["occurrence_at"] = o.at
+	if o.override.title != nil {
+		event["title"] = *o.override.title
+	}
+	if o.override.description != nil {
+		event["description"] = *o.override.description
+	}
+}
+
+// maxUpcomingOccurrences is how many occurrences GetEvent lists for a
+// series.
+const maxUpcomingOccurrences = 10
+
+// upcomingOccurrences lists a series' next occurrences with their RSVP
+// counts, blurred unless exact, and the viewer's RSVPs.
+func (h *Handler) upcomingOccurrences(ctx context.Context, eventID, userID string, s *series, overrides map[int64]occurrenceOverride, exact bool) ([]gin.H, error) {
+	rsvps, err := h.occurrenceRSVPs(ctx, []string{eventID}, userID)
+	if err != nil {
+		return nil, err
+	}
+	policy := counts.FromConfig(h.cfg)
+	now := time.Now()
+	upcoming := []gin.H{}
+	for _, o := range s.occurrences(now.Add(-24*time.Hour), now.Add(maxRecurrenceWindow), maxUpcomingOccurrences, overrides) {
+		entry := gin.H{}
+		setOccurrenceFields(entry, o)
+		r := rsvps[eventID][o.at.Unix()]
+		policy.Set(entry, "rsvp_count", r.going, exact)
+		if r.status != nil {
+			entry["user_rsvp"] = *r.status
+		}
+		upcoming = append(upcoming, entry)
+	}
+	return upcoming, nil
+}
+
+// rsvpOccurrence checks the occurrence an RSVP refers to: required for
+// a series, absent for a one-off event. New RSVPs also need it not to
+// be cancelled. It writes the error response and returns false if the
+// reference is wrong.
+func (h *Handler) rsvpOccurrence(c *gin.Context, eventID string, s *series, occ *time.Time, newRSVP bool) (*time.Time, bool) {
+	if s == nil {
+		if occ != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "event is not recurring"})
+			return nil, false
+		}
+		return nil, true
+	}
+	if occ == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "occurrence is required for recurring events"})
+		return nil, false
+	}
+
+	all, err := h.occurrenceOverrides(c.Request.Context(), []string{eventID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check occurrence"})
+		return nil, false
+	}
+	o, ok := s.occurrence(*occ, all[eventID])
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "occurrence not found"})
+		return nil, false
+	}
+	if newRSVP && o.override.cancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "occurrence is cancelled"})
+		return nil, false
+	}
+	return &o.at, true
+}
+
+const (
+	// maxNearbyEvents and maxMapEvents cap the public listings.
+	maxNearbyEvents = 50
+	maxMapEvents    = 100
+)
+
+// publicWindowSQL matches uncancelled public events over e that start
+// after from, or series that may have an occurrence in [from, to).
+func publicWindowSQL(from, to string) string {
+	return `(e.starts_at > ` + from + `
+		       OR (e.recurrence_rule IS NOT NULL AND e.starts_at < ` + to + `
+		           AND (e.recurrence_ends_at IS NULL OR e.recurrence_ends_at > ` + from + `)))
+		  AND e.is_cancelled = false
+		  AND e.location_visibility = 'public'`
+}
+
+// publicEvent is a row of a public listing, with the listing built.
+type publicEvent struct {
+	id, title, eventType string
+	lat, lon             float64
+	locationName         *string
+	startsAt             time.Time
+	endsAt               *time.Time
+	recurrenceRule       *string
+	timezone             string
+	rsvpCount            int
+	event                gin.H
+}
+
+// expandPublicEvents replaces each series in found by its occurrences
+// in [from, to), and returns the first max listings by start. One-off
+// events are only held to to if the client bounded the window.
+func (h *Handler) expandPublicEvents(ctx context.Context, found []publicEvent, from, to time.Time, bounded bool, max int) ([]gin.H, error) {
+	var seriesIDs []string
+	for _, e := range found {
+		if e.recurrenceRule != nil {
+			seriesIDs = append(seriesIDs, e.id)
+		}
+	}
+	overrides, err := h.occurrenceOverrides(ctx, seriesIDs)
+	if err != nil {
+		return nil, err
+	}
+	rsvps, err := h.occurrenceRSVPs(ctx, seriesIDs, "")
+	if err != nil {
+		return nil, err
+	}
+
+	policy := counts.FromConfig(h.cfg)
+	var listings []eventListing
+	for _, e := range found {
+		s := newSeries(e.recurrenceRule, e.timezone, e.startsAt, e.endsAt)
+		if s == nil {
+			if !bounded || e.startsAt.Before(to) {
+				listings = append(listings, eventListing{e.startsAt, e.event})
+			}
+			continue
+		}
+		for _, o := range s.occurrences(from, to, max, overrides[e.id]) {
+			listings = append(listings, eventListing{o.startsAt, occurrenceListing(e.event, s, o, rsvps[e.id][o.at.Unix()], policy)})
+		}
+	}
+	return sortListings(listings, 0, max), nil
+}
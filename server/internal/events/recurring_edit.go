@@ -0,0 +1,413 @@
+package events
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/kuurier/server/internal/recurrence"
+)
+
+// updateSeries applies an UpdateEventRequest that edits one occurrence,
+// splits a series, or changes the recurrence itself. The caller has
+// checked that the user organizes the event.
+func (h *Handler) updateSeries(c *gin.Context, eventID string, req UpdateEventRequest) {
+	ctx := c.Request.Context()
+
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var startsAt time.Time
+	var endsAt, revealAt *time.Time
+	var rule *string
+	var timezone string
+	err = tx.QueryRow(ctx, `
+		SELECT starts_at, ends_at, location_reveal_at, recurrence_rule, timezone
+		FROM events WHERE id = $1
+		FOR UPDATE
+	`, eventID).Scan(&startsAt, &endsAt, &revealAt, &rule, &timezone)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	s := newSeries(rule, timezone, startsAt, endsAt)
+
+	// What attendees knew, to tell them what changed
+	before, err := h.eventState(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	overrides, err := h.occurrenceOverrides(ctx, []string{eventID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = "all"
+		if req.Occurrence != nil {
+			scope = "occurrence"
+		}
+	}
+
+	// at is where the edit takes effect: the occurrence, or the start
+	// of the event for edits to all of it.
+	at := startsAt
+	if scope != "all" && req.Occurrence != nil {
+		if s == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "event is not recurring"})
+			return
+		}
+		o, ok := s.occurrence(time.Unix(*req.Occurrence, 0), nil)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "occurrence not found"})
+			return
+		}
+		at = o.at
+	}
+
+	if scope == "occurrence" {
+		if req.Occurrence == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "occurrence is required"})
+			return
+		}
+		if req.LocationVisibility != nil || req.LocationRevealAt != nil || req.Language != nil ||
+			req.Recurrence != nil || req.Timezone != nil || req.Latitude != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "only the title, description, location name and area, times and is_cancelled can be changed for one occurrence",
+			})
+			return
+		}
+		if req.StartsAt != nil && req.EndsAt != nil && *req.EndsAt <= *req.StartsAt {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be after starts_at"})
+			return
+		}
+		if err := updateOccurrence(ctx, tx, eventID, at, req); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update occurrence"})
+			return
+		}
+		if err := tx.Commit(ctx); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+			return
+		}
+		if edited, err := h.occurrenceOverrides(ctx, []string{eventID}); err == nil {
+			go h.notifyChanges(eventID, &at,
+				before.forOccurrence(s.instance(at, overrides[eventID][at.Unix()])),
+				before.forOccurrence(s.instance(at, edited[eventID][at.Unix()])))
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "occurrence updated", "occurrence_at": at})
+		return
+	}
+
+	// The edited part of the series starts at newStart, keeping its
+	// length unless ends_at is given.
+	newStart := at
+	if req.StartsAt != nil {
+		newStart = time.Unix(*req.StartsAt, 0)
+	}
+	var newEnd *time.Time
+	switch {
+	case req.EndsAt != nil:
+		t := time.Unix(*req.EndsAt, 0)
+		newEnd = &t
+	case endsAt != nil:
+		t := newStart.Add(endsAt.Sub(startsAt))
+		newEnd = &t
+	}
+	if newEnd != nil && !newEnd.After(newStart) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ends_at must be after starts_at"})
+		return
+	}
+
+	newRule := ""
+	switch {
+	case req.Recurrence != nil:
+		newRule = *req.Recurrence
+	case s != nil:
+		r := s.rule
+		if r.Count > 0 && !at.Equal(startsAt) {
+			// The later part keeps what's left of COUNT.
+			r.Count -= len(s.rule.Between(s.start, s.start, at, r.Count))
+		}
+		newRule = r.String()
+	}
+	if req.Timezone != nil {
+		timezone = *req.Timezone
+	}
+	spec, err := parseRecurrence(newRule, timezone, newStart.Unix(), nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Timed locations keep revealing the same time before the start.
+	var newReveal *time.Time
+	if req.LocationRevealAt != nil {
+		t := time.Unix(*req.LocationRevealAt, 0)
+		newReveal = &t
+	} else if revealAt != nil {
+		t := newStart.Add(revealAt.Sub(startsAt))
+		newReveal = &t
+	}
+
+	response := gin.H{"message": "event updated"}
+	updatedID := eventID
+	if at.Equal(startsAt) {
+		err = reshapeEvent(ctx, tx, eventID, newStart.Sub(startsAt), newStart, newEnd, newReveal, spec, req)
+	} else {
+		truncated := s.rule
+		truncated.Count, truncated.Until = 0, at.Add(-time.Second)
+		updatedID, err = splitSeries(ctx, tx, eventID, at, truncated, s.start, newStart, newEnd, newReveal, spec, req)
+		response["message"] = "future occurrences updated"
+		response["event_id"] = updatedID
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update event"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+	if after, err := h.eventState(ctx, updatedID); err == nil {
+		go h.notifyChanges(updatedID, nil, before, after)
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// updateOccurrence records a per-occurrence edit. Fields left out of
+// req keep any earlier edit.
+func updateOccurrence(ctx context.Context, tx pgx.Tx, eventID string, at time.Time, req UpdateEventRequest) error {
+	var startsAt, endsAt *time.Time
+	if req.StartsAt != nil {
+		t := time.Unix(*req.StartsAt, 0)
+		startsAt = &t
+	}
+	if req.EndsAt != nil {
+		t := time.Unix(*req.EndsAt, 0)
+		endsAt = &t
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO event_occurrences (event_id, occurrence_at, is_cancelled, title, description,
+		                               location_name, location_area, starts_at, ends_at)
+		VALUES ($1, $2, COALESCE($3, false), $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (event_id, occurrence_at) DO UPDATE SET
+			is_cancelled = COALESCE($3, event_occurrences.is_cancelled),
+			title = COALESCE($4, event_occurrences.title),
+			description = COALESCE($5, event_occurrences.description),
+			location_name = COALESCE($6, event_occurrences.location_name),
+			location_area = COALESCE($7, event_occurrences.location_area),
+			starts_at = COALESCE($8, event_occurrences.starts_at),
+			ends_at = COALESCE($9, event_occurrences.ends_at),
+			updated_at = NOW()
+	`, eventID, at, req.IsCancelled, req.Title, req.Description,
+		req.LocationName, req.LocationArea, startsAt, endsAt); err != nil {
+		return err
+	}
+
+	// Calendar clients re-sync on LAST-MODIFIED.
+	_, err := tx.Exec(ctx, `UPDATE events SET updated_at = NOW() WHERE id = $1`, eventID)
+	return err
+}
+
+// applyEventFields writes the plain fields of req to eventID, as
+// UpdateEvent does for one-off events.
+func applyEventFields(ctx context.Context, tx pgx.Tx, eventID string, req UpdateEventRequest, revealAt *time.Time) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE events SET
+			title = COALESCE($2, title),
+			description = COALESCE($3, description),
+			location_name = COALESCE($4, location_name),
+			location_area = COALESCE($5, location_area),
+			location_visibility = COALESCE($6, location_visibility),
+			location_reveal_at = $7,
+			is_cancelled = COALESCE($8, is_cancelled),
+			language = COALESCE(LOWER($9), language),
+			location = COALESCE(ST_SetSRID(ST_MakePoint($11, $10), 4326)::geography, location),
+			approval_min_trust = CASE WHEN $12::int IS NULL THEN approval_min_trust ELSE NULLIF($12, 0) END,
+			approval_org_id = CASE WHEN $13::text IS NULL THEN approval_org_id ELSE NULLIF($13, '')::uuid END,
+			org_id = CASE WHEN $14::text IS NULL THEN org_id ELSE NULLIF($14, '')::uuid END,
+			accessibility = COALESCE($15, accessibility),
+			risk_level = CASE WHEN $16::text IS NULL THEN risk_level ELSE NULLIF($16, '') END,
+			legal_hotline = CASE WHEN $17::text IS NULL THEN legal_hotline ELSE NULLIF($17, '') END,
+			bust_card = CASE WHEN $18::text IS NULL THEN bust_card ELSE NULLIF($18, '') END,
+			updated_at = NOW()
+		WHERE id = $1
+	`, eventID, req.Title, req.Description, req.LocationName, req.LocationArea,
+		req.LocationVisibility, revealAt, req.IsCancelled, req.Language, req.Latitude, req.Longitude,
+		req.AutoApproveTrust, req.AutoApproveOrgID, req.OrgID,
+		req.Accessibility, req.RiskLevel, req.LegalHotline, req.BustCard)
+	return err
+}
+
+// reshapeEvent changes an event's times and recurrence in place.
+// Occurrence edits and RSVPs move with the start.
+func reshapeEvent(ctx context.Context, tx pgx.Tx, eventID string, delta time.Duration,
+	newStart time.Time, newEnd, revealAt *time.Time, spec recurrenceSpec, req UpdateEventRequest) error {
+	if _, err := tx.Exec(ctx, `
+		UPDATE events SET starts_at = $2, ends_at = $3,
+		       recurrence_rule = $4, recurrence_ends_at = $5, timezone = $6
+		WHERE id = $1
+	`, eventID, newStart, newEnd, spec.rule, spec.endsAt, spec.timezone); err != nil {
+		return err
+	}
+	if err := applyEventFields(ctx, tx, eventID, req, revealAt); err != nil {
+		return err
+	}
+	if delta != 0 {
+		// Delete and re-insert rather than UPDATE, which can trip the
+		// unique indexes mid-statement when shifting by a whole period.
+		if _, err := tx.Exec(ctx, `
+			WITH moved AS (DELETE FROM event_occurrences WHERE event_id = $1 RETURNING *)
+			INSERT INTO event_occurrences (event_id, occurrence_at, is_cancelled, title, description,
+			                               location_name, location_area, starts_at, ends_at, updated_at)
+			SELECT event_id, occurrence_at + make_interval(secs => $2), is_cancelled, title, description,
+			       location_name, location_area, starts_at, ends_at, updated_at
+			FROM moved
+		`, eventID, delta.Seconds()); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `
+			WITH moved AS (
+				DELETE FROM event_rsvps WHERE event_id = $1 AND occurrence_at IS NOT NULL RETURNING *
+			)
+			INSERT INTO event_rsvps (event_id, user_id, status, created_at, occurrence_at, approval, decided_at)
+			SELECT event_id, user_id, status, created_at, occurrence_at + make_interval(secs => $2), approval, decided_at
+			FROM moved
+		`, eventID, delta.Seconds()); err != nil {
+			return err
+		}
+	}
+	return pruneOccurrences(ctx, tx, eventID, newSeries(spec.rule, spec.timezone, newStart, newEnd), newStart)
+}
+
+// splitSeries ends a series just before at and continues it as a new
+// event from newStart with spec, carrying over topics, co-organizers,
+// the chat channel, and the edits and RSVPs of the moved occurrences.
+func splitSeries(ctx context.Context, tx pgx.Tx, eventID string, at time.Time, truncated recurrence.Rule,
+	seriesStart, newStart time.Time, newEnd, revealAt *time.Time, spec recurrenceSpec, req UpdateEventRequest) (string, error) {
+	last, _ := truncated.Last(seriesStart)
+	if _, err := tx.Exec(ctx, `
+		UPDATE events SET recurrence_rule = $2, recurrence_ends_at = $3, updated_at = NOW()
+		WHERE id = $1
+	`, eventID, truncated.String(), last); err != nil {
+		return "", err
+	}
+
+	newID := uuid.New().String()
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO events (id, organizer_id, title, description, event_type, location, location_name,
+		                    location_area, location_visibility, location_reveal_at, starts_at, ends_at,
+		                    is_cancelled, channel_id, language, recurrence_rule, recurrence_ends_at, timezone,
+		                    approval_min_trust, approval_org_id, org_id,
+		                    accessibility, risk_level, legal_hotline, bust_card)
+		SELECT $2, organizer_id, title, description, event_type, location, location_name,
+		       location_area, location_visibility, $3, $4, $5,
+		       is_cancelled, channel_id, language, $6, $7, $8,
+		       approval_min_trust, approval_org_id, org_id,
+		       accessibility, risk_level, legal_hotline, bust_card
+		FROM events WHERE id = $1
+	`, eventID, newID, revealAt, newStart, newEnd, spec.rule, spec.endsAt, spec.timezone); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO event_topics (event_id, topic_id)
+		SELECT $2, topic_id FROM event_topics WHERE event_id = $1
+	`, eventID, newID); err != nil {
+		return "", err
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO event_co_organizers (event_id, user_id, invited_by, status, created_at, accepted_at)
+		SELECT $2, user_id, invited_by, status, created_at, accepted_at FROM event_co_organizers WHERE event_id = $1
+	`, eventID, newID); err != nil {
+		return "", err
+	}
+
+	shift := newStart.Sub(at).Seconds()
+	for _, table := range []string{"event_occurrences", "event_rsvps"} {
+		if _, err := tx.Exec(ctx, `
+			UPDATE `+table+` SET event_id = $2, occurrence_at = occurrence_at + make_interval(secs => $4)
+			WHERE event_id = $1 AND occurrence_at >= $3
+		`, eventID, newID, at, shift); err != nil {
+			return "", err
+		}
+	}
+
+	if err := applyEventFields(ctx, tx, newID, req, revealAt); err != nil {
+		return "", err
+	}
+	return newID, pruneOccurrences(ctx, tx, newID, newSeries(spec.rule, spec.timezone, newStart, newEnd), newStart)
+}
+
+// pruneOccurrences drops edits and RSVPs for occurrences s no longer
+// generates. If the event stopped recurring (s is nil), RSVPs to its
+// first occurrence become RSVPs to the event; if it started, RSVPs to
+// the event become RSVPs to the first occurrence.
+func pruneOccurrences(ctx context.Context, tx pgx.Tx, eventID string, s *series, first time.Time) error {
+	if s == nil {
+		if _, err := tx.Exec(ctx, `DELETE FROM event_occurrences WHERE event_id = $1`, eventID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM event_rsvps WHERE event_id = $1 AND occurrence_at IS NOT NULL AND occurrence_at <> $2
+		`, eventID, first); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `UPDATE event_rsvps SET occurrence_at = NULL WHERE event_id = $1`, eventID)
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE event_rsvps SET occurrence_at = $2 WHERE event_id = $1 AND occurrence_at IS NULL
+	`, eventID, first); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT occurrence_at FROM event_occurrences WHERE event_id = $1
+		UNION
+		SELECT occurrence_at FROM event_rsvps WHERE event_id = $1 AND occurrence_at IS NOT NULL
+	`, eventID)
+	if err != nil {
+		return err
+	}
+	stale, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (time.Time, error) {
+		var t time.Time
+		err := row.Scan(&t)
+		return t, err
+	})
+	if err != nil {
+		return err
+	}
+	kept := stale[:0]
+	for _, t := range stale {
+		if !s.rule.Includes(s.start, t.In(s.start.Location())) {
+			kept = append(kept, t)
+		}
+	}
+	stale = kept
+	if len(stale) == 0 {
+		return nil
+	}
+
+	for _, table := range []string{"event_occurrences", "event_rsvps"} {
+		if _, err := tx.Exec(ctx, `
+			DELETE FROM `+table+` WHERE event_id = $1 AND occurrence_at = ANY($2)
+		`, eventID, stale); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package events
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	friday := time.Date(2026, 3, 20, 19, 0, 0, 0, berlin)
+
+	spec, err := parseRecurrence("FREQ=WEEKLY;BYDAY=FR;COUNT=3", "Europe/Berlin", friday.Unix(),
+		[]int64{friday.AddDate(0, 0, 7).Unix()})
+	require.NoError(t, err)
+	require.NotNil(t, spec.rule)
+	assert.Equal(t, "FREQ=WEEKLY;COUNT=3;BYDAY=FR", *spec.rule)
+	require.NotNil(t, spec.endsAt)
+	assert.True(t, spec.endsAt.Equal(friday.AddDate(0, 0, 14)), "ends at the third occurrence")
+	assert.Len(t, spec.exceptions, 1)
+
+	spec, err = parseRecurrence("", "", friday.Unix(), nil)
+	require.NoError(t, err)
+	assert.Nil(t, spec.rule)
+	assert.Equal(t, "UTC", spec.timezone)
+
+	for name, tc := range map[string]struct {
+		rule, tz   string
+		start      int64
+		exceptions []int64
+	}{
+		"unknown timezone":        {"FREQ=WEEKLY", "Mars/Olympus", friday.Unix(), nil},
+		"invalid rule":            {"FREQ=HOURLY", "UTC", friday.Unix(), nil},
+		"start not an occurrence": {"FREQ=WEEKLY;BYDAY=SA", "Europe/Berlin", friday.Unix(), nil},
+		"exception not an occurrence": {"FREQ=WEEKLY;BYDAY=FR", "Europe/Berlin", friday.Unix(),
+			[]int64{friday.AddDate(0, 0, 1).Unix()}},
+		"exceptions without a rule": {"", "UTC", friday.Unix(), []int64{friday.Unix()}},
+	} {
+		_, err := parseRecurrence(tc.rule, tc.tz, tc.start, tc.exceptions)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestSeriesOccurrences(t *testing.T) {
+	start := time.Date(2026, 4, 6, 18, 0, 0, 0, time.UTC) // a Monday
+	end := start.Add(2 * time.Hour)
+	rule := "FREQ=WEEKLY;BYDAY=MO"
+	s := newSeries(&rule, "UTC", start, &end)
+	require.NotNil(t, s)
+	assert.Nil(t, newSeries(nil, "UTC", start, &end), "one-off events aren't series")
+
+	moved := start.AddDate(0, 0, 14).Add(time.Hour)
+	title := "Assembly (moved)"
+	overrides := map[int64]occurrenceOverride{
+		start.AddDate(0, 0, 7).Unix():  {cancelled: true},
+		start.AddDate(0, 0, 14).Unix(): {title: &title, startsAt: &moved},
+	}
+
+	got := s.occurrences(start, start.AddDate(0, 0, 28), 10, overrides)
+	require.Len(t, got, 3, "the cancelled week is skipped")
+	assert.True(t, got[0].at.Equal(start))
+	assert.True(t, got[0].endsAt.Equal(end))
+
+	edited := got[1]
+	assert.True(t, edited.at.Equal(start.AddDate(0, 0, 14)), "an occurrence keeps the start the rule generates")
+	assert.True(t, edited.startsAt.Equal(moved))
+	assert.True(t, edited.endsAt.Equal(moved.Add(2*time.Hour)), "moved occurrences keep their length")
+	assert.Equal(t, title, *edited.override.title)
+
+	assert.Len(t, s.occurrences(start, start.AddDate(0, 0, 28), 2, overrides), 2)
+
+	_, ok := s.occurrence(start.Add(time.Hour), overrides)
+	assert.False(t, ok)
+
+	reveal := start.Add(-3 * time.Hour)
+	assert.True(t, s.revealAt(edited, &reveal).Equal(moved.Add(-3*time.Hour)))
+	assert.Nil(t, s.revealAt(edited, nil))
+}
+
+func TestSortListings(t *testing.T) {
+	base := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	listings := []eventListing{
+		{startsAt: base.Add(2 * time.Hour), event: gin.H{"id": "c"}},
+		{startsAt: base, event: gin.H{"id": "a"}},
+		{startsAt: base.Add(time.Hour), event: gin.H{"id": "b"}},
+	}
+	assert.Equal(t, []gin.H{{"id": "b"}, {"id": "c"}}, sortListings(listings, 1, 5))
+	assert.Equal(t, []gin.H{}, sortListings(nil, 0, 5))
+}
+
+func TestCalendarSeries(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	start := time.Date(2026, 3, 20, 19, 0, 0, 0, berlin)
+	rule := "FREQ=WEEKLY;BYDAY=FR"
+	title := "Special session"
+	row := calendarRow{
+		id: "e1", organizerID: "org", title: "Weekly meeting", locationVisibility: "public",
+		startsAt: start, updatedAt: start, recurrenceRule: &rule, timezone: "Europe/Berlin",
+	}
+	events := calendarSeries(row, "someone", map[int64]occurrenceOverride{
+		start.AddDate(0, 0, 7).Unix():  {cancelled: true},
+		start.AddDate(0, 0, 14).Unix(): {title: &title},
+	})
+	require.Len(t, events, 2)
+	assert.Equal(t, rule, events[0].rrule)
+	assert.Len(t, events[0].exdates, 1)
+	require.NotNil(t, events[1].recurrenceID)
+	assert.Equal(t, title, events[1].title)
+
+	out := renderCalendar("Kuurier", events, start)
+	assert.Contains(t, out, "DTSTART;TZID=Europe/Berlin:20260320T190000\r\n")
+	assert.Contains(t, out, "RRULE:FREQ=WEEKLY;BYDAY=FR\r\n")
+	assert.Contains(t, out, "EXDATE;TZID=Europe/Berlin:20260327T190000\r\n")
+	assert.Contains(t, out, "RECURRENCE-ID;TZID=Europe/Berlin:20260403T190000\r\n", "local time after the DST change")
+	assert.Equal(t, 2, strings.Count(out, "UID:e1@kuurier"))
+
+	row.recurrenceRule = nil
+	assert.Len(t, calendarSeries(row, "someone", nil), 1)
+}
@@ -0,0 +1,312 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/push"
+	"github.com/kuurier/server/internal/storage"
+)
+
+const (
+	// reminderKindReveal is the event_reminders kind for a timed
+	// location being revealed; offset reminders are "before:<seconds>".
+	reminderKindReveal = "reveal"
+
+	// reminderRetention is how long sent reminders are remembered after
+	// their occurrence started. Nothing is due for it after that.
+	reminderRetention = 7 * 24 * time.Hour
+
+	// maxReminderOccurrences bounds how many occurrences of one series
+	// a run looks at.
+	maxReminderOccurrences = 50
+)
+
+// Reminder is the worker job that reminds going and interested RSVPs
+// before events start, and tells them when a timed location is
+// revealed. Every send is recorded in event_reminders first, so a
+// reminder is never pushed twice.
+type Reminder struct {
+	// Reuse the Handler for loading occurrence edits.
+	h       *Handler
+	push    *push.Service
+	offsets []time.Duration
+}
+
+// NewReminder returns a Reminder for cfg.EventReminderOffsets.
+func NewReminder(cfg *config.Config, db *storage.Postgres, pushService *push.Service) *Reminder {
+	offsets := slices.Clone(cfg.EventReminderOffsets)
+	slices.Sort(offsets)
+	return &Reminder{h: NewHandler(cfg, db, nil, nil, nil), push: pushService, offsets: offsets}
+}
+
+// reminderEvent is an event or series that may have reminders due.
+type reminderEvent struct {
+	id, title                  string
+	locationName, locationArea *string
+	lat, lon                   float64
+	locationVisibility         string
+	locationRevealAt           *time.Time
+	startsAt                   time.Time
+	endsAt                     *time.Time
+	recurrenceRule             *string
+	timezone                   string
+}
+
+// dueReminder is one reminder for one occurrence's RSVPs.
+type dueReminder struct {
+	eventID        string
+	occurrenceAt   time.Time  // event_reminders.occurrence_at
+	rsvpOccurrence *time.Time // event_rsvps.occurrence_at; nil for one-off events
+	kind           string
+	rsvpedBefore   time.Time // only RSVPs made by then are reminded
+	notification   push.Notification
+}
+
+// RunOnce sends every reminder that is due and hasn't been sent, and
+// forgets reminders for occurrences long past.
+func (r *Reminder) RunOnce(ctx context.Context) error {
+	now := time.Now()
+	db := r.h.db.Pool()
+
+	if _, err := db.Exec(ctx, `DELETE FROM event_reminders WHERE occurrence_at < $1`,
+		now.Add(-reminderRetention)); err != nil {
+		return fmt.Errorf("prune event reminders: %w", err)
+	}
+
+	events, err := r.candidates(ctx, now)
+	if err != nil {
+		return fmt.Errorf("list reminder candidates: %w", err)
+	}
+	var seriesIDs []string
+	for _, ev := range events {
+		if ev.recurrenceRule != nil {
+			seriesIDs = append(seriesIDs, ev.id)
+		}
+	}
+	overrides, err := r.h.occurrenceOverrides(ctx, seriesIDs)
+	if err != nil {
+		return fmt.Errorf("load occurrence edits: %w", err)
+	}
+
+	sent := 0
+	for _, ev := range events {
+		for _, due := range dueReminders(ev, overrides[ev.id], r.offsets, now) {
+			n, err := r.send(ctx, due)
+			if err != nil {
+				log.Printf("events: reminder %s for %s: %v", due.kind, due.eventID, err)
+				continue
+			}
+			sent += n
+		}
+	}
+	if sent > 0 {
+		log.Printf("events: sent %d event reminders", sent)
+	}
+	return nil
+}
+
+// candidates loads uncancelled events with RSVPs that may have a
+// reminder due: one-off events starting within the largest offset or
+// whose timed location has been revealed, and series still running.
+func (r *Reminder) candidates(ctx context.Context, now time.Time) ([]reminderEvent, error) {
+	var horizon time.Duration
+	if len(r.offsets) > 0 {
+		horizon = r.offsets[len(r.offsets)-1]
+	}
+	rows, err := r.h.db.Pool().Query(ctx, `
+		SELECT e.id, e.title, e.location_name, e.location_area,
+		       ST_Y(e.location::geometry), ST_X(e.location::geometry),
+		       e.location_visibility, e.location_reveal_at,
+		       e.starts_at, e.ends_at, e.recurrence_rule, e.timezone
+		FROM events e
+		WHERE e.is_cancelled = false
+		  AND (
+		        (e.recurrence_rule IS NULL AND e.starts_at > $1
+		         AND (e.starts_at <= $2 OR (e.location_visibility = 'timed' AND e.location_reveal_at <= $1)))
+		     OR (e.recurrence_rule IS NOT NULL AND (e.recurrence_ends_at IS NULL OR e.recurrence_ends_at > $1))
+		  )
+		  AND EXISTS (SELECT 1 FROM event_rsvps r WHERE r.event_id = e.id AND r.status IN ('going', 'interested')
+		              AND r.approval = 'approved')
+	`, now, now.Add(horizon))
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (reminderEvent, error) {
+		var ev reminderEvent
+		err := row.Scan(&ev.id, &ev.title, &ev.locationName, &ev.locationArea, &ev.lat, &ev.lon,
+			&ev.locationVisibility, &ev.locationRevealAt,
+			&ev.startsAt, &ev.endsAt, &ev.recurrenceRule, &ev.timezone)
+		return ev, err
+	})
+}
+
+// dueReminders lists the reminders due at now for ev's upcoming
+// occurrences. offsets must be sorted ascending. Only the shortest
+// offset that has passed is due, so a worker catching up after
+// downtime doesn't send "tomorrow" and "in an hour" together.
+func dueReminders(ev reminderEvent, overrides map[int64]occurrenceOverride, offsets []time.Duration, now time.Time) []dueReminder {
+	s := newSeries(ev.recurrenceRule, ev.timezone, ev.startsAt, ev.endsAt)
+
+	var occurrences []occurrence
+	if s == nil {
+		occurrences = []occurrence{{at: ev.startsAt, startsAt: ev.startsAt, endsAt: ev.endsAt}}
+	} else {
+		var horizon time.Duration
+		if len(offsets) > 0 {
+			horizon = offsets[len(offsets)-1]
+		}
+		if ev.locationVisibility == "timed" && ev.locationRevealAt != nil {
+			horizon = max(horizon, ev.startsAt.Sub(*ev.locationRevealAt))
+		}
+		occurrences = s.occurrences(now.Add(-horizon), now.Add(horizon), maxReminderOccurrences, overrides)
+	}
+
+	var due []dueReminder
+	for _, o := range occurrences {
+		if !o.startsAt.After(now) {
+			continue
+		}
+		base := dueReminder{eventID: ev.id, occurrenceAt: o.at}
+		if s != nil {
+			at := o.at
+			base.rsvpOccurrence = &at
+		}
+		title := ev.title
+		if o.override.title != nil {
+			title = *o.override.title
+		}
+		name, area := ev.locationName, ev.locationArea
+		if o.override.locationName != nil {
+			name = o.override.locationName
+		}
+		if o.override.locationArea != nil {
+			area = o.override.locationArea
+		}
+		// RSVPs may see the exact location; the area is the fallback.
+		place := name
+		if place == nil || *place == "" {
+			place = area
+		}
+
+		for _, offset := range offsets {
+			remindAt := o.startsAt.Add(-offset)
+			if now.Before(remindAt) {
+				continue
+			}
+			d := base
+			d.kind = "before:" + strconv.Itoa(int(offset.Seconds()))
+			d.rsvpedBefore = remindAt
+			body := "Starts " + formatLead(o.startsAt.Sub(now))
+			if place != nil && *place != "" {
+				body += " at " + *place
+			}
+			d.notification = reminderNotification(ev.id, title, body, s != nil, o.at)
+			due = append(due, d)
+			break
+		}
+
+		if ev.locationVisibility != "timed" {
+			continue
+		}
+		revealAt := ev.locationRevealAt
+		if s != nil {
+			revealAt = s.revealAt(o, ev.locationRevealAt)
+		}
+		if revealAt == nil || now.Before(*revealAt) {
+			continue
+		}
+		// RSVPs made after the reveal saw the location when they did.
+		d := base
+		d.kind = reminderKindReveal
+		d.rsvpedBefore = *revealAt
+		location := fmt.Sprintf("%.6f, %.6f", ev.lat, ev.lon)
+		if name != nil && *name != "" {
+			location = *name
+		}
+		d.notification = reminderNotification(ev.id, title, "Location revealed: "+location, s != nil, o.at)
+		d.notification.Data["location_name"] = location
+		d.notification.Data["latitude"] = strconv.FormatFloat(ev.lat, 'f', 6, 64)
+		d.notification.Data["longitude"] = strconv.FormatFloat(ev.lon, 'f', 6, 64)
+		due = append(due, d)
+	}
+	return due
+}
+
+func reminderNotification(eventID, title, body string, recurring bool, at time.Time) push.Notification {
+	n := push.Notification{
+		Title:    title,
+		Body:     body,
+		Priority: "normal",
+		Category: "EVENT",
+		ThreadID: "event-" + eventID,
+		Data: map[string]string{
+			"type":     string(push.NotificationTypeEventRemind),
+			"event_id": eventID,
+		},
+	}
+	if recurring {
+		n.Data["occurrence_at"] = strconv.FormatInt(at.Unix(), 10)
+	}
+	return n
+}
+
+// formatLead renders how far off a start is: "in 45 minutes",
+// "in 2 hours", "tomorrow", "in 3 days".
+func formatLead(d time.Duration) string {
+	switch {
+	case d < 90*time.Minute:
+		m := max(int(d.Round(time.Minute).Minutes()), 1)
+		if m == 60 {
+			return "in 1 hour"
+		}
+		return "in " + plural(m, "minute")
+	case d < 36*time.Hour:
+		if h := int(d.Round(time.Hour).Hours()); h < 24 {
+			return "in " + plural(h, "hour")
+		}
+		return "tomorrow"
+	default:
+		return "in " + plural(int(d.Round(24*time.Hour).Hours()/24), "day")
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return strconv.Itoa(n) + " " + unit + "s"
+}
+
+// send records due for every matching RSVP not yet reminded and pushes
+// to them. Rows are claimed before pushing: a crash between the two
+// loses a reminder rather than sending it twice.
+func (r *Reminder) send(ctx context.Context, due dueReminder) (int, error) {
+	rows, err := r.h.db.Pool().Query(ctx, `
+		INSERT INTO event_reminders (event_id, occurrence_at, kind, user_id)
+		SELECT event_id, $3, $4, user_id
+		FROM event_rsvps
+		WHERE event_id = $1 AND occurrence_at IS NOT DISTINCT FROM $2
+		  AND status IN ('going', 'interested') AND approval = 'approved'
+		  AND created_at <= $5
+		ON CONFLICT DO NOTHING
+		RETURNING user_id
+	`, due.eventID, due.rsvpOccurrence, due.occurrenceAt, due.kind, due.rsvpedBefore)
+	if err != nil {
+		return 0, err
+	}
+	userIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil || len(userIDs) == 0 {
+		return 0, err
+	}
+	if r.push != nil {
+		_ = r.push.SendToUsers(ctx, userIDs, due.notification)
+	}
+	return len(userIDs), nil
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testReminderOffsets = []time.Duration{time.Hour, 24 * time.Hour}
+
+func TestDueReminders_Offsets(t *testing.T) {
+	start := time.Date(2026, 5, 1, 14, 0, 0, 0, time.UTC)
+	place := "Union Hall"
+	ev := reminderEvent{id: "e1", title: "Strike meeting", locationName: &place,
+		locationVisibility: "rsvp", startsAt: start}
+
+	assert.Empty(t, dueReminders(ev, nil, testReminderOffsets, start.Add(-25*time.Hour)))
+
+	due := dueReminders(ev, nil, testReminderOffsets, start.Add(-23*time.Hour))
+	require.Len(t, due, 1)
+	assert.Equal(t, "before:86400", due[0].kind)
+	assert.True(t, due[0].rsvpedBefore.Equal(start.Add(-24*time.Hour)))
+	assert.True(t, due[0].occurrenceAt.Equal(start))
+	assert.Nil(t, due[0].rsvpOccurrence, "one-off events match RSVPs without an occurrence")
+	assert.Equal(t, "Starts in 23 hours at Union Hall", due[0].notification.Body)
+	assert.Equal(t, "event_reminder", due[0].notification.Data["type"])
+
+	due = dueReminders(ev, nil, testReminderOffsets, start.Add(-30*time.Minute))
+	require.Len(t, due, 1, "only the shortest passed offset is due")
+	assert.Equal(t, "before:3600", due[0].kind)
+	assert.Equal(t, "Starts in 30 minutes at Union Hall", due[0].notification.Body)
+
+	assert.Empty(t, dueReminders(ev, nil, testReminderOffsets, start), "nothing once it has started")
+}
+
+func TestDueReminders_Reveal(t *testing.T) {
+	start := time.Date(2026, 5, 1, 14, 0, 0, 0, time.UTC)
+	reveal := start.Add(-3 * time.Hour)
+	area := "Kreuzberg"
+	ev := reminderEvent{id: "e1", title: "Action", locationArea: &area, lat: 52.5, lon: 13.4,
+		locationVisibility: "timed", locationRevealAt: &reveal, startsAt: start}
+
+	due := dueReminders(ev, nil, testReminderOffsets, reveal.Add(-time.Minute))
+	require.Len(t, due, 1, "no reveal reminder before the reveal")
+	assert.Equal(t, "before:86400", due[0].kind)
+
+	due = dueReminders(ev, nil, testReminderOffsets, reveal.Add(time.Minute))
+	require.Len(t, due, 2)
+	assert.Equal(t, "before:86400", due[0].kind)
+	assert.Equal(t, "Starts in 3 hours at Kreuzberg", due[0].notification.Body, "the area until revealed")
+	assert.Equal(t, reminderKindReveal, due[1].kind)
+	assert.True(t, due[1].rsvpedBefore.Equal(reveal))
+	assert.Equal(t, "Location revealed: 52.500000, 13.400000", due[1].notification.Body)
+	assert.Equal(t, "13.400000", due[1].notification.Data["longitude"])
+}
+
+func TestDueReminders_Series(t *testing.T) {
+	start := time.Date(2026, 4, 6, 18, 0, 0, 0, time.UTC) // a Monday
+	rule := "FREQ=WEEKLY;BYDAY=MO"
+	ev := reminderEvent{id: "e1", title: "Assembly", locationVisibility: "public",
+		startsAt: start, recurrenceRule: &rule, timezone: "UTC"}
+
+	third := start.AddDate(0, 0, 14)
+	title := "Assembly (AGM)"
+	overrides := map[int64]occurrenceOverride{
+		start.AddDate(0, 0, 7).Unix(): {cancelled: true},
+		third.Unix():                  {title: &title},
+	}
+
+	assert.Empty(t, dueReminders(ev, overrides, testReminderOffsets, start.AddDate(0, 0, 7).Add(-time.Hour)),
+		"cancelled occurrences aren't reminded")
+
+	due := dueReminders(ev, overrides, testReminderOffsets, third.Add(-2*time.Hour))
+	require.Len(t, due, 1)
+	require.NotNil(t, due[0].rsvpOccurrence)
+	assert.True(t, due[0].rsvpOccurrence.Equal(third))
+	assert.Equal(t, title, due[0].notification.Title)
+	assert.Equal(t, "Starts in 2 hours", due[0].notification.Body)
+	assert.Equal(t, strconv.FormatInt(third.Unix(), 10), due[0].notification.Data["occurrence_at"])
+}
+
+func TestFormatLead(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		20 * time.Second:                "in 1 minute",
+		45 * time.Minute:                "in 45 minutes",
+		59*time.Minute + 50*time.Second: "in 1 hour",
+		5 * time.Hour:                   "in 5 hours",
+		23*time.Hour + 40*time.Minute:   "tomorrow",
+		30 * time.Hour:                  "tomorrow",
+		72 * time.Hour:                  "in 3 days",
+	} {
+		assert.Equal(t, want, formatLead(d), d.String())
+	}
+}
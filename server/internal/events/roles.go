@@ -0,0 +1,1026 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kuurier/server/internal/counts"
+)
+
+// maxEventRoles caps how many roles one event can define.
+const maxEventRoles = 20
+
+// Sign-up statuses. Pending and confirmed sign-ups hold a place.
+const (
+	signupPending    = "pending"
+	signupConfirmed  = "confirmed"
+	signupWaitlisted = "waitlisted"
+	signupDeclined   = "declined"
+)
+
+// RoleRequest defines a volunteer role on an event.
+type RoleRequest struct {
+	Name             string  `json:"name" binding:"required,max=50"`
+	Description      *string `json:"description" binding:"omitempty,max=500"`
+	Capacity         int     `json:"capacity" binding:"required,min=1,max=1000"`
+	RequiresApproval bool    `json:"requires_approval"`
+}
+
+// UpdateRoleRequest changes a role. Lowering the capacity doesn't
+// remove anyone already holding a place.
+type UpdateRoleRequest struct {
+	Name             *string `json:"name" binding:"omitempty,min=1,max=50"`
+	Description      *string `json:"description" binding:"omitempty,max=500"`
+	Capacity         *int    `json:"capacity" binding:"omitempty,min=1,max=1000"`
+	RequiresApproval *bool   `json:"requires_approval"`
+}
+
+// SignupRequest signs the user up for a role.
+type SignupRequest struct {
+	Occurrence *int64  `json:"occurrence"`
+	Note       *string `json:"note" binding:"omitempty,max=200"` // for the organizer
+}
+
+// DecideSignupRequest is an organizer's decision on a sign-up.
+type DecideSignupRequest struct {
+	Status     string `json:"status" binding:"required,oneof=confirmed declined"`
+	Occurrence *int64 `json:"occurrence"`
+}
+
+// eventRole is a role as locked for a sign-up change.
+type eventRole struct {
+	id, name         string
+	capacity         int
+	requiresApproval bool
+}
+
+// signupStatus is the status of a new sign-up to a role with taken of
+// its places held.
+func signupStatus(capacity, taken int, requiresApproval bool) string {
+	switch {
+	case taken >= capacity:
+		return signupWaitlisted
+	case requiresApproval:
+		return signupPending
+	default:
+		return signupConfirmed
+	}
+}
+
+// ListRoles returns an event's roles with how many places are taken,
+// and the user's own sign-up. Series need ?occurrence=. Counts are
+// blurred for anyone who doesn't run the event.
+func (h *Handler) ListRoles(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	occ, err := parseOccurrence(c.Query("occurrence"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	st, err := h.eventState(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	occurrenceAt, ok := h.rsvpOccurrence(c, eventID, newSeries(st.recurrence, st.timezone, st.startsAt, st.endsAt), occ, false)
+	if !ok {
+		return
+	}
+	manager, err := h.eventManager(ctx, eventID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch roles"})
+		return
+	}
+	policy := counts.FromConfig(h.cfg)
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT r.id, r.name, r.description, r.capacity, r.requires_approval,
+		       COUNT(s.user_id) FILTER (WHERE s.status = 'confirmed'),
+		       COUNT(s.user_id) FILTER (WHERE s.status = 'pending'),
+		       COUNT(s.user_id) FILTER (WHERE s.status = 'waitlisted'),
+		       MAX(s.status) FILTER (WHERE s.user_id = $2)
+		FROM event_roles r
+		LEFT JOIN event_role_signups s ON s.role_id = r.id AND s.occurrence_at IS NOT DISTINCT FROM $3
+		WHERE r.event_id = $1
+		GROUP BY r.id
+		ORDER BY r.created_at
+	`, eventID, userID, occurrenceAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch roles"})
+		return
+	}
+	roles, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (gin.H, error) {
+		var id, name string
+		var description, myStatus *string
+		var capacity, confirmed, pending, waitlisted int
+		var requiresApproval bool
+		if err := row.Scan(&id, &name, &description, &capacity, &requiresApproval,
+			&confirmed, &pending, &waitlisted, &myStatus); err != nil {
+			return nil, err
+		}
+		spotsLeft := max(capacity-confirmed-pending, 0)
+		role := gin.H{
+			"id":                id,
+			"name":              name,
+			"capacity":          capacity,
+			"requires_approval": requiresApproval,
+			"full":              spotsLeft == 0,
+		}
+		// Exact places left give the confirmed count back from capacity
+		policy.Set(role, "confirmed", confirmed, manager != "")
+		policy.Set(role, "spots_left", spotsLeft, manager != "")
+		policy.Set(role, "waitlisted", waitlisted, manager != "")
+		if description != nil {
+			role["description"] = *description
+		}
+		if myStatus != nil {
+			role["my_status"] = *myStatus
+		}
+		return role, nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch roles"})
+		return
+	}
+	if roles == nil {
+		roles = []gin.H{}
+	}
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// CreateRole adds a role to an event (organizers only). The first role
+// of an event with a channel also creates the role holders' channel.
+func (h *Handler) CreateRole(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+		return
+	}
+
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var title string
+	var channelID, rolesChannelID *string
+	err = tx.QueryRow(ctx, `
+		SELECT title, channel_id, roles_channel_id FROM events WHERE id = $1 FOR UPDATE
+	`, eventID).Scan(&title, &channelID, &rolesChannelID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	manager, err := h.eventManager(ctx, eventID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if manager == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the organizers can add roles"})
+		return
+	}
+
+	var count int
+	if err := tx.QueryRow(ctx, `SELECT COUNT(*) FROM event_roles WHERE event_id = $1`, eventID).Scan(&count); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if count >= maxEventRoles {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many roles for this event"})
+		return
+	}
+
+	var roleID string
+	err = tx.QueryRow(ctx, `
+		INSERT INTO event_roles (event_id, name, description, capacity, requires_approval)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (event_id, name) DO NOTHING
+		RETURNING id
+	`, eventID, req.Name, req.Description, req.Capacity, req.RequiresApproval).Scan(&roleID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusConflict, gin.H{"error": "a role with this name already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create role"})
+		return
+	}
+
+	if channelID != nil && rolesChannelID == nil {
+		id, err := createRolesChannel(ctx, tx, eventID, userID, title)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		rolesChannelID = &id
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+
+	response := gin.H{
+		"id":                roleID,
+		"event_id":          eventID,
+		"name":              req.Name,
+		"capacity":          req.Capacity,
+		"requires_approval": req.RequiresApproval,
+	}
+	if req.Description != nil {
+		response["description"] = *req.Description
+	}
+	if rolesChannelID != nil {
+		response["roles_channel_id"] = *rolesChannelID
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// createRolesChannel creates the role holders' channel of an event,
+// with the organizer and co-organizers as admins.
+func createRolesChannel(ctx context.Context, tx pgx.Tx, eventID, createdBy, title string) (string, error) {
+	channelID := uuid.New().String()
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO channels (id, name, description, type, event_id, created_by, created_at, updated_at)
+		VALUES ($1, $2, 'Role holders only', 'event', $3, $4, NOW(), NOW())
+	`, channelID, "Crew: "+title, eventID, createdBy); err != nil {
+		return "", errors.New("failed to create roles channel")
+	}
+	if _, err := tx.Exec(ctx, `UPDATE events SET roles_channel_id = $1 WHERE id = $2`, channelID, eventID); err != nil {
+		return "", errors.New("failed to link roles channel")
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO channel_members (channel_id, user_id, role, joined_at)
+		SELECT $1::uuid, organizer_id, 'admin', NOW() FROM events WHERE id = $2
+		UNION
+		SELECT $1::uuid, user_id, 'admin', NOW() FROM event_co_organizers WHERE event_id = $2 AND status = 'accepted'
+	`, channelID, eventID); err != nil {
+		return "", errors.New("failed to add organizers to roles channel")
+	}
+	return channelID, nil
+}
+
+// UpdateRole changes a role (organizers only). Raising the capacity
+// moves waitlisted sign-ups up.
+func (h *Handler) UpdateRole(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req UpdateRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"})
+			return
+		}
+		req.Name = &name
+	}
+
+	tx, title, ok := h.beginRoleChange(c, eventID, userID)
+	if !ok {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	role, err := lockRole(ctx, tx, eventID, c.Param("roleId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+		return
+	}
+
+	if req.Name != nil && *req.Name != role.name {
+		var taken bool
+		if err := tx.QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM event_roles WHERE event_id = $1 AND name = $2)
+		`, eventID, *req.Name).Scan(&taken); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if taken {
+			c.JSON(http.StatusConflict, gin.H{"error": "a role with this name already exists"})
+			return
+		}
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE event_roles SET
+			name = COALESCE($2, name),
+			description = COALESCE($3, description),
+			capacity = COALESCE($4, capacity),
+			requires_approval = COALESCE($5, requires_approval)
+		WHERE id = $1
+	`, role.id, req.Name, req.Description, req.Capacity, req.RequiresApproval)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update role"})
+		return
+	}
+	if req.Name != nil {
+		role.name = *req.Name
+	}
+	if req.RequiresApproval != nil {
+		role.requiresApproval = *req.RequiresApproval
+	}
+
+	notices := map[string]string{}
+	if req.Capacity != nil && *req.Capacity > role.capacity {
+		role.capacity = *req.Capacity
+		occurrences, err := waitlistedOccurrences(ctx, tx, role.id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update waitlist"})
+			return
+		}
+		for _, occ := range occurrences {
+			if err := promoteWaitlist(ctx, tx, role, occ, notices); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update waitlist"})
+				return
+			}
+		}
+		if err := syncRolesChannel(ctx, tx, eventID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update roles channel"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+	go h.notifyUsers(eventID, title, notices)
+
+	c.JSON(http.StatusOK, gin.H{"message": "role updated"})
+}
+
+// DeleteRole removes a role and its sign-ups (organizers only).
+func (h *Handler) DeleteRole(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	tx, _, ok := h.beginRoleChange(c, eventID, userID)
+	if !ok {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `DELETE FROM event_roles WHERE id = $1 AND event_id = $2`, c.Param("roleId"), eventID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete role"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+		return
+	}
+	if err := syncRolesChannel(ctx, tx, eventID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update roles channel"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "role deleted"})
+}
+
+// beginRoleChange starts a transaction for an organizer's change to
+// an event's roles, locking the event. It writes the error response
+// and returns false if the user can't make it.
+func (h *Handler) beginRoleChange(c *gin.Context, eventID, userID string) (pgx.Tx, string, bool) {
+	ctx := c.Request.Context()
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return nil, "", false
+	}
+	var title string
+	err = tx.QueryRow(ctx, `SELECT title FROM events WHERE id = $1 FOR UPDATE`, eventID).Scan(&title)
+	if err != nil {
+		tx.Rollback(ctx)
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return nil, "", false
+	}
+	manager, err := h.eventManager(ctx, eventID, userID)
+	if err != nil || manager == "" {
+		tx.Rollback(ctx)
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the organizers can manage roles"})
+		return nil, "", false
+	}
+	return tx, title, true
+}
+
+// lockRole loads a role of eventID, locking it so that sign-ups to it
+// are counted one at a time.
+func lockRole(ctx context.Context, tx pgx.Tx, eventID, roleID string) (eventRole, error) {
+	var r eventRole
+	err := tx.QueryRow(ctx, `
+		SELECT id, name, capacity, requires_approval
+		FROM event_roles WHERE id::text = $1 AND event_id = $2
+		FOR UPDATE
+	`, roleID, eventID).Scan(&r.id, &r.name, &r.capacity, &r.requiresApproval)
+	return r, err
+}
+
+// takenPlaces counts the sign-ups holding a place in a role.
+func takenPlaces(ctx context.Context, tx pgx.Tx, roleID string, occurrenceAt *time.Time) (int, error) {
+	var taken int
+	err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM event_role_signups
+		WHERE role_id = $1 AND occurrence_at IS NOT DISTINCT FROM $2 AND status IN ('pending', 'confirmed')
+	`, roleID, occurrenceAt).Scan(&taken)
+	return taken, err
+}
+
+func waitlistedOccurrences(ctx context.Context, tx pgx.Tx, roleID string) ([]*time.Time, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT DISTINCT occurrence_at FROM event_role_signups WHERE role_id = $1 AND status = 'waitlisted'
+	`, roleID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[*time.Time])
+}
+
+// promoteWaitlist moves waitlisted sign-ups into the free places of a
+// role, first come first served, and adds what to tell each of them to
+// notices.
+func promoteWaitlist(ctx context.Context, tx pgx.Tx, role eventRole, occurrenceAt *time.Time, notices map[string]string) error {
+	taken, err := takenPlaces(ctx, tx, role.id, occurrenceAt)
+	if err != nil {
+		return err
+	}
+	free := role.capacity - taken
+	if free <= 0 {
+		return nil
+	}
+	status := signupStatus(role.capacity, taken, role.requiresApproval)
+	rows, err := tx.Query(ctx, `
+		UPDATE event_role_signups SET status = $3, updated_at = NOW()
+		WHERE role_id = $1 AND occurrence_at IS NOT DISTINCT FROM $2
+		  AND user_id IN (
+		        SELECT user_id FROM event_role_signups
+		        WHERE role_id = $1 AND occurrence_at IS NOT DISTINCT FROM $2 AND status = 'waitlisted'
+		        ORDER BY created_at
+		        LIMIT $4
+		  )
+		RETURNING user_id
+	`, role.id, occurrenceAt, status, free)
+	if err != nil {
+		return err
+	}
+	promoted, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	for _, id := range promoted {
+		if status == signupConfirmed {
+			notices[id] = "A place opened up: you're confirmed as " + role.name
+		} else {
+			notices[id] = "A place opened up as " + role.name + "; your sign-up awaits the organizer's approval"
+		}
+	}
+	return nil
+}
+
+// syncRolesChannel makes the confirmed role holders of an event, across
+// all its roles and occurrences, the members of its roles channel. The
+// organizer and other admins stay.
+func syncRolesChannel(ctx context.Context, tx pgx.Tx, eventID string) error {
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO channel_members (channel_id, user_id, role, joined_at)
+		SELECT DISTINCT e.roles_channel_id, s.user_id, 'member', NOW()
+		FROM events e
+		JOIN event_roles r ON r.event_id = e.id
+		JOIN event_role_signups s ON s.role_id = r.id
+		WHERE e.id = $1 AND e.roles_channel_id IS NOT NULL AND s.status = 'confirmed'
+		ON CONFLICT (channel_id, user_id) DO NOTHING
+	`, eventID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(ctx, `
+		DELETE FROM channel_members m
+		USING events e
+		WHERE e.id = $1 AND m.channel_id = e.roles_channel_id AND m.role <> 'admin'
+		  AND NOT EXISTS (
+		        SELECT 1 FROM event_roles r
+		        JOIN event_role_signups s ON s.role_id = r.id
+		        WHERE r.event_id = e.id AND s.user_id = m.user_id AND s.status = 'confirmed'
+		  )
+	`, eventID)
+	return err
+}
+
+// SignUp signs the user up for a role. The sign-up is confirmed, held
+// for the organizer's approval, or waitlisted if the role is full.
+// Signing up for a role counts as going. At approval-gated events it
+// takes an approved RSVP.
+func (h *Handler) SignUp(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req SignupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	st, err := h.eventState(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	if st.cancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "event is cancelled"})
+		return
+	}
+	var occ *time.Time
+	if req.Occurrence != nil {
+		t := time.Unix(*req.Occurrence, 0)
+		occ = &t
+	}
+	occurrenceAt, ok := h.rsvpOccurrence(c, eventID, newSeries(st.recurrence, st.timezone, st.startsAt, st.endsAt), occ, true)
+	if !ok {
+		return
+	}
+
+	// Approval-gated events only take role holders they've let in
+	if st.visibility == "approval" {
+		manager, err := h.eventManager(ctx, eventID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		approved := manager != ""
+		if !approved {
+			h.db.Pool().QueryRow(ctx, `
+				SELECT EXISTS(SELECT 1 FROM event_rsvps
+				              WHERE event_id = $1 AND user_id = $2 AND occurrence_at IS NOT DISTINCT FROM $3
+				                AND approval = 'approved')
+			`, eventID, userID, occurrenceAt).Scan(&approved)
+		}
+		if !approved {
+			c.JSON(http.StatusForbidden, gin.H{"error": "RSVP and be approved by the organizer before signing up for a role"})
+			return
+		}
+	}
+
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	role, err := lockRole(ctx, tx, eventID, c.Param("roleId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+		return
+	}
+
+	var existing string
+	err = tx.QueryRow(ctx, `
+		SELECT status FROM event_role_signups
+		WHERE role_id = $1 AND user_id = $2 AND occurrence_at IS NOT DISTINCT FROM $3
+	`, role.id, userID, occurrenceAt).Scan(&existing)
+	switch {
+	case err == nil && existing == signupDeclined:
+		c.JSON(http.StatusForbidden, gin.H{"error": "the organizer declined your sign-up for this role"})
+		return
+	case err == nil:
+		c.JSON(http.StatusConflict, gin.H{"error": "already signed up for this role", "status": existing})
+		return
+	case !errors.Is(err, pgx.ErrNoRows):
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+
+	taken, err := takenPlaces(ctx, tx, role.id, occurrenceAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	status := signupStatus(role.capacity, taken, role.requiresApproval)
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO event_role_signups (role_id, user_id, occurrence_at, status, note)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+	`, role.id, userID, occurrenceAt, status, req.Note); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to sign up"})
+		return
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO event_rsvps (event_id, user_id, status, occurrence_at)
+		VALUES ($1, $2, 'going', $3)
+		ON CONFLICT (event_id, user_id, occurrence_at) DO UPDATE SET status = 'going'
+	`, eventID, userID, occurrenceAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record RSVP"})
+		return
+	}
+	if st.channelID != nil {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO channel_members (channel_id, user_id, role, joined_at)
+			VALUES ($1, $2, 'member', NOW())
+			ON CONFLICT (channel_id, user_id) DO NOTHING
+		`, *st.channelID, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to join event channel"})
+			return
+		}
+	}
+	if status == signupConfirmed {
+		if err := syncRolesChannel(ctx, tx, eventID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to join roles channel"})
+			return
+		}
+	}
+
+	var position int
+	if status == signupWaitlisted {
+		if err := tx.QueryRow(ctx, `
+			SELECT COUNT(*) FROM event_role_signups
+			WHERE role_id = $1 AND occurrence_at IS NOT DISTINCT FROM $2 AND status = 'waitlisted'
+		`, role.id, occurrenceAt).Scan(&position); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+	if status == signupPending {
+		go h.notifyManagers(eventID, st.title, "New sign-up as "+role.name+" awaits your approval")
+	}
+
+	response := gin.H{"role_id": role.id, "status": status}
+	if occurrenceAt != nil {
+		response["occurrence_at"] = *occurrenceAt
+	}
+	if position > 0 {
+		response["waitlist_position"] = position
+	}
+	if st.channelID != nil {
+		response["channel_id"] = *st.channelID
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// Withdraw takes the user off a role, giving their place to the next
+// on the waitlist. Declined sign-ups stay on record.
+func (h *Handler) Withdraw(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	occ, err := parseOccurrence(c.Query("occurrence"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	st, err := h.eventState(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	occurrenceAt, ok := h.rsvpOccurrence(c, eventID, newSeries(st.recurrence, st.timezone, st.startsAt, st.endsAt), occ, false)
+	if !ok {
+		return
+	}
+
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	role, err := lockRole(ctx, tx, eventID, c.Param("roleId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+		return
+	}
+	notices := map[string]string{}
+	found, err := releaseSignup(ctx, tx, role, userID, occurrenceAt, notices)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to withdraw"})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not signed up for this role"})
+		return
+	}
+	if err := syncRolesChannel(ctx, tx, eventID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update roles channel"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+	go h.notifyUsers(eventID, st.title, notices)
+
+	c.JSON(http.StatusOK, gin.H{"message": "withdrawn from role"})
+}
+
+// releaseSignup removes a user's sign-up, unless declined, and promotes
+// the waitlist if it held a place. It reports whether there was one.
+func releaseSignup(ctx context.Context, tx pgx.Tx, role eventRole, userID string, occurrenceAt *time.Time, notices map[string]string) (bool, error) {
+	var status string
+	err := tx.QueryRow(ctx, `
+		DELETE FROM event_role_signups
+		WHERE role_id = $1 AND user_id = $2 AND occurrence_at IS NOT DISTINCT FROM $3 AND status <> 'declined'
+		RETURNING status
+	`, role.id, userID, occurrenceAt).Scan(&status)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if status == signupWaitlisted {
+		return true, nil
+	}
+	return true, promoteWaitlist(ctx, tx, role, occurrenceAt, notices)
+}
+
+// withdrawFromRoles releases all of a user's sign-ups to an event (one
+// occurrence of a series), for when they're no longer going.
+func (h *Handler) withdrawFromRoles(ctx context.Context, eventID, userID string, occurrenceAt *time.Time) error {
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var title string
+	if err := tx.QueryRow(ctx, `SELECT title FROM events WHERE id = $1`, eventID).Scan(&title); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, `
+		SELECT r.id::text FROM event_roles r
+		JOIN event_role_signups s ON s.role_id = r.id
+		WHERE r.event_id = $1 AND s.user_id = $2 AND s.occurrence_at IS NOT DISTINCT FROM $3
+		  AND s.status <> 'declined'
+	`, eventID, userID, occurrenceAt)
+	if err != nil {
+		return err
+	}
+	roleIDs, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil || len(roleIDs) == 0 {
+		return err
+	}
+
+	notices := map[string]string{}
+	for _, roleID := range roleIDs {
+		role, err := lockRole(ctx, tx, eventID, roleID)
+		if err != nil {
+			return err
+		}
+		if _, err := releaseSignup(ctx, tx, role, userID, occurrenceAt, notices); err != nil {
+			return err
+		}
+	}
+	if err := syncRolesChannel(ctx, tx, eventID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	go h.notifyUsers(eventID, title, notices)
+	return nil
+}
+
+// DecideSignup confirms or declines a sign-up (organizers only).
+// Confirming a waitlisted sign-up needs a free place.
+func (h *Handler) DecideSignup(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	signupUserID := c.Param("userId")
+	ctx := c.Request.Context()
+
+	var req DecideSignupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	st, err := h.eventState(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	var occ *time.Time
+	if req.Occurrence != nil {
+		t := time.Unix(*req.Occurrence, 0)
+		occ = &t
+	}
+	occurrenceAt, ok := h.rsvpOccurrence(c, eventID, newSeries(st.recurrence, st.timezone, st.startsAt, st.endsAt), occ, false)
+	if !ok {
+		return
+	}
+
+	tx, title, ok := h.beginRoleChange(c, eventID, userID)
+	if !ok {
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	role, err := lockRole(ctx, tx, eventID, c.Param("roleId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "role not found"})
+		return
+	}
+
+	var current string
+	err = tx.QueryRow(ctx, `
+		SELECT status FROM event_role_signups
+		WHERE role_id = $1 AND user_id::text = $2 AND occurrence_at IS NOT DISTINCT FROM $3
+	`, role.id, signupUserID, occurrenceAt).Scan(&current)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "sign-up not found"})
+		return
+	}
+	if current == req.Status {
+		c.JSON(http.StatusOK, gin.H{"status": current})
+		return
+	}
+	if req.Status == signupConfirmed && (current == signupWaitlisted || current == signupDeclined) {
+		taken, err := takenPlaces(ctx, tx, role.id, occurrenceAt)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if taken >= role.capacity {
+			c.JSON(http.StatusConflict, gin.H{"error": "role is full"})
+			return
+		}
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE event_role_signups SET status = $4, updated_at = NOW()
+		WHERE role_id = $1 AND user_id::text = $2 AND occurrence_at IS NOT DISTINCT FROM $3
+	`, role.id, signupUserID, occurrenceAt, req.Status); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update sign-up"})
+		return
+	}
+
+	notices := map[string]string{}
+	if req.Status == signupConfirmed {
+		notices[signupUserID] = "You're confirmed as " + role.name
+	} else {
+		notices[signupUserID] = "Your sign-up as " + role.name + " wasn't accepted"
+		if current == signupPending || current == signupConfirmed {
+			if err := promoteWaitlist(ctx, tx, role, occurrenceAt, notices); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update waitlist"})
+				return
+			}
+		}
+	}
+	if err := syncRolesChannel(ctx, tx, eventID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update roles channel"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to commit transaction"})
+		return
+	}
+	go h.notifyUsers(eventID, title, notices)
+
+	c.JSON(http.StatusOK, gin.H{"status": req.Status})
+}
+
+// GetRoster returns every role of an event with its sign-ups, in the
+// order they were made (organizers only). Series need ?occurrence=.
+func (h *Handler) GetRoster(c *gin.Context) {
+	userID := c.GetString("user_id")
+	eventID := c.Param("id")
+	ctx := c.Request.Context()
+
+	occ, err := parseOccurrence(c.Query("occurrence"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	st, err := h.eventState(ctx, eventID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "event not found"})
+		return
+	}
+	manager, err := h.eventManager(ctx, eventID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	if manager == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the organizers can see the roster"})
+		return
+	}
+	occurrenceAt, ok := h.rsvpOccurrence(c, eventID, newSeries(st.recurrence, st.timezone, st.startsAt, st.endsAt), occ, false)
+	if !ok {
+		return
+	}
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT r.id, r.name, r.capacity, r.requires_approval,
+		       s.user_id, u.display_name, s.status, s.note, s.created_at
+		FROM event_roles r
+		LEFT JOIN event_role_signups s ON s.role_id = r.id AND s.occurrence_at IS NOT DISTINCT FROM $2
+		LEFT JOIN users u ON u.id = s.user_id
+		WHERE r.event_id = $1
+		ORDER BY r.created_at, s.created_at
+	`, eventID, occurrenceAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch roster"})
+		return
+	}
+	defer rows.Close()
+
+	roster := []gin.H{}
+	var signups []gin.H
+	var lastRoleID string
+	waitlisted := 0
+	for rows.Next() {
+		var roleID, name string
+		var capacity int
+		var requiresApproval bool
+		var signupUserID, displayName, status, note *string
+		var signedUpAt *time.Time
+		if err := rows.Scan(&roleID, &name, &capacity, &requiresApproval,
+			&signupUserID, &displayName, &status, &note, &signedUpAt); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch roster"})
+			return
+		}
+		if roleID != lastRoleID {
+			signups = []gin.H{}
+			waitlisted = 0
+			roster = append(roster, gin.H{
+				"id":                roleID,
+				"name":              name,
+				"capacity":          capacity,
+				"requires_approval": requiresApproval,
+				"signups":           signups,
+			})
+			lastRoleID = roleID
+		}
+		if signupUserID == nil {
+			continue
+		}
+		signup := gin.H{
+			"user_id":      *signupUserID,
+			"status":       *status,
+			"signed_up_at": *signedUpAt,
+		}
+		if displayName != nil {
+			signup["display_name"] = *displayName
+		}
+		if note != nil {
+			signup["note"] = *note
+		}
+		if *status == signupWaitlisted {
+			waitlisted++
+			signup["waitlist_position"] = waitlisted
+		}
+		signups = append(signups, signup)
+		roster[len(roster)-1]["signups"] = signups
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch roster"})
+		return
+	}
+
+	response := gin.H{"roles": roster}
+	if occurrenceAt != nil {
+		response["occurrence_at"] = *occurrenceAt
+	}
+	c.JSON(http.StatusOK, response)
+}
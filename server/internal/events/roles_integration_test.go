@@ -0,0 +1,291 @@
+//go:build integration
+
+package events
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// role creates a role as organizerID and returns its ID.
+func (s *testServer) role(eventID, organizerID string, capacity int, requiresApproval bool) string {
+	s.t.Helper()
+	code, resp := s.call(http.MethodPost, "/events/"+eventID+"/roles", organizerID, gin.H{
+		"name": "Marshal", "capacity": capacity, "requires_approval": requiresApproval,
+	})
+	require.Equal(s.t, http.StatusCreated, code, resp)
+	return resp["id"].(string)
+}
+
+// signupStatusOf returns userID's sign-up status for roleID, or "" if
+// they have none.
+func (s *testServer) signupStatusOf(roleID, userID string) string {
+	s.t.Helper()
+	var status string
+	err := s.pool.QueryRow(context.Background(), `
+		SELECT COALESCE((SELECT status FROM event_role_signups WHERE role_id = $1 AND user_id = $2), '')
+	`, roleID, userID).Scan(&status)
+	require.NoError(s.t, err)
+	return status
+}
+
+func TestRoles_SignUpWaitlistsOnceFull(t *testing.T) {
+	s := newTestServer(t)
+	organizer := s.user("Organizer")
+	eventID := s.event(organizer)
+	roleID := s.role(eventID, organizer, 2, false)
+	signup := "/events/" + eventID + "/roles/" + roleID + "/signup"
+
+	a, b, c, d := s.user("A"), s.user("B"), s.user("C"), s.user("D")
+	for _, u := range []string{a, b} {
+		code, resp := s.call(http.MethodPost, signup, u, gin.H{})
+		require.Equal(t, http.StatusCreated, code, resp)
+		assert.Equal(t, signupConfirmed, resp["status"])
+	}
+	code, resp := s.call(http.MethodPost, signup, c, gin.H{})
+	require.Equal(t, http.StatusCreated, code, resp)
+	assert.Equal(t, signupWaitlisted, resp["status"])
+	assert.EqualValues(t, 1, resp["waitlist_position"])
+
+	code, resp = s.call(http.MethodPost, signup, d, gin.H{})
+	require.Equal(t, http.StatusCreated, code, resp)
+	assert.EqualValues(t, 2, resp["waitlist_position"])
+
+	code, resp = s.call(http.MethodPost, signup, a, gin.H{})
+	assert.Equal(t, http.StatusConflict, code, "signing up twice")
+	assert.Equal(t, signupConfirmed, resp["status"])
+
+	var going int
+	require.NoError(t, s.pool.QueryRow(context.Background(), `
+		SELECT COUNT(*) FROM event_rsvps WHERE event_id = $1 AND status = 'going'
+	`, eventID).Scan(&going))
+	assert.Equal(t, 4, going, "signing up counts as going, waitlisted or not")
+
+	code, resp = s.call(http.MethodGet, "/events/"+eventID+"/roles", c, nil)
+	require.Equal(t, http.StatusOK, code, resp)
+	roles := resp["roles"].([]any)
+	require.Len(t, roles, 1)
+	listed := roles[0].(map[string]any)
+	assert.EqualValues(t, 2, listed["confirmed"])
+	assert.EqualValues(t, 0, listed["spots_left"])
+	assert.EqualValues(t, 2, listed["waitlisted"])
+	assert.Equal(t, signupWaitlisted, listed["my_status"])
+}
+
+func TestListRoles_BlursCountsForNonOrganizers(t *testing.T) {
+	s := newTestServer(t)
+	s.cfg.PublicCountThreshold = 10
+	s.cfg.PublicCountBucket = 5
+	organizer := s.user("Organizer")
+	eventID := s.event(organizer)
+	roleID := s.role(eventID, organizer, 3, false)
+	volunteer := s.user("Volunteer")
+	for _, u := range []string{volunteer, s.user("Other")} {
+		code, resp := s.call(http.MethodPost, "/events/"+eventID+"/roles/"+roleID+"/signup", u, gin.H{})
+		require.Equal(t, http.StatusCreated, code, resp)
+	}
+
+	listed := func(userID string) map[string]any {
+		t.Helper()
+		code, resp := s.call(http.MethodGet, "/events/"+eventID+"/roles", userID, nil)
+		require.Equal(t, http.StatusOK, code, resp)
+		roles := resp["roles"].([]any)
+		require.Len(t, roles, 1)
+		return roles[0].(map[string]any)
+	}
+
+	role := listed(volunteer)
+	assert.EqualValues(t, 0, role["confirmed"])
+	assert.Equal(t, "fewer than 10", role["confirmed_label"])
+	assert.EqualValues(t, 0, role["spots_left"], "places left would give the confirmed count away")
+	assert.Equal(t, "fewer than 10", role["spots_left_label"])
+	assert.Equal(t, false, role["full"])
+	assert.EqualValues(t, 3, role["capacity"])
+
+	role = listed(organizer)
+	assert.EqualValues(t, 2, role["confirmed"])
+	assert.EqualValues(t, 1, role["spots_left"])
+	assert.NotContains(t, role, "confirmed_label")
+}
+
+func TestRoles_WithdrawPromotesWaitlistInOrder(t *testing.T) {
+	s := newTestServer(t)
+	organizer := s.user("Organizer")
+	eventID := s.event(organizer)
+	roleID := s.role(eventID, organizer, 1, false)
+	signup := "/events/" + eventID + "/roles/" + roleID + "/signup"
+
+	a, b, c := s.user("A"), s.user("B"), s.user("C")
+	for _, u := range []string{a, b, c} {
+		code, resp := s.call(http.MethodPost, signup, u, gin.H{})
+		require.Equal(t, http.StatusCreated, code, resp)
+	}
+	require.Equal(t, signupWaitlisted, s.signupStatusOf(roleID, b))
+
+	// A waitlisted withdrawal frees no place
+	code, resp := s.call(http.MethodDelete, signup, c, nil)
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, "", s.signupStatusOf(roleID, c))
+	assert.Equal(t, signupWaitlisted, s.signupStatusOf(roleID, b))
+
+	code, resp = s.call(http.MethodDelete, signup, a, nil)
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, "", s.signupStatusOf(roleID, a))
+	assert.Equal(t, signupConfirmed, s.signupStatusOf(roleID, b), "first on the waitlist moves up")
+
+	code, _ = s.call(http.MethodDelete, signup, a, nil)
+	assert.Equal(t, http.StatusNotFound, code, "withdrawing twice")
+}
+
+func TestRoles_PromotionAwaitsApproval(t *testing.T) {
+	s := newTestServer(t)
+	organizer := s.user("Organizer")
+	eventID := s.event(organizer)
+	roleID := s.role(eventID, organizer, 1, true)
+	signup := "/events/" + eventID + "/roles/" + roleID + "/signup"
+
+	a, b := s.user("A"), s.user("B")
+	code, resp := s.call(http.MethodPost, signup, a, gin.H{})
+	require.Equal(t, http.StatusCreated, code, resp)
+	assert.Equal(t, signupPending, resp["status"], "a pending sign-up holds the place")
+	code, resp = s.call(http.MethodPost, signup, b, gin.H{})
+	require.Equal(t, http.StatusCreated, code, resp)
+	assert.Equal(t, signupWaitlisted, resp["status"])
+
+	code, resp = s.call(http.MethodPut, "/events/"+eventID+"/roles/"+roleID+"/signups/"+b, organizer, gin.H{"status": "confirmed"})
+	assert.Equal(t, http.StatusConflict, code, "confirming past capacity: %v", resp)
+
+	code, resp = s.call(http.MethodPut, "/events/"+eventID+"/roles/"+roleID+"/signups/"+a, organizer, gin.H{"status": "declined"})
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, signupDeclined, s.signupStatusOf(roleID, a))
+	assert.Equal(t, signupPending, s.signupStatusOf(roleID, b), "promoted, still for approval")
+
+	code, _ = s.call(http.MethodPost, signup, a, gin.H{})
+	assert.Equal(t, http.StatusForbidden, code, "declined sign-ups can't sign up again")
+	code, _ = s.call(http.MethodDelete, signup, a, nil)
+	assert.Equal(t, http.StatusNotFound, code, "declined sign-ups stay on record")
+}
+
+func TestRoles_RaisingCapacityPromotesWaitlist(t *testing.T) {
+	s := newTestServer(t)
+	organizer := s.user("Organizer")
+	eventID := s.event(organizer)
+	roleID := s.role(eventID, organizer, 1, false)
+	signup := "/events/" + eventID + "/roles/" + roleID + "/signup"
+
+	a, b, c := s.user("A"), s.user("B"), s.user("C")
+	for _, u := range []string{a, b, c} {
+		code, resp := s.call(http.MethodPost, signup, u, gin.H{})
+		require.Equal(t, http.StatusCreated, code, resp)
+	}
+
+	code, resp := s.call(http.MethodPut, "/events/"+eventID+"/roles/"+roleID, organizer, gin.H{"capacity": 2})
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, signupConfirmed, s.signupStatusOf(roleID, b))
+	assert.Equal(t, signupWaitlisted, s.signupStatusOf(roleID, c))
+
+	// Lowering it keeps everyone's place
+	code, resp = s.call(http.MethodPut, "/events/"+eventID+"/roles/"+roleID, organizer, gin.H{"capacity": 1})
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, signupConfirmed, s.signupStatusOf(roleID, a))
+	assert.Equal(t, signupConfirmed, s.signupStatusOf(roleID, b))
+}
+
+func TestRoles_OnlyOrganizersManageRoles(t *testing.T) {
+	s := newTestServer(t)
+	organizer := s.user("Organizer")
+	eventID := s.event(organizer)
+	roleID := s.role(eventID, organizer, 1, true)
+	volunteer, invited, coOrganizer := s.user("Volunteer"), s.user("Invited"), s.user("Co-organizer")
+	s.exec(`INSERT INTO event_co_organizers (event_id, user_id, status) VALUES ($1, $2, 'pending'), ($1, $3, 'accepted')`,
+		eventID, invited, coOrganizer)
+
+	code, resp := s.call(http.MethodPost, "/events/"+eventID+"/roles/"+roleID+"/signup", volunteer, gin.H{})
+	require.Equal(t, http.StatusCreated, code, resp)
+
+	for _, u := range []string{volunteer, invited} {
+		code, _ = s.call(http.MethodPost, "/events/"+eventID+"/roles", u, gin.H{"name": "Medic", "capacity": 2})
+		assert.Equal(t, http.StatusForbidden, code)
+		code, _ = s.call(http.MethodPut, "/events/"+eventID+"/roles/"+roleID, u, gin.H{"capacity": 5})
+		assert.Equal(t, http.StatusForbidden, code)
+		code, _ = s.call(http.MethodDelete, "/events/"+eventID+"/roles/"+roleID, u, nil)
+		assert.Equal(t, http.StatusForbidden, code)
+		code, _ = s.call(http.MethodPut, "/events/"+eventID+"/roles/"+roleID+"/signups/"+volunteer, u, gin.H{"status": "confirmed"})
+		assert.Equal(t, http.StatusForbidden, code)
+		code, _ = s.call(http.MethodGet, "/events/"+eventID+"/roster", u, nil)
+		assert.Equal(t, http.StatusForbidden, code)
+	}
+	assert.Equal(t, signupPending, s.signupStatusOf(roleID, volunteer))
+
+	code, resp = s.call(http.MethodPut, "/events/"+eventID+"/roles/"+roleID+"/signups/"+volunteer, coOrganizer, gin.H{"status": "confirmed"})
+	require.Equal(t, http.StatusOK, code, resp)
+	code, resp = s.call(http.MethodGet, "/events/"+eventID+"/roster", coOrganizer, nil)
+	require.Equal(t, http.StatusOK, code, resp)
+	roster := resp["roles"].([]any)
+	require.Len(t, roster, 1)
+	signups := roster[0].(map[string]any)["signups"].([]any)
+	require.Len(t, signups, 1)
+	assert.Equal(t, volunteer, signups[0].(map[string]any)["user_id"])
+	assert.Equal(t, signupConfirmed, signups[0].(map[string]any)["status"])
+
+	code, resp = s.call(http.MethodPost, "/events/"+eventID+"/roles", coOrganizer, gin.H{"name": "Marshal", "capacity": 2})
+	assert.Equal(t, http.StatusConflict, code, "role names are unique per event: %v", resp)
+	code, resp = s.call(http.MethodDelete, "/events/"+eventID+"/roles/"+roleID, coOrganizer, nil)
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.Equal(t, "", s.signupStatusOf(roleID, volunteer), "sign-ups go with the role")
+}
+
+func TestRoles_RolesChannelFollowsConfirmedHolders(t *testing.T) {
+	s := newTestServer(t)
+	organizer := s.user("Organizer")
+	eventID := s.event(organizer)
+	roleID := s.role(eventID, organizer, 1, false)
+	signup := "/events/" + eventID + "/roles/" + roleID + "/signup"
+
+	var channelID string
+	require.NoError(t, s.pool.QueryRow(context.Background(), `
+		WITH org AS (INSERT INTO organizations (name, created_by) VALUES ('Org', $1) RETURNING id)
+		INSERT INTO channels (org_id, name, type, event_id, created_by)
+		SELECT id, 'Crew: Rally', 'event', $2, $1 FROM org
+		RETURNING id
+	`, organizer, eventID).Scan(&channelID))
+	s.exec(`UPDATE events SET roles_channel_id = $1 WHERE id = $2`, channelID, eventID)
+	s.exec(`INSERT INTO channel_members (channel_id, user_id, role) VALUES ($1, $2, 'admin')`, channelID, organizer)
+
+	members := func() []string {
+		t.Helper()
+		rows, err := s.pool.Query(context.Background(), `
+			SELECT user_id::text FROM channel_members WHERE channel_id = $1 ORDER BY user_id
+		`, channelID)
+		require.NoError(t, err)
+		defer rows.Close()
+		var ids []string
+		for rows.Next() {
+			var id string
+			require.NoError(t, rows.Scan(&id))
+			ids = append(ids, id)
+		}
+		require.NoError(t, rows.Err())
+		return ids
+	}
+
+	a, b := s.user("A"), s.user("B")
+	for _, u := range []string{a, b} {
+		code, resp := s.call(http.MethodPost, signup, u, gin.H{})
+		require.Equal(t, http.StatusCreated, code, resp)
+	}
+	assert.ElementsMatch(t, []string{organizer, a}, members(), "waitlisted sign-ups aren't in the crew")
+
+	code, resp := s.call(http.MethodDelete, signup, a, nil)
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.ElementsMatch(t, []string{organizer, b}, members(), "the promoted sign-up joins, the withdrawn one leaves")
+
+	code, resp = s.call(http.MethodDelete, "/events/"+eventID+"/roles/"+roleID, organizer, nil)
+	require.Equal(t, http.StatusOK, code, resp)
+	assert.ElementsMatch(t, []string{organizer}, members(), "admins stay when no one holds a role")
+}
@@ -0,0 +1,16 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignupStatus(t *testing.T) {
+	assert.Equal(t, signupConfirmed, signupStatus(3, 0, false))
+	assert.Equal(t, signupConfirmed, signupStatus(3, 2, false))
+	assert.Equal(t, signupPending, signupStatus(3, 2, true))
+	assert.Equal(t, signupWaitlisted, signupStatus(3, 3, false))
+	assert.Equal(t, signupWaitlisted, signupStatus(3, 3, true), "full roles waitlist before approval")
+	assert.Equal(t, signupWaitlisted, signupStatus(2, 3, false), "capacity lowered below the places held")
+}
@@ -0,0 +1,713 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/kuurier/server/internal/api/types"
+)
+
+// SchemaVersion is bumped whenever a field is removed or changes
+// meaning in types.DataExportArchive. Adding fields does not bump it.
+const SchemaVersion = 1
+
+// signingKeyInfo labels the key archives are signed with, derived from
+// the server secret so it is never the key that signs tokens.
+const signingKeyInfo = "kuurier data export signature v1"
+
+// signingKey derives the archive signing key from secret.
+func signingKey(secret []byte) ([]byte, error) {
+	return hkdf.Key(sha256.New, secret, nil, signingKeyInfo, sha256.Size)
+}
+
+// signArchive returns the hex SHA-256 digest and hex HMAC-SHA256
+// signature of data. The key is a server secret, so only the server
+// can vouch for an archive; the digest is enough for offline integrity.
+func signArchive(key, data []byte) (digest, signature string) {
+	sum := sha256.Sum256(data)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(sum[:]), hex.EncodeToString(mac.Sum(nil))
+}
+
+// packArchive serializes the archive and wraps it in a zip with
+// data.json and signature.json. Returns the zip bytes and the
+// signature block that was written into it.
+func packArchive(key []byte, userID string, archive *types.DataExportArchive) ([]byte, types.DataExportSignature, error) {
+	data, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return nil, types.DataExportSignature{}, fmt.Errorf("marshal archive: %w", err)
+	}
+	digest, signature := signArchive(key, data)
+	sig := types.DataExportSignature{
+		Algorithm:   "HMAC-SHA256",
+		ExportID:    archive.ExportID,
+		UserID:      userID,
+		GeneratedAt: archive.GeneratedAt,
+		SHA256:      digest,
+		Signature:   signature,
+	}
+	sigJSON, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return nil, sig, fmt.Errorf("marshal signature: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []struct {
+		name string
+		body []byte
+	}{
+		{"data.json", data},
+		{"signature.json", sigJSON},
+	} {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:     f.name,
+			Method:   zip.Deflate,
+			Modified: archive.GeneratedAt,
+		})
+		if err != nil {
+			return nil, sig, err
+		}
+		if _, err := w.Write(f.body); err != nil {
+			return nil, sig, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, sig, err
+	}
+	return buf.Bytes(), sig, nil
+}
+
+// buildArchive collects everything the server stores about userID.
+// Each section is its own query so a schema change in one table only
+// breaks one collector.
+func (e *Exporter) buildArchive(ctx context.Context, exportID, userID string) (*types.DataExportArchive, error) {
+	a := &types.DataExportArchive{
+		SchemaVersion:   SchemaVersion,
+		ExportID:        exportID,
+		GeneratedAt:     time.Now().UTC(),
+		VouchesGiven:    []types.ExportVouch{},
+		VouchesReceived: []types.ExportVouch{},
+		Invites:         []types.ExportInvite{},
+		Subscriptions:   []types.ExportSubscription{},
+		Digests:         []types.ExportDigest{},
+		DigestRuns:      []types.ExportDigestRun{},
+		Mutes:           []types.ExportMute{},
+		Blocks:          []types.ExportBlock{},
+		TopicProposals:  []types.ExportTopicProposal{},
+		Posts:           []types.ExportPost{},
+		Replies:         []types.ExportReply{},
+		Events:          []types.ExportEvent{},
+		RSVPs:           []types.ExportRSVP{},
+		Announcements:   []types.ExportAnnouncement{},
+		RoleSignups:     []types.ExportRoleSignup{},
+		CoOrganizing:    []types.ExportCoOrganizer{},
+		Alerts:          []types.ExportAlert{},
+		AlertResponses:  []types.ExportAlertResponse{},
+		SafetyTimers:    []types.ExportSafetyTimer{},
+		Devices:         []types.ExportDevice{},
+		PushTokens:      []types.ExportPushToken{},
+		CalendarFeeds:   []types.ExportCalendarFeed{},
+		Messages:        []types.ExportMessage{},
+	}
+
+	collectors := []struct {
+		name string
+		fn   func(context.Context, string, *types.DataExportArchive) error
+	}{
+		{"account", e.collectAccount},
+		{"vouches", e.collectVouches},
+		{"invites", e.collectInvites},
+		{"subscriptions", e.collectSubscriptions},
+		{"digests", e.collectDigests},
+		{"mutes", e.collectMutes},
+		{"topic_proposals", e.collectTopicProposals},
+		{"posts", e.collectPosts},
+		{"replies", e.collectReplies},
+		{"events", e.collectEvents},
+		{"rsvps", e.collectRSVPs},
+		{"event_announcements", e.collectAnnouncements},
+		{"event_role_signups", e.collectRoleSignups},
+		{"event_co_organizing", e.collectCoOrganizing},
+		{"alerts", e.collectAlerts},
+		{"alert_responses", e.collectAlertResponses},
+		{"safety_timers", e.collectSafetyTimers},
+		{"devices", e.collectDevices},
+		{"push_tokens", e.collectPushTokens},
+		{"calendar_feeds", e.collectCalendarFeeds},
+		{"messages", e.collectMessages},
+	}
+	for _, c := range collectors {
+		if err := c.fn(ctx, userID, a); err != nil {
+			return nil, fmt.Errorf("collect %s: %w", c.name, err)
+		}
+	}
+	return a, nil
+}
+
+func (e *Exporter) collectAccount(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	var publicKey []byte
+	acc := &a.Account
+	err := e.db.Pool().QueryRow(ctx, `
+		SELECT id, public_key, display_name, trust_score, is_verified, is_admin,
+		       invited_by, invite_code_used, skills, created_at, last_active_at
+		FROM users WHERE id = $1
+	`, userID).Scan(
+		&acc.ID, &publicKey, &acc.DisplayName, &acc.TrustScore, &acc.IsVerified, &acc.IsAdmin,
+		&acc.InvitedBy, &acc.InviteCodeUsed, &acc.Skills, &acc.CreatedAt, &acc.LastActiveAt,
+	)
+	if err != nil {
+		return err
+	}
+	acc.PublicKey = base64.StdEncoding.EncodeToString(publicKey)
+	return nil
+}
+
+func (e *Exporter) collectVouches(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT voucher_id = $1 AS given,
+		       CASE WHEN voucher_id = $1 THEN vouchee_id ELSE voucher_id END,
+		       vouch_type, created_at
+		FROM vouches
+		WHERE voucher_id = $1 OR vouchee_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var given bool
+		var v types.ExportVouch
+		if err := rows.Scan(&given, &v.UserID, &v.VouchType, &v.CreatedAt); err != nil {
+			return err
+		}
+		if given {
+			a.VouchesGiven = append(a.VouchesGiven, v)
+		} else {
+			a.VouchesReceived = append(a.VouchesReceived, v)
+		}
+	}
+	return rows.Err()
+}
+
+func (e *Exporter) collectInvites(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT code, invitee_id, created_at, expires_at, used_at
+		FROM invite_codes WHERE inviter_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.Invites, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportInvite, error) {
+		var inv types.ExportInvite
+		err := row.Scan(&inv.Code, &inv.InviteeID, &inv.CreatedAt, &inv.ExpiresAt, &inv.UsedAt)
+		return inv, err
+	})
+	return err
+}
+
+func (e *Exporter) collectSubscriptions(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT s.id, t.slug,
+		       ST_Y(s.location::geometry), ST_X(s.location::geometry),
+		       s.radius_meters, s.min_urgency, s.digest_mode, s.is_active, s.created_at
+		FROM subscriptions s
+		LEFT JOIN topics t ON t.id = s.topic_id
+		WHERE s.user_id = $1
+		ORDER BY s.created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.Subscriptions, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportSubscription, error) {
+		var s types.ExportSubscription
+		var lat, lon *float64
+		err := row.Scan(&s.ID, &s.TopicSlug, &lat, &lon,
+			&s.RadiusMeters, &s.MinUrgency, &s.DigestMode, &s.IsActive, &s.CreatedAt)
+		s.Location = latLng(lat, lon)
+		return s, err
+	})
+	return err
+}
+
+func (e *Exporter) collectDigests(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT id, digest_mode, period_start, period_end,
+		       post_ids::text[], event_ids::text[], created_at, read_at
+		FROM digests
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.Digests, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportDigest, error) {
+		var d types.ExportDigest
+		err := row.Scan(&d.ID, &d.DigestMode, &d.PeriodStart, &d.PeriodEnd,
+			&d.PostIDs, &d.EventIDs, &d.CreatedAt, &d.ReadAt)
+		return d, err
+	})
+	if err != nil {
+		return err
+	}
+
+	rows, err = e.db.Pool().Query(ctx, `
+		SELECT digest_mode, last_run_at FROM digest_runs WHERE user_id = $1 ORDER BY digest_mode
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.DigestRuns, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportDigestRun, error) {
+		var r types.ExportDigestRun
+		err := row.Scan(&r.DigestMode, &r.LastRunAt)
+		return r, err
+	})
+	return err
+}
+
+func (e *Exporter) collectMutes(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT mute_type, value, expires_at, created_at
+		FROM user_mutes WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.Mutes, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportMute, error) {
+		var m types.ExportMute
+		err := row.Scan(&m.Type, &m.Value, &m.ExpiresAt, &m.CreatedAt)
+		return m, err
+	})
+	if err != nil {
+		return err
+	}
+
+	rows, err = e.db.Pool().Query(ctx, `
+		SELECT blocked_id, created_at
+		FROM user_blocks WHERE blocker_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.Blocks, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportBlock, error) {
+		var b types.ExportBlock
+		err := row.Scan(&b.UserID, &b.CreatedAt)
+		return b, err
+	})
+	return err
+}
+
+func (e *Exporter) collectTopicProposals(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT p.id, p.slug, p.name, p.status, p.proposer_id IS NOT DISTINCT FROM $1::uuid, e.created_at, p.created_at
+		FROM topic_proposals p
+		LEFT JOIN topic_proposal_endorsements e ON e.proposal_id = p.id AND e.user_id = $1
+		WHERE p.proposer_id = $1 OR e.user_id IS NOT NULL
+		ORDER BY p.created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.TopicProposals, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportTopicProposal, error) {
+		var p types.ExportTopicProposal
+		err := row.Scan(&p.ID, &p.Slug, &p.Name, &p.Status, &p.Proposed, &p.EndorsedAt, &p.CreatedAt)
+		return p, err
+	})
+	return err
+}
+
+func (e *Exporter) collectPosts(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT p.id, p.content, p.source_type, p.urgency,
+		       ST_Y(p.location::geometry), ST_X(p.location::geometry),
+		       p.location_name, p.verification_score, p.is_flagged, p.created_at, p.expires_at,
+		       p.edited_at,
+		       COALESCE(ARRAY(
+		           SELECT t.slug FROM post_topics pt JOIN topics t ON t.id = pt.topic_id
+		           WHERE pt.post_id = p.id ORDER BY t.slug
+		       ), '{}')
+		FROM posts p
+		WHERE p.author_id = $1
+		ORDER BY p.created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.Posts, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportPost, error) {
+		var p types.ExportPost
+		var lat, lon *float64
+		err := row.Scan(&p.ID, &p.Content, &p.SourceType, &p.Urgency, &lat, &lon,
+			&p.LocationName, &p.VerificationScore, &p.IsFlagged, &p.CreatedAt, &p.ExpiresAt,
+			&p.EditedAt, &p.Topics)
+		p.Location = latLng(lat, lon)
+		p.Media = []types.Media{}
+		p.Revisions = []types.ExportPostRevision{}
+		return p, err
+	})
+	if err != nil || len(a.Posts) == 0 {
+		return err
+	}
+
+	index := make(map[string]int, len(a.Posts))
+	for i, p := range a.Posts {
+		index[p.ID] = i
+	}
+	rows, err = e.db.Pool().Query(ctx, `
+		SELECT pm.post_id, pm.id, pm.media_url, pm.media_type, pm.created_at
+		FROM post_media pm
+		JOIN posts p ON p.id = pm.post_id
+		WHERE p.author_id = $1
+		ORDER BY pm.created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var postID string
+		var m types.Media
+		if err := rows.Scan(&postID, &m.ID, &m.URL, &m.Type, &m.CreatedAt); err != nil {
+			return err
+		}
+		if i, ok := index[postID]; ok {
+			a.Posts[i].Media = append(a.Posts[i].Media, m)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = e.db.Pool().Query(ctx, `
+		SELECT pr.post_id, pr.revision, pr.content,
+		       ST_Y(pr.location::geometry), ST_X(pr.location::geometry),
+		       pr.location_name, pr.urgency, pr.created_at, pr.replaced_at
+		FROM post_revisions pr
+		JOIN posts p ON p.id = pr.post_id
+		WHERE p.author_id = $1
+		ORDER BY pr.post_id, pr.revision
+	`, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var postID string
+		var r types.ExportPostRevision
+		var lat, lon *float64
+		if err := rows.Scan(&postID, &r.Revision, &r.Content, &lat, &lon,
+			&r.LocationName, &r.Urgency, &r.CreatedAt, &r.ReplacedAt); err != nil {
+			return err
+		}
+		r.Location = latLng(lat, lon)
+		if i, ok := index[postID]; ok {
+			a.Posts[i].Revisions = append(a.Posts[i].Revisions, r)
+		}
+	}
+	return rows.Err()
+}
+
+func (e *Exporter) collectReplies(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT id, post_id, parent_id, content, is_firsthand, corroborates,
+		       verification_score, is_flagged, created_at, deleted_at
+		FROM post_replies WHERE author_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.Replies, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportReply, error) {
+		var r types.ExportReply
+		err := row.Scan(&r.ID, &r.PostID, &r.ParentID, &r.Content, &r.IsFirsthand, &r.Corroborates,
+			&r.VerificationScore, &r.IsFlagged, &r.CreatedAt, &r.DeletedAt)
+		return r, err
+	})
+	return err
+}
+
+func (e *Exporter) collectEvents(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT id, title, description, event_type,
+		       ST_Y(location::geometry), ST_X(location::geometry),
+		       location_name, location_area, location_visibility, location_reveal_at,
+		       starts_at, ends_at, is_cancelled, recurrence_rule, timezone, org_id,
+		       accessibility, risk_level, legal_hotline, bust_card, created_at
+		FROM events
+		WHERE organizer_id = $1
+		ORDER BY starts_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.Events, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportEvent, error) {
+		var ev types.ExportEvent
+		err := row.Scan(&ev.ID, &ev.Title, &ev.Description, &ev.EventType,
+			&ev.Location.Latitude, &ev.Location.Longitude,
+			&ev.LocationName, &ev.LocationArea, &ev.LocationVisibility, &ev.LocationRevealAt,
+			&ev.StartsAt, &ev.EndsAt, &ev.IsCancelled, &ev.Recurrence, &ev.Timezone, &ev.OrgID,
+			&ev.Accessibility, &ev.RiskLevel, &ev.LegalHotline, &ev.BustCard, &ev.CreatedAt)
+		return ev, err
+	})
+	return err
+}
+
+func (e *Exporter) collectRSVPs(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT r.event_id, e.title, r.occurrence_at, r.status, r.approval, r.created_at
+		FROM event_rsvps r
+		JOIN events e ON e.id = r.event_id
+		WHERE r.user_id = $1
+		ORDER BY r.created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.RSVPs, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportRSVP, error) {
+		var r types.ExportRSVP
+		err := row.Scan(&r.EventID, &r.EventTitle, &r.OccurrenceAt, &r.Status, &r.Approval, &r.CreatedAt)
+		return r, err
+	})
+	return err
+}
+
+func (e *Exporter) collectRoleSignups(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT r.event_id, e.title, r.name, s.occurrence_at, s.status, s.note, s.created_at
+		FROM event_role_signups s
+		JOIN event_roles r ON r.id = s.role_id
+		JOIN events e ON e.id = r.event_id
+		WHERE s.user_id = $1
+		ORDER BY s.created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.RoleSignups, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportRoleSignup, error) {
+		var s types.ExportRoleSignup
+		err := row.Scan(&s.EventID, &s.EventTitle, &s.Role, &s.OccurrenceAt, &s.Status, &s.Note, &s.CreatedAt)
+		return s, err
+	})
+	return err
+}
+
+func (e *Exporter) collectCoOrganizing(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT co.event_id, e.title, co.status, co.created_at, co.accepted_at
+		FROM event_co_organizers co
+		JOIN events e ON e.id = co.event_id
+		WHERE co.user_id = $1
+		ORDER BY co.created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.CoOrganizing, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportCoOrganizer, error) {
+		var co types.ExportCoOrganizer
+		err := row.Scan(&co.EventID, &co.EventTitle, &co.Status, &co.InvitedAt, &co.AcceptedAt)
+		return co, err
+	})
+	return err
+}
+
+func (e *Exporter) collectAnnouncements(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT id, event_id, occurrence_at, body, is_urgent, created_at
+		FROM event_announcements
+		WHERE author_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.Announcements, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportAnnouncement, error) {
+		var an types.ExportAnnouncement
+		err := row.Scan(&an.ID, &an.EventID, &an.OccurrenceAt, &an.Body, &an.IsUrgent, &an.CreatedAt)
+		return an, err
+	})
+	return err
+}
+
+func (e *Exporter) collectAlerts(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT id, title, description, severity,
+		       ST_Y(location::geometry), ST_X(location::geometry),
+		       location_name, radius_meters, required_skills, status, created_at, resolved_at
+		FROM alerts
+		WHERE author_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.Alerts, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportAlert, error) {
+		var al types.ExportAlert
+		err := row.Scan(&al.ID, &al.Title, &al.Description, &al.Severity,
+			&al.Location.Latitude, &al.Location.Longitude,
+			&al.LocationName, &al.RadiusMeters, &al.RequiredSkills, &al.Status, &al.CreatedAt, &al.ResolvedAt)
+		return al, err
+	})
+	return err
+}
+
+func (e *Exporter) collectAlertResponses(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT alert_id, status, eta_minutes,
+		       ST_Y(location::geometry), ST_X(location::geometry),
+		       created_at, updated_at
+		FROM alert_responses
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.AlertResponses, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportAlertResponse, error) {
+		var r types.ExportAlertResponse
+		var lat, lon *float64
+		err := row.Scan(&r.AlertID, &r.Status, &r.ETAMinutes, &lat, &lon, &r.CreatedAt, &r.UpdatedAt)
+		r.Location = latLng(lat, lon)
+		return r, err
+	})
+	return err
+}
+
+func (e *Exporter) collectSafetyTimers(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT id, deadline, status,
+		       ST_Y(last_location::geometry), ST_X(last_location::geometry),
+		       last_location_at, created_at, fired_at
+		FROM safety_timers
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.SafetyTimers, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportSafetyTimer, error) {
+		var t types.ExportSafetyTimer
+		var lat, lon *float64
+		err := row.Scan(&t.ID, &t.Deadline, &t.Status, &lat, &lon, &t.LastLocationAt, &t.CreatedAt, &t.FiredAt)
+		t.LastLocation = latLng(lat, lon)
+		t.Contacts = []types.ExportSafetyTimerContact{}
+		return t, err
+	})
+	if err != nil {
+		return err
+	}
+
+	for i := range a.SafetyTimers {
+		t := &a.SafetyTimers[i]
+		rows, err := e.db.Pool().Query(ctx, `
+			SELECT contact_id, encrypted_note FROM safety_timer_contacts
+			WHERE timer_id = $1
+			ORDER BY contact_id
+		`, t.ID)
+		if err != nil {
+			return err
+		}
+		t.Contacts, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportSafetyTimerContact, error) {
+			var c types.ExportSafetyTimerContact
+			err := row.Scan(&c.UserID, &c.EncryptedNote)
+			return c, err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Exporter) collectDevices(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT id, device_type, device_name, COALESCE(is_active, false), created_at, last_active_at
+		FROM devices
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.Devices, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportDevice, error) {
+		var d types.ExportDevice
+		err := row.Scan(&d.ID, &d.DeviceType, &d.DeviceName, &d.IsActive, &d.CreatedAt, &d.LastActiveAt)
+		return d, err
+	})
+	return err
+}
+
+func (e *Exporter) collectPushTokens(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT id, platform, RIGHT(token, 6), created_at
+		FROM push_tokens
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.PushTokens, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportPushToken, error) {
+		var t types.ExportPushToken
+		err := row.Scan(&t.ID, &t.Platform, &t.TokenSuffix, &t.CreatedAt)
+		return t, err
+	})
+	return err
+}
+
+func (e *Exporter) collectCalendarFeeds(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT id, feed_type, target_id, created_at, last_used_at
+		FROM calendar_feeds
+		WHERE user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.CalendarFeeds, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportCalendarFeed, error) {
+		var f types.ExportCalendarFeed
+		err := row.Scan(&f.ID, &f.Type, &f.TargetID, &f.CreatedAt, &f.LastUsedAt)
+		return f, err
+	})
+	return err
+}
+
+func (e *Exporter) collectMessages(ctx context.Context, userID string, a *types.DataExportArchive) error {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT id, channel_id, message_type, ciphertext, reply_to_id,
+		       created_at, edited_at, deleted_at
+		FROM messages
+		WHERE sender_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return err
+	}
+	a.Messages, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (types.ExportMessage, error) {
+		var m types.ExportMessage
+		err := row.Scan(&m.ID, &m.ChannelID, &m.MessageType, &m.Ciphertext, &m.ReplyToID,
+			&m.CreatedAt, &m.EditedAt, &m.DeletedAt)
+		return m, err
+	})
+	return err
+}
+
+func latLng(lat, lon *float64) *types.LatLng {
+	if lat == nil || lon == nil {
+		return nil
+	}
+	return &types.LatLng{Latitude: *lat, Longitude: *lon}
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/kuurier/server/internal/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignArchive_DigestAndHMAC(t *testing.T) {
+	key := []byte("test-key-that-is-at-least-32-bytes!!")
+	data := []byte(`{"schema_version":1}`)
+
+	digest, signature := signArchive(key, data)
+
+	sum := sha256.Sum256(data)
+	assert.Equal(t, hex.EncodeToString(sum[:]), digest)
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), signature)
+
+	// Different key, different signature; digest is key-independent.
+	otherDigest, otherSig := signArchive([]byte("another-key-that-is-32-bytes-long!!"), data)
+	assert.Equal(t, digest, otherDigest)
+	assert.NotEqual(t, signature, otherSig)
+}
+
+func TestSigningKey_DerivedFromSecret(t *testing.T) {
+	secret := []byte("test-secret-that-is-at-least-32-bytes")
+
+	key, err := signingKey(secret)
+	require.NoError(t, err)
+	assert.Len(t, key, sha256.Size)
+	assert.NotEqual(t, secret, key, "archives aren't signed with the token secret")
+
+	again, err := signingKey(secret)
+	require.NoError(t, err)
+	assert.Equal(t, key, again, "stable, so signatures can be checked later")
+
+	other, err := signingKey([]byte("another-secret-that-is-32-bytes-long"))
+	require.NoError(t, err)
+	assert.NotEqual(t, key, other)
+}
+
+func TestPackArchive_SignatureCoversDataJSON(t *testing.T) {
+	key := []byte("test-key-that-is-at-least-32-bytes!!")
+	archive := &types.DataExportArchive{
+		SchemaVersion: SchemaVersion,
+		ExportID:      "export-1",
+		GeneratedAt:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Account:       types.ExportAccount{ID: "user-1"},
+		Messages: []types.ExportMessage{
+			{ID: "m1", Ciphertext: []byte{0x01, 0x02, 0xff}},
+		},
+	}
+
+	zipped, sig, err := packArchive(key, "user-1", archive)
+	require.NoError(t, err)
+
+	zr, err := zip.NewReader(bytes.NewReader(zipped), int64(len(zipped)))
+	require.NoError(t, err)
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		body, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		files[f.Name] = body
+	}
+	require.Contains(t, files, "data.json")
+	require.Contains(t, files, "signature.json")
+
+	digest, signature := signArchive(key, files["data.json"])
+	assert.Equal(t, digest, sig.SHA256)
+	assert.Equal(t, signature, sig.Signature)
+
+	var onDisk types.DataExportSignature
+	require.NoError(t, json.Unmarshal(files["signature.json"], &onDisk))
+	assert.Equal(t, sig, onDisk)
+	assert.Equal(t, "HMAC-SHA256", onDisk.Algorithm)
+	assert.Equal(t, "user-1", onDisk.UserID)
+
+	// Ciphertext round-trips byte-for-byte.
+	var decoded types.DataExportArchive
+	require.NoError(t, json.Unmarshal(files["data.json"], &decoded))
+	require.Len(t, decoded.Messages, 1)
+	assert.Equal(t, []byte{0x01, 0x02, 0xff}, decoded.Messages[0].Ciphertext)
+}
@@ -0,0 +1,218 @@
+// Package export builds personal data export archives.
+//
+// Flow:
+//  1. POST /me/export inserts a 'pending' row in data_exports.
+//  2. The worker's Exporter.RunOnce claims pending rows, collects
+//     everything the server holds about the user, signs it, and
+//     uploads a zip under a random key to the export bucket, which
+//     has no public access.
+//  3. POST /me/export/:id/download streams the zip exactly once and
+//     deletes it. Archives never downloaded are deleted by the expiry
+//     sweep after ArchiveTTL.
+//
+// Message bodies are exported as stored: ciphertext. The server has
+// no way to decrypt them and the export doesn't pretend otherwise.
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/storage"
+)
+
+const (
+	// ArchiveTTL is how long a built archive waits for its download.
+	ArchiveTTL = 7 * 24 * time.Hour
+
+	// claimBatch bounds how many exports one RunOnce pass builds.
+	// Archives for heavy users can be large; keep passes short.
+	claimBatch = 5
+
+	// stuckAfter reclaims rows left in 'processing' by a worker that
+	// died mid-build.
+	stuckAfter = 30 * time.Minute
+)
+
+// Exporter is the worker-side job that builds pending exports.
+type Exporter struct {
+	cfg   *config.Config
+	db    *storage.Postgres
+	minio *storage.MinIO
+}
+
+// NewExporter creates a new export job.
+func NewExporter(cfg *config.Config, db *storage.Postgres, minio *storage.MinIO) *Exporter {
+	return &Exporter{cfg: cfg, db: db, minio: minio}
+}
+
+// RunOnce builds up to claimBatch pending exports and then removes
+// archives that have expired or whose download was interrupted.
+func (e *Exporter) RunOnce(ctx context.Context) error {
+	jobs, err := e.claim(ctx)
+	if err != nil {
+		return fmt.Errorf("claim exports: %w", err)
+	}
+
+	built := 0
+	for _, job := range jobs {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := e.build(ctx, job.id, job.userID); err != nil {
+			// Per-export failures are recorded on the row and surfaced
+			// to the user through the status endpoint.
+			slog.WarnContext(ctx, "data export failed",
+				slog.String("export_id", job.id),
+				slog.String("error", err.Error()))
+			_, _ = e.db.Pool().Exec(ctx, `
+				UPDATE data_exports SET status = 'failed', error = $2, completed_at = NOW()
+				WHERE id = $1
+			`, job.id, err.Error())
+			continue
+		}
+		built++
+	}
+
+	expired, err := e.sweepExpired(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "data export sweep failed", slog.String("error", err.Error()))
+	}
+	if built > 0 || expired > 0 {
+		slog.InfoContext(ctx, "data export pass complete",
+			slog.Int("built", built),
+			slog.Int("expired", expired))
+	}
+	return nil
+}
+
+type exportJob struct {
+	id     string
+	userID string
+}
+
+// claim moves a batch of pending (or stuck) exports to 'processing'.
+// SKIP LOCKED lets several workers run the job without double-building.
+func (e *Exporter) claim(ctx context.Context) ([]exportJob, error) {
+	rows, err := e.db.Pool().Query(ctx, `
+		UPDATE data_exports SET status = 'processing', started_at = NOW()
+		WHERE id IN (
+			SELECT id FROM data_exports
+			WHERE status = 'pending'
+			   OR (status = 'processing' AND started_at < NOW() - $1::interval)
+			ORDER BY created_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, user_id
+	`, fmt.Sprintf("%d seconds", int(stuckAfter.Seconds())), claimBatch)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []exportJob
+	for rows.Next() {
+		var j exportJob
+		if err := rows.Scan(&j.id, &j.userID); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+func (e *Exporter) build(ctx context.Context, exportID, userID string) error {
+	archive, err := e.buildArchive(ctx, exportID, userID)
+	if err != nil {
+		return err
+	}
+	key, err := signingKey(e.cfg.JWTSecret)
+	if err != nil {
+		return err
+	}
+	zipped, sig, err := packArchive(key, userID, archive)
+	if err != nil {
+		return err
+	}
+
+	// The export bucket is private, but don't make the key guessable
+	// from the export ID either.
+	objectName, err := randomObjectName()
+	if err != nil {
+		return err
+	}
+	if _, err := e.minio.UploadFile(ctx, objectName, bytes.NewReader(zipped), int64(len(zipped)), "application/zip"); err != nil {
+		return err
+	}
+
+	_, err = e.db.Pool().Exec(ctx, `
+		UPDATE data_exports
+		SET status = 'ready', object_name = $2, size_bytes = $3, sha256 = $4, signature = $5,
+		    completed_at = NOW(), expires_at = NOW() + $6::interval, error = NULL
+		WHERE id = $1
+	`, exportID, objectName, len(zipped), sig.SHA256, sig.Signature,
+		fmt.Sprintf("%d seconds", int(ArchiveTTL.Seconds())))
+	if err != nil {
+		// Don't leave an orphaned archive behind if we couldn't record it.
+		_ = e.minio.DeleteFile(ctx, objectName)
+		return err
+	}
+	return nil
+}
+
+// sweepExpired deletes archives past expires_at. Downloading an
+// archive sets expires_at to when it started, so this also cleans up
+// after downloads that never finished.
+func (e *Exporter) sweepExpired(ctx context.Context) (int, error) {
+	rows, err := e.db.Pool().Query(ctx, `
+		SELECT id, object_name FROM data_exports
+		WHERE status = 'ready' AND expires_at < NOW()
+		LIMIT 100
+	`)
+	if err != nil {
+		return 0, err
+	}
+	type expiredExport struct{ id, objectName string }
+	var expired []expiredExport
+	for rows.Next() {
+		var x expiredExport
+		if err := rows.Scan(&x.id, &x.objectName); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		expired = append(expired, x)
+	}
+	rows.Close()
+
+	n := 0
+	for _, x := range expired {
+		if err := e.minio.DeleteFile(ctx, x.objectName); err != nil {
+			slog.WarnContext(ctx, "delete export archive failed",
+				slog.String("export_id", x.id),
+				slog.String("error", err.Error()))
+			continue
+		}
+		if _, err := e.db.Pool().Exec(ctx, `
+			UPDATE data_exports SET status = 'expired', object_name = NULL WHERE id = $1
+		`, x.id); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func randomObjectName() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "exports/" + hex.EncodeToString(b) + ".zip", nil
+}
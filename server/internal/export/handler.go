@@ -0,0 +1,211 @@
+package export
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kuurier/server/internal/api/types"
+	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/storage"
+)
+
+// requestCooldown limits how often a user can request a new export.
+// Building one touches every user-owned table, so it's not free.
+const requestCooldown = 24 * time.Hour
+
+// Handler handles personal data export endpoints
+type Handler struct {
+	cfg   *config.Config
+	db    *storage.Postgres
+	minio *storage.MinIO
+}
+
+// NewHandler creates a new export handler
+func NewHandler(cfg *config.Config, db *storage.Postgres, minio *storage.MinIO) *Handler {
+	return &Handler{cfg: cfg, db: db, minio: minio}
+}
+
+// RequestExport queues a new data export for the current user
+func (h *Handler) RequestExport(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ctx := c.Request.Context()
+
+	// One in-flight export at a time, and at most one per cooldown.
+	var recent int
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT COUNT(*) FROM data_exports
+		WHERE user_id = $1
+		  AND (status IN ('pending', 'processing')
+		       OR (status <> 'failed' AND created_at > NOW() - $2::interval))
+	`, userID, fmt.Sprintf("%d seconds", int(requestCooldown.Seconds()))).Scan(&recent)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check existing exports"})
+		return
+	}
+	if recent > 0 {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "an export was requested recently; try again later"})
+		return
+	}
+
+	var status types.DataExportStatus
+	err = h.db.Pool().QueryRow(ctx, `
+		INSERT INTO data_exports (user_id) VALUES ($1)
+		RETURNING id, status, created_at
+	`, userID).Scan(&status.ID, &status.Status, &status.CreatedAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to request export"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, status)
+}
+
+// ListExports returns the current user's recent export requests
+func (h *Handler) ListExports(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	rows, err := h.db.Pool().Query(c.Request.Context(), `
+		SELECT id, status, created_at, completed_at, expires_at, downloaded_at,
+		       COALESCE(size_bytes, 0), COALESCE(sha256, '')
+		FROM data_exports
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 20
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch exports"})
+		return
+	}
+	defer rows.Close()
+
+	exports := []types.DataExportStatus{}
+	for rows.Next() {
+		var s types.DataExportStatus
+		if err := rows.Scan(&s.ID, &s.Status, &s.CreatedAt, &s.CompletedAt, &s.ExpiresAt,
+			&s.DownloadedAt, &s.SizeBytes, &s.SHA256); err != nil {
+			continue
+		}
+		exports = append(exports, s)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"exports": exports})
+}
+
+// GetExport returns the status of one export
+func (h *Handler) GetExport(c *gin.Context) {
+	status, err := h.getStatus(c.Request.Context(), c.Param("id"), c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "export not found"})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// DownloadExport streams a ready export's archive, once. The first
+// call that completes wins and deletes the archive; later calls get
+// 410 Gone. The archive is never reachable by URL.
+func (h *Handler) DownloadExport(c *gin.Context) {
+	userID := c.GetString("user_id")
+	exportID := c.Param("id")
+	ctx := c.Request.Context()
+
+	// Mark consumed in one statement, so two concurrent calls can't
+	// both succeed. If this handler dies mid-download, the expiry
+	// sweep removes the archive.
+	var objectName, sha string
+	err := h.db.Pool().QueryRow(ctx, `
+		UPDATE data_exports
+		SET downloaded_at = NOW(), expires_at = NOW()
+		WHERE id = $1 AND user_id = $2
+		  AND status = 'ready' AND downloaded_at IS NULL AND expires_at > NOW()
+		RETURNING object_name, COALESCE(sha256, '')
+	`, exportID, userID).Scan(&objectName, &sha)
+	if err == pgx.ErrNoRows {
+		status, err := h.getStatus(ctx, exportID, userID)
+		switch {
+		case err != nil:
+			c.JSON(http.StatusNotFound, gin.H{"error": "export not found"})
+		case status.Status == "ready" || status.Status == "expired":
+			c.JSON(http.StatusGone, gin.H{"error": "export has already been downloaded or has expired"})
+		default:
+			c.JSON(http.StatusConflict, gin.H{"error": "export is not ready", "status": status.Status})
+		}
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to download export"})
+		return
+	}
+
+	archive, size, err := h.minio.GetFile(ctx, objectName)
+	if err != nil {
+		h.restoreDownload(exportID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to download export"})
+		return
+	}
+	defer archive.Close()
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="kuurier-export-%s.zip"`, exportID))
+	c.Header("Content-Length", strconv.FormatInt(size, 10))
+	c.Header("X-Export-SHA256", sha)
+	c.Header("Content-Type", "application/zip")
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+	n, err := io.Copy(c.Writer, archive)
+	if err != nil || n != size {
+		// The user didn't get the archive; give them their one shot back
+		slog.WarnContext(ctx, "data export download interrupted",
+			slog.String("export_id", exportID),
+			slog.Int64("sent", n))
+		h.restoreDownload(exportID)
+		return
+	}
+
+	// Handed out: delete it now rather than at the next sweep
+	if err := h.minio.DeleteFile(context.Background(), objectName); err != nil {
+		slog.WarnContext(ctx, "delete downloaded export archive failed",
+			slog.String("export_id", exportID),
+			slog.String("error", err.Error()))
+		return
+	}
+	if _, err := h.db.Pool().Exec(context.Background(), `
+		UPDATE data_exports SET status = 'expired', object_name = NULL WHERE id = $1
+	`, exportID); err != nil {
+		slog.WarnContext(ctx, "mark export downloaded failed",
+			slog.String("export_id", exportID),
+			slog.String("error", err.Error()))
+	}
+}
+
+// restoreDownload undoes claiming exportID's download, when nothing
+// usable was handed out. The request may be gone, so it doesn't use
+// its context.
+func (h *Handler) restoreDownload(exportID string) {
+	_, _ = h.db.Pool().Exec(context.Background(), `
+		UPDATE data_exports
+		SET downloaded_at = NULL, expires_at = completed_at + $2::interval
+		WHERE id = $1
+	`, exportID, fmt.Sprintf("%d seconds", int(ArchiveTTL.Seconds())))
+}
+
+func (h *Handler) getStatus(ctx context.Context, exportID, userID string) (*types.DataExportStatus, error) {
+	var s types.DataExportStatus
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT id, status, created_at, completed_at, expires_at, downloaded_at,
+		       COALESCE(size_bytes, 0), COALESCE(sha256, '')
+		FROM data_exports
+		WHERE id = $1 AND user_id = $2
+	`, exportID, userID).Scan(&s.ID, &s.Status, &s.CreatedAt, &s.CompletedAt, &s.ExpiresAt,
+		&s.DownloadedAt, &s.SizeBytes, &s.SHA256)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
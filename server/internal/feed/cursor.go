@@ -0,0 +1,427 @@
+// Cursor pagination for feed v2.
+//
+// limit/offset paging over a ranking that is recomputed per request
+// shows duplicates and skips items as new posts arrive and scores
+// drift. A cursor pins the page sequence to one ranking epoch:
+//
+//   - materialized: the materialized_feeds generation the first page
+//     came from, plus a (score, post_id) keyset so rows inserted
+//     incrementally into that generation don't shift later pages.
+//   - live: a hash of the candidate set. The ranked post IDs for that
+//     hash are kept in Redis for snapshotTTL; later pages read the
+//     same list instead of re-ranking.
+//   - news: a (created_at, id) keyset.
+//
+// Cursors are opaque to clients: base64url JSON plus an HMAC tag
+// bound to the requesting user, so they can't be forged or replayed
+// by someone else. Every cursor also records when its listing started,
+// which is what GET /feed/v2/new uses for pull-to-refresh.
+package feed
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	cursorVersion = 1
+
+	// snapshotTTL bounds how long a live-ranked listing stays pinned.
+	// After that, later pages are re-ranked best-effort (see GetFeedV2).
+	snapshotTTL = 30 * time.Minute
+
+	// maxCursorAge rejects cursors from long-abandoned sessions; the
+	// client should start from the top instead.
+	maxCursorAge = 24 * time.Hour
+)
+
+// Cursor sources. cursorSourceSince is only valid for /feed/v2/new.
+const (
+	cursorSourceMaterialized = "materialized"
+	cursorSourceLive         = "live"
+	cursorSourceNews         = "news"
+	cursorSourceSince        = "since"
+)
+
+var (
+	errInvalidCursor = errors.New("invalid cursor")
+	errExpiredCursor = errors.New("cursor expired")
+)
+
+// feedCursor is the signed payload behind an opaque cursor string.
+// JSON keys are short because the cursor ends up in every URL.
+type feedCursor struct {
+	Version    int      `json:"v"`
+	FeedType   FeedType `json:"ft"`
+	Params     string   `json:"p"` // requestParamsHash of the first page's query
+	Source     string   `json:"src"`
+	SnapshotAt int64    `json:"t"` // unix seconds when the listing started
+
+	// Position. Offset is always set (it also backs next_offset);
+	// the keyset fields are used by the materialized and news paths.
+	Offset     int     `json:"o,omitempty"`
+	Generation int64   `json:"g,omitempty"`
+	Snapshot   string  `json:"h,omitempty"`
+	LastScore  float64 `json:"s,omitempty"`
+	LastTime   int64   `json:"lt,omitempty"` // unix micros
+	LastID     string  `json:"id,omitempty"`
+}
+
+// encodeCursor signs c for userID and returns the opaque string.
+func encodeCursor(key []byte, userID string, c feedCursor) string {
+	c.Version = cursorVersion
+	payload, _ := json.Marshal(c)
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + base64.RawURLEncoding.EncodeToString(cursorTag(key, userID, body))
+}
+
+// decodeCursor verifies and parses a cursor issued to userID.
+func decodeCursor(key []byte, userID, s string, now time.Time) (*feedCursor, error) {
+	body, tag, ok := strings.Cut(s, ".")
+	if !ok {
+		return nil, errInvalidCursor
+	}
+	gotTag, err := base64.RawURLEncoding.DecodeString(tag)
+	if err != nil || !hmac.Equal(gotTag, cursorTag(key, userID, body)) {
+		return nil, errInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	var c feedCursor
+	if err := json.Unmarshal(payload, &c); err != nil || c.Version != cursorVersion {
+		return nil, errInvalidCursor
+	}
+	if now.Sub(time.Unix(c.SnapshotAt, 0)) > maxCursorAge {
+		return nil, errExpiredCursor
+	}
+	return &c, nil
+}
+
+func cursorTag(key []byte, userID, body string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("feed-cursor|" + userID + "|" + body))
+	return mac.Sum(nil)[:16]
+}
+
+// requestParamsHash fingerprints the query parameters that affect
+// ranking. A cursor is only valid with the parameters it was issued for.
+func requestParamsHash(lat, lon *float64, radiusMeters, minUrgency int) string {
+	var b strings.Builder
+	if lat != nil && lon != nil {
+		fmt.Fprintf(&b, "%.5f,%.5f", *lat, *lon)
+	}
+	fmt.Fprintf(&b, "|%d|%d", radiusMeters, minUrgency)
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:8])
+}
+
+// candidateSetHash identifies a live ranking epoch: the same feed
+// type, parameters and candidate IDs produce the same hash.
+func candidateSetHash(feedType FeedType, params string, candidates []postCandidate) string {
+	ids := make([]string, 0, len(candidates))
+	for _, p := range candidates {
+		ids = append(ids, p.id)
+	}
+	sort.Strings(ids)
+	h := sha256.New()
+	h.Write([]byte(string(feedType) + "|" + params))
+	for _, id := range ids {
+		h.Write([]byte("|" + id))
+	}
+	return hex.EncodeToString(h.Sum(nil)[:12])
+}
+
+// snapshotEntry is one ranked post in a cached live listing.
+type snapshotEntry struct {
+	ID    string   `json:"id"`
+	Score float64  `json:"s"`
+	Why   []string `json:"w,omitempty"`
+}
+
+func snapshotKey(userID, hash string) string {
+	return "feed:snapshot:" + userID + ":" + hash
+}
+
+func (h *Handler) storeSnapshot(ctx context.Context, userID, hash string, items []scoredFeedItem) {
+	entries := make([]snapshotEntry, 0, len(items))
+	for _, item := range items {
+		if item.post != nil {
+			entries = append(entries, snapshotEntry{ID: item.post.id, Score: item.score, Why: item.why})
+		}
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return
+	}
+	if err := h.redis.Set(ctx, snapshotKey(userID, hash), data, snapshotTTL); err != nil {
+		log.Printf("feed: store snapshot: %v", err)
+	}
+}
+
+// loadSnapshot returns the cached listing for hash, or nil if it has
+// expired.
+func (h *Handler) loadSnapshot(ctx context.Context, userID, hash string) []snapshotEntry {
+	data, err := h.redis.Get(ctx, snapshotKey(userID, hash))
+	if err != nil {
+		return nil
+	}
+	var entries []snapshotEntry
+	if err := json.Unmarshal([]byte(data), &entries); err != nil {
+		return nil
+	}
+	return entries
+}
+
+// hydrateSnapshot turns cached entries back into feed items. Posts
+// deleted or flagged since the snapshot was taken drop out.
+func (h *Handler) hydrateSnapshot(ctx context.Context, entries []snapshotEntry) ([]scoredFeedItem, error) {
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, e.ID)
+	}
+	posts, err := h.loadPostsByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*postCandidate, len(posts))
+	for i := range posts {
+		byID[posts[i].id] = &posts[i]
+	}
+	items := make([]scoredFeedItem, 0, len(entries))
+	for _, e := range entries {
+		if post, ok := byID[e.ID]; ok {
+			items = append(items, scoredFeedItem{score: e.Score, itemType: "post", post: post, why: e.Why})
+		}
+	}
+	return items, nil
+}
+
+// loadPostsByIDs fetches unflagged posts in the order of ids.
+func (h *Handler) loadPostsByIDs(ctx context.Context, ids []string) ([]postCandidate, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT p.id, p.author_id, p.content, p.source_type,
+		       ST_Y(p.location::geometry) as lat,
+		       ST_X(p.location::geometry) as lon,
+		       p.location_name, p.urgency, p.created_at, p.verification_score,
+		       COALESCE(u.trust_score, 0) AS trust_score, p.edited_at
+		FROM posts p
+		LEFT JOIN users u ON u.id = p.author_id
+		WHERE p.id = ANY($1::uuid[]) AND p.is_flagged = false
+		ORDER BY array_position($1::uuid[], p.id)
+	`, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []postCandidate
+	for rows.Next() {
+		var post postCandidate
+		if err := rows.Scan(
+			&post.id, &post.authorID, &post.content, &post.sourceType,
+			&post.latitude, &post.longitude,
+			&post.locationName, &post.urgency, &post.createdAt, &post.verificationScore,
+			&post.authorTrustScore, &post.editedAt,
+		); err != nil {
+			continue
+		}
+		posts = append(posts, post)
+	}
+	return posts, rows.Err()
+}
+
+// respondCursorError maps cursor decode errors to HTTP responses.
+func respondCursorError(c *gin.Context, err error) {
+	if errors.Is(err, errExpiredCursor) {
+		c.JSON(http.StatusGone, gin.H{"error": "cursor expired; reload the feed"})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": "invalid cursor"})
+}
+
+// GetFeedV2New returns items that arrived after the listing behind
+// cursor started — the pull-to-refresh counterpart of GetFeedV2. The
+// response carries a fresh cursor to pass on the next refresh.
+func (h *Handler) GetFeedV2New(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ctx := c.Request.Context()
+
+	q := parseFeedQuery(c)
+	cur, err := decodeCursor(h.cfg.JWTSecret, userID, c.Query("cursor"), time.Now())
+	if err != nil {
+		respondCursorError(c, err)
+		return
+	}
+	if cur.FeedType != q.feedType || cur.Params != q.params {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cursor does not match feed parameters"})
+		return
+	}
+	since := time.Unix(cur.SnapshotAt, 0)
+	now := time.Now()
+
+	var scored []scoredFeedItem
+	if q.feedType == FeedTypeNews {
+		scored, err = h.fetchNewsSince(ctx, since)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch news"})
+			return
+		}
+	} else {
+		subscriptions, topicTree, err := h.getUserSubscriptions(ctx, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load subscriptions"})
+			return
+		}
+		// Candidates come newest-first, so the new ones are a prefix.
+		candidates, err := h.fetchFeedCandidates(ctx, 1200)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch candidates"})
+			return
+		}
+		var fresh []postCandidate
+		for _, post := range candidates {
+			if post.createdAt.After(since) {
+				fresh = append(fresh, post)
+			}
+		}
+		scored = h.rankFeedCandidates(q.feedType, fresh, subscriptions, topicTree, q.lat, q.lon, q.radiusMeters, q.minUrgency, h.loadMutes(ctx, userID))
+	}
+
+	total := len(scored)
+	if len(scored) > q.limit {
+		scored = scored[:q.limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items":    h.buildFeedResponseItems(ctx, scored),
+		"count":    total,
+		"has_more": total > len(scored),
+		"cursor": encodeCursor(h.cfg.JWTSecret, userID, feedCursor{
+			FeedType:   q.feedType,
+			Params:     q.params,
+			Source:     cursorSourceSince,
+			SnapshotAt: now.Unix(),
+		}),
+	})
+}
+
+// fetchNewsSince returns news posts created after since, newest first.
+func (h *Handler) fetchNewsSince(ctx context.Context, since time.Time) ([]scoredFeedItem, error) {
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT p.id, p.author_id, p.content, p.source_type,
+		       ST_Y(p.location::geometry) as lat,
+		       ST_X(p.location::geometry) as lon,
+		       p.location_name, p.urgency, p.created_at, p.verification_score,
+		       COALESCE(u.trust_score, 0) as trust_score
+		FROM posts p
+		LEFT JOIN users u ON u.id = p.author_id
+		WHERE p.source_type = 'mainstream'
+		  AND p.is_flagged = false
+		  AND p.created_at > $1
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT 200
+	`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var scored []scoredFeedItem
+	for rows.Next() {
+		var post postCandidate
+		if err := rows.Scan(
+			&post.id, &post.authorID, &post.content, &post.sourceType,
+			&post.latitude, &post.longitude,
+			&post.locationName, &post.urgency, &post.createdAt, &post.verificationScore,
+			&post.authorTrustScore,
+		); err != nil {
+			continue
+		}
+		scored = append(scored, scoredFeedItem{
+			score:    recencyScore(post.createdAt),
+			itemType: "post",
+			post:     &post,
+			why:      []string{"News"},
+		})
+	}
+	return scored, rows.Err()
+}
+
+// feedQuery holds the parsed GetFeedV2 query parameters.
+type feedQuery struct {
+	feedType     FeedType
+	limit        int
+	offset       int
+	lat, lon     *float64
+	radiusMeters int
+	minUrgency   int
+	params       string
+}
+
+func parseFeedQuery(c *gin.Context) feedQuery {
+	q := feedQuery{
+		feedType: FeedType(c.DefaultQuery("type", string(FeedTypeForYou))),
+	}
+	q.limit, _ = strconv.Atoi(c.DefaultQuery("limit", "30"))
+	q.offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if q.limit < 1 {
+		q.limit = 1
+	}
+	if q.limit > 100 {
+		q.limit = 100
+	}
+	if q.offset < 0 {
+		q.offset = 0
+	}
+
+	if v, err := strconv.ParseFloat(c.Query("lat"), 64); err == nil {
+		q.lat = &v
+	}
+	if v, err := strconv.ParseFloat(c.Query("lon"), 64); err == nil {
+		q.lon = &v
+	}
+
+	q.radiusMeters, _ = strconv.Atoi(c.DefaultQuery("radius_m", "50000"))
+	if q.radiusMeters > 200000 {
+		q.radiusMeters = 200000
+	}
+	q.minUrgency, _ = strconv.Atoi(c.DefaultQuery("min_urgency", "0"))
+	q.params = requestParamsHash(q.lat, q.lon, q.radiusMeters, q.minUrgency)
+	return q
+}
+
+// materializable reports whether q can be answered from
+// materialized_feeds, which are ranked without a request location or
+// urgency filter. Local and crisis filter by the request location;
+// the other types only use it for scoring.
+func (q feedQuery) materializable() bool {
+	if q.minUrgency > 0 {
+		return false
+	}
+	switch q.feedType {
+	case FeedTypeForYou, FeedTypeFollowing:
+		return true
+	case FeedTypeLocal, FeedTypeCrisis:
+		return q.lat == nil || q.lon == nil
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package feed
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testCursorKey = []byte("test-secret-that-is-at-least-32-bytes")
+
+func TestCursor_RoundTrip(t *testing.T) {
+	now := time.Now()
+	in := feedCursor{
+		FeedType:   FeedTypeForYou,
+		Params:     "abc",
+		Source:     cursorSourceMaterialized,
+		SnapshotAt: now.Unix(),
+		Offset:     30,
+		Generation: 1234567,
+		LastScore:  0.42,
+		LastID:     "post-1",
+	}
+
+	s := encodeCursor(testCursorKey, "user-1", in)
+	out, err := decodeCursor(testCursorKey, "user-1", s, now)
+	require.NoError(t, err)
+
+	in.Version = cursorVersion
+	assert.Equal(t, in, *out)
+}
+
+func TestCursor_BoundToUserAndKey(t *testing.T) {
+	now := time.Now()
+	s := encodeCursor(testCursorKey, "user-1", feedCursor{FeedType: FeedTypeLocal, SnapshotAt: now.Unix()})
+
+	_, err := decodeCursor(testCursorKey, "user-2", s, now)
+	assert.ErrorIs(t, err, errInvalidCursor, "another user's cursor must not verify")
+
+	_, err = decodeCursor([]byte("a-different-secret-of-32-bytes-or-more"), "user-1", s, now)
+	assert.ErrorIs(t, err, errInvalidCursor)
+}
+
+func TestCursor_RejectsTampering(t *testing.T) {
+	now := time.Now()
+	s := encodeCursor(testCursorKey, "user-1", feedCursor{FeedType: FeedTypeLocal, SnapshotAt: now.Unix(), Offset: 30})
+	body, tag, _ := strings.Cut(s, ".")
+
+	other := encodeCursor(testCursorKey, "user-1", feedCursor{FeedType: FeedTypeLocal, SnapshotAt: now.Unix(), Offset: 900})
+	otherBody, _, _ := strings.Cut(other, ".")
+
+	for _, bad := range []string{
+		"",
+		"no-dot",
+		body + ".",
+		otherBody + "." + tag, // valid body, wrong tag
+		body + "." + tag + "x",
+	} {
+		_, err := decodeCursor(testCursorKey, "user-1", bad, now)
+		assert.ErrorIs(t, err, errInvalidCursor, "cursor %q", bad)
+	}
+}
+
+func TestCursor_Expires(t *testing.T) {
+	issued := time.Now().Add(-maxCursorAge - time.Minute)
+	s := encodeCursor(testCursorKey, "user-1", feedCursor{FeedType: FeedTypeForYou, SnapshotAt: issued.Unix()})
+
+	_, err := decodeCursor(testCursorKey, "user-1", s, time.Now())
+	assert.ErrorIs(t, err, errExpiredCursor)
+}
+
+func TestCandidateSetHash_OrderIndependent(t *testing.T) {
+	a := []postCandidate{{id: "1"}, {id: "2"}, {id: "3"}}
+	b := []postCandidate{{id: "3"}, {id: "1"}, {id: "2"}}
+
+	assert.Equal(t,
+		candidateSetHash(FeedTypeForYou, "p", a),
+		candidateSetHash(FeedTypeForYou, "p", b))
+	assert.NotEqual(t,
+		candidateSetHash(FeedTypeForYou, "p", a),
+		candidateSetHash(FeedTypeForYou, "p", a[:2]), "a new candidate changes the epoch")
+	assert.NotEqual(t,
+		candidateSetHash(FeedTypeForYou, "p", a),
+		candidateSetHash(FeedTypeLocal, "p", a))
+}
+
+func TestRequestParamsHash(t *testing.T) {
+	lat, lon := 52.37, 4.89
+	withLoc := requestParamsHash(&lat, &lon, 50000, 0)
+
+	assert.Equal(t, withLoc, requestParamsHash(&lat, &lon, 50000, 0))
+	assert.NotEqual(t, withLoc, requestParamsHash(nil, nil, 50000, 0))
+	assert.NotEqual(t, withLoc, requestParamsHash(&lat, &lon, 10000, 0))
+	assert.NotEqual(t, withLoc, requestParamsHash(&lat, &lon, 50000, 2))
+}
+
+func TestFeedQuery_Materializable(t *testing.T) {
+	lat, lon := 52.37, 4.89
+	cases := []struct {
+		q    feedQuery
+		want bool
+	}{
+		{feedQuery{feedType: FeedTypeForYou}, true},
+		{feedQuery{feedType: FeedTypeForYou, lat: &lat, lon: &lon}, true},
+		{feedQuery{feedType: FeedTypeFollowing, lat: &lat, lon: &lon}, true},
+		{feedQuery{feedType: FeedTypeLocal}, true},
+		{feedQuery{feedType: FeedTypeLocal, lat: &lat, lon: &lon}, false},
+		{feedQuery{feedType: FeedTypeCrisis, lat: &lat, lon: &lon}, false},
+		{feedQuery{feedType: FeedTypeFollowing, minUrgency: 2}, false},
+		{feedQuery{feedType: FeedTypeNews}, false},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, tc.q.materializable(), "%+v", tc.q)
+	}
+}
@@ -0,0 +1,568 @@
+// Subscription delivery: realtime pushes and daily/weekly digests.
+//
+// subscriptions.digest_mode decides how a subscriber hears about
+// matching content outside the app:
+//
+//   - realtime: a push as soon as a matching urgent post is created
+//     (notifyRealtimeSubscribers, called from CreatePost).
+//   - daily / weekly: the worker's Digester collects matching posts
+//     and events since the user's last digest, ranks posts with the
+//     same rankFeedCandidates used for the feed, stores one digest
+//     row and sends one summary push. The app shows the newest unread
+//     digest at the top of the feed and opens it via GET /feed/digests/:id.
+package feed
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/mutes"
+	"github.com/kuurier/server/internal/push"
+	"github.com/kuurier/server/internal/storage"
+)
+
+const (
+	// realtimePushMinUrgency is the post urgency that triggers an
+	// immediate push to realtime subscribers. Lower urgencies only
+	// show up in the feed.
+	realtimePushMinUrgency = 3
+
+	// Per-digest caps. A digest is a summary, not a second feed.
+	maxDigestPosts  = 20
+	maxDigestEvents = 10
+)
+
+// digestPeriods maps each batched digest_mode to its period.
+var digestPeriods = map[string]time.Duration{
+	"daily":  24 * time.Hour,
+	"weekly": 7 * 24 * time.Hour,
+}
+
+// realtimeSubscribersSQL selects the users to push post $1 by author
+// $2 to. Matching mirrors postMatchesTopics/postMatchesLocation, but in
+// SQL so the subscription indexes do the filtering; like the feed, it
+// leaves out subscribers who muted the post or blocked its author.
+var realtimeSubscribersSQL = `
+	SELECT DISTINCT s.user_id
+	FROM subscriptions s
+	JOIN posts p ON p.id = $1
+	WHERE s.is_active = true
+	  AND s.digest_mode = 'realtime'
+	  AND s.user_id <> $2
+	  AND s.min_urgency <= p.urgency
+	  AND (
+	        s.topic_id IN (SELECT tl.ancestor_id FROM post_topics pt JOIN topic_lineage tl ON tl.topic_id = pt.topic_id WHERE pt.post_id = p.id)
+	     OR (s.location IS NOT NULL AND p.location IS NOT NULL AND s.radius_meters IS NOT NULL
+	         AND ST_DWithin(s.location, p.location, s.radius_meters))
+	  )
+	  AND NOT ` + mutes.HiddenSQL("s.user_id", "p.author_id", "p.source_type", "p.content",
+	"ARRAY(SELECT topic_id::text FROM post_topics WHERE post_id = p.id)")
+
+// notifyRealtimeSubscribers pushes a newly created urgent post to
+// every user with an active realtime subscription that matches it by
+// topic or location. Runs in its own goroutine after the response is
+// written, so it uses a fresh context.
+func (h *Handler) notifyRealtimeSubscribers(postID, authorID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var content string
+	var locationName *string
+	var urgency int
+	err := h.db.Pool().QueryRow(ctx,
+		"SELECT content, location_name, urgency FROM posts WHERE id = $1",
+		postID,
+	).Scan(&content, &locationName, &urgency)
+	if err != nil {
+		log.Printf("feed: realtime notify: load post %s: %v", postID, err)
+		return
+	}
+
+	rows, err := h.db.Pool().Query(ctx, realtimeSubscribersSQL, postID, authorID)
+	if err != nil {
+		log.Printf("feed: realtime notify: match subscribers for %s: %v", postID, err)
+		return
+	}
+	var userIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			userIDs = append(userIDs, id)
+		}
+	}
+	rows.Close()
+	if len(userIDs) == 0 {
+		return
+	}
+
+	title := "🚨 Urgent update"
+	if locationName != nil && *locationName != "" {
+		title += " near " + *locationName
+	}
+	notification := push.Notification{
+		Title:    title,
+		Body:     truncateRunes(content, 140),
+		Priority: "normal", // urgent posts still respect quiet hours; SOS alerts don't
+		Category: "POST",
+		ThreadID: "post-" + postID,
+		Data: map[string]string{
+			"type":    string(push.NotificationTypePost),
+			"post_id": postID,
+		},
+	}
+	_ = h.push.SendToUsers(ctx, userIDs, notification)
+}
+
+// Digester is the worker job that builds daily and weekly digests.
+type Digester struct {
+	// Reuse the Handler so digests rank posts exactly like the feed.
+	h *Handler
+}
+
+// NewDigester returns a Digester backed by a feed Handler that can
+// send pushes through pushService.
+func NewDigester(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, pushService *push.Service) *Digester {
+	return &Digester{h: NewHandler(cfg, db, redis, pushService)}
+}
+
+// RunOnce builds digests for every user whose daily or weekly period
+// has elapsed. Safe to run frequently: users who aren't due are skipped.
+func (d *Digester) RunOnce(ctx context.Context) error {
+	var candidates []postCandidate
+	sent := 0
+	for _, mode := range []string{"daily", "weekly"} {
+		due, err := d.dueUsers(ctx, mode, digestPeriods[mode])
+		if err != nil {
+			return fmt.Errorf("list %s digest users: %w", mode, err)
+		}
+		if len(due) == 0 {
+			continue
+		}
+
+		// Candidate fetch covers 14 days, enough for weekly digests.
+		// Load lazily and share across modes.
+		if candidates == nil {
+			candidates, err = d.h.fetchFeedCandidates(ctx, 1200)
+			if err != nil {
+				return fmt.Errorf("fetch candidates: %w", err)
+			}
+		}
+
+		now := time.Now()
+		for _, u := range due {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			since := digestWindowStart(u.lastRunAt, now, digestPeriods[mode])
+			ok, err := d.digestFor(ctx, u.userID, mode, since, now, candidates)
+			if err != nil {
+				// Per-user failures shouldn't kill the whole pass; the
+				// run isn't recorded, so the user is retried next tick.
+				slog.WarnContext(ctx, "digest user failed",
+					slog.String("user_id", u.userID),
+					slog.String("mode", mode),
+					slog.String("error", err.Error()))
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+	if sent > 0 {
+		slog.InfoContext(ctx, "digests sent", slog.Int("count", sent))
+	}
+	return nil
+}
+
+type digestUser struct {
+	userID    string
+	lastRunAt *time.Time
+}
+
+func (d *Digester) dueUsers(ctx context.Context, mode string, period time.Duration) ([]digestUser, error) {
+	rows, err := d.h.db.Pool().Query(ctx, `
+		SELECT DISTINCT s.user_id, dr.last_run_at
+		FROM subscriptions s
+		LEFT JOIN digest_runs dr ON dr.user_id = s.user_id AND dr.digest_mode = s.digest_mode
+		WHERE s.is_active = true
+		  AND s.digest_mode = $1
+		  AND (dr.last_run_at IS NULL OR dr.last_run_at <= NOW() - $2::interval)
+	`, mode, fmt.Sprintf("%d seconds", int(period.Seconds())))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []digestUser
+	for rows.Next() {
+		var u digestUser
+		if err := rows.Scan(&u.userID, &u.lastRunAt); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// digestFor builds and delivers one user's digest. Returns true if a
+// digest was stored (i.e. anything matched).
+func (d *Digester) digestFor(ctx context.Context, userID, mode string, since, now time.Time, candidates []postCandidate) (bool, error) {
+	allSubs, topicTree, err := d.h.getUserSubscriptions(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("get subscriptions: %w", err)
+	}
+	// Only this mode's subscriptions contribute; a user can have a
+	// realtime topic and a weekly neighbourhood side by side.
+	var subs []feedSubscription
+	for _, sub := range allSubs {
+		if sub.digestMode == mode {
+			subs = append(subs, sub)
+		}
+	}
+
+	var window []postCandidate
+	for _, post := range candidates {
+		if post.createdAt.After(since) && !post.createdAt.After(now) && post.authorID != userID {
+			window = append(window, post)
+		}
+	}
+	scored := d.h.rankFeedCandidates(FeedTypeFollowing, window, subs, topicTree, nil, nil, 50000, 0, d.h.loadMutes(ctx, userID))
+	if len(scored) > maxDigestPosts {
+		scored = scored[:maxDigestPosts]
+	}
+	postIDs := make([]string, 0, len(scored))
+	for _, item := range scored {
+		postIDs = append(postIDs, item.post.id)
+	}
+
+	eventIDs, err := d.matchingEvents(ctx, userID, mode, since)
+	if err != nil {
+		return false, fmt.Errorf("match events: %w", err)
+	}
+
+	tx, err := d.h.db.Pool().Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var digestID string
+	if len(postIDs) > 0 || len(eventIDs) > 0 {
+		err = tx.QueryRow(ctx, `
+			INSERT INTO digests (user_id, digest_mode, period_start, period_end, post_ids, event_ids)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		`, userID, mode, since, now, postIDs, eventIDs).Scan(&digestID)
+		if err != nil {
+			return false, err
+		}
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO digest_runs (user_id, digest_mode, last_run_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, digest_mode) DO UPDATE SET last_run_at = EXCLUDED.last_run_at
+	`, userID, mode, now); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	if digestID == "" {
+		return false, nil
+	}
+	if d.h.push != nil {
+		title, body := digestSummary(mode, len(postIDs), len(eventIDs))
+		_ = d.h.push.SendToUser(ctx, userID, push.Notification{
+			Title:    title,
+			Body:     body,
+			Priority: "normal",
+			Category: "DIGEST",
+			ThreadID: "digest-" + mode,
+			Data: map[string]string{
+				"type":      string(push.NotificationTypeDigest),
+				"digest_id": digestID,
+			},
+		})
+	}
+	return true, nil
+}
+
+// matchingEvents returns upcoming events created since the window
+// start that match one of the user's subscriptions in this mode.
+// Location matching only considers public events so a digest never
+// leaks that a hidden-location event is near the subscriber.
+func (d *Digester) matchingEvents(ctx context.Context, userID, mode string, since time.Time) ([]string, error) {
+	rows, err := d.h.db.Pool().Query(ctx, `
+		SELECT e.id
+		FROM events e
+		WHERE e.created_at > $3
+		  AND e.starts_at > NOW()
+		  AND e.is_cancelled = false
+		  AND e.organizer_id <> $1
+		  AND EXISTS (
+		        SELECT 1 FROM subscriptions s
+		        WHERE s.user_id = $1 AND s.is_active = true AND s.digest_mode = $2
+		          AND (
+		                s.topic_id IN (SELECT tl.ancestor_id FROM event_topics et JOIN topic_lineage tl ON tl.topic_id = et.topic_id WHERE et.event_id = e.id)
+		             OR (e.location_visibility = 'public'
+		                 AND s.location IS NOT NULL AND s.radius_meters IS NOT NULL
+		                 AND ST_DWithin(s.location, e.location, s.radius_meters))
+		          )
+		  )
+		ORDER BY e.starts_at ASC
+		LIMIT $4
+	`, userID, mode, since, maxDigestEvents)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// digestWindowStart returns where a digest's window begins: the last
+// run, or one period back for a user's first digest.
+func digestWindowStart(lastRunAt *time.Time, now time.Time, period time.Duration) time.Time {
+	if lastRunAt != nil {
+		return *lastRunAt
+	}
+	return now.Add(-period)
+}
+
+// digestSummary builds the push title/body for a digest.
+func digestSummary(mode string, posts, events int) (string, string) {
+	title := "Your daily digest"
+	if mode == "weekly" {
+		title = "Your weekly digest"
+	}
+
+	var parts []string
+	if posts > 0 {
+		parts = append(parts, pluralize(posts, "new post", "new posts"))
+	}
+	if events > 0 {
+		parts = append(parts, pluralize(events, "upcoming event", "upcoming events"))
+	}
+	body := ""
+	switch len(parts) {
+	case 1:
+		body = parts[0]
+	case 2:
+		body = parts[0] + " and " + parts[1]
+	}
+	return title, body + " matching your subscriptions"
+}
+
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return strconv.Itoa(n) + " " + plural
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// withDigestItem prepends the newest unread digest to the first page
+// of the personal feeds. It doesn't count toward limit/offset, so
+// paging is unaffected.
+func (h *Handler) withDigestItem(ctx context.Context, userID string, feedType FeedType, offset int, items []gin.H) []gin.H {
+	if offset != 0 || (feedType != FeedTypeForYou && feedType != FeedTypeFollowing) {
+		return items
+	}
+	digest := h.latestDigestItem(ctx, userID)
+	if digest == nil {
+		return items
+	}
+	return append([]gin.H{digest}, items...)
+}
+
+// latestDigestItem returns the newest unread digest as a feed item,
+// or nil.
+func (h *Handler) latestDigestItem(ctx context.Context, userID string) gin.H {
+	var id, mode string
+	var periodStart, periodEnd, createdAt time.Time
+	var postCount, eventCount int
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT id, digest_mode, period_start, period_end, created_at,
+		       COALESCE(array_length(post_ids, 1), 0), COALESCE(array_length(event_ids, 1), 0)
+		FROM digests
+		WHERE user_id = $1 AND read_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, userID).Scan(&id, &mode, &periodStart, &periodEnd, &createdAt, &postCount, &eventCount)
+	if err != nil {
+		return nil
+	}
+	title, body := digestSummary(mode, postCount, eventCount)
+	return gin.H{
+		"id":   "digest-" + id,
+		"type": "digest",
+		"digest": gin.H{
+			"id":           id,
+			"digest_mode":  mode,
+			"title":        title,
+			"summary":      body,
+			"post_count":   postCount,
+			"event_count":  eventCount,
+			"period_start": periodStart,
+			"period_end":   periodEnd,
+			"created_at":   createdAt,
+		},
+	}
+}
+
+// ListDigests returns the user's recent digests
+func (h *Handler) ListDigests(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ctx := c.Request.Context()
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT id, digest_mode, period_start, period_end, created_at, read_at,
+		       COALESCE(array_length(post_ids, 1), 0), COALESCE(array_length(event_ids, 1), 0)
+		FROM digests
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT 30
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch digests"})
+		return
+	}
+	defer rows.Close()
+
+	digests := []gin.H{}
+	for rows.Next() {
+		var id, mode string
+		var periodStart, periodEnd, createdAt time.Time
+		var readAt *time.Time
+		var postCount, eventCount int
+		if err := rows.Scan(&id, &mode, &periodStart, &periodEnd, &createdAt, &readAt, &postCount, &eventCount); err != nil {
+			continue
+		}
+		title, body := digestSummary(mode, postCount, eventCount)
+		digests = append(digests, gin.H{
+			"id":           id,
+			"digest_mode":  mode,
+			"title":        title,
+			"summary":      body,
+			"post_count":   postCount,
+			"event_count":  eventCount,
+			"period_start": periodStart,
+			"period_end":   periodEnd,
+			"created_at":   createdAt,
+			"read_at":      readAt,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"digests": digests})
+}
+
+// GetDigest returns one digest's posts and events and marks it read
+func (h *Handler) GetDigest(c *gin.Context) {
+	userID := c.GetString("user_id")
+	digestID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var mode string
+	var periodStart, periodEnd time.Time
+	var postIDs, eventIDs []string
+	err := h.db.Pool().QueryRow(ctx, `
+		UPDATE digests SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2
+		RETURNING digest_mode, period_start, period_end, post_ids::text[], event_ids::text[]
+	`, digestID, userID).Scan(&mode, &periodStart, &periodEnd, &postIDs, &eventIDs)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "digest not found"})
+		return
+	}
+
+	// Posts in stored (ranked) order. Posts deleted or flagged since
+	// the digest was built simply drop out.
+	posts, err := h.loadPostsByIDs(ctx, postIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch digest posts"})
+		return
+	}
+	scored := make([]scoredFeedItem, 0, len(posts))
+	for i := range posts {
+		scored = append(scored, scoredFeedItem{
+			itemType: "post",
+			post:     &posts[i],
+			why:      []string{"Digest"},
+		})
+	}
+
+	events := []gin.H{}
+	if len(eventIDs) > 0 {
+		rows, err := h.db.Pool().Query(ctx, `
+			SELECT id, title, event_type, starts_at, location_visibility, location_name, location_area, is_cancelled
+			FROM events
+			WHERE id = ANY($1::uuid[])
+			ORDER BY starts_at ASC
+		`, eventIDs)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch digest events"})
+			return
+		}
+		for rows.Next() {
+			var id, title, eventType, visibility string
+			var startsAt time.Time
+			var locationName, locationArea *string
+			var isCancelled bool
+			if err := rows.Scan(&id, &title, &eventType, &startsAt, &visibility, &locationName, &locationArea, &isCancelled); err != nil {
+				continue
+			}
+			event := gin.H{
+				"id":           id,
+				"title":        title,
+				"event_type":   eventType,
+				"starts_at":    startsAt,
+				"is_cancelled": isCancelled,
+			}
+			// Full location details live behind GET /events/:id, which
+			// applies the visibility rules; only the public name or the
+			// organizer's general area is safe to show here.
+			if visibility == "public" && locationName != nil {
+				event["location_name"] = *locationName
+			} else if locationArea != nil {
+				event["location_area"] = *locationArea
+			}
+			events = append(events, event)
+		}
+		rows.Close()
+	}
+
+	title, _ := digestSummary(mode, len(postIDs), len(eventIDs))
+	c.JSON(http.StatusOK, gin.H{
+		"id":           digestID,
+		"digest_mode":  mode,
+		"title":        title,
+		"period_start": periodStart,
+		"period_end":   periodEnd,
+		"items":        h.buildFeedResponseItems(ctx, scored),
+		"events":       events,
+	})
+}
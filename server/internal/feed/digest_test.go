@@ -0,0 +1,49 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDigestWindowStart(t *testing.T) {
+	now := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+	last := now.Add(-30 * time.Hour)
+
+	assert.Equal(t, last, digestWindowStart(&last, now, 24*time.Hour),
+		"window starts at the previous run so nothing is skipped after a late tick")
+	assert.Equal(t, now.Add(-24*time.Hour), digestWindowStart(nil, now, 24*time.Hour))
+	assert.Equal(t, now.Add(-7*24*time.Hour), digestWindowStart(nil, now, digestPeriods["weekly"]))
+}
+
+func TestDigestSummary(t *testing.T) {
+	cases := []struct {
+		mode          string
+		posts, events int
+		title, body   string
+	}{
+		{"daily", 3, 0, "Your daily digest", "3 new posts matching your subscriptions"},
+		{"daily", 1, 1, "Your daily digest", "1 new post and 1 upcoming event matching your subscriptions"},
+		{"weekly", 0, 2, "Your weekly digest", "2 upcoming events matching your subscriptions"},
+	}
+	for _, tc := range cases {
+		title, body := digestSummary(tc.mode, tc.posts, tc.events)
+		assert.Equal(t, tc.title, title)
+		assert.Equal(t, tc.body, body)
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	assert.Equal(t, "short", truncateRunes("short", 10))
+	assert.Equal(t, "abcd…", truncateRunes("abcdefgh", 5))
+	// Multi-byte characters are never split.
+	assert.Equal(t, "🚨🚨…", truncateRunes("🚨🚨🚨🚨", 3))
+}
+
+func TestRealtimeSubscribersSQL_HidesMutedAndBlocked(t *testing.T) {
+	// Keyed on each subscriber, not on the author
+	assert.Contains(t, realtimeSubscribersSQL, "um.user_id = s.user_id")
+	assert.Contains(t, realtimeSubscribersSQL, "ub.blocker_id = s.user_id AND ub.blocked_id = p.author_id")
+	assert.NotContains(t, realtimeSubscribersSQL, "%!", "no fmt verb errors")
+}
@@ -12,7 +12,10 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/mutes"
+	"github.com/kuurier/server/internal/push"
 	"github.com/kuurier/server/internal/storage"
+	"github.com/kuurier/server/internal/topics"
 )
 
 // Handler handles feed-related endpoints.
@@ -26,11 +29,12 @@ type Handler struct {
 	cfg   *config.Config
 	db    *storage.Postgres
 	redis *storage.Redis
+	push  *push.Service // optional; nil disables realtime subscription pushes
 }
 
 // NewHandler creates a new feed handler.
-func NewHandler(cfg *config.Config, db *storage.Postgres, redis *storage.Redis) *Handler {
-	return &Handler{cfg: cfg, db: db, redis: redis}
+func NewHandler(cfg *config.Config, db *storage.Postgres, redis *storage.Redis, pushService *push.Service) *Handler {
+	return &Handler{cfg: cfg, db: db, redis: redis, push: pushService}
 }
 
 // CreatePostRequest represents a new post
@@ -43,6 +47,9 @@ type CreatePostRequest struct {
 	Urgency      int      `json:"urgency" binding:"min=1,max=3"`
 	TopicIDs     []string `json:"topic_ids"`
 	ExpiresAt    *int64   `json:"expires_at"` // Unix timestamp
+	// Language is the ISO 639-1 code the post is written in; search
+	// stems it with the matching dictionary.
+	Language string `json:"language" binding:"omitempty,len=2,alpha"`
 }
 
 // FeedType represents the type of feed requested.
@@ -62,6 +69,7 @@ type feedSubscription struct {
 	longitude    *float64
 	radiusMeters *int
 	minUrgency   int
+	digestMode   string
 }
 
 type postCandidate struct {
@@ -77,6 +85,8 @@ type postCandidate struct {
 	verificationScore int
 	authorTrustScore  int
 	topicIDs          []string
+	corroborations    int // distinct users corroborating via firsthand replies
+	editedAt          *time.Time
 }
 
 type scoredFeedItem struct {
@@ -116,12 +126,15 @@ func (h *Handler) GetFeed(c *gin.Context) {
 			   p.location_name, p.urgency, p.created_at, p.verification_score
 		FROM posts p
 		LEFT JOIN post_topics pt ON p.id = pt.post_id
+		LEFT JOIN topic_lineage tl ON tl.topic_id = pt.topic_id
 		LEFT JOIN subscriptions s ON s.user_id = $1 AND s.is_active = true
 		WHERE p.is_flagged = false
 		  AND (p.expires_at IS NULL OR p.expires_at > NOW())
+		  AND NOT `+mutes.HiddenSQL("$1", "p.author_id", "p.source_type", "p.content",
+		"ARRAY(SELECT topic_id::text FROM post_topics WHERE post_id = p.id)")+`
 		  AND (
 			  -- Match by topic
-			  (s.topic_id IS NOT NULL AND pt.topic_id = s.topic_id AND p.urgency >= s.min_urgency)
+			  (s.topic_id IS NOT NULL AND tl.ancestor_id = s.topic_id AND p.urgency >= s.min_urgency)
 			  OR
 			  -- Match by location
 			  (s.location IS NOT NULL AND ST_DWithin(p.location, s.location, s.radius_meters) AND p.urgency >= s.min_urgency)
@@ -176,10 +189,12 @@ func (h *Handler) GetFeed(c *gin.Context) {
 
 	// Batch-load media for all posts in a single query (eliminates N+1)
 	mediaMap := h.getPostMediaBatch(ctx, postIDs)
+	replyMap := h.getReplyStatsBatch(ctx, postIDs)
 	for i, post := range posts {
 		if media, ok := mediaMap[post["id"].(string)]; ok && len(media) > 0 {
 			posts[i]["media"] = media
 		}
+		posts[i]["reply_count"] = replyMap[post["id"].(string)].count
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -190,64 +205,83 @@ func (h *Handler) GetFeed(c *gin.Context) {
 }
 
 // GetFeedV2 returns the ranked, multi-source feed with optional personalization.
+//
+// Paging: pass the previous response's next_cursor as ?cursor= to get
+// a stable continuation (see cursor.go). limit/offset still work for
+// older clients but can show duplicates as the ranking moves.
 func (h *Handler) GetFeedV2(c *gin.Context) {
 	userID := c.GetString("user_id")
 	ctx := c.Request.Context()
 
-	feedType := FeedType(c.DefaultQuery("type", string(FeedTypeForYou)))
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "30"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
-	if limit < 1 {
-		limit = 1
-	}
-	if limit > 100 {
-		limit = 100
-	}
-	if offset < 0 {
-		offset = 0
-	}
+	q := parseFeedQuery(c)
+	feedType, limit, offset := q.feedType, q.limit, q.offset
 
-	var lat *float64
-	var lon *float64
-	if q := c.Query("lat"); q != "" {
-		if v, err := strconv.ParseFloat(q, 64); err == nil {
-			lat = &v
+	var cur *feedCursor
+	if raw := c.Query("cursor"); raw != "" {
+		var err error
+		cur, err = decodeCursor(h.cfg.JWTSecret, userID, raw, time.Now())
+		if err != nil {
+			respondCursorError(c, err)
+			return
 		}
-	}
-	if q := c.Query("lon"); q != "" {
-		if v, err := strconv.ParseFloat(q, 64); err == nil {
-			lon = &v
+		if cur.FeedType != feedType || cur.Params != q.params || cur.Source == cursorSourceSince {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cursor does not match feed parameters"})
+			return
 		}
+		offset = cur.Offset
 	}
 
-	radiusMeters, _ := strconv.Atoi(c.DefaultQuery("radius_m", "50000"))
-	if radiusMeters > 200000 {
-		radiusMeters = 200000
-	}
-	minUrgency, _ := strconv.Atoi(c.DefaultQuery("min_urgency", "0"))
-
 	if feedType == FeedTypeNews {
-		h.respondWithNewsFeed(c, limit, offset)
+		h.respondWithNewsFeed(c, q, cur)
 		return
 	}
 
-	// Materialized path: the "for_you" feed is precomputed by the
-	// worker every few minutes. Serve from materialized_feeds when
-	// fresh results exist — an indexed range scan instead of a
-	// 1200-row fetch + Go-side ranking per request.
+	// Materialized path: for_you, following, local and crisis are
+	// precomputed by the worker and kept current as posts are created.
+	// Serve from materialized_feeds when fresh results exist — an
+	// indexed range scan instead of a 1200-row fetch + Go-side ranking
+	// per request.
 	//
-	// Crisis, local, and following feeds stay on the live path: they
-	// depend on request-time signals (user location, urgency filter)
-	// or are small enough that materialization buys little.
-	if feedType == FeedTypeForYou && h.cfg.FeedMaterialized {
-		if served := h.serveMaterialized(c, userID, feedType, limit, offset); served {
+	// Requests with request-time signals the materializer doesn't
+	// have (see feedQuery.materializable) stay on the live path.
+	//
+	// A cursor stays on the source its first page came from.
+	if h.cfg.FeedMaterialized && q.materializable() && (cur == nil || cur.Source == cursorSourceMaterialized) {
+		if served := h.serveMaterialized(c, userID, q, cur); served {
 			return
 		}
 		// Otherwise fall through to the live path below. The next
 		// materialization tick will populate the cache for this user.
 	}
 
-	subscriptions, topicNames, err := h.getUserSubscriptions(ctx, userID)
+	// Live path. The first page ranks and snapshots the listing;
+	// cursor pages read the snapshot so the order can't shift under
+	// the client.
+	snapshotAt := time.Now()
+	var snapshotHash string
+	var scoredItems []scoredFeedItem
+	if cur != nil && cur.Source == cursorSourceLive {
+		snapshotAt = time.Unix(cur.SnapshotAt, 0)
+		snapshotHash = cur.Snapshot
+		if entries := h.loadSnapshot(ctx, userID, snapshotHash); entries != nil {
+			end := offset + limit
+			if offset > len(entries) {
+				offset = len(entries)
+			}
+			if end > len(entries) {
+				end = len(entries)
+			}
+			page, err := h.hydrateSnapshot(ctx, entries[offset:end])
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch feed"})
+				return
+			}
+			h.respondLivePage(c, userID, q, page, offset, end, len(entries), snapshotHash, snapshotAt)
+			return
+		}
+	}
+
+	subscriptions, topicTree, err := h.getUserSubscriptions(ctx, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load subscriptions"})
 		return
@@ -258,36 +292,68 @@ func (h *Handler) GetFeedV2(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch candidates"})
 		return
 	}
+	if cur != nil {
+		// The snapshot expired mid-scroll (or the listing came from a
+		// materialized generation that is gone). Re-rank without posts
+		// newer than the listing so later pages shift as little as
+		// possible; pull-to-refresh picks those up.
+		kept := candidates[:0]
+		for _, post := range candidates {
+			if !post.createdAt.After(snapshotAt) {
+				kept = append(kept, post)
+			}
+		}
+		candidates = kept
+	}
 
-	scoredItems := h.rankFeedCandidates(feedType, candidates, subscriptions, topicNames, lat, lon, radiusMeters, minUrgency)
+	scoredItems = h.rankFeedCandidates(feedType, candidates, subscriptions, topicTree, q.lat, q.lon, q.radiusMeters, q.minUrgency, h.loadMutes(ctx, userID))
+	snapshotHash = candidateSetHash(feedType, q.params, candidates)
+	h.storeSnapshot(ctx, userID, snapshotHash, scoredItems)
 
 	// News articles enter the feed naturally as posts with
 	// source_type='mainstream' (written by the news bot), so no
 	// separate live-fetch mix-in here.
 
-	items := h.buildFeedResponseItems(ctx, scoredItems)
-
 	// Apply pagination
 	start := offset
-	if start > len(items) {
-		start = len(items)
+	if start > len(scoredItems) {
+		start = len(scoredItems)
 	}
 	end := start + limit
-	if end > len(items) {
-		end = len(items)
+	if end > len(scoredItems) {
+		end = len(scoredItems)
 	}
 
-	paged := items[start:end]
-	nextOffset := offset + len(paged)
-	if nextOffset >= len(items) {
+	h.respondLivePage(c, userID, q, scoredItems[start:end], start, end, len(scoredItems), snapshotHash, snapshotAt)
+}
+
+// respondLivePage writes one page of a live-ranked listing. start/end
+// index into the full listing of length total.
+func (h *Handler) respondLivePage(c *gin.Context, userID string, q feedQuery, page []scoredFeedItem, start, end, total int, snapshotHash string, snapshotAt time.Time) {
+	ctx := c.Request.Context()
+	items := h.buildFeedResponseItems(ctx, page)
+
+	nextOffset := end
+	nextCursor := ""
+	if end >= total {
 		nextOffset = -1
+	} else {
+		nextCursor = encodeCursor(h.cfg.JWTSecret, userID, feedCursor{
+			FeedType:   q.feedType,
+			Params:     q.params,
+			Source:     cursorSourceLive,
+			SnapshotAt: snapshotAt.Unix(),
+			Snapshot:   snapshotHash,
+			Offset:     end,
+		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"items":       paged,
-		"limit":       limit,
-		"offset":      offset,
+		"items":       h.withDigestItem(ctx, userID, q.feedType, start, items),
+		"limit":       q.limit,
+		"offset":      start,
 		"next_offset": nextOffset,
+		"next_cursor": nextCursor,
 	})
 }
 
@@ -332,14 +398,14 @@ func (h *Handler) CreatePost(c *gin.Context) {
 	var err error
 	if req.Latitude != nil && req.Longitude != nil {
 		_, err = h.db.Pool().Exec(ctx, `
-			INSERT INTO posts (id, author_id, content, source_type, location, location_name, urgency, expires_at)
-			VALUES ($1, $2, $3, $4, ST_SetSRID(ST_MakePoint($5, $6)::geography, 4326), $7, $8, $9)
-		`, postID, userID, req.Content, req.SourceType, *req.Longitude, *req.Latitude, req.LocationName, req.Urgency, expiresAt)
+			INSERT INTO posts (id, author_id, content, source_type, location, location_name, urgency, expires_at, language)
+			VALUES ($1, $2, $3, $4, ST_SetSRID(ST_MakePoint($5, $6)::geography, 4326), $7, $8, $9, NULLIF(LOWER($10), ''))
+		`, postID, userID, req.Content, req.SourceType, *req.Longitude, *req.Latitude, req.LocationName, req.Urgency, expiresAt, req.Language)
 	} else {
 		_, err = h.db.Pool().Exec(ctx, `
-			INSERT INTO posts (id, author_id, content, source_type, location_name, urgency, expires_at)
-			VALUES ($1, $2, $3, $4, $5, $6, $7)
-		`, postID, userID, req.Content, req.SourceType, req.LocationName, req.Urgency, expiresAt)
+			INSERT INTO posts (id, author_id, content, source_type, location_name, urgency, expires_at, language)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF(LOWER($8), ''))
+		`, postID, userID, req.Content, req.SourceType, req.LocationName, req.Urgency, expiresAt, req.Language)
 	}
 
 	if err != nil {
@@ -351,7 +417,8 @@ func (h *Handler) CreatePost(c *gin.Context) {
 	if len(req.TopicIDs) > 0 {
 		for _, topicID := range req.TopicIDs {
 			if _, err := h.db.Pool().Exec(ctx, `
-				INSERT INTO post_topics (post_id, topic_id) VALUES ($1, $2)
+				INSERT INTO post_topics (post_id, topic_id)
+				SELECT $1, id FROM topics WHERE id = $2 AND archived_at IS NULL
 				ON CONFLICT DO NOTHING
 			`, postID, topicID); err != nil {
 				log.Printf("feed: failed to insert topic %s for post %s: %v", topicID, postID, err)
@@ -359,6 +426,18 @@ func (h *Handler) CreatePost(c *gin.Context) {
 		}
 	}
 
+	// Put the post into interested users' materialized feeds now
+	// rather than at the next full rebuild.
+	if h.cfg.FeedMaterialized {
+		go h.materializePost(postID)
+	}
+
+	// Realtime subscribers hear about urgent posts immediately;
+	// everything else waits for the feed or their digest.
+	if h.push != nil && req.Urgency >= realtimePushMinUrgency {
+		go h.notifyRealtimeSubscribers(postID, userID)
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"id":         postID,
 		"message":    "post created",
@@ -374,22 +453,26 @@ func (h *Handler) GetPost(c *gin.Context) {
 	var id, authorID, content, sourceType string
 	var lat, lon *float64
 	var locationName *string
-	var urgency, verificationScore int
+	var urgency, verificationScore, revision int
 	var createdAt time.Time
+	var editedAt *time.Time
 
 	err := h.db.Pool().QueryRow(ctx, `
 		SELECT p.id, p.author_id, p.content, p.source_type,
 			   ST_Y(p.location::geometry), ST_X(p.location::geometry),
-			   p.location_name, p.urgency, p.created_at, p.verification_score
+			   p.location_name, p.urgency, p.created_at, p.verification_score,
+			   p.edited_at, p.revision
 		FROM posts p
 		WHERE p.id = $1 AND p.is_flagged = false
-	`, postID).Scan(&id, &authorID, &content, &sourceType, &lat, &lon, &locationName, &urgency, &createdAt, &verificationScore)
+	`, postID).Scan(&id, &authorID, &content, &sourceType, &lat, &lon, &locationName, &urgency, &createdAt, &verificationScore,
+		&editedAt, &revision)
 
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
 		return
 	}
 
+	replies := h.getReplyStatsBatch(ctx, []string{id})[id]
 	post := gin.H{
 		"id":                 id,
 		"author_id":          authorID,
@@ -398,6 +481,8 @@ func (h *Handler) GetPost(c *gin.Context) {
 		"urgency":            urgency,
 		"created_at":         createdAt,
 		"verification_score": verificationScore,
+		"reply_count":        replies.count,
+		"corroborations":     replies.corroborations,
 	}
 
 	if lat != nil && lon != nil {
@@ -406,6 +491,10 @@ func (h *Handler) GetPost(c *gin.Context) {
 	if locationName != nil {
 		post["location_name"] = *locationName
 	}
+	if editedAt != nil {
+		post["edited_at"] = *editedAt
+		post["revision"] = revision
+	}
 
 	// Fetch media for this post
 	media := h.getPostMedia(ctx, id)
@@ -467,6 +556,7 @@ func (h *Handler) FlagPost(c *gin.Context) {
 	_, err := h.db.Pool().Exec(ctx, `
 		UPDATE posts
 		SET verification_score = verification_score - 1,
+			flag_count = flag_count + 1,
 			is_flagged = CASE WHEN verification_score - 1 < -5 THEN true ELSE is_flagged END
 		WHERE id = $1
 	`, postID)
@@ -479,33 +569,6 @@ func (h *Handler) FlagPost(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "flag recorded"})
 }
 
-// GetTopics returns all available topics
-func (h *Handler) GetTopics(c *gin.Context) {
-	ctx := c.Request.Context()
-
-	rows, err := h.db.Pool().Query(ctx, "SELECT id, slug, name, icon FROM topics ORDER BY name")
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch topics"})
-		return
-	}
-	defer rows.Close()
-
-	var topics []gin.H
-	for rows.Next() {
-		var id, slug, name string
-		var icon *string
-		if err := rows.Scan(&id, &slug, &name, &icon); err == nil {
-			topic := gin.H{"id": id, "slug": slug, "name": name}
-			if icon != nil {
-				topic["icon"] = *icon
-			}
-			topics = append(topics, topic)
-		}
-	}
-
-	c.JSON(http.StatusOK, gin.H{"topics": topics})
-}
-
 // GetSubscriptions returns user's subscriptions
 func (h *Handler) GetSubscriptions(c *gin.Context) {
 	userID := c.GetString("user_id")
@@ -751,8 +814,29 @@ func (h *Handler) getPostMediaBatch(ctx context.Context, postIDs []string) map[s
 // an in-process cache. That path is gone — the news bot (now in the
 // worker process) writes articles to the posts table, and this
 // handler is a simple paginated query against that table.
-func (h *Handler) respondWithNewsFeed(c *gin.Context, limit, offset int) {
+//
+// With a cursor, paging is a (created_at, id) keyset so articles
+// arriving mid-scroll don't push already-seen ones onto the next page.
+func (h *Handler) respondWithNewsFeed(c *gin.Context, q feedQuery, cur *feedCursor) {
 	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+	limit, offset := q.limit, q.offset
+
+	snapshotAt := time.Now()
+	var afterTime *time.Time
+	var afterID *string
+	if cur != nil {
+		snapshotAt = time.Unix(cur.SnapshotAt, 0)
+		offset = cur.Offset
+		if cur.Source == cursorSourceNews && cur.LastID != "" {
+			t := time.UnixMicro(cur.LastTime)
+			afterTime, afterID = &t, &cur.LastID
+		}
+	}
+	keysetOffset := offset
+	if afterTime != nil {
+		keysetOffset = 0
+	}
 
 	rows, err := h.db.Pool().Query(ctx, `
 		SELECT p.id, p.author_id, p.content, p.source_type,
@@ -765,9 +849,10 @@ func (h *Handler) respondWithNewsFeed(c *gin.Context, limit, offset int) {
 		WHERE p.source_type = 'mainstream'
 		  AND p.is_flagged = false
 		  AND p.created_at > NOW() - INTERVAL '7 days'
-		ORDER BY p.created_at DESC
+		  AND ($3::timestamptz IS NULL OR (p.created_at, p.id) < ($3, $4::uuid))
+		ORDER BY p.created_at DESC, p.id DESC
 		LIMIT $1 OFFSET $2
-	`, limit, offset)
+	`, limit, keysetOffset, afterTime, afterID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch news"})
 		return
@@ -796,9 +881,21 @@ func (h *Handler) respondWithNewsFeed(c *gin.Context, limit, offset int) {
 	items := h.buildFeedResponseItems(ctx, scored)
 
 	nextOffset := offset + len(items)
+	nextCursor := ""
 	// If we got fewer than the limit, we're at the end.
 	if len(items) < limit {
 		nextOffset = -1
+	} else {
+		last := scored[len(scored)-1].post
+		nextCursor = encodeCursor(h.cfg.JWTSecret, userID, feedCursor{
+			FeedType:   q.feedType,
+			Params:     q.params,
+			Source:     cursorSourceNews,
+			SnapshotAt: snapshotAt.Unix(),
+			Offset:     nextOffset,
+			LastTime:   last.createdAt.UnixMicro(),
+			LastID:     last.id,
+		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -806,16 +903,18 @@ func (h *Handler) respondWithNewsFeed(c *gin.Context, limit, offset int) {
 		"limit":       limit,
 		"offset":      offset,
 		"next_offset": nextOffset,
+		"next_cursor": nextCursor,
 	})
 }
 
-func (h *Handler) getUserSubscriptions(ctx context.Context, userID string) ([]feedSubscription, map[string]string, error) {
+func (h *Handler) getUserSubscriptions(ctx context.Context, userID string) ([]feedSubscription, *topics.Tree, error) {
 	rows, err := h.db.Pool().Query(ctx, `
 		SELECT s.topic_id,
 			   ST_Y(s.location::geometry) as lat,
 			   ST_X(s.location::geometry) as lon,
 			   s.radius_meters,
-			   s.min_urgency
+			   s.min_urgency,
+			   s.digest_mode
 		FROM subscriptions s
 		WHERE s.user_id = $1 AND s.is_active = true
 	`, userID)
@@ -830,7 +929,8 @@ func (h *Handler) getUserSubscriptions(ctx context.Context, userID string) ([]fe
 		var lat, lon *float64
 		var radius *int
 		var minUrgency int
-		if err := rows.Scan(&topicID, &lat, &lon, &radius, &minUrgency); err != nil {
+		var digestMode string
+		if err := rows.Scan(&topicID, &lat, &lon, &radius, &minUrgency, &digestMode); err != nil {
 			log.Printf("feed: subscription scan error: %v", err)
 			continue
 		}
@@ -840,24 +940,27 @@ func (h *Handler) getUserSubscriptions(ctx context.Context, userID string) ([]fe
 			longitude:    lon,
 			radiusMeters: radius,
 			minUrgency:   minUrgency,
+			digestMode:   digestMode,
 		})
 	}
 
-	topicRows, err := h.db.Pool().Query(ctx, `SELECT id, name FROM topics`)
+	tree, err := topics.Load(ctx, h.db)
 	if err != nil {
 		return subs, nil, err
 	}
-	defer topicRows.Close()
 
-	topicNames := make(map[string]string)
-	for topicRows.Next() {
-		var id, name string
-		if err := topicRows.Scan(&id, &name); err == nil {
-			topicNames[id] = name
-		}
-	}
+	return subs, tree, nil
+}
 
-	return subs, topicNames, nil
+// loadMutes returns userID's mutes. Failing to load them shouldn't
+// take the feed down, so the error is logged and nothing is hidden.
+func (h *Handler) loadMutes(ctx context.Context, userID string) *mutes.List {
+	muted, err := mutes.Load(ctx, h.db, userID)
+	if err != nil {
+		log.Printf("feed: load mutes for %s: %v", userID, err)
+		return nil
+	}
+	return muted
 }
 
 func (h *Handler) fetchFeedCandidates(ctx context.Context, limit int) ([]postCandidate, error) {
@@ -867,7 +970,9 @@ func (h *Handler) fetchFeedCandidates(ctx context.Context, limit int) ([]postCan
 			   ST_X(p.location::geometry) as lon,
 			   p.location_name, p.urgency, p.created_at, p.verification_score,
 			   u.trust_score,
-			   ARRAY_REMOVE(ARRAY_AGG(DISTINCT pt.topic_id::text), NULL) AS topic_ids
+			   ARRAY_REMOVE(ARRAY_AGG(DISTINCT pt.topic_id::text), NULL) AS topic_ids,
+			   `+corroborationsSQL+` AS corroborations,
+			   p.edited_at
 		FROM posts p
 		JOIN users u ON u.id = p.author_id
 		LEFT JOIN post_topics pt ON pt.post_id = p.id
@@ -901,6 +1006,8 @@ func (h *Handler) fetchFeedCandidates(ctx context.Context, limit int) ([]postCan
 			&post.verificationScore,
 			&post.authorTrustScore,
 			&topicIDs,
+			&post.corroborations,
+			&post.editedAt,
 		); err != nil {
 			log.Printf("feed: candidate scan error: %v", err)
 			continue
@@ -916,16 +1023,23 @@ func (h *Handler) rankFeedCandidates(
 	feedType FeedType,
 	candidates []postCandidate,
 	subscriptions []feedSubscription,
-	topicNames map[string]string,
+	topicTree *topics.Tree,
 	userLat, userLon *float64,
 	radiusMeters int,
 	minUrgency int,
+	muted *mutes.List,
 ) []scoredFeedItem {
+	// A topic subscription also matches the topics nested under it,
+	// at the lowest min_urgency of any subscription covering them.
 	subscriptionTopics := make(map[string]int)
 	var locationSubs []feedSubscription
 	for _, sub := range subscriptions {
 		if sub.topicID != nil {
-			subscriptionTopics[*sub.topicID] = sub.minUrgency
+			for _, id := range topicTree.WithDescendants(*sub.topicID) {
+				if minUrgency, ok := subscriptionTopics[id]; !ok || sub.minUrgency < minUrgency {
+					subscriptionTopics[id] = sub.minUrgency
+				}
+			}
 		}
 		if sub.latitude != nil && sub.longitude != nil {
 			locationSubs = append(locationSubs, sub)
@@ -937,6 +1051,9 @@ func (h *Handler) rankFeedCandidates(
 		if minUrgency > 0 && post.urgency < minUrgency {
 			continue
 		}
+		if muted.HidesPost(post.authorID, post.sourceType, post.content, post.topicIDs) {
+			continue
+		}
 
 		topicMatch, matchedTopic := postMatchesTopics(post.topicIDs, subscriptionTopics, post.urgency)
 		locationMatch := postMatchesLocation(post, locationSubs)
@@ -1001,7 +1118,7 @@ func (h *Handler) rankFeedCandidates(
 			score += 0.15 * recency
 		}
 
-		why := buildWhyList(feedType, topicMatch, matchedTopic, topicNames, proximity, urgency, trust)
+		why := buildWhyList(feedType, topicMatch, matchedTopic, topicTree.Names(), proximity, urgency, trust)
 
 		scored = append(scored, scoredFeedItem{
 			score:    score,
@@ -1052,8 +1169,11 @@ func (h *Handler) buildFeedResponseItems(ctx context.Context, items []scoredFeed
 		}
 	}
 	mediaMap := h.getPostMediaBatch(ctx, postIDs)
+	replyMap := h.getReplyStatsBatch(ctx, postIDs)
+	incidentMap := h.getIncidentsBatch(ctx, postIDs)
 
 	result := make([]gin.H, 0, len(items))
+	shownIncidents := make(map[string]bool)
 	for _, item := range items {
 		// All feed items are posts now. News articles appear as posts
 		// with source_type='mainstream' after Phase 4.
@@ -1062,6 +1182,15 @@ func (h *Handler) buildFeedResponseItems(ctx context.Context, items []scoredFeed
 		}
 		{
 			post := item.post
+			// Posts in an incident collapse into one incident item at
+			// the best-ranked member's position; the rest of its posts
+			// on this page are dropped. Clients can skip post_ids they
+			// meet again on later pages.
+			incident, inIncident := incidentMap[post.id]
+			if inIncident && shownIncidents[incident["id"].(string)] {
+				continue
+			}
+			replies := replyMap[post.id]
 			postJSON := gin.H{
 				"id":                 post.id,
 				"author_id":          post.authorID,
@@ -1070,6 +1199,8 @@ func (h *Handler) buildFeedResponseItems(ctx context.Context, items []scoredFeed
 				"urgency":            post.urgency,
 				"created_at":         post.createdAt,
 				"verification_score": post.verificationScore,
+				"reply_count":        replies.count,
+				"corroborations":     replies.corroborations,
 			}
 
 			if post.latitude != nil && post.longitude != nil {
@@ -1078,11 +1209,30 @@ func (h *Handler) buildFeedResponseItems(ctx context.Context, items []scoredFeed
 			if post.locationName != nil {
 				postJSON["location_name"] = *post.locationName
 			}
+			if post.editedAt != nil {
+				postJSON["edited_at"] = *post.editedAt
+			}
 
 			if media, ok := mediaMap[post.id]; ok && len(media) > 0 {
 				postJSON["media"] = media
 			}
 
+			if inIncident {
+				incidentID := incident["id"].(string)
+				shownIncidents[incidentID] = true
+				body := gin.H{"lead_post": postJSON}
+				for k, v := range incident {
+					body[k] = v
+				}
+				result = append(result, gin.H{
+					"id":       "incident-" + incidentID,
+					"type":     "incident",
+					"incident": body,
+					"why":      item.why,
+				})
+				continue
+			}
+
 			result = append(result, gin.H{
 				"id":   "post-" + post.id,
 				"type": "post",
@@ -1101,10 +1251,19 @@ func recencyScore(createdAt time.Time) float64 {
 }
 
 func confidenceScore(post postCandidate) float64 {
-	trustNorm := math.Min(float64(post.authorTrustScore)/100.0, 1.0)
-	verificationNorm := (math.Min(math.Max(float64(post.verificationScore), -5.0), 10.0) + 5.0) / 15.0
+	return ConfidenceScore(post.authorTrustScore, post.verificationScore, post.corroborations, post.sourceType)
+}
+
+// ConfidenceScore rates how much to trust a post, in [0, 1], from its
+// author's trust score, community verification, corroborating
+// firsthand replies and source type. Search ranks posts with it too,
+// so both agree on what "credible" means.
+func ConfidenceScore(authorTrustScore, verificationScore, corroborations int, sourceType string) float64 {
+	trustNorm := math.Min(float64(authorTrustScore)/100.0, 1.0)
+	verification := float64(verificationScore + corroborationWeight*corroborations)
+	verificationNorm := (math.Min(math.Max(verification, -5.0), 10.0) + 5.0) / 15.0
 	sourceWeight := 0.6
-	switch post.sourceType {
+	switch sourceType {
 	case "firsthand":
 		sourceWeight = 1.0
 	case "aggregated":
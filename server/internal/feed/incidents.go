@@ -0,0 +1,543 @@
+// Incident clustering.
+//
+// Several members often post about the same kettle or eviction within
+// minutes of each other. Individually each post may have weak
+// confidence; together they are strong evidence. The worker's
+// IncidentClusterer groups recent located posts that are close in
+// space and time and either share a topic or read alike (SimHash over
+// their words), and stores every group reported by at least
+// incidentMinAuthors distinct authors as an incident.
+//
+// Feeds collapse an incident's posts into a single "incident" item,
+// and the map shows incidents as their own layer (geo.GetIncidents).
+package feed
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"log/slog"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kuurier/server/internal/storage"
+)
+
+const (
+	// incidentWindow is how far back the job looks for posts. Incidents
+	// last seen before that are left as they are.
+	incidentWindow = 6 * time.Hour
+
+	// incidentRadius and incidentTimeGap bound how far apart two posts
+	// can be and still describe the same thing.
+	incidentRadius  = 500.0 // meters
+	incidentTimeGap = 90 * time.Minute
+
+	// incidentMaxHamming is the most SimHash bits two posts may differ
+	// in to count as similar text. Unrelated texts differ in about 32.
+	incidentMaxHamming = 20
+
+	// incidentMinAuthors is how many distinct authors a cluster needs
+	// before it is an incident. One person posting three times is not
+	// corroboration.
+	incidentMinAuthors = 2
+
+	// maxIncidentCandidates caps the posts clustered per run.
+	maxIncidentCandidates = 2000
+)
+
+// incidentPost is a post as seen by the clusterer.
+type incidentPost struct {
+	postCandidate
+	fingerprint uint64
+	hasText     bool // false when the content had no usable words
+}
+
+// incidentSummary is what gets stored for one cluster.
+type incidentSummary struct {
+	postIDs      []string
+	leadPostID   string
+	latitude     float64
+	longitude    float64
+	radiusMeters int
+	locationName *string
+	topicIDs     []string
+	authorCount  int
+	confidence   float64
+	maxUrgency   int
+	firstSeenAt  time.Time
+	lastSeenAt   time.Time
+}
+
+// IncidentClusterer is the worker job that maintains the incidents table.
+type IncidentClusterer struct {
+	db *storage.Postgres
+}
+
+// NewIncidentClusterer returns an IncidentClusterer.
+func NewIncidentClusterer(db *storage.Postgres) *IncidentClusterer {
+	return &IncidentClusterer{db: db}
+}
+
+// RunOnce re-clusters the last incidentWindow of located posts, plus
+// every post of an incident still active in that window, and rewrites
+// their incidents. An existing incident keeps its ID when most of a
+// new cluster's posts belonged to it; incidents none of whose posts
+// cluster any more are deleted.
+func (ic *IncidentClusterer) RunOnce(ctx context.Context) error {
+	posts, err := ic.fetchCandidates(ctx)
+	if err != nil {
+		return fmt.Errorf("fetch incident candidates: %w", err)
+	}
+	if len(posts) == 0 {
+		return nil
+	}
+
+	postIDs := make([]string, len(posts))
+	for i, p := range posts {
+		postIDs[i] = p.id
+	}
+
+	tx, err := ic.db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	previous := make(map[string]string) // post ID -> incident ID
+	rows, err := tx.Query(ctx, `
+		SELECT post_id, incident_id FROM incident_posts WHERE post_id = ANY($1::uuid[])
+	`, postIDs)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var postID, incidentID string
+		if err := rows.Scan(&postID, &incidentID); err != nil {
+			rows.Close()
+			return err
+		}
+		previous[postID] = incidentID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	// Clear the touched incidents' memberships, including posts since
+	// flagged or expired, which aren't candidates any more.
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM incident_posts
+		WHERE incident_id IN (SELECT incident_id FROM incident_posts WHERE post_id = ANY($1::uuid[]))
+	`, postIDs); err != nil {
+		return err
+	}
+
+	kept := make(map[string]bool)
+	for _, cluster := range clusterIncidentPosts(posts) {
+		summary := summarizeIncident(cluster)
+		if summary.authorCount < incidentMinAuthors {
+			continue
+		}
+
+		id := reusableIncidentID(summary.postIDs, previous, kept)
+		if id == "" {
+			err = tx.QueryRow(ctx, `
+				INSERT INTO incidents (location, radius_meters, location_name, lead_post_id, topic_ids,
+				                       post_count, author_count, confidence, max_urgency,
+				                       first_seen_at, last_seen_at)
+				VALUES (ST_SetSRID(ST_MakePoint($1, $2), 4326)::geography, $3, $4, $5, $6::uuid[],
+				        $7, $8, $9, $10, $11, $12)
+				RETURNING id
+			`, summary.longitude, summary.latitude, summary.radiusMeters, summary.locationName,
+				summary.leadPostID, summary.topicIDs, len(summary.postIDs), summary.authorCount,
+				summary.confidence, summary.maxUrgency, summary.firstSeenAt, summary.lastSeenAt,
+			).Scan(&id)
+		} else {
+			_, err = tx.Exec(ctx, `
+				UPDATE incidents
+				SET location = ST_SetSRID(ST_MakePoint($2, $3), 4326)::geography,
+				    radius_meters = $4, location_name = $5, lead_post_id = $6, topic_ids = $7::uuid[],
+				    post_count = $8, author_count = $9, confidence = $10, max_urgency = $11,
+				    first_seen_at = $12, last_seen_at = $13, updated_at = NOW()
+				WHERE id = $1
+			`, id, summary.longitude, summary.latitude, summary.radiusMeters, summary.locationName,
+				summary.leadPostID, summary.topicIDs, len(summary.postIDs), summary.authorCount,
+				summary.confidence, summary.maxUrgency, summary.firstSeenAt, summary.lastSeenAt)
+		}
+		if err != nil {
+			return fmt.Errorf("save incident: %w", err)
+		}
+		kept[id] = true
+
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO incident_posts (post_id, incident_id)
+			SELECT unnest($1::uuid[]), $2
+		`, summary.postIDs, id); err != nil {
+			return fmt.Errorf("save incident posts: %w", err)
+		}
+	}
+
+	var dissolved []string
+	seen := make(map[string]bool)
+	for _, id := range previous {
+		if !kept[id] && !seen[id] {
+			seen[id] = true
+			dissolved = append(dissolved, id)
+		}
+	}
+	if len(dissolved) > 0 {
+		if _, err := tx.Exec(ctx, `DELETE FROM incidents WHERE id = ANY($1::uuid[])`, dissolved); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "incidents clustered",
+		slog.Int("posts", len(posts)),
+		slog.Int("incidents", len(kept)),
+		slog.Int("dissolved", len(dissolved)))
+	return nil
+}
+
+// fetchCandidates loads located posts from the last incidentWindow and
+// the members of incidents still active in it, so an incident that
+// straddles the window edge keeps its older posts.
+func (ic *IncidentClusterer) fetchCandidates(ctx context.Context) ([]incidentPost, error) {
+	rows, err := ic.db.Pool().Query(ctx, `
+		SELECT p.id, p.author_id, p.content, p.source_type,
+		       ST_Y(p.location::geometry) as lat,
+		       ST_X(p.location::geometry) as lon,
+		       p.location_name, p.urgency, p.created_at, p.verification_score,
+		       u.trust_score,
+		       ARRAY_REMOVE(ARRAY_AGG(DISTINCT pt.topic_id::text), NULL) AS topic_ids,
+		       `+corroborationsSQL+` AS corroborations
+		FROM posts p
+		JOIN users u ON u.id = p.author_id
+		LEFT JOIN post_topics pt ON pt.post_id = p.id
+		WHERE p.location IS NOT NULL
+		  AND p.is_flagged = false
+		  AND (p.expires_at IS NULL OR p.expires_at > NOW())
+		  AND (p.created_at > NOW() - $1::interval
+		       OR p.id IN (SELECT ip.post_id FROM incident_posts ip
+		                   JOIN incidents i ON i.id = ip.incident_id
+		                   WHERE i.last_seen_at > NOW() - $1::interval))
+		GROUP BY p.id, p.author_id, p.content, p.source_type, p.location, p.location_name,
+		         p.urgency, p.created_at, p.verification_score, u.trust_score
+		ORDER BY p.created_at DESC
+		LIMIT $2
+	`, fmt.Sprintf("%d seconds", int(incidentWindow.Seconds())), maxIncidentCandidates)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []incidentPost
+	for rows.Next() {
+		var post postCandidate
+		if err := rows.Scan(
+			&post.id, &post.authorID, &post.content, &post.sourceType,
+			&post.latitude, &post.longitude,
+			&post.locationName, &post.urgency, &post.createdAt, &post.verificationScore,
+			&post.authorTrustScore, &post.topicIDs, &post.corroborations,
+		); err != nil {
+			log.Printf("incidents: candidate scan error: %v", err)
+			continue
+		}
+		posts = append(posts, newIncidentPost(post))
+	}
+	return posts, rows.Err()
+}
+
+func newIncidentPost(post postCandidate) incidentPost {
+	fingerprint, ok := simHash(post.content)
+	return incidentPost{postCandidate: post, fingerprint: fingerprint, hasText: ok}
+}
+
+// clusterIncidentPosts groups posts by single linkage: two posts are
+// linked when they are within incidentRadius and incidentTimeGap of
+// each other and either share a topic or have similar text. Clusters
+// come back in the order of their first post in the input.
+func clusterIncidentPosts(posts []incidentPost) [][]incidentPost {
+	parent := make([]int, len(posts))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	for i := range posts {
+		for j := i + 1; j < len(posts); j++ {
+			if incidentLinked(posts[i], posts[j]) {
+				if a, b := find(i), find(j); a != b {
+					parent[b] = a
+				}
+			}
+		}
+	}
+
+	index := make(map[int]int)
+	var clusters [][]incidentPost
+	for i, p := range posts {
+		root := find(i)
+		n, ok := index[root]
+		if !ok {
+			n = len(clusters)
+			index[root] = n
+			clusters = append(clusters, nil)
+		}
+		clusters[n] = append(clusters[n], p)
+	}
+	return clusters
+}
+
+func incidentLinked(a, b incidentPost) bool {
+	if a.latitude == nil || a.longitude == nil || b.latitude == nil || b.longitude == nil {
+		return false
+	}
+	gap := a.createdAt.Sub(b.createdAt)
+	if gap < 0 {
+		gap = -gap
+	}
+	if gap > incidentTimeGap {
+		return false
+	}
+	if distanceMeters(*a.latitude, *a.longitude, *b.latitude, *b.longitude) > incidentRadius {
+		return false
+	}
+	if sharesTopic(a.topicIDs, b.topicIDs) {
+		return true
+	}
+	return a.hasText && b.hasText && bits.OnesCount64(a.fingerprint^b.fingerprint) <= incidentMaxHamming
+}
+
+func sharesTopic(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// simHash returns a 64-bit SimHash of the distinct words (three or
+// more letters) in text, so texts sharing most of their words have
+// fingerprints differing in few bits. ok is false when text has no
+// such words; an empty fingerprint says nothing about similarity.
+func simHash(text string) (fingerprint uint64, ok bool) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var weights [64]int
+	seen := make(map[string]bool)
+	for _, w := range words {
+		if len([]rune(w)) < 3 || seen[w] {
+			continue
+		}
+		seen[w] = true
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(w))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	if len(seen) == 0 {
+		return 0, false
+	}
+	for bit, w := range weights {
+		if w > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint, true
+}
+
+// summarizeIncident computes the stored fields for one cluster. The
+// lead post is the most confident one, newest first on ties; the
+// location name is the lead's, or any member's if it has none.
+func summarizeIncident(cluster []incidentPost) incidentSummary {
+	var s incidentSummary
+	var sumLat, sumLon float64
+	var leadConfidence float64
+	var leadCreatedAt time.Time
+	authorBest := make(map[string]float64)
+	topics := make(map[string]bool)
+
+	for i, p := range cluster {
+		s.postIDs = append(s.postIDs, p.id)
+		sumLat += *p.latitude
+		sumLon += *p.longitude
+
+		conf := confidenceScore(p.postCandidate)
+		if i == 0 || conf > leadConfidence || (conf == leadConfidence && p.createdAt.After(leadCreatedAt)) {
+			s.leadPostID = p.id
+			leadConfidence = conf
+			leadCreatedAt = p.createdAt
+			if p.locationName != nil {
+				s.locationName = p.locationName
+			}
+		}
+		if s.locationName == nil {
+			s.locationName = p.locationName
+		}
+		if conf > authorBest[p.authorID] {
+			authorBest[p.authorID] = conf
+		}
+		for _, t := range p.topicIDs {
+			if !topics[t] {
+				topics[t] = true
+				s.topicIDs = append(s.topicIDs, t)
+			}
+		}
+		if p.urgency > s.maxUrgency {
+			s.maxUrgency = p.urgency
+		}
+		if s.firstSeenAt.IsZero() || p.createdAt.Before(s.firstSeenAt) {
+			s.firstSeenAt = p.createdAt
+		}
+		if p.createdAt.After(s.lastSeenAt) {
+			s.lastSeenAt = p.createdAt
+		}
+	}
+
+	s.latitude = sumLat / float64(len(cluster))
+	s.longitude = sumLon / float64(len(cluster))
+	for _, p := range cluster {
+		d := int(math.Ceil(distanceMeters(s.latitude, s.longitude, *p.latitude, *p.longitude)))
+		if d > s.radiusMeters {
+			s.radiusMeters = d
+		}
+	}
+
+	s.authorCount = len(authorBest)
+	best := make([]float64, 0, len(authorBest))
+	for _, c := range authorBest {
+		best = append(best, c)
+	}
+	s.confidence = combinedConfidence(best)
+	if s.topicIDs == nil {
+		s.topicIDs = []string{}
+	}
+	return s
+}
+
+// combinedConfidence merges each author's best confidence as
+// independent evidence (noisy-OR). Every author after the most
+// confident one counts at half weight, so a handful of new accounts
+// can't talk an incident up to certainty.
+func combinedConfidence(perAuthor []float64) float64 {
+	if len(perAuthor) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), perAuthor...)
+	sort.Sort(sort.Reverse(sort.Float64Slice(sorted)))
+
+	doubt := 1 - sorted[0]
+	for _, c := range sorted[1:] {
+		doubt *= 1 - c/2
+	}
+	return 1 - doubt
+}
+
+// reusableIncidentID picks the previous incident that most of postIDs
+// belonged to, skipping ones already claimed this run. Empty means
+// the cluster is a new incident.
+func reusableIncidentID(postIDs []string, previous map[string]string, claimed map[string]bool) string {
+	votes := make(map[string]int)
+	for _, id := range postIDs {
+		if incidentID, ok := previous[id]; ok && !claimed[incidentID] {
+			votes[incidentID]++
+		}
+	}
+	best, bestVotes := "", 0
+	for id, n := range votes {
+		if n > bestVotes || (n == bestVotes && id < best) {
+			best, bestVotes = id, n
+		}
+	}
+	return best
+}
+
+// getIncidentsBatch returns the incident each of postIDs belongs to,
+// as the "incident" body of a feed item (without its lead post).
+func (h *Handler) getIncidentsBatch(ctx context.Context, postIDs []string) map[string]gin.H {
+	result := make(map[string]gin.H)
+	if len(postIDs) == 0 {
+		return result
+	}
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT ip.post_id, i.id, i.post_count, i.author_count, i.confidence, i.max_urgency,
+		       ST_Y(i.location::geometry), ST_X(i.location::geometry), i.radius_meters,
+		       i.location_name, i.topic_ids::text[], i.first_seen_at, i.last_seen_at,
+		       ARRAY(SELECT m.post_id::text FROM incident_posts m JOIN posts p ON p.id = m.post_id
+		             WHERE m.incident_id = i.id ORDER BY p.created_at)
+		FROM incident_posts ip
+		JOIN incidents i ON i.id = ip.incident_id
+		WHERE ip.post_id = ANY($1::uuid[])
+	`, postIDs)
+	if err != nil {
+		log.Printf("feed: batch incident query error: %v", err)
+		return result
+	}
+	defer rows.Close()
+
+	byID := make(map[string]gin.H)
+	for rows.Next() {
+		var postID, id string
+		var postCount, authorCount, maxUrgency, radius int
+		var confidence, lat, lon float64
+		var locationName *string
+		var topicIDs, memberIDs []string
+		var firstSeen, lastSeen time.Time
+		if err := rows.Scan(&postID, &id, &postCount, &authorCount, &confidence, &maxUrgency,
+			&lat, &lon, &radius, &locationName, &topicIDs, &firstSeen, &lastSeen, &memberIDs); err != nil {
+			log.Printf("feed: incident scan error: %v", err)
+			continue
+		}
+		if incident, ok := byID[id]; ok {
+			result[postID] = incident
+			continue
+		}
+		incident := gin.H{
+			"id":             id,
+			"post_count":     postCount,
+			"corroborations": authorCount,
+			"confidence":     confidence,
+			"urgency":        maxUrgency,
+			"location":       gin.H{"latitude": lat, "longitude": lon},
+			"radius_meters":  radius,
+			"topic_ids":      topicIDs,
+			"post_ids":       memberIDs,
+			"first_seen_at":  firstSeen,
+			"last_seen_at":   lastSeen,
+		}
+		if locationName != nil {
+			incident["location_name"] = *locationName
+		}
+		byID[id] = incident
+		result[postID] = incident
+	}
+	return result
+}
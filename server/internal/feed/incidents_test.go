@@ -0,0 +1,110 @@
+package feed
+
+import (
+	"math/bits"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimHash_SimilarTextsAreClose(t *testing.T) {
+	a, ok := simHash("Police are kettling protesters at Central Square, avoid the north exit")
+	require.True(t, ok)
+	b, _ := simHash("police kettling protesters at central square right now, north exit blocked")
+	c, _ := simHash("Food bank on Elm Street needs volunteers for Saturday morning shifts")
+
+	assert.LessOrEqual(t, bits.OnesCount64(a^b), incidentMaxHamming)
+	assert.Greater(t, bits.OnesCount64(a^c), incidentMaxHamming)
+
+	same, _ := simHash("POLICE are kettling protesters at central square; avoid the north exit!")
+	assert.Equal(t, a, same, "case and punctuation don't matter")
+
+	_, ok = simHash("ok !! :)")
+	assert.False(t, ok, "no usable words")
+}
+
+func TestClusterIncidentPosts(t *testing.T) {
+	base := time.Now()
+	post := func(id, author string, lat, lon float64, minutes int, topics []string, content string) incidentPost {
+		return newIncidentPost(postCandidate{
+			id: id, authorID: author, latitude: &lat, longitude: &lon,
+			createdAt: base.Add(time.Duration(minutes) * time.Minute),
+			topicIDs:  topics, content: content, sourceType: "firsthand", urgency: 2,
+		})
+	}
+
+	posts := []incidentPost{
+		post("a", "u1", 52.5200, 13.4050, 0, []string{"police"}, "Kettle forming"),
+		// ~150 m away, shares a topic.
+		post("b", "u2", 52.5210, 13.4060, 10, []string{"police"}, "Lines of vans here"),
+		// Same place, no topic, but reads like a.
+		post("c", "u3", 52.5205, 13.4052, 20, nil, "Kettle forming at the square"),
+		// Same place but hours later: a different incident.
+		post("d", "u4", 52.5200, 13.4050, 300, []string{"police"}, "Kettle forming"),
+		// Same time and topic but across town.
+		post("e", "u5", 52.4800, 13.3500, 5, []string{"police"}, "Kettle forming"),
+		// Same place and time, unrelated topic and text.
+		post("f", "u6", 52.5201, 13.4051, 5, []string{"housing"}, "Eviction notices posted on every door"),
+	}
+
+	var groups [][]string
+	for _, cluster := range clusterIncidentPosts(posts) {
+		var ids []string
+		for _, p := range cluster {
+			ids = append(ids, p.id)
+		}
+		groups = append(groups, ids)
+	}
+	assert.Equal(t, [][]string{{"a", "b", "c"}, {"d"}, {"e"}, {"f"}}, groups)
+}
+
+func TestSummarizeIncident(t *testing.T) {
+	base := time.Now()
+	name := "Central Square"
+	lat1, lon1, lat2, lon2 := 52.5200, 13.4050, 52.5210, 13.4060
+	cluster := []incidentPost{
+		newIncidentPost(postCandidate{id: "a", authorID: "u1", latitude: &lat1, longitude: &lon1,
+			createdAt: base, urgency: 1, authorTrustScore: 20, sourceType: "firsthand", topicIDs: []string{"t1"}}),
+		newIncidentPost(postCandidate{id: "b", authorID: "u1", latitude: &lat1, longitude: &lon1,
+			createdAt: base.Add(5 * time.Minute), urgency: 3, authorTrustScore: 20, sourceType: "firsthand"}),
+		newIncidentPost(postCandidate{id: "c", authorID: "u2", latitude: &lat2, longitude: &lon2, locationName: &name,
+			createdAt: base.Add(10 * time.Minute), urgency: 2, authorTrustScore: 80, sourceType: "firsthand", topicIDs: []string{"t1", "t2"}}),
+	}
+
+	s := summarizeIncident(cluster)
+	assert.Equal(t, []string{"a", "b", "c"}, s.postIDs)
+	assert.Equal(t, "c", s.leadPostID, "most confident post leads")
+	assert.Equal(t, 2, s.authorCount, "one author posting twice counts once")
+	assert.Equal(t, 3, s.maxUrgency)
+	assert.Equal(t, []string{"t1", "t2"}, s.topicIDs)
+	assert.Equal(t, &name, s.locationName)
+	assert.Equal(t, base, s.firstSeenAt)
+	assert.Equal(t, base.Add(10*time.Minute), s.lastSeenAt)
+	assert.InDelta(t, (2*lat1+lat2)/3, s.latitude, 1e-9)
+	assert.Greater(t, s.radiusMeters, 0)
+	assert.Greater(t, s.confidence, confidenceScore(cluster[2].postCandidate),
+		"a second author raises confidence above the best single post")
+}
+
+func TestCombinedConfidence(t *testing.T) {
+	assert.Equal(t, 0.0, combinedConfidence(nil))
+	assert.InDelta(t, 0.6, combinedConfidence([]float64{0.6}), 1e-9)
+	assert.InDelta(t, 0.68, combinedConfidence([]float64{0.4, 0.6}), 1e-9, "second author counts at half weight")
+
+	// Many weak authors approach but never reach certainty, and stay
+	// well short of it for a handful of them.
+	weak := []float64{0.3, 0.3, 0.3, 0.3, 0.3}
+	assert.Less(t, combinedConfidence(weak), 0.7)
+	assert.Less(t, combinedConfidence(append(weak, weak...)), 1.0)
+}
+
+func TestReusableIncidentID(t *testing.T) {
+	previous := map[string]string{"a": "inc1", "b": "inc2", "c": "inc2"}
+
+	assert.Equal(t, "inc2", reusableIncidentID([]string{"a", "b", "c"}, previous, map[string]bool{}))
+	assert.Equal(t, "inc1", reusableIncidentID([]string{"a", "b", "c"}, previous, map[string]bool{"inc2": true}),
+		"an incident claimed by another cluster isn't reused")
+	assert.Equal(t, "", reusableIncidentID([]string{"x"}, previous, map[string]bool{}))
+}
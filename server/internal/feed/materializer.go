@@ -2,12 +2,18 @@
 // GetFeedV2 becomes a simple indexed SELECT instead of loading 1200
 // rows and scoring them in Go per request.
 //
-// The worker process calls RunOnce on a ~5 minute tick. It:
-//   1. Lists users with last_active_at within the recent window.
-//   2. For each user × supported feed type, fetches candidates,
-//      runs the same ranker used in the live path, and UPSERTs into
-//      materialized_feeds.
-//   3. Deletes stale rows for users that have gone dormant.
+// Two paths keep materialized_feeds current:
+//   - Incremental: CreatePost hands the new post to materializePost,
+//     which finds interested users through the subscription indexes,
+//     scores the post for each of them and inserts it into their
+//     newest generation.
+//   - Full rebuild: the worker calls RunOnce every FullRebuildInterval.
+//     It lists users with last_active_at within the recent window,
+//     re-ranks every materialized feed type for them into a new
+//     generation, and deletes stale rows for users that have gone
+//     dormant. This is the consistency check: it picks up posts the
+//     incremental path can't see (news bot inserts, non-subscribers)
+//     and refreshes decayed recency scores.
 //
 // We reuse the Handler's helpers (fetchFeedCandidates, rankFeedCandidates,
 // getUserSubscriptions) so there's exactly one scoring implementation
@@ -17,6 +23,7 @@ package feed
 import (
 	"context"
 	"fmt"
+	"log"
 	"log/slog"
 	"net/http"
 	"time"
@@ -24,9 +31,36 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/kuurier/server/internal/config"
 	"github.com/kuurier/server/internal/metrics"
+	"github.com/kuurier/server/internal/mutes"
 	"github.com/kuurier/server/internal/storage"
 )
 
+const (
+	// FullRebuildInterval is how often the worker should run RunOnce.
+	// Incremental inserts cover new posts in between.
+	FullRebuildInterval = 30 * time.Minute
+
+	// materializedMaxAge is how old a user's newest generation may be
+	// before GetFeedV2 stops trusting it and ranks live instead. Two
+	// rebuild intervals, so one missed pass doesn't fall back.
+	materializedMaxAge = 2 * FullRebuildInterval
+
+	// generationGrace is how long a superseded materialized generation
+	// stays readable for cursors that started on it.
+	generationGrace = 15 * time.Minute
+
+	// maxMaterializedItems caps what a rebuild persists per feed. Clients
+	// page through, but 200 items is enough headroom for deep scrolling
+	// without bloating the table.
+	maxMaterializedItems = 200
+)
+
+// materializedFeedTypes are the feeds the worker precomputes. They're
+// ranked without request-time location, so local and crisis only
+// serve from here when the request doesn't pass lat/lon (see
+// feedQuery.materializable).
+var materializedFeedTypes = []FeedType{FeedTypeForYou, FeedTypeFollowing, FeedTypeLocal, FeedTypeCrisis}
+
 // Materializer runs the feed precomputation job. Constructed once in
 // the worker's main and Run()-ed on a schedule.
 type Materializer struct {
@@ -37,14 +71,11 @@ type Materializer struct {
 // NewMaterializer returns a Materializer backed by a feed Handler
 // that shares its DB pool + config with the rest of the package.
 func NewMaterializer(cfg *config.Config, db *storage.Postgres, redis *storage.Redis) *Materializer {
-	return &Materializer{h: NewHandler(cfg, db, redis)}
+	return &Materializer{h: NewHandler(cfg, db, redis, nil)}
 }
 
-// RunOnce computes materialized feeds for recently-active users.
-//
-// Currently materializes the default "for_you" feed only. Following
-// feeds ("local", "crisis", "following") are small-query-driven and
-// time-sensitive — caching them buys less.
+// RunOnce rebuilds every materialized feed type for recently-active
+// users.
 func (m *Materializer) RunOnce(ctx context.Context) error {
 	start := time.Now()
 	users, err := m.activeUsers(ctx, 7*24*time.Hour)
@@ -60,13 +91,11 @@ func (m *Materializer) RunOnce(ctx context.Context) error {
 		return fmt.Errorf("fetch candidates: %w", err)
 	}
 
-	feedType := FeedTypeForYou
-
 	for _, userID := range users {
 		if err := ctx.Err(); err != nil {
 			return err
 		}
-		if err := m.materializeFor(ctx, userID, feedType, candidates); err != nil {
+		if err := m.materializeFor(ctx, userID, candidates); err != nil {
 			// Per-user failures shouldn't kill the whole pass.
 			slog.WarnContext(ctx, "materialize user failed",
 				slog.String("user_id", userID),
@@ -110,45 +139,56 @@ func (m *Materializer) activeUsers(ctx context.Context, since time.Duration) ([]
 	return ids, rows.Err()
 }
 
-func (m *Materializer) materializeFor(ctx context.Context, userID string, feedType FeedType, candidates []postCandidate) error {
-	subs, topicNames, err := m.h.getUserSubscriptions(ctx, userID)
+func (m *Materializer) materializeFor(ctx context.Context, userID string, candidates []postCandidate) error {
+	subs, topicTree, err := m.h.getUserSubscriptions(ctx, userID)
 	if err != nil {
 		return fmt.Errorf("get subscriptions: %w", err)
 	}
-	scored := m.h.rankFeedCandidates(feedType, candidates, subs, topicNames, nil, nil, 50000, 0)
-
-	// Cap what we persist — clients page through but 200 items is
-	// enough headroom for deep scrolling without bloating the table.
-	const maxItems = 200
-	if len(scored) > maxItems {
-		scored = scored[:maxItems]
+	muted := m.h.loadMutes(ctx, userID)
+	for _, feedType := range materializedFeedTypes {
+		scored := m.h.rankFeedCandidates(feedType, candidates, subs, topicTree, nil, nil, 50000, 0, muted)
+		if len(scored) > maxMaterializedItems {
+			scored = scored[:maxMaterializedItems]
+		}
+		if err := m.writeGeneration(ctx, userID, feedType, scored); err != nil {
+			return fmt.Errorf("%s: %w", feedType, err)
+		}
 	}
+	return nil
+}
 
-	// Atomic swap: delete old rows for this user+feed_type and insert new.
+// writeGeneration writes scored as a new generation, then drops
+// earlier generations older than generationGrace. Readers always take
+// the newest generation for a first page; cursors keep reading the
+// generation they started on until it is pruned.
+func (m *Materializer) writeGeneration(ctx context.Context, userID string, feedType FeedType, scored []scoredFeedItem) error {
+	generation := time.Now().UnixMicro()
 	tx, err := m.h.db.Pool().Begin(ctx)
 	if err != nil {
 		return err
 	}
 	defer func() { _ = tx.Rollback(ctx) }()
 
-	if _, err := tx.Exec(ctx,
-		`DELETE FROM materialized_feeds WHERE user_id = $1 AND feed_type = $2`,
-		userID, string(feedType)); err != nil {
-		return err
-	}
-
 	for _, item := range scored {
 		if item.post == nil {
 			continue
 		}
 		if _, err := tx.Exec(ctx, `
-			INSERT INTO materialized_feeds (user_id, feed_type, post_id, score, why, computed_at)
-			VALUES ($1, $2, $3, $4, $5, NOW())
-		`, userID, string(feedType), item.post.id, item.score, item.why); err != nil {
+			INSERT INTO materialized_feeds (user_id, feed_type, generation, post_id, score, why, computed_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		`, userID, string(feedType), generation, item.post.id, item.score, item.why); err != nil {
 			return err
 		}
 	}
 
+	if _, err := tx.Exec(ctx, `
+		DELETE FROM materialized_feeds
+		WHERE user_id = $1 AND feed_type = $2 AND generation < $3
+		  AND computed_at < NOW() - $4::interval
+	`, userID, string(feedType), generation, fmt.Sprintf("%d seconds", int(generationGrace.Seconds()))); err != nil {
+		return err
+	}
+
 	return tx.Commit(ctx)
 }
 
@@ -156,19 +196,53 @@ func (m *Materializer) materializeFor(ctx context.Context, userID string, feedTy
 // table. Returns true if it served a response; false means the
 // caller should fall back to the live compute path (no materialized
 // rows exist yet for this user, or they're too stale).
-func (h *Handler) serveMaterialized(c *gin.Context, userID string, feedType FeedType, limit, offset int) bool {
+//
+// A first page reads the newest generation. A cursor page reads the
+// cursor's generation, keyset-paged by (score, post_id); if that
+// generation has been pruned, it returns false and the live path
+// re-ranks best-effort.
+func (h *Handler) serveMaterialized(c *gin.Context, userID string, q feedQuery, cur *feedCursor) bool {
 	ctx := c.Request.Context()
+	feedType, limit, offset := q.feedType, q.limit, q.offset
 
-	// Staleness check: if the newest row is older than 10 minutes,
-	// don't serve it — the materializer missed a tick (crash? deploy?
-	// new user) and the user gets the fresh live result.
-	var newest *time.Time
-	err := h.db.Pool().QueryRow(ctx, `
-		SELECT MAX(computed_at) FROM materialized_feeds
-		WHERE user_id = $1 AND feed_type = $2
-	`, userID, string(feedType)).Scan(&newest)
-	if err != nil || newest == nil || time.Since(*newest) > 10*time.Minute {
-		return false
+	var generation int64
+	snapshotAt := time.Now()
+	var afterScore *float64
+	var afterID *string
+	if cur != nil {
+		var exists bool
+		err := h.db.Pool().QueryRow(ctx, `
+			SELECT EXISTS(SELECT 1 FROM materialized_feeds
+			              WHERE user_id = $1 AND feed_type = $2 AND generation = $3)
+		`, userID, string(feedType), cur.Generation).Scan(&exists)
+		if err != nil || !exists {
+			return false
+		}
+		generation = cur.Generation
+		snapshotAt = time.Unix(cur.SnapshotAt, 0)
+		offset = cur.Offset
+		if cur.LastID != "" {
+			afterScore, afterID = &cur.LastScore, &cur.LastID
+		}
+	} else {
+		// Staleness check: if the newest generation is older than
+		// materializedMaxAge, don't serve it — the materializer missed
+		// ticks (crash? deploy? new user) and the user gets the fresh
+		// live result.
+		var newest *time.Time
+		var newestGen *int64
+		err := h.db.Pool().QueryRow(ctx, `
+			SELECT MAX(computed_at), MAX(generation) FROM materialized_feeds
+			WHERE user_id = $1 AND feed_type = $2
+		`, userID, string(feedType)).Scan(&newest, &newestGen)
+		if err != nil || newest == nil || newestGen == nil || time.Since(*newest) > materializedMaxAge {
+			return false
+		}
+		generation = *newestGen
+	}
+	keysetOffset := offset
+	if afterScore != nil {
+		keysetOffset = 0
 	}
 
 	rows, err := h.db.Pool().Query(ctx, `
@@ -177,15 +251,18 @@ func (h *Handler) serveMaterialized(c *gin.Context, userID string, feedType Feed
 		       ST_Y(p.location::geometry) as lat,
 		       ST_X(p.location::geometry) as lon,
 		       p.location_name, p.urgency, p.created_at, p.verification_score,
-		       COALESCE(u.trust_score, 0) AS trust_score
+		       COALESCE(u.trust_score, 0) AS trust_score, p.edited_at
 		FROM materialized_feeds mf
 		JOIN posts p ON p.id = mf.post_id
 		LEFT JOIN users u ON u.id = p.author_id
-		WHERE mf.user_id = $1 AND mf.feed_type = $2
+		WHERE mf.user_id = $1 AND mf.feed_type = $2 AND mf.generation = $3
 		  AND p.is_flagged = false
-		ORDER BY mf.score DESC
-		LIMIT $3 OFFSET $4
-	`, userID, string(feedType), limit, offset)
+		  AND NOT `+mutes.HiddenSQL("$1", "p.author_id", "p.source_type", "p.content",
+		"ARRAY(SELECT topic_id::text FROM post_topics WHERE post_id = p.id)")+`
+		  AND ($6::float8 IS NULL OR (mf.score, mf.post_id) < ($6, $7::uuid))
+		ORDER BY mf.score DESC, mf.post_id DESC
+		LIMIT $4 OFFSET $5
+	`, userID, string(feedType), generation, limit, keysetOffset, afterScore, afterID)
 	if err != nil {
 		return false
 	}
@@ -201,7 +278,7 @@ func (h *Handler) serveMaterialized(c *gin.Context, userID string, feedType Feed
 			&post.authorID, &post.content, &post.sourceType,
 			&post.latitude, &post.longitude,
 			&post.locationName, &post.urgency, &post.createdAt, &post.verificationScore,
-			&post.authorTrustScore,
+			&post.authorTrustScore, &post.editedAt,
 		); err != nil {
 			continue
 		}
@@ -215,20 +292,173 @@ func (h *Handler) serveMaterialized(c *gin.Context, userID string, feedType Feed
 
 	items := h.buildFeedResponseItems(ctx, scored)
 	nextOffset := offset + len(items)
+	nextCursor := ""
 	if len(items) < limit {
 		nextOffset = -1
+	} else {
+		last := scored[len(scored)-1]
+		nextCursor = encodeCursor(h.cfg.JWTSecret, userID, feedCursor{
+			FeedType:   feedType,
+			Params:     q.params,
+			Source:     cursorSourceMaterialized,
+			SnapshotAt: snapshotAt.Unix(),
+			Offset:     nextOffset,
+			Generation: generation,
+			LastScore:  last.score,
+			LastID:     last.post.id,
+		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"items":       items,
+		"items":       h.withDigestItem(ctx, userID, feedType, offset, items),
 		"limit":       limit,
 		"offset":      offset,
 		"next_offset": nextOffset,
+		"next_cursor": nextCursor,
 		"source":      "materialized",
 	})
 	return true
 }
 
+// materializePost inserts a newly created post into the materialized
+// feeds it belongs in, so it shows up before the next full rebuild.
+// Runs in its own goroutine after CreatePost responds, so it uses a
+// fresh context.
+//
+// Rows go into each user's newest generation, stamped with that
+// generation's computed_at so an incremental insert never makes a
+// stale generation look fresh. Cursors already paging that
+// generation are keyset-based and aren't shifted by the insert.
+func (h *Handler) materializePost(postID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	post, err := h.loadCandidate(ctx, postID)
+	if err != nil {
+		log.Printf("feed: materialize post %s: %v", postID, err)
+		return
+	}
+	candidates := []postCandidate{*post}
+
+	// Crisis ranking doesn't depend on the reader, so the most urgent
+	// posts go straight into every materialized crisis feed instead of
+	// only subscribers' — they can't wait for the next rebuild.
+	if post.urgency >= realtimePushMinUrgency {
+		if scored := h.rankFeedCandidates(FeedTypeCrisis, candidates, nil, nil, nil, nil, 50000, 0, nil); len(scored) > 0 {
+			if _, err := h.db.Pool().Exec(ctx, `
+				INSERT INTO materialized_feeds (user_id, feed_type, generation, post_id, score, why, computed_at)
+				SELECT DISTINCT ON (mf.user_id) mf.user_id, mf.feed_type, mf.generation, $2, $3, $4, mf.computed_at
+				FROM materialized_feeds mf
+				JOIN posts p ON p.id = $2
+				WHERE mf.feed_type = $1
+				  AND NOT `+mutes.HiddenSQL("mf.user_id", "p.author_id", "p.source_type", "p.content",
+				"ARRAY(SELECT topic_id::text FROM post_topics WHERE post_id = p.id)")+`
+				ORDER BY mf.user_id, mf.generation DESC
+				ON CONFLICT DO NOTHING
+			`, string(FeedTypeCrisis), postID, scored[0].score, scored[0].why); err != nil {
+				log.Printf("feed: materialize post %s: crisis fan-out: %v", postID, err)
+			}
+		}
+	}
+
+	// Everyone else hears about the post through their subscriptions.
+	// Matching mirrors postMatchesTopics/postMatchesLocation (as in
+	// notifyRealtimeSubscribers); users without materialized rows are
+	// served live and skipped.
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT DISTINCT s.user_id
+		FROM subscriptions s
+		JOIN posts p ON p.id = $1
+		WHERE s.is_active = true
+		  AND s.min_urgency <= p.urgency
+		  AND (
+		        s.topic_id IN (SELECT tl.ancestor_id FROM post_topics pt JOIN topic_lineage tl ON tl.topic_id = pt.topic_id WHERE pt.post_id = p.id)
+		     OR (s.location IS NOT NULL AND p.location IS NOT NULL AND s.radius_meters IS NOT NULL
+		         AND ST_DWithin(s.location, p.location, s.radius_meters))
+		  )
+		  AND EXISTS (SELECT 1 FROM materialized_feeds mf WHERE mf.user_id = s.user_id)
+	`, postID)
+	if err != nil {
+		log.Printf("feed: materialize post %s: match subscribers: %v", postID, err)
+		return
+	}
+	var userIDs []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err == nil {
+			userIDs = append(userIDs, id)
+		}
+	}
+	rows.Close()
+
+	for _, userID := range userIDs {
+		subs, topicTree, err := h.getUserSubscriptions(ctx, userID)
+		if err != nil {
+			log.Printf("feed: materialize post %s for %s: %v", postID, userID, err)
+			continue
+		}
+		muted := h.loadMutes(ctx, userID)
+		for _, feedType := range materializedFeedTypes {
+			scored := h.rankFeedCandidates(feedType, candidates, subs, topicTree, nil, nil, 50000, 0, muted)
+			if len(scored) == 0 {
+				continue
+			}
+			// Upsert so a subscriber's personal score and "why" replace
+			// the reader-independent crisis row written above.
+			if _, err := h.db.Pool().Exec(ctx, `
+				INSERT INTO materialized_feeds (user_id, feed_type, generation, post_id, score, why, computed_at)
+				SELECT user_id, feed_type, generation, $3, $4, $5, computed_at
+				FROM materialized_feeds
+				WHERE user_id = $1 AND feed_type = $2
+				ORDER BY generation DESC
+				LIMIT 1
+				ON CONFLICT (user_id, feed_type, generation, post_id)
+				DO UPDATE SET score = EXCLUDED.score, why = EXCLUDED.why
+			`, userID, string(feedType), postID, scored[0].score, scored[0].why); err != nil {
+				log.Printf("feed: materialize post %s for %s: %v", postID, userID, err)
+			}
+		}
+	}
+}
+
+// loadCandidate loads one post with its topics, filtered like
+// fetchFeedCandidates.
+func (h *Handler) loadCandidate(ctx context.Context, postID string) (*postCandidate, error) {
+	var post postCandidate
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT p.id, p.author_id, p.content, p.source_type,
+			   ST_Y(p.location::geometry) as lat,
+			   ST_X(p.location::geometry) as lon,
+			   p.location_name, p.urgency, p.created_at, p.verification_score,
+			   u.trust_score,
+			   ARRAY(SELECT topic_id::text FROM post_topics WHERE post_id = p.id) AS topic_ids,
+			   `+corroborationsSQL+` AS corroborations
+		FROM posts p
+		JOIN users u ON u.id = p.author_id
+		WHERE p.id = $1
+		  AND p.is_flagged = false
+		  AND (p.expires_at IS NULL OR p.expires_at > NOW())
+	`, postID).Scan(
+		&post.id,
+		&post.authorID,
+		&post.content,
+		&post.sourceType,
+		&post.latitude,
+		&post.longitude,
+		&post.locationName,
+		&post.urgency,
+		&post.createdAt,
+		&post.verificationScore,
+		&post.authorTrustScore,
+		&post.topicIDs,
+		&post.corroborations,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
 func (m *Materializer) pruneStale(ctx context.Context, olderThan time.Duration) (int, error) {
 	res, err := m.h.db.Pool().Exec(ctx, `
 		DELETE FROM materialized_feeds
@@ -0,0 +1,401 @@
+// Threaded replies on posts.
+//
+// Replies nest up to maxReplyDepth levels under a post; answering a
+// reply at the deepest level attaches the answer next to it instead,
+// so a thread never runs off the side of a phone screen.
+//
+// A firsthand reply ("confirmed, I'm here and it's peaceful") can
+// corroborate its post: if a trusted user other than the author says
+// they're within corroborationRadius of the post's location within
+// corroborationWindow of it being posted, the reply is marked as
+// corroborating. Each distinct corroborating user counts like
+// corroborationWeight verifications in confidenceScore. The reply's
+// location is only used for that check and is never stored.
+package feed
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxReplyDepth is the deepest nesting level; 0 is a direct reply.
+	maxReplyDepth = 2
+
+	// maxThreadReplies caps how many replies GET /replies returns.
+	maxThreadReplies = 500
+
+	// replyMinTrust is the trust needed to reply. Lower than posting
+	// (25): adding context under someone else's report is lower risk
+	// than starting a new one.
+	replyMinTrust = 15
+
+	// corroborationMinTrust is the trust a firsthand reply's author
+	// needs for the reply to count as corroboration. Same bar as posting.
+	corroborationMinTrust = 25
+
+	corroborationRadius = 1000.0 // meters
+	corroborationWindow = 12 * time.Hour
+
+	// corroborationWeight is how many verifications one corroborating
+	// user is worth in confidenceScore.
+	corroborationWeight = 2
+
+	// replyFlagThreshold hides a reply once its score drops below it,
+	// same as FlagPost.
+	replyFlagThreshold = -5
+)
+
+// corroborationsSQL counts distinct corroborating users for the post
+// aliased p. Select it alongside a post to fill postCandidate.corroborations.
+const corroborationsSQL = `(SELECT COUNT(DISTINCT r.author_id) FROM post_replies r
+	WHERE r.post_id = p.id AND r.corroborates AND NOT r.is_flagged)`
+
+// CreateReplyRequest represents a new reply
+type CreateReplyRequest struct {
+	Content   string   `json:"content" binding:"required,max=1000"`
+	ParentID  *string  `json:"parent_id"` // Reply being answered; omit to answer the post
+	Firsthand bool     `json:"firsthand"` // Author is reporting from the scene
+	Latitude  *float64 `json:"latitude"`  // Only used to check corroboration; never stored
+	Longitude *float64 `json:"longitude"`
+}
+
+// replyStats is the per-post summary shown in feed items.
+type replyStats struct {
+	count          int
+	corroborations int
+}
+
+// CreateReply adds a reply to a post or to another reply
+func (h *Handler) CreateReply(c *gin.Context) {
+	userID := c.GetString("user_id")
+	trustScore := c.GetFloat64("trust_score")
+	postID := c.Param("id")
+
+	if trustScore < replyMinTrust {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":    "insufficient trust level to reply",
+			"required": replyMinTrust,
+			"current":  int(trustScore),
+		})
+		return
+	}
+
+	var req CreateReplyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "latitude and longitude must be given together"})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var authorID string
+	var postLat, postLon *float64
+	var postCreatedAt time.Time
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT author_id, ST_Y(location::geometry), ST_X(location::geometry), created_at
+		FROM posts
+		WHERE id = $1 AND is_flagged = false
+	`, postID).Scan(&authorID, &postLat, &postLon, &postCreatedAt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+
+	depth := 0
+	parentID := req.ParentID
+	if parentID != nil {
+		var parentDepth int
+		var grandparentID *string
+		err := h.db.Pool().QueryRow(ctx, `
+			SELECT depth, parent_id FROM post_replies
+			WHERE id = $1 AND post_id = $2 AND is_flagged = false
+		`, *parentID, postID).Scan(&parentDepth, &grandparentID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "parent reply not found"})
+			return
+		}
+		depth = parentDepth + 1
+		if depth > maxReplyDepth {
+			// Attach next to the parent rather than under it.
+			depth = parentDepth
+			parentID = grandparentID
+		}
+	}
+
+	corroborates := req.Firsthand &&
+		userID != authorID &&
+		trustScore >= corroborationMinTrust &&
+		time.Since(postCreatedAt) <= corroborationWindow &&
+		postLat != nil && postLon != nil &&
+		req.Latitude != nil && req.Longitude != nil &&
+		distanceMeters(*postLat, *postLon, *req.Latitude, *req.Longitude) <= corroborationRadius
+
+	var replyID string
+	var createdAt time.Time
+	err = h.db.Pool().QueryRow(ctx, `
+		INSERT INTO post_replies (post_id, parent_id, author_id, content, depth, is_firsthand, corroborates)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, postID, parentID, userID, req.Content, depth, req.Firsthand, corroborates).Scan(&replyID, &createdAt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create reply"})
+		return
+	}
+
+	response := gin.H{
+		"id":           replyID,
+		"post_id":      postID,
+		"depth":        depth,
+		"corroborates": corroborates,
+		"created_at":   createdAt,
+	}
+	if parentID != nil {
+		response["parent_id"] = *parentID
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// threadReply is one row of a reply thread.
+type threadReply struct {
+	id                string
+	parentID          *string
+	authorID          string
+	content           string
+	depth             int
+	isFirsthand       bool
+	corroborates      bool
+	verificationScore int
+	createdAt         time.Time
+	deleted           bool
+}
+
+// ListReplies returns a post's replies in thread order: each reply is
+// followed by its answers, oldest first at every level.
+func (h *Handler) ListReplies(c *gin.Context) {
+	postID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var exists bool
+	if err := h.db.Pool().QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM posts WHERE id = $1 AND is_flagged = false)", postID,
+	).Scan(&exists); err != nil || !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT id, parent_id, author_id, content, depth, is_firsthand, corroborates,
+		       verification_score, created_at, deleted_at IS NOT NULL
+		FROM post_replies
+		WHERE post_id = $1 AND is_flagged = false
+		ORDER BY created_at ASC
+		LIMIT $2
+	`, postID, maxThreadReplies)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch replies"})
+		return
+	}
+	defer rows.Close()
+
+	var replies []threadReply
+	for rows.Next() {
+		var r threadReply
+		if err := rows.Scan(&r.id, &r.parentID, &r.authorID, &r.content, &r.depth, &r.isFirsthand,
+			&r.corroborates, &r.verificationScore, &r.createdAt, &r.deleted); err != nil {
+			log.Printf("feed: reply scan error: %v", err)
+			continue
+		}
+		replies = append(replies, r)
+	}
+
+	ordered := threadOrder(replies)
+	result := make([]gin.H, 0, len(ordered))
+	for _, r := range ordered {
+		reply := gin.H{
+			"id":                 r.id,
+			"depth":              r.depth,
+			"created_at":         r.createdAt,
+			"verification_score": r.verificationScore,
+			"deleted":            r.deleted,
+		}
+		if r.parentID != nil {
+			reply["parent_id"] = *r.parentID
+		}
+		if !r.deleted {
+			reply["author_id"] = r.authorID
+			reply["content"] = r.content
+			reply["is_firsthand"] = r.isFirsthand
+			reply["corroborates"] = r.corroborates
+		}
+		result = append(result, reply)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"replies": result, "count": len(result)})
+}
+
+// threadOrder arranges replies (sorted oldest first) depth-first.
+// Answers to a reply that isn't in the list — flagged, or past the
+// fetch cap — are hidden along with it.
+func threadOrder(replies []threadReply) []threadReply {
+	children := make(map[string][]threadReply)
+	var roots []threadReply
+	for _, r := range replies {
+		if r.parentID == nil {
+			roots = append(roots, r)
+		} else {
+			children[*r.parentID] = append(children[*r.parentID], r)
+		}
+	}
+
+	ordered := make([]threadReply, 0, len(replies))
+	var walk func([]threadReply)
+	walk = func(level []threadReply) {
+		for _, r := range level {
+			ordered = append(ordered, r)
+			walk(children[r.id])
+		}
+	}
+	walk(roots)
+	return ordered
+}
+
+// DeleteReply deletes a reply (only by author). A reply that has been
+// answered is blanked instead, so the answers keep their place in the
+// thread.
+func (h *Handler) DeleteReply(c *gin.Context) {
+	userID := c.GetString("user_id")
+	postID := c.Param("id")
+	replyID := c.Param("reply_id")
+	ctx := c.Request.Context()
+
+	result, err := h.db.Pool().Exec(ctx, `
+		DELETE FROM post_replies r
+		WHERE r.id = $1 AND r.post_id = $2 AND r.author_id = $3
+		  AND NOT EXISTS (SELECT 1 FROM post_replies child WHERE child.parent_id = r.id)
+	`, replyID, postID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete reply"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		result, err = h.db.Pool().Exec(ctx, `
+			UPDATE post_replies
+			SET content = '[deleted]', is_firsthand = false, corroborates = false, deleted_at = NOW()
+			WHERE id = $1 AND post_id = $2 AND author_id = $3 AND deleted_at IS NULL
+		`, replyID, postID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete reply"})
+			return
+		}
+		if result.RowsAffected() == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "reply not found or unauthorized"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "reply deleted"})
+}
+
+// VerifyReply records a verification vote on a reply
+func (h *Handler) VerifyReply(c *gin.Context) {
+	h.voteOnReply(c, 1, "verification recorded")
+}
+
+// FlagReply records a flag on a reply; enough flags hide it
+func (h *Handler) FlagReply(c *gin.Context) {
+	h.voteOnReply(c, -1, "flag recorded")
+}
+
+// voteOnReply records the user's vote, replacing any earlier vote of
+// theirs, and applies the difference to the reply's score.
+func (h *Handler) voteOnReply(c *gin.Context, vote int, message string) {
+	userID := c.GetString("user_id")
+	postID := c.Param("id")
+	replyID := c.Param("reply_id")
+	ctx := c.Request.Context()
+
+	var authorID string
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT author_id FROM post_replies
+		WHERE id = $1 AND post_id = $2 AND is_flagged = false AND deleted_at IS NULL
+	`, replyID, postID).Scan(&authorID)
+	if err == pgx.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{"error": "reply not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record vote"})
+		return
+	}
+	if authorID == userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "cannot vote on your own reply"})
+		return
+	}
+
+	// Both CTEs see the snapshot before the upsert, so prev is the
+	// user's earlier vote (if any).
+	_, err = h.db.Pool().Exec(ctx, `
+		WITH prev AS (
+			SELECT vote FROM post_reply_votes WHERE reply_id = $1 AND user_id = $2
+		), upsert AS (
+			INSERT INTO post_reply_votes (reply_id, user_id, vote) VALUES ($1, $2, $3)
+			ON CONFLICT (reply_id, user_id) DO UPDATE SET vote = EXCLUDED.vote, created_at = NOW()
+		)
+		UPDATE post_replies
+		SET verification_score = verification_score + $3 - COALESCE((SELECT vote FROM prev), 0),
+		    is_flagged = CASE
+		        WHEN verification_score + $3 - COALESCE((SELECT vote FROM prev), 0) < $4 THEN true
+		        ELSE is_flagged
+		    END
+		WHERE id = $1
+	`, replyID, userID, vote, replyFlagThreshold)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to record vote"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// getReplyStatsBatch returns reply and corroboration counts for posts.
+// Posts without replies are absent from the map.
+func (h *Handler) getReplyStatsBatch(ctx context.Context, postIDs []string) map[string]replyStats {
+	result := make(map[string]replyStats)
+	if len(postIDs) == 0 {
+		return result
+	}
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT post_id, COUNT(*),
+		       COUNT(DISTINCT author_id) FILTER (WHERE corroborates)
+		FROM post_replies
+		WHERE post_id = ANY($1) AND is_flagged = false AND deleted_at IS NULL
+		GROUP BY post_id
+	`, postIDs)
+	if err != nil {
+		log.Printf("feed: batch reply count query error: %v", err)
+		return result
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID string
+		var stats replyStats
+		if err := rows.Scan(&postID, &stats.count, &stats.corroborations); err != nil {
+			log.Printf("feed: reply count scan error: %v", err)
+			continue
+		}
+		result[postID] = stats
+	}
+	return result
+}
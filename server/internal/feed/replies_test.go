@@ -0,0 +1,45 @@
+package feed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThreadOrder_DepthFirstOldestFirst(t *testing.T) {
+	ref := func(s string) *string { return &s }
+	base := time.Now()
+	at := func(min int) time.Time { return base.Add(time.Duration(min) * time.Minute) }
+
+	// Input is ordered by created_at, as ListReplies fetches it.
+	replies := []threadReply{
+		{id: "a", createdAt: at(0)},
+		{id: "b", createdAt: at(1)},
+		{id: "a1", parentID: ref("a"), depth: 1, createdAt: at(2)},
+		{id: "b1", parentID: ref("b"), depth: 1, createdAt: at(3)},
+		{id: "a1x", parentID: ref("a1"), depth: 2, createdAt: at(4)},
+		{id: "a2", parentID: ref("a"), depth: 1, createdAt: at(5)},
+		{id: "orphan", parentID: ref("flagged"), depth: 1, createdAt: at(6)},
+	}
+
+	var ids []string
+	for _, r := range threadOrder(replies) {
+		ids = append(ids, r.id)
+	}
+	assert.Equal(t, []string{"a", "a1", "a1x", "a2", "b", "b1"}, ids,
+		"answers follow their parent; answers to hidden replies are hidden")
+}
+
+func TestConfidenceScore_CorroborationRaisesConfidence(t *testing.T) {
+	post := postCandidate{authorTrustScore: 30, verificationScore: 0, sourceType: "firsthand"}
+	corroborated := post
+	corroborated.corroborations = 2
+
+	assert.Greater(t, confidenceScore(corroborated), confidenceScore(post))
+
+	// Corroboration is capped along with verification.
+	flooded := post
+	flooded.corroborations = 1000
+	assert.LessOrEqual(t, confidenceScore(flooded), 1.0)
+}
@@ -0,0 +1,463 @@
+// Post editing with revision history.
+//
+// Authors can correct a post's content, location and urgency instead
+// of deleting it. Each edit copies the version it replaces into
+// post_revisions; GET .../revisions lists every version with what
+// changed and a word diff of the content.
+//
+// Verification votes were cast for what the post said before, so
+// classifyEdit grades each edit:
+//   - minor (typo fix, location nudged by up to minorMoveMeters, new
+//     location name): verification is kept;
+//   - material (content reworded, location moved within
+//     corroborationRadius or added/removed, urgency changed): positive
+//     verification is halved;
+//   - rewrite (content mostly replaced, or moved beyond
+//     corroborationRadius): positive verification is reset to zero and
+//     replies stop counting as corroboration, since they vouched for
+//     the old report.
+//
+// Negative verification is never washed away by editing, and posts
+// that have been flagged at least once can't be edited at all, to
+// stop bait-and-switch.
+package feed
+
+import (
+	"context"
+	"errors"
+	"math/bits"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kuurier/server/internal/api/types"
+)
+
+const (
+	// minorEditHamming is the most SimHash bits an edit may change in
+	// the content and still count as a typo fix. Case, punctuation and
+	// whitespace don't change the fingerprint at all.
+	minorEditHamming = 6
+
+	// minorMoveMeters is how far a location can be corrected without
+	// affecting verification.
+	minorMoveMeters = 100.0
+
+	// maxPostRevisions caps edits per post.
+	maxPostRevisions = 50
+)
+
+// editImpact grades how much an edit invalidates earlier verification.
+type editImpact int
+
+const (
+	editMinor editImpact = iota
+	editMaterial
+	editRewrite
+)
+
+// UpdatePostRequest edits a post. Omitted fields are left unchanged;
+// latitude and longitude go together, and clear_location removes the
+// location.
+type UpdatePostRequest struct {
+	Content       *string  `json:"content" binding:"omitempty,min=1,max=2000"`
+	Latitude      *float64 `json:"latitude"`
+	Longitude     *float64 `json:"longitude"`
+	ClearLocation bool     `json:"clear_location"`
+	LocationName  *string  `json:"location_name" binding:"omitempty,max=200"`
+	Urgency       *int     `json:"urgency" binding:"omitempty,min=1,max=3"`
+}
+
+// postVersion is the editable part of a post.
+type postVersion struct {
+	content      string
+	latitude     *float64
+	longitude    *float64
+	locationName *string
+	urgency      int
+}
+
+var errPostNotFound = errors.New("post not found")
+
+// UpdatePost edits one of the user's own posts
+func (h *Handler) UpdatePost(c *gin.Context) {
+	userID := c.GetString("user_id")
+	postID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req UpdatePostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "latitude and longitude must be set together"})
+		return
+	}
+	if req.ClearLocation && req.Latitude != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot set and clear the location"})
+		return
+	}
+
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update post"})
+		return
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	var before postVersion
+	var authorID string
+	var verificationScore, revision, flagCount int
+	var isFlagged bool
+	var createdAt time.Time
+	var editedAt *time.Time
+	err = tx.QueryRow(ctx, `
+		SELECT author_id, content, ST_Y(location::geometry), ST_X(location::geometry),
+		       location_name, urgency, verification_score, revision, is_flagged, flag_count,
+		       created_at, edited_at
+		FROM posts WHERE id = $1
+		FOR UPDATE
+	`, postID).Scan(&authorID, &before.content, &before.latitude, &before.longitude,
+		&before.locationName, &before.urgency, &verificationScore, &revision, &isFlagged, &flagCount,
+		&createdAt, &editedAt)
+	if err != nil || authorID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found or unauthorized"})
+		return
+	}
+	if isFlagged || flagCount > 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "flagged posts can't be edited"})
+		return
+	}
+	if revision >= maxPostRevisions {
+		c.JSON(http.StatusConflict, gin.H{"error": "edit limit reached for this post"})
+		return
+	}
+
+	after := before
+	if req.Content != nil {
+		after.content = *req.Content
+	}
+	if req.Latitude != nil {
+		after.latitude, after.longitude = req.Latitude, req.Longitude
+	}
+	if req.ClearLocation {
+		after.latitude, after.longitude = nil, nil
+	}
+	if req.LocationName != nil {
+		after.locationName = req.LocationName
+		if *req.LocationName == "" {
+			after.locationName = nil
+		}
+	}
+	if req.Urgency != nil {
+		after.urgency = *req.Urgency
+	}
+	if len(versionChanges(before, after)) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no changes"})
+		return
+	}
+
+	impact := classifyEdit(before, after)
+	newScore := decayVerification(verificationScore, impact)
+	versionCreatedAt := createdAt
+	if editedAt != nil {
+		versionCreatedAt = *editedAt
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO post_revisions (post_id, revision, content, location, location_name,
+		                            urgency, verification_score, created_at)
+		SELECT id, revision, content, location, location_name, urgency, verification_score, $2
+		FROM posts WHERE id = $1
+	`, postID, versionCreatedAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update post"})
+		return
+	}
+
+	var now time.Time
+	err = tx.QueryRow(ctx, `
+		UPDATE posts
+		SET content = $2,
+		    location = CASE WHEN $3::float8 IS NULL THEN NULL
+		                    ELSE ST_SetSRID(ST_MakePoint($4::float8, $3::float8), 4326)::geography END,
+		    location_name = $5, urgency = $6, verification_score = $7,
+		    revision = revision + 1, edited_at = NOW()
+		WHERE id = $1
+		RETURNING edited_at
+	`, postID, after.content, after.latitude, after.longitude, after.locationName, after.urgency, newScore).Scan(&now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update post"})
+		return
+	}
+
+	if impact == editRewrite {
+		if _, err := tx.Exec(ctx, `
+			UPDATE post_replies SET corroborates = false
+			WHERE post_id = $1 AND corroborates = true
+		`, postID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update post"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update post"})
+		return
+	}
+
+	verification := "kept"
+	switch impact {
+	case editMaterial:
+		verification = "decayed"
+	case editRewrite:
+		verification = "reset"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":                 postID,
+		"message":            "post updated",
+		"revision":           revision + 1,
+		"edited_at":          now,
+		"verification_score": newScore,
+		"verification":       verification,
+	})
+}
+
+// ListPostRevisions returns every version of a post, oldest first
+func (h *Handler) ListPostRevisions(c *gin.Context) {
+	postID := c.Param("id")
+	ctx := c.Request.Context()
+
+	versions, err := h.loadPostVersions(ctx, postID)
+	if errors.Is(err, errPostNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "post not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch revisions"})
+		return
+	}
+
+	resp := types.PostRevisionsResponse{PostID: postID, Revisions: make([]types.PostRevision, 0, len(versions))}
+	for i, v := range versions {
+		rev := v.rev
+		rev.Changes = []string{}
+		rev.ContentDiff = []types.DiffSpan{}
+		if i > 0 {
+			rev.Changes = versionChanges(versions[i-1].version, v.version)
+			if versions[i-1].version.content != v.version.content {
+				rev.ContentDiff = wordDiff(versions[i-1].version.content, v.version.content)
+			}
+		}
+		resp.Revisions = append(resp.Revisions, rev)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type storedVersion struct {
+	version postVersion
+	rev     types.PostRevision
+}
+
+// loadPostVersions returns the replaced versions of a visible post
+// followed by the current one.
+func (h *Handler) loadPostVersions(ctx context.Context, postID string) ([]storedVersion, error) {
+	var current storedVersion
+	var createdAt time.Time
+	var editedAt *time.Time
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT revision, content, ST_Y(location::geometry), ST_X(location::geometry),
+		       location_name, urgency, verification_score, created_at, edited_at
+		FROM posts WHERE id = $1 AND is_flagged = false
+	`, postID).Scan(&current.rev.Revision, &current.version.content,
+		&current.version.latitude, &current.version.longitude, &current.version.locationName,
+		&current.version.urgency, &current.rev.VerificationScore, &createdAt, &editedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errPostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	current.rev.CreatedAt = createdAt
+	if editedAt != nil {
+		current.rev.CreatedAt = *editedAt
+	}
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT revision, content, ST_Y(location::geometry), ST_X(location::geometry),
+		       location_name, urgency, verification_score, created_at, replaced_at
+		FROM post_revisions
+		WHERE post_id = $1
+		ORDER BY revision
+	`, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var versions []storedVersion
+	for rows.Next() {
+		var v storedVersion
+		var replacedAt time.Time
+		if err := rows.Scan(&v.rev.Revision, &v.version.content,
+			&v.version.latitude, &v.version.longitude, &v.version.locationName,
+			&v.version.urgency, &v.rev.VerificationScore, &v.rev.CreatedAt, &replacedAt); err != nil {
+			return nil, err
+		}
+		v.rev.ReplacedAt = &replacedAt
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	versions = append(versions, current)
+
+	for i := range versions {
+		v := &versions[i]
+		v.rev.Content = v.version.content
+		v.rev.LocationName = v.version.locationName
+		v.rev.Urgency = v.version.urgency
+		if v.version.latitude != nil && v.version.longitude != nil {
+			v.rev.Location = &types.LatLng{Latitude: *v.version.latitude, Longitude: *v.version.longitude}
+		}
+	}
+	return versions, nil
+}
+
+// classifyEdit grades the change from before to after; the most
+// severe change to any field wins.
+func classifyEdit(before, after postVersion) editImpact {
+	impact := editMinor
+	raise := func(to editImpact) {
+		if to > impact {
+			impact = to
+		}
+	}
+
+	if before.content != after.content {
+		a, okA := simHash(before.content)
+		b, okB := simHash(after.content)
+		switch d := bits.OnesCount64(a ^ b); {
+		case !okA || !okB:
+			raise(editMaterial)
+		case d > incidentMaxHamming:
+			raise(editRewrite)
+		case d > minorEditHamming:
+			raise(editMaterial)
+		}
+	}
+
+	hadLocation := before.latitude != nil && before.longitude != nil
+	hasLocation := after.latitude != nil && after.longitude != nil
+	switch {
+	case hadLocation && hasLocation:
+		moved := distanceMeters(*before.latitude, *before.longitude, *after.latitude, *after.longitude)
+		if moved > corroborationRadius {
+			raise(editRewrite)
+		} else if moved > minorMoveMeters {
+			raise(editMaterial)
+		}
+	case hadLocation != hasLocation:
+		raise(editMaterial)
+	}
+
+	if before.urgency != after.urgency {
+		raise(editMaterial)
+	}
+	return impact
+}
+
+// decayVerification applies an edit's impact to a verification score.
+// Only positive scores shrink.
+func decayVerification(score int, impact editImpact) int {
+	if score <= 0 {
+		return score
+	}
+	switch impact {
+	case editMaterial:
+		return score / 2
+	case editRewrite:
+		return 0
+	}
+	return score
+}
+
+// versionChanges lists the fields that differ between two versions.
+func versionChanges(before, after postVersion) []string {
+	changes := []string{}
+	if before.content != after.content {
+		changes = append(changes, "content")
+	}
+	if !sameFloat(before.latitude, after.latitude) || !sameFloat(before.longitude, after.longitude) {
+		changes = append(changes, "location")
+	}
+	if !sameString(before.locationName, after.locationName) {
+		changes = append(changes, "location_name")
+	}
+	if before.urgency != after.urgency {
+		changes = append(changes, "urgency")
+	}
+	return changes
+}
+
+func sameFloat(a, b *float64) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+func sameString(a, b *string) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// wordDiff diffs two texts word by word (longest common subsequence)
+// and merges runs of the same operation into spans.
+func wordDiff(before, after string) []types.DiffSpan {
+	a, b := strings.Fields(before), strings.Fields(after)
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	spans := []types.DiffSpan{}
+	emit := func(op, word string) {
+		if n := len(spans); n > 0 && spans[n-1].Op == op {
+			spans[n-1].Text += " " + word
+			return
+		}
+		spans = append(spans, types.DiffSpan{Op: op, Text: word})
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			emit("equal", a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			emit("delete", a[i])
+			i++
+		default:
+			emit("insert", b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		emit("delete", a[i])
+	}
+	for ; j < len(b); j++ {
+		emit("insert", b[j])
+	}
+	return spans
+}
@@ -0,0 +1,87 @@
+package feed
+
+import (
+	"testing"
+
+	"github.com/kuurier/server/internal/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyEdit(t *testing.T) {
+	lat, lon := 52.5200, 13.4050
+	nearLat := 52.5205  // ~55 m north
+	movedLat := 52.5250 // ~550 m north
+	farLat := 52.5400   // ~2.2 km north
+	name := "Central Square"
+	base := postVersion{
+		content:   "Police are kettling protesters at Central Square, avoid the north exit",
+		latitude:  &lat,
+		longitude: &lon,
+		urgency:   2,
+	}
+	edit := func(f func(v *postVersion)) postVersion {
+		v := base
+		f(&v)
+		return v
+	}
+
+	tests := []struct {
+		name  string
+		after postVersion
+		want  editImpact
+	}{
+		{"punctuation fix", edit(func(v *postVersion) {
+			v.content = "Police are kettling protesters at Central Square; avoid the north exit!"
+		}), editMinor},
+		{"location name added", edit(func(v *postVersion) { v.locationName = &name }), editMinor},
+		{"location nudged", edit(func(v *postVersion) { v.latitude = &nearLat }), editMinor},
+		{"location moved", edit(func(v *postVersion) { v.latitude = &movedLat }), editMaterial},
+		{"location removed", edit(func(v *postVersion) { v.latitude, v.longitude = nil, nil }), editMaterial},
+		{"urgency changed", edit(func(v *postVersion) { v.urgency = 3 }), editMaterial},
+		{"moved across town", edit(func(v *postVersion) { v.latitude = &farLat }), editRewrite},
+		{"content replaced", edit(func(v *postVersion) {
+			v.content = "Food bank on Elm Street needs volunteers for Saturday morning shifts"
+		}), editRewrite},
+		{"worst change wins", edit(func(v *postVersion) {
+			v.urgency = 1
+			v.latitude = &farLat
+		}), editRewrite},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, classifyEdit(base, tt.after))
+		})
+	}
+}
+
+func TestDecayVerification(t *testing.T) {
+	assert.Equal(t, 7, decayVerification(7, editMinor))
+	assert.Equal(t, 3, decayVerification(7, editMaterial))
+	assert.Equal(t, 0, decayVerification(7, editRewrite))
+	assert.Equal(t, -3, decayVerification(-3, editRewrite), "editing never clears negative verification")
+}
+
+func TestVersionChanges(t *testing.T) {
+	lat, lon, otherLat := 52.52, 13.405, 52.53
+	before := postVersion{content: "a", latitude: &lat, longitude: &lon, urgency: 1}
+
+	assert.Empty(t, versionChanges(before, before))
+
+	after := before
+	after.latitude = &otherLat
+	after.urgency = 2
+	assert.Equal(t, []string{"location", "urgency"}, versionChanges(before, after))
+}
+
+func TestWordDiff(t *testing.T) {
+	got := wordDiff("kettle forming at the north exit", "kettle forming at the south exit now")
+	assert.Equal(t, []types.DiffSpan{
+		{Op: "equal", Text: "kettle forming at the"},
+		{Op: "delete", Text: "north"},
+		{Op: "insert", Text: "south"},
+		{Op: "equal", Text: "exit"},
+		{Op: "insert", Text: "now"},
+	}, got)
+
+	assert.Equal(t, []types.DiffSpan{{Op: "insert", Text: "new post"}}, wordDiff("", "new post"))
+}
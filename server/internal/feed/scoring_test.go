@@ -161,7 +161,7 @@ func TestRankFeedCandidates_CrisisFiltering(t *testing.T) {
 	}
 
 	scored := (&Handler{}).rankFeedCandidates(
-		FeedTypeCrisis, candidates, nil, nil, &lat, &lon, 50000, 0,
+		FeedTypeCrisis, candidates, nil, nil, &lat, &lon, 50000, 0, nil,
 	)
 
 	// Only urgency >= 2 and within 72 hours should appear
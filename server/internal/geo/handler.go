@@ -6,6 +6,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/mutes"
 	"github.com/kuurier/server/internal/storage"
 )
 
@@ -254,9 +255,84 @@ func (h *Handler) GetClusters(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"markers": clusters, "clustered": true})
 }
 
+// GetIncidents returns incidents (clusters of posts corroborated by
+// several members, built by the worker) for the incident map layer.
+// Defaults to incidents active in the last 24 hours.
+func (h *Handler) GetIncidents(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	// Parse bounding box
+	minLat, _ := strconv.ParseFloat(c.Query("min_lat"), 64)
+	maxLat, _ := strconv.ParseFloat(c.Query("max_lat"), 64)
+	minLon, _ := strconv.ParseFloat(c.Query("min_lon"), 64)
+	maxLon, _ := strconv.ParseFloat(c.Query("max_lon"), 64)
+
+	// Default to world view
+	if minLat == 0 && maxLat == 0 {
+		minLat, maxLat = -90, 90
+		minLon, maxLon = -180, 180
+	}
+
+	hours, _ := strconv.Atoi(c.DefaultQuery("hours", "24"))
+	if hours < 1 || hours > 168 {
+		hours = 24
+	}
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT i.id, ST_Y(i.location::geometry) as lat, ST_X(i.location::geometry) as lon,
+			   i.radius_meters, i.location_name, i.post_count, i.author_count,
+			   i.confidence, i.max_urgency, i.lead_post_id, i.first_seen_at, i.last_seen_at
+		FROM incidents i
+		WHERE ST_Y(i.location::geometry) BETWEEN $1 AND $2
+		  AND ST_X(i.location::geometry) BETWEEN $3 AND $4
+		  AND i.last_seen_at > NOW() - make_interval(hours => $5)
+		ORDER BY i.max_urgency DESC, i.confidence DESC
+		LIMIT 200
+	`, minLat, maxLat, minLon, maxLon, hours)
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch incidents"})
+		return
+	}
+	defer rows.Close()
+
+	var incidents []gin.H
+	for rows.Next() {
+		var id string
+		var lat, lon, confidence float64
+		var radius, postCount, authorCount, maxUrgency int
+		var locationName, leadPostID *string
+		var firstSeen, lastSeen interface{}
+
+		if err := rows.Scan(&id, &lat, &lon, &radius, &locationName, &postCount, &authorCount,
+			&confidence, &maxUrgency, &leadPostID, &firstSeen, &lastSeen); err != nil {
+			continue
+		}
+
+		incidents = append(incidents, gin.H{
+			"type":           "incident",
+			"id":             id,
+			"latitude":       lat,
+			"longitude":      lon,
+			"radius_meters":  radius,
+			"location_name":  locationName,
+			"post_count":     postCount,
+			"corroborations": authorCount,
+			"confidence":     confidence,
+			"max_urgency":    maxUrgency,
+			"lead_post_id":   leadPostID,
+			"first_seen_at":  firstSeen,
+			"last_seen_at":   lastSeen,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"markers": incidents, "hours": hours})
+}
+
 // GetNearby returns posts near a specific location
 func (h *Handler) GetNearby(c *gin.Context) {
 	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
 
 	lat, err := strconv.ParseFloat(c.Query("latitude"), 64)
 	if err != nil {
@@ -292,9 +368,11 @@ func (h *Handler) GetNearby(c *gin.Context) {
 		  AND p.is_flagged = false
 		  AND (p.expires_at IS NULL OR p.expires_at > NOW())
 		  AND ST_DWithin(p.location, ST_MakePoint($2, $1)::geography, $3)
+		  AND NOT `+mutes.HiddenSQL("$5", "p.author_id", "p.source_type", "p.content",
+		"ARRAY(SELECT topic_id::text FROM post_topics WHERE post_id = p.id)")+`
 		ORDER BY distance_meters ASC
 		LIMIT $4
-	`, lat, lon, radiusMeters, limit)
+	`, lat, lon, radiusMeters, limit, userID)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch nearby posts"})
@@ -7,6 +7,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/mutes"
 	"github.com/kuurier/server/internal/storage"
 )
 
@@ -271,6 +272,17 @@ func (h *ChannelHandler) GetOrCreateDM(c *gin.Context) {
 		return
 	}
 
+	// No DMs between users where either has blocked the other
+	blocked, err := mutes.Blocked(ctx, h.db, userID, req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create DM channel"})
+		return
+	}
+	if blocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": "cannot message this user"})
+		return
+	}
+
 	// Get or create DM channel using the helper function
 	var channelID string
 	err = h.db.Pool().QueryRow(ctx, `SELECT get_or_create_dm_channel($1, $2)`, userID, req.UserID).Scan(&channelID)
@@ -313,6 +325,17 @@ func (h *ChannelHandler) AddChannelMember(c *gin.Context) {
 		return
 	}
 
+	// Users who blocked the admin (or were blocked by them) can't be added
+	blocked, err := mutes.Blocked(ctx, h.db, userID, req.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to add member"})
+		return
+	}
+	if blocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": "cannot add this user"})
+		return
+	}
+
 	// Add member
 	_, err = h.db.Pool().Exec(ctx, `
 		INSERT INTO channel_members (channel_id, user_id, role, joined_at)
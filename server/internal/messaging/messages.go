@@ -65,6 +65,25 @@ func (h *MessageHandler) SendMessage(c *gin.Context) {
 		return
 	}
 
+	// A block also closes existing DMs in both directions
+	var blocked bool
+	err = h.db.Pool().QueryRow(c.Request.Context(), `
+		SELECT EXISTS(
+			SELECT 1 FROM channels ch
+			JOIN channel_members cm ON cm.channel_id = ch.id AND cm.user_id <> $2
+			JOIN user_blocks b ON (b.blocker_id = cm.user_id AND b.blocked_id = $2)
+			                   OR (b.blocker_id = $2 AND b.blocked_id = cm.user_id)
+			WHERE ch.id = $1 AND ch.type = 'dm'
+		)`, req.ChannelID, userID).Scan(&blocked)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+		return
+	}
+	if blocked {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Cannot message this user"})
+		return
+	}
+
 	// Default message type
 	msgType := req.MessageType
 	if msgType == "" {
@@ -48,6 +48,12 @@ func Logger() gin.HandlerFunc {
 		status := c.Writer.Status()
 		method := c.Request.Method
 
+		// Tokens in the path (calendar feed URLs) are credentials;
+		// log the route template instead.
+		if route := c.FullPath(); strings.Contains(route, ":token") {
+			path = route
+		}
+
 		attrs := []any{
 			slog.String("request_id", c.GetString(RequestIDContextKey)),
 			slog.String("method", method),
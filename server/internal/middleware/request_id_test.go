@@ -198,3 +198,21 @@ func TestLogger_IncludesUserIDWhenSet(t *testing.T) {
 	require.Len(t, lines, 1)
 	assert.Equal(t, "u-42", lines[0]["user_id"])
 }
+
+func TestLogger_RedactsPathTokens(t *testing.T) {
+	buf := captureSlog(t)
+
+	router := gin.New()
+	router.Use(RequestID())
+	router.Use(Logger())
+	router.GET("/calendar/:token", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest("GET", "/calendar/s3cr3t-feed-token.ics", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	lines := parseLogLines(t, buf)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "/calendar/:token", lines[0]["path"])
+	assert.NotContains(t, buf.String(), "s3cr3t")
+}
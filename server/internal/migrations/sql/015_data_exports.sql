@@ -0,0 +1,37 @@
+-- Migration 015: Personal data exports
+--
+-- A user can ask for an archive of everything the server holds about
+-- them. The API inserts a 'pending' row; the worker builds the archive,
+-- signs it, uploads it to object storage and flips the row to 'ready'.
+-- The download URL is handed out exactly once (downloaded_at), after
+-- which the object is removed by the worker's expiry sweep.
+
+CREATE TABLE IF NOT EXISTS data_exports (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status         VARCHAR(20) NOT NULL DEFAULT 'pending'
+                   CHECK (status IN ('pending', 'processing', 'ready', 'failed', 'expired')),
+    object_name    VARCHAR(200),              -- Random, unguessable object key
+    size_bytes     BIGINT,
+    sha256         VARCHAR(64),               -- Hex digest of data.json
+    signature      VARCHAR(64),               -- Hex HMAC-SHA256 of data.json
+    error          TEXT,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    started_at     TIMESTAMPTZ,
+    completed_at   TIMESTAMPTZ,
+    expires_at     TIMESTAMPTZ,
+    downloaded_at  TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user
+    ON data_exports (user_id, created_at DESC);
+
+-- Worker queue scan: pending (and stuck processing) jobs, oldest first.
+CREATE INDEX IF NOT EXISTS idx_data_exports_queue
+    ON data_exports (created_at)
+    WHERE status IN ('pending', 'processing');
+
+-- Expiry sweep for archives still sitting in object storage.
+CREATE INDEX IF NOT EXISTS idx_data_exports_expires
+    ON data_exports (expires_at)
+    WHERE status = 'ready';
@@ -0,0 +1,36 @@
+-- Migration 016: Subscription digests
+--
+-- subscriptions.digest_mode has existed since 001 but nothing read it.
+-- The worker's digest job now groups daily/weekly subscriptions into
+-- one summary per user per period. Each summary is stored here so the
+-- app can show it in-feed and open the full list of items.
+
+CREATE TABLE IF NOT EXISTS digests (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id       UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    digest_mode   VARCHAR(20) NOT NULL CHECK (digest_mode IN ('daily', 'weekly')),
+    period_start  TIMESTAMPTZ NOT NULL,
+    period_end    TIMESTAMPTZ NOT NULL,
+    post_ids      UUID[] NOT NULL DEFAULT '{}',   -- Ranked, best first
+    event_ids     UUID[] NOT NULL DEFAULT '{}',   -- Soonest first
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    read_at       TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_digests_user
+    ON digests (user_id, created_at DESC);
+
+-- When each user's digest last ran, per mode. Kept separate from
+-- digests because a run that finds nothing still has to advance the
+-- window, but shouldn't leave an empty digest behind.
+CREATE TABLE IF NOT EXISTS digest_runs (
+    user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    digest_mode  VARCHAR(20) NOT NULL,
+    last_run_at  TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (user_id, digest_mode)
+);
+
+-- Digest job scan and realtime fan-out both filter on digest_mode.
+CREATE INDEX IF NOT EXISTS idx_subscriptions_digest_mode
+    ON subscriptions (digest_mode, user_id)
+    WHERE is_active = true;
@@ -0,0 +1,29 @@
+-- Migration 017: Materialized feed generations
+--
+-- Feed v2 cursors pin a scroll session to the ranking it started
+-- with. For materialized feeds that means the materializer can no
+-- longer delete-and-replace a user's rows in place: each pass writes
+-- a new generation, and older generations stay readable for a short
+-- grace period so in-flight cursors can finish paging.
+
+ALTER TABLE materialized_feeds ADD COLUMN IF NOT EXISTS generation BIGINT NOT NULL DEFAULT 0;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.key_column_usage
+        WHERE table_name = 'materialized_feeds'
+          AND constraint_name = 'materialized_feeds_pkey'
+          AND column_name = 'generation'
+    ) THEN
+        ALTER TABLE materialized_feeds DROP CONSTRAINT IF EXISTS materialized_feeds_pkey;
+        ALTER TABLE materialized_feeds ADD PRIMARY KEY (user_id, feed_type, generation, post_id);
+    END IF;
+END
+$$;
+
+-- Read path is now "top N of this user/feed/generation, keyset by
+-- (score, post_id)".
+DROP INDEX IF EXISTS idx_mf_read;
+CREATE INDEX IF NOT EXISTS idx_mf_read
+    ON materialized_feeds (user_id, feed_type, generation, score DESC, post_id DESC);
@@ -0,0 +1,79 @@
+-- Migration 018: Full-text search
+--
+-- Posts and events record the language they're written in (ISO 639-1,
+-- NULL if unknown). text_search_config maps that to a Postgres text
+-- search configuration so each row is stemmed in its own language.
+--
+-- Every search vector also carries the 'simple' (unstemmed) lexemes.
+-- Searches OR a query stemmed in the searcher's language with a
+-- simple one, so a word typed exactly as written matches in any
+-- language, and one tsquery per statement keeps the GIN indexes usable.
+
+-- plpgsql rather than sql so the planner can't inline the text ->
+-- regconfig cast (which is only STABLE) into the generated columns.
+CREATE OR REPLACE FUNCTION text_search_config(lang TEXT) RETURNS regconfig
+LANGUAGE plpgsql IMMUTABLE PARALLEL SAFE AS $$
+BEGIN
+    RETURN CASE lower(lang)
+        WHEN 'da' THEN 'danish'
+        WHEN 'de' THEN 'german'
+        WHEN 'en' THEN 'english'
+        WHEN 'es' THEN 'spanish'
+        WHEN 'fi' THEN 'finnish'
+        WHEN 'fr' THEN 'french'
+        WHEN 'hu' THEN 'hungarian'
+        WHEN 'it' THEN 'italian'
+        WHEN 'nl' THEN 'dutch'
+        WHEN 'no' THEN 'norwegian'
+        WHEN 'pt' THEN 'portuguese'
+        WHEN 'ro' THEN 'romanian'
+        WHEN 'ru' THEN 'russian'
+        WHEN 'sv' THEN 'swedish'
+        WHEN 'tr' THEN 'turkish'
+        ELSE 'simple'
+    END::regconfig;
+END
+$$;
+
+-- ============================================
+-- POSTS
+-- ============================================
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS language VARCHAR(8);
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector(text_search_config(language), content), 'A')
+        || to_tsvector('simple', content)
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_posts_search ON posts USING GIN (search_vector);
+
+-- ============================================
+-- EVENTS
+-- ============================================
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS language VARCHAR(8);
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector(text_search_config(language), title), 'A')
+        || setweight(to_tsvector(text_search_config(language), COALESCE(description, '')), 'B')
+        || to_tsvector('simple', title || ' ' || COALESCE(description, ''))
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_events_search ON events USING GIN (search_vector);
+
+-- ============================================
+-- ORGANIZATIONS
+-- ============================================
+-- Names are proper nouns; stemming them does more harm than good.
+
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', name), 'A')
+        || setweight(to_tsvector('simple', COALESCE(description, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_organizations_search ON organizations USING GIN (search_vector)
+    WHERE is_public = true;
@@ -0,0 +1,44 @@
+-- Migration 019: Threaded replies on posts
+--
+-- Replies live in their own table rather than as posts with a parent:
+-- every feed, map and search query reads posts, and none of them
+-- should have to learn to skip replies.
+--
+-- A reply never stores a location. The client may send one with a
+-- firsthand reply; the server only uses it to decide whether the reply
+-- corroborates the post (firsthand, close to the post, soon after it)
+-- and keeps the boolean. Corroborating replies from distinct users
+-- raise the post's confidence score.
+
+CREATE TABLE IF NOT EXISTS post_replies (
+    id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id             UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    parent_id           UUID REFERENCES post_replies(id) ON DELETE CASCADE,  -- NULL for a direct reply to the post
+    author_id           UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    content             TEXT NOT NULL,
+    depth               INT NOT NULL DEFAULT 0 CHECK (depth BETWEEN 0 AND 2),
+    is_firsthand        BOOLEAN NOT NULL DEFAULT FALSE,
+    corroborates        BOOLEAN NOT NULL DEFAULT FALSE,
+    verification_score  INT NOT NULL DEFAULT 0,
+    is_flagged          BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    deleted_at          TIMESTAMPTZ,  -- Set when a reply with answers is deleted; content is blanked
+
+    CONSTRAINT reply_content_length CHECK (char_length(content) BETWEEN 1 AND 1000)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_replies_post ON post_replies (post_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_post_replies_parent ON post_replies (parent_id);
+CREATE INDEX IF NOT EXISTS idx_post_replies_author ON post_replies (author_id);
+CREATE INDEX IF NOT EXISTS idx_post_replies_corroborating
+    ON post_replies (post_id, author_id) WHERE corroborates AND NOT is_flagged;
+
+-- One verify/flag vote per user per reply.
+CREATE TABLE IF NOT EXISTS post_reply_votes (
+    reply_id    UUID NOT NULL REFERENCES post_replies(id) ON DELETE CASCADE,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    vote        SMALLINT NOT NULL CHECK (vote IN (-1, 1)),
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (reply_id, user_id)
+);
@@ -0,0 +1,36 @@
+-- Migration 020: Incidents
+--
+-- Members often post about the same kettle or eviction within minutes
+-- of each other. The worker's incident job clusters recent located
+-- posts by distance, time, shared topics and text similarity; each
+-- cluster reported by at least two distinct authors becomes an
+-- incident, shown as one feed item and as a map layer.
+
+CREATE TABLE IF NOT EXISTS incidents (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    location        GEOGRAPHY(POINT, 4326) NOT NULL,   -- Centroid of member posts
+    radius_meters   INTEGER NOT NULL DEFAULT 0,        -- Farthest member from the centroid
+    location_name   VARCHAR(200),
+    lead_post_id    UUID REFERENCES posts(id) ON DELETE SET NULL,
+    topic_ids       UUID[] NOT NULL DEFAULT '{}',
+    post_count      INTEGER NOT NULL,
+    author_count    INTEGER NOT NULL,                  -- Distinct authors (corroborations)
+    confidence      REAL NOT NULL,                     -- Combined, 0-1
+    max_urgency     INTEGER NOT NULL DEFAULT 1,
+    first_seen_at   TIMESTAMPTZ NOT NULL,
+    last_seen_at    TIMESTAMPTZ NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_incidents_location ON incidents USING GIST(location);
+CREATE INDEX IF NOT EXISTS idx_incidents_last_seen ON incidents (last_seen_at DESC);
+
+-- A post belongs to at most one incident. Re-clustering replaces the
+-- memberships of every post it looked at.
+CREATE TABLE IF NOT EXISTS incident_posts (
+    post_id      UUID PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    incident_id  UUID NOT NULL REFERENCES incidents(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_incident_posts_incident ON incident_posts (incident_id);
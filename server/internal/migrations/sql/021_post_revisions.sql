@@ -0,0 +1,29 @@
+-- Migration 021: Post editing with revision history
+--
+-- Authors can correct a post instead of deleting it and losing its
+-- verification. Each edit copies the version it replaces into
+-- post_revisions, so anyone can see what changed. Verification is
+-- decayed or reset by the API when content, location or urgency
+-- change materially.
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS revision INTEGER NOT NULL DEFAULT 1;
+
+-- FlagPost only decremented verification_score, so "has this post
+-- ever been flagged" wasn't recorded. Edits are refused once it is
+-- non-zero, to stop bait-and-switch after a report.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS flag_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS post_revisions (
+    id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    post_id             UUID NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    revision            INTEGER NOT NULL,          -- posts.revision of this version
+    content             TEXT NOT NULL,
+    location            GEOGRAPHY(POINT, 4326),
+    location_name       VARCHAR(200),
+    urgency             INTEGER NOT NULL,
+    verification_score  INTEGER NOT NULL,          -- Score when it was replaced
+    created_at          TIMESTAMPTZ NOT NULL,      -- When this version was written
+    replaced_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (post_id, revision)
+);
@@ -0,0 +1,28 @@
+-- Migration 022: Mutes and blocks
+--
+-- Users can mute other users (including the news bot), topics,
+-- source types and keywords. Muted content is dropped from feeds,
+-- nearby posts and event listings; nobody is told.
+--
+-- Blocking a user also mutes them, and stops them from opening a DM
+-- with you, messaging you in an existing DM or adding you to channels.
+
+CREATE TABLE IF NOT EXISTS user_mutes (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    mute_type   VARCHAR(20) NOT NULL CHECK (mute_type IN ('user', 'topic', 'source_type', 'keyword')),
+    value       TEXT NOT NULL,       -- User ID, topic ID, source type, or normalized keyword
+    expires_at  TIMESTAMPTZ,         -- NULL = until removed
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, mute_type, value)
+);
+
+CREATE TABLE IF NOT EXISTS user_blocks (
+    blocker_id  UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    blocked_id  UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked ON user_blocks (blocked_id);
@@ -0,0 +1,60 @@
+-- Migration 023: Topic administration, hierarchy and proposals
+--
+-- Topics were seeded once in 001 and never changed. Admins can now
+-- create, rename and archive topics, nest them under a parent
+-- (e.g. a specific pipeline campaign under Climate Action) and give
+-- them names per language. Trusted users can propose topics; a
+-- proposal becomes a topic once enough trusted users endorse it.
+
+ALTER TABLE topics ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES topics(id) ON DELETE SET NULL;
+ALTER TABLE topics ADD COLUMN IF NOT EXISTS description TEXT;
+ALTER TABLE topics ADD COLUMN IF NOT EXISTS archived_at TIMESTAMPTZ;  -- Hidden from lists and new posts; existing tags stay
+ALTER TABLE topics ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_topics_parent ON topics (parent_id) WHERE parent_id IS NOT NULL;
+
+-- Every topic paired with itself and each of its ancestors. A
+-- subscription to ancestor_id matches content tagged topic_id.
+-- The API keeps the tree shallow and acyclic.
+CREATE OR REPLACE RECURSIVE VIEW topic_lineage (topic_id, ancestor_id) AS
+    SELECT id, id FROM topics
+    UNION
+    SELECT tl.topic_id, t.parent_id
+    FROM topic_lineage tl
+    JOIN topics t ON t.id = tl.ancestor_id
+    WHERE t.parent_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS topic_names (
+    topic_id    UUID NOT NULL REFERENCES topics(id) ON DELETE CASCADE,
+    lang        VARCHAR(2) NOT NULL,     -- ISO 639-1, as posts.language
+    name        VARCHAR(100) NOT NULL,
+    PRIMARY KEY (topic_id, lang)
+);
+
+CREATE TABLE IF NOT EXISTS topic_proposals (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    proposer_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    slug        VARCHAR(50) NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    icon        VARCHAR(50),
+    description TEXT,
+    parent_id   UUID REFERENCES topics(id) ON DELETE SET NULL,
+    status      VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'rejected', 'expired')),
+    topic_id    UUID REFERENCES topics(id) ON DELETE SET NULL,  -- Set once accepted
+    reason      TEXT,                                           -- Admin's note on rejection
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    decided_at  TIMESTAMPTZ
+);
+
+-- One open proposal per slug
+CREATE UNIQUE INDEX IF NOT EXISTS idx_topic_proposals_pending_slug ON topic_proposals (slug) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_topic_proposals_proposer ON topic_proposals (proposer_id);
+
+CREATE TABLE IF NOT EXISTS topic_proposal_endorsements (
+    proposal_id UUID NOT NULL REFERENCES topic_proposals(id) ON DELETE CASCADE,
+    user_id     UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (proposal_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_topic_proposal_endorsements_user ON topic_proposal_endorsements (user_id);
@@ -0,0 +1,20 @@
+-- Migration 024: Calendar feed tokens
+--
+-- Calendar apps subscribe to an .ics URL and can't send a bearer JWT,
+-- so each subscription URL carries a random token instead. Only the
+-- token's SHA-256 is stored; deleting the row revokes the URL. A feed
+-- is rendered with its owner's view of each event, so RSVP-gated
+-- locations only show in the feeds of users allowed to see them.
+
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash   BYTEA NOT NULL UNIQUE,
+    feed_type    VARCHAR(20) NOT NULL CHECK (feed_type IN ('personal', 'topic', 'organization')),
+    target_id    UUID,                -- Topic or organization ID; NULL for personal feeds
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ,
+    CHECK ((feed_type = 'personal') = (target_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_calendar_feeds_user ON calendar_feeds (user_id);
@@ -0,0 +1,39 @@
+-- Migration 025: Recurring events
+--
+-- An event with a recurrence_rule (an RFC 5545 RRULE) is a series:
+-- starts_at is the first occurrence and the rule generates the rest,
+-- expanded server-side in the event's timezone so wall-clock times
+-- survive DST changes. recurrence_ends_at is the last occurrence's
+-- start for rules with COUNT or UNTIL, and lets listings skip series
+-- that have ended without expanding them.
+--
+-- An occurrence is identified by the start the rule generates for it
+-- (RECURRENCE-ID in iCalendar terms). event_occurrences holds the
+-- exceptions: cancelled occurrences and per-occurrence edits.
+-- RSVPs to a series are per occurrence; occurrence_at is NULL for
+-- RSVPs to one-off events.
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS recurrence_rule TEXT;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS recurrence_ends_at TIMESTAMPTZ;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+CREATE INDEX IF NOT EXISTS idx_events_recurring ON events (recurrence_ends_at) WHERE recurrence_rule IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS event_occurrences (
+    event_id       UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    occurrence_at  TIMESTAMPTZ NOT NULL,   -- Start generated by the rule
+    is_cancelled   BOOLEAN NOT NULL DEFAULT FALSE,
+    title          VARCHAR(200),           -- NULL fields fall back to the series
+    description    TEXT,
+    location_name  VARCHAR(200),
+    location_area  VARCHAR(200),
+    starts_at      TIMESTAMPTZ,
+    ends_at        TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (event_id, occurrence_at)
+);
+
+ALTER TABLE event_rsvps ADD COLUMN IF NOT EXISTS occurrence_at TIMESTAMPTZ;
+ALTER TABLE event_rsvps DROP CONSTRAINT IF EXISTS event_rsvps_pkey;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_rsvps_occurrence
+    ON event_rsvps (event_id, user_id, occurrence_at) NULLS NOT DISTINCT;
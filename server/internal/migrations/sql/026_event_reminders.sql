@@ -0,0 +1,22 @@
+-- Migration 026: Event reminders
+--
+-- One row per reminder pushed to an RSVP. The worker claims rows with
+-- INSERT ... ON CONFLICT DO NOTHING before sending, so a reminder goes
+-- out at most once even if the worker restarts mid-run.
+--
+-- occurrence_at identifies the occurrence of a series; for one-off
+-- events it is starts_at, so a rescheduled event is reminded again.
+-- kind is 'before:<seconds>' for reminders at a configured offset and
+-- 'reveal' for a timed location being revealed.
+
+CREATE TABLE IF NOT EXISTS event_reminders (
+    event_id       UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    occurrence_at  TIMESTAMPTZ NOT NULL,
+    kind           VARCHAR(32) NOT NULL,
+    user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    sent_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (event_id, occurrence_at, kind, user_id)
+);
+
+-- Rows for past occurrences are pruned by the worker.
+CREATE INDEX IF NOT EXISTS idx_event_reminders_occurrence ON event_reminders (occurrence_at);
@@ -0,0 +1,19 @@
+-- Migration 027: Event announcements
+--
+-- Short updates an organizer broadcasts to an event's attendees
+-- ("meet at the north entrance instead"). They're pushed when posted
+-- and kept here so attendees who missed the push can read them.
+-- occurrence_at targets one occurrence of a series; NULL reaches
+-- every attendee still to come.
+
+CREATE TABLE IF NOT EXISTS event_announcements (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id       UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    occurrence_at  TIMESTAMPTZ,
+    author_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body           VARCHAR(500) NOT NULL,
+    is_urgent      BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_announcements_event ON event_announcements (event_id, created_at DESC);
@@ -0,0 +1,44 @@
+-- Migration 028: Event roles and volunteer sign-up
+--
+-- Organizers define roles (marshal, street medic, legal observer, ...)
+-- with a capacity. Attendees sign up for a role; a sign-up is pending
+-- while it awaits the organizer's approval, confirmed once it holds a
+-- place, or waitlisted while the role is full. Pending sign-ups hold a
+-- place too, so approving one never overfills a role. Waitlisted
+-- sign-ups move up in the order they were made.
+--
+-- Like RSVPs, sign-ups to a series are per occurrence (capacity is per
+-- occurrence); occurrence_at is NULL for one-off events.
+--
+-- roles_channel_id is the role holders' channel, created next to the
+-- event channel when the first role is added. Confirmed role holders
+-- are its members.
+
+CREATE TABLE IF NOT EXISTS event_roles (
+    id                 UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id           UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    name               VARCHAR(50) NOT NULL,
+    description        TEXT,
+    capacity           INTEGER NOT NULL CHECK (capacity > 0),
+    requires_approval  BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (event_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS event_role_signups (
+    role_id        UUID NOT NULL REFERENCES event_roles(id) ON DELETE CASCADE,
+    user_id        UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    occurrence_at  TIMESTAMPTZ,
+    status         VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'confirmed', 'waitlisted', 'declined')),
+    note           VARCHAR(200),    -- e.g. qualifications, for the organizer
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_role_signups_user
+    ON event_role_signups (role_id, user_id, occurrence_at) NULLS NOT DISTINCT;
+CREATE INDEX IF NOT EXISTS idx_event_role_signups_queue
+    ON event_role_signups (role_id, occurrence_at, created_at) WHERE status = 'waitlisted';
+CREATE INDEX IF NOT EXISTS idx_event_role_signups_member ON event_role_signups (user_id);
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS roles_channel_id UUID REFERENCES channels(id) ON DELETE SET NULL;
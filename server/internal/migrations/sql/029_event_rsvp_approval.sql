@@ -0,0 +1,24 @@
+-- Migration 029: Approval-gated RSVPs
+--
+-- With location_visibility = 'approval', an RSVP starts out pending
+-- and only an approved RSVP reveals the exact location, joins the
+-- event channel or receives attendee updates. The organizer approves
+-- or denies each request, or has RSVPs approved automatically for
+-- requesters with at least approval_min_trust trust, or who are
+-- members of approval_org_id.
+--
+-- RSVPs to other events are approved as they're made, so everywhere
+-- an RSVP grants access it is an approved one.
+
+ALTER TABLE events DROP CONSTRAINT IF EXISTS events_location_visibility_check;
+ALTER TABLE events ADD CONSTRAINT events_location_visibility_check
+    CHECK (location_visibility IN ('public', 'rsvp', 'timed', 'approval'));
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS approval_min_trust INT;
+ALTER TABLE events ADD COLUMN IF NOT EXISTS approval_org_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
+
+ALTER TABLE event_rsvps ADD COLUMN IF NOT EXISTS approval VARCHAR(10) NOT NULL DEFAULT 'approved'
+    CHECK (approval IN ('pending', 'approved', 'denied'));
+ALTER TABLE event_rsvps ADD COLUMN IF NOT EXISTS decided_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_event_rsvps_pending ON event_rsvps (event_id, created_at) WHERE approval = 'pending';
@@ -0,0 +1,35 @@
+-- Migration 030: Privacy-preserving event check-in
+--
+-- Attendees check in by submitting the event's current QR token, which
+-- rotates every minute and is derived from checkin_secret. A check-in
+-- is stored only as an opaque tag (an HMAC of the attendee under the
+-- event's secret, to count each person once) and the time bucket it
+-- fell in; never the user or the exact time.
+--
+-- Once an occurrence is over, the worker folds its check-ins into
+-- event_checkin_counts and deletes them, so nothing left links an
+-- attendee to the event. Buckets with fewer than k check-ins are
+-- merged into their neighbours first, and nothing is kept when the
+-- whole turnout is below k.
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS checkin_secret BYTEA;
+
+CREATE TABLE IF NOT EXISTS event_checkins (
+    event_id       UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    occurrence_at  TIMESTAMPTZ,            -- NULL for one-off events
+    tag            BYTEA NOT NULL,
+    bucket         TIMESTAMPTZ NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_checkins_tag
+    ON event_checkins (event_id, occurrence_at, tag) NULLS NOT DISTINCT;
+
+CREATE TABLE IF NOT EXISTS event_checkin_counts (
+    event_id       UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    occurrence_at  TIMESTAMPTZ,
+    bucket         TIMESTAMPTZ NOT NULL,
+    count          INT NOT NULL CHECK (count > 0)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_checkin_counts_bucket
+    ON event_checkin_counts (event_id, occurrence_at, bucket) NULLS NOT DISTINCT;
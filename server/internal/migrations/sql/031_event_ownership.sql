@@ -0,0 +1,46 @@
+-- Migration 031: Organization-owned events and co-organizers
+--
+-- An event can belong to an organization (org_id). Its admins and
+-- moderators may then run the event alongside the organizer, so it
+-- doesn't stall when the organizer is arrested or drops out.
+--
+-- Co-organizers are users the organizer (or the owning org) invited to
+-- help run one event. An invitation is pending until the invitee
+-- accepts it; only accepted co-organizers may edit.
+--
+-- event_transfer_requests hands organizer_id to someone else, the way
+-- admin_transfer_requests hands over an organization: the organizer,
+-- or an admin of the owning org, asks, and the recipient has a week to
+-- accept. Only one request per recipient can be pending at a time.
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS org_id UUID REFERENCES organizations(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_events_org ON events (org_id, starts_at) WHERE org_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS event_co_organizers (
+    event_id     UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    user_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    invited_by   UUID REFERENCES users(id) ON DELETE SET NULL,
+    status       VARCHAR(10) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted')),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    accepted_at  TIMESTAMPTZ,
+    PRIMARY KEY (event_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_event_co_organizers_user ON event_co_organizers (user_id);
+
+CREATE TABLE IF NOT EXISTS event_transfer_requests (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    event_id      UUID NOT NULL REFERENCES events(id) ON DELETE CASCADE,
+    from_user_id  UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    to_user_id    UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status        VARCHAR(20) NOT NULL DEFAULT 'pending',  -- pending, accepted, rejected, expired
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    responded_at  TIMESTAMPTZ,
+    expires_at    TIMESTAMPTZ NOT NULL DEFAULT (NOW() + INTERVAL '7 days')
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_event_transfers_pending
+    ON event_transfer_requests (event_id, to_user_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_event_transfers_recipient
+    ON event_transfer_requests (to_user_id) WHERE status = 'pending';
@@ -0,0 +1,23 @@
+-- Migration 032: Accessibility, risk and legal support on events
+--
+-- accessibility lists what the event offers from a fixed set
+-- (wheelchair, asl, family_friendly, ...), so attendees can filter on
+-- it instead of reading descriptions.
+--
+-- risk_level tells attendees what to expect from police:
+--   low         permitted or otherwise unlikely to draw police action
+--   elevated    unpermitted, or confrontation is possible
+--   arrestable  includes planned civil disobedience
+-- NULL means the organizer didn't say.
+--
+-- legal_hotline and bust_card are the event's legal support: the
+-- number to call on arrest and what to know before going. They are
+-- shown only to RSVPs, like an RSVP-only location.
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS accessibility TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE events ADD COLUMN IF NOT EXISTS risk_level VARCHAR(20)
+    CHECK (risk_level IN ('low', 'elevated', 'arrestable'));
+ALTER TABLE events ADD COLUMN IF NOT EXISTS legal_hotline VARCHAR(50);
+ALTER TABLE events ADD COLUMN IF NOT EXISTS bust_card TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_events_accessibility ON events USING GIN (accessibility);
@@ -0,0 +1,45 @@
+-- Migration 033: Alert lifecycle
+--
+-- Alerts used to stay active until their author resolved them. The
+-- worker now runs them through a lifecycle:
+--
+--   expires_at          when an active alert is resolved for its
+--                       author, from a TTL by severity set on creation
+--                       and reactivation
+--   next_escalation_at  when the worker escalates the alert if no one
+--                       has responded: each step doubles the radius up
+--                       to the 50 km cap and re-broadcasts it, and the
+--                       last tells the author's trusted contacts (those
+--                       they vouched for who vouched for them). NULL
+--                       once there's nothing left to do, and for
+--                       awareness alerts, which never escalate.
+--
+-- alert_timeline records each transition, by the author or the
+-- worker, for GetAlert:
+--   created, resolved, false_alarm, reactivated   the author
+--   radius_widened, contacts_notified, expired    the worker
+
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS next_escalation_at TIMESTAMPTZ;
+
+-- Alerts already active get the TTL of their severity from now, so
+-- they don't all expire at once on deploy
+UPDATE alerts SET expires_at = NOW() + CASE severity
+        WHEN 1 THEN INTERVAL '6 hours'
+        WHEN 2 THEN INTERVAL '12 hours'
+        ELSE INTERVAL '24 hours'
+    END
+WHERE status = 'active' AND expires_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_alerts_active_expiry ON alerts (expires_at) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_alerts_escalation ON alerts (next_escalation_at) WHERE status = 'active';
+
+CREATE TABLE IF NOT EXISTS alert_timeline (
+    id             BIGSERIAL PRIMARY KEY,
+    alert_id       UUID NOT NULL REFERENCES alerts(id) ON DELETE CASCADE,
+    kind           VARCHAR(30) NOT NULL,
+    radius_meters  INT,                     -- the radius as of created and radius_widened
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_timeline_alert ON alert_timeline (alert_id, created_at);
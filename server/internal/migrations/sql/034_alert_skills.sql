@@ -0,0 +1,22 @@
+-- Migration 034: Skill-based alert routing
+--
+-- users.skills are what a user can help with in an emergency, from a
+-- fixed set (medic, legal_observer, translator, driver). They are
+-- private: only used to route alerts, and shown to an alert's author
+-- for the people responding to it.
+--
+-- alerts.required_skills are the skills an alert asks for. Users with
+-- one of them and a location subscription in the alert's area are
+-- pushed the alert first; everyone else at broadcast_at, which the
+-- worker clears once it has broadcast. NULL: broadcast on creation.
+-- Both steps go on the alert's timeline, as skills_notified and
+-- broadcast.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS skills TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_users_skills ON users USING GIN (skills) WHERE skills <> '{}';
+
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS required_skills TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE alerts ADD COLUMN IF NOT EXISTS broadcast_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_alerts_broadcast ON alerts (broadcast_at) WHERE broadcast_at IS NOT NULL;
@@ -0,0 +1,33 @@
+-- Migration 035: Live responder location sharing
+--
+-- A responder on their way to an SOS alert can share their location
+-- live. Each update is streamed over WebSocket to the alert's author,
+-- and to the other responders if the responder chose to. The session
+-- ends when the responder arrives or can't make it, when the alert is
+-- resolved, or at expires_at, whichever comes first.
+--
+-- alert_location_points is the session's track, so an author who
+-- reconnects can see where responders are coming from. Ending the
+-- session deletes it with the track; nothing is kept afterwards, and
+-- neither table is part of data exports.
+
+CREATE TABLE IF NOT EXISTS alert_location_shares (
+    alert_id               UUID NOT NULL REFERENCES alerts(id) ON DELETE CASCADE,
+    user_id                UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    share_with_responders  BOOLEAN NOT NULL DEFAULT FALSE,
+    started_at             TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at             TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (alert_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_location_shares_expiry ON alert_location_shares (expires_at);
+
+CREATE TABLE IF NOT EXISTS alert_location_points (
+    alert_id     UUID NOT NULL,
+    user_id      UUID NOT NULL,
+    location     GEOGRAPHY(POINT, 4326) NOT NULL,
+    recorded_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    FOREIGN KEY (alert_id, user_id) REFERENCES alert_location_shares (alert_id, user_id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_location_points_share ON alert_location_points (alert_id, user_id, recorded_at);
@@ -0,0 +1,38 @@
+-- Migration 036: Personal safety timers
+--
+-- A safety timer is a check-in deadline: if the user hasn't cancelled
+-- or extended it by then, the worker alerts the contacts they picked
+-- (people they vouch with or have a DM with) with a system message in
+-- their DM and a high-priority push. The last location the user chose
+-- to share, if any, goes only in the push, never in the messages,
+-- which are kept. A user has at most one timer at a time. A fired
+-- timer stays until the user checks in, which tells the same contacts
+-- they're safe, or for a week.
+--
+-- The note for each contact is encrypted by the client for that
+-- contact, like a message; the server only stores it and posts it to
+-- their DM when the timer fires, then clears it. Nothing is kept once
+-- the timer is cancelled.
+
+CREATE TABLE IF NOT EXISTS safety_timers (
+    id                UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id           UUID NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    deadline          TIMESTAMPTZ NOT NULL,
+    status            VARCHAR(20) NOT NULL DEFAULT 'active',  -- active, fired
+    last_location     GEOGRAPHY(POINT, 4326),
+    last_location_at  TIMESTAMPTZ,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    fired_at          TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_safety_timers_due ON safety_timers (deadline) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_safety_timers_fired ON safety_timers (fired_at) WHERE status = 'fired';
+
+CREATE TABLE IF NOT EXISTS safety_timer_contacts (
+    timer_id        UUID NOT NULL REFERENCES safety_timers(id) ON DELETE CASCADE,
+    contact_id      UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    encrypted_note  BYTEA,
+    PRIMARY KEY (timer_id, contact_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_safety_timer_contacts_contact ON safety_timer_contacts (contact_id);
@@ -0,0 +1,274 @@
+package mutes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/storage"
+)
+
+// maxMutes caps how many mutes one user can have.
+const maxMutes = 500
+
+// Handler handles mute and block endpoints
+type Handler struct {
+	cfg *config.Config
+	db  *storage.Postgres
+}
+
+// NewHandler creates a new mutes handler
+func NewHandler(cfg *config.Config, db *storage.Postgres) *Handler {
+	return &Handler{cfg: cfg, db: db}
+}
+
+// CreateMuteRequest mutes a user (by ID), topic (by ID), source type
+// or keyword. A keyword may be a short phrase; it matches whole words.
+type CreateMuteRequest struct {
+	Type      string `json:"type" binding:"required,oneof=user topic source_type keyword"`
+	Value     string `json:"value" binding:"required,max=100"`
+	ExpiresAt *int64 `json:"expires_at"` // Unix timestamp; omit to mute until removed
+}
+
+// ListMutes returns the user's active mutes
+func (h *Handler) ListMutes(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	rows, err := h.db.Pool().Query(c.Request.Context(), `
+		SELECT m.id, m.mute_type, m.value, m.expires_at, m.created_at,
+		       COALESCE(u.display_name, t.name)
+		FROM user_mutes m
+		LEFT JOIN users u ON m.mute_type = 'user' AND u.id::text = m.value
+		LEFT JOIN topics t ON m.mute_type = 'topic' AND t.id::text = m.value
+		WHERE m.user_id = $1 AND (m.expires_at IS NULL OR m.expires_at > NOW())
+		ORDER BY m.created_at DESC
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch mutes"})
+		return
+	}
+	defer rows.Close()
+
+	mutes := []gin.H{}
+	for rows.Next() {
+		var id, muteType, value string
+		var expiresAt *time.Time
+		var createdAt time.Time
+		var label *string
+		if err := rows.Scan(&id, &muteType, &value, &expiresAt, &createdAt, &label); err != nil {
+			continue
+		}
+		mute := gin.H{
+			"id":         id,
+			"type":       muteType,
+			"value":      value,
+			"created_at": createdAt,
+		}
+		if expiresAt != nil {
+			mute["expires_at"] = *expiresAt
+		}
+		if label != nil {
+			mute["label"] = *label
+		}
+		mutes = append(mutes, mute)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mutes": mutes})
+}
+
+// CreateMute adds a mute. Muting something already muted updates its
+// expiry.
+func (h *Handler) CreateMute(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ctx := c.Request.Context()
+
+	var req CreateMuteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	value := req.Value
+	switch req.Type {
+	case TypeUser, TypeTopic:
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "value must be an ID"})
+			return
+		}
+		value = parsed.String()
+		if req.Type == TypeUser && value == userID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot mute yourself"})
+			return
+		}
+		table := "users"
+		if req.Type == TypeTopic {
+			table = "topics"
+		}
+		var exists bool
+		if err := h.db.Pool().QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM `+table+` WHERE id = $1)`, value).Scan(&exists); err != nil || !exists {
+			c.JSON(http.StatusNotFound, gin.H{"error": req.Type + " not found"})
+			return
+		}
+	case TypeSourceType:
+		if value != "firsthand" && value != "aggregated" && value != "mainstream" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "source type must be firsthand, aggregated or mainstream"})
+			return
+		}
+	case TypeKeyword:
+		value = NormalizeKeyword(value)
+		if len([]rune(value)) < 2 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "keyword must contain at least two letters or digits"})
+			return
+		}
+	}
+
+	var expiresAt *time.Time
+	if req.ExpiresAt != nil {
+		t := time.Unix(*req.ExpiresAt, 0)
+		if !t.After(time.Now()) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+			return
+		}
+		expiresAt = &t
+	}
+
+	var count int
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT COUNT(*) FROM user_mutes
+		WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+	`, userID).Scan(&count)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create mute"})
+		return
+	}
+	if count >= maxMutes {
+		c.JSON(http.StatusConflict, gin.H{"error": "mute limit reached"})
+		return
+	}
+
+	var id string
+	err = h.db.Pool().QueryRow(ctx, `
+		INSERT INTO user_mutes (user_id, mute_type, value, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, mute_type, value) DO UPDATE SET expires_at = EXCLUDED.expires_at
+		RETURNING id
+	`, userID, req.Type, value, expiresAt).Scan(&id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create mute"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": id, "type": req.Type, "value": value, "message": "muted"})
+}
+
+// DeleteMute removes a mute
+func (h *Handler) DeleteMute(c *gin.Context) {
+	userID := c.GetString("user_id")
+	muteID := c.Param("id")
+
+	result, err := h.db.Pool().Exec(c.Request.Context(),
+		"DELETE FROM user_mutes WHERE id = $1 AND user_id = $2",
+		muteID, userID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete mute"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "mute not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "mute removed"})
+}
+
+// ListBlocks returns the users the current user has blocked
+func (h *Handler) ListBlocks(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	rows, err := h.db.Pool().Query(c.Request.Context(), `
+		SELECT b.blocked_id, u.display_name, b.created_at
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = $1
+		ORDER BY b.created_at DESC
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch blocks"})
+		return
+	}
+	defer rows.Close()
+
+	blocks := []gin.H{}
+	for rows.Next() {
+		var blockedID string
+		var displayName *string
+		var createdAt time.Time
+		if err := rows.Scan(&blockedID, &displayName, &createdAt); err != nil {
+			continue
+		}
+		block := gin.H{"user_id": blockedID, "created_at": createdAt}
+		if displayName != nil {
+			block["display_name"] = *displayName
+		}
+		blocks = append(blocks, block)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"blocks": blocks})
+}
+
+// BlockUser blocks a user
+func (h *Handler) BlockUser(c *gin.Context) {
+	userID := c.GetString("user_id")
+	targetID := c.Param("user_id")
+	ctx := c.Request.Context()
+
+	if _, err := uuid.Parse(targetID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user ID"})
+		return
+	}
+	if targetID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot block yourself"})
+		return
+	}
+
+	var exists bool
+	if err := h.db.Pool().QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, targetID).Scan(&exists); err != nil || !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		return
+	}
+
+	if _, err := h.db.Pool().Exec(ctx, `
+		INSERT INTO user_blocks (blocker_id, blocked_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, userID, targetID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to block user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user blocked"})
+}
+
+// UnblockUser removes a block
+func (h *Handler) UnblockUser(c *gin.Context) {
+	userID := c.GetString("user_id")
+	targetID := c.Param("user_id")
+
+	result, err := h.db.Pool().Exec(c.Request.Context(),
+		"DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2",
+		userID, targetID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to unblock user"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "block not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "user unblocked"})
+}
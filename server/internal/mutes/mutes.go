@@ -0,0 +1,147 @@
+// Package mutes holds per-user mute lists and user blocks.
+//
+// A mute hides content silently: posts by a muted user (the news bot
+// is just bot.BotUserID), in a muted topic, of a muted source type or
+// containing a muted keyword are dropped from feeds, nearby posts and
+// event listings. Blocking a user mutes them too, and additionally
+// stops them from opening a DM with you, messaging you in an existing
+// DM or adding you to channels.
+//
+// Go code that ranks candidates in memory uses Load and List.HidesPost;
+// SQL listings use HiddenSQL so paging stays correct.
+package mutes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/kuurier/server/internal/storage"
+)
+
+// Mute types.
+const (
+	TypeUser       = "user"
+	TypeTopic      = "topic"
+	TypeSourceType = "source_type"
+	TypeKeyword    = "keyword"
+)
+
+// List is a user's active mutes and blocks. A nil *List hides nothing.
+type List struct {
+	users       map[string]bool // muted or blocked
+	topics      map[string]bool
+	sourceTypes map[string]bool
+	keywords    []string // normalized, see NormalizeKeyword
+}
+
+// Load returns userID's active mutes, with blocked users counted as
+// muted users.
+func Load(ctx context.Context, db *storage.Postgres, userID string) (*List, error) {
+	rows, err := db.Pool().Query(ctx, `
+		SELECT mute_type, value FROM user_mutes
+		WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > NOW())
+		UNION ALL
+		SELECT 'user', blocked_id::text FROM user_blocks WHERE blocker_id = $1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	l := &List{}
+	for rows.Next() {
+		var muteType, value string
+		if err := rows.Scan(&muteType, &value); err != nil {
+			return nil, err
+		}
+		l.add(muteType, value)
+	}
+	return l, rows.Err()
+}
+
+func (l *List) add(muteType, value string) {
+	set := func(m *map[string]bool) {
+		if *m == nil {
+			*m = make(map[string]bool)
+		}
+		(*m)[value] = true
+	}
+	switch muteType {
+	case TypeUser:
+		set(&l.users)
+	case TypeTopic:
+		set(&l.topics)
+	case TypeSourceType:
+		set(&l.sourceTypes)
+	case TypeKeyword:
+		l.keywords = append(l.keywords, value)
+	}
+}
+
+// HidesPost reports whether a post should be hidden from the list's
+// owner.
+func (l *List) HidesPost(authorID, sourceType, content string, topicIDs []string) bool {
+	if l == nil {
+		return false
+	}
+	if l.users[authorID] || l.sourceTypes[sourceType] {
+		return true
+	}
+	for _, t := range topicIDs {
+		if l.topics[t] {
+			return true
+		}
+	}
+	if len(l.keywords) > 0 {
+		text := " " + NormalizeKeyword(content) + " "
+		for _, k := range l.keywords {
+			if strings.Contains(text, " "+k+" ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NormalizeKeyword lower-cases s and reduces it to its words separated
+// by single spaces, so keyword mutes match whole words and phrases
+// regardless of case and punctuation. Must agree with the
+// normalization in HiddenSQL.
+func NormalizeKeyword(s string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ")
+}
+
+// HiddenSQL returns a boolean SQL expression that is true when a row
+// is muted by, or its author blocked by, the user in placeholder
+// userArg (e.g. "$1"). The other arguments are SQL expressions for
+// the row's author ID, source type (or NULL), searchable text and
+// topic IDs as a text[] (or NULL::text[]). Use it as "AND NOT (...)".
+func HiddenSQL(userArg, author, sourceType, text, topicIDs string) string {
+	return fmt.Sprintf(`(EXISTS (
+		SELECT 1 FROM user_mutes um
+		WHERE um.user_id = %[1]s AND (um.expires_at IS NULL OR um.expires_at > NOW())
+		  AND ((um.mute_type = 'user' AND um.value = (%[2]s)::text)
+		    OR (um.mute_type = 'source_type' AND um.value = %[3]s)
+		    OR (um.mute_type = 'topic' AND um.value = ANY(%[5]s))
+		    OR (um.mute_type = 'keyword'
+		        AND ' ' || regexp_replace(lower(%[4]s), '[^[:alnum:]]+', ' ', 'g') || ' ' LIKE '%% ' || um.value || ' %%'))
+	) OR EXISTS (
+		SELECT 1 FROM user_blocks ub WHERE ub.blocker_id = %[1]s AND ub.blocked_id = %[2]s
+	))`, userArg, author, sourceType, text, topicIDs)
+}
+
+// Blocked reports whether either user has blocked the other.
+func Blocked(ctx context.Context, db *storage.Postgres, userA, userB string) (bool, error) {
+	var blocked bool
+	err := db.Pool().QueryRow(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM user_blocks
+			WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1)
+		)
+	`, userA, userB).Scan(&blocked)
+	return blocked, err
+}
@@ -0,0 +1,55 @@
+package mutes
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeKeyword(t *testing.T) {
+	assert.Equal(t, "tear gas", NormalizeKeyword("  Tear-GAS! "))
+	assert.Equal(t, "räumung", NormalizeKeyword("Räumung"))
+	assert.Equal(t, "", NormalizeKeyword("?!"))
+}
+
+func TestHidesPost(t *testing.T) {
+	l := &List{}
+	l.add(TypeUser, "bot")
+	l.add(TypeTopic, "t-sport")
+	l.add(TypeSourceType, "aggregated")
+	l.add(TypeKeyword, NormalizeKeyword("tear gas"))
+
+	tests := []struct {
+		name       string
+		author     string
+		sourceType string
+		content    string
+		topics     []string
+		hidden     bool
+	}{
+		{"muted author", "bot", "mainstream", "Headline", nil, true},
+		{"muted topic", "u1", "firsthand", "Match tonight", []string{"t-other", "t-sport"}, true},
+		{"muted source type", "u1", "aggregated", "Roundup", nil, true},
+		{"keyword phrase, any case and punctuation", "u1", "firsthand", "Police used TEAR-GAS near the park", nil, true},
+		{"keyword needs whole words", "u1", "firsthand", "Tear gasket seal", nil, false},
+		{"nothing muted", "u1", "firsthand", "Peaceful march", []string{"t-other"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.hidden, l.HidesPost(tt.author, tt.sourceType, tt.content, tt.topics))
+		})
+	}
+
+	var none *List
+	assert.False(t, none.HidesPost("bot", "mainstream", "tear gas", nil), "nil list hides nothing")
+}
+
+func TestHiddenSQL_SubstitutesExpressions(t *testing.T) {
+	sql := HiddenSQL("$3", "p.author_id", "p.source_type", "p.content", "NULL::text[]")
+	assert.Equal(t, 2, strings.Count(sql, "= $3"), "mutes and blocks are both keyed on the viewer")
+	assert.Contains(t, sql, "ub.blocked_id = p.author_id")
+	assert.Contains(t, sql, "ANY(NULL::text[])")
+	assert.Contains(t, sql, "LIKE '% ' || um.value || ' %'")
+	assert.NotContains(t, sql, "%!", "no fmt verb errors")
+}
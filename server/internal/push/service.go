@@ -4,6 +4,7 @@ import (
 	"context"
 	"log"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/kuurier/server/internal/config"
 	"github.com/kuurier/server/internal/storage"
 )
@@ -44,6 +45,8 @@ const (
 	NotificationTypeMessage     NotificationType = "message"
 	NotificationTypeEvent       NotificationType = "event"
 	NotificationTypeEventRemind NotificationType = "event_reminder"
+	NotificationTypePost        NotificationType = "post"
+	NotificationTypeDigest      NotificationType = "digest"
 )
 
 // SendToUser sends a notification to a specific user
@@ -99,49 +102,89 @@ func (s *Service) SendToUsers(ctx context.Context, userIDs []string, notificatio
 	return nil
 }
 
-// SendToNearbyUsers sends a notification to users near a location
-func (s *Service) SendToNearbyUsers(ctx context.Context, lat, lon float64, radiusMeters int, notification Notification, excludeUserID string) error {
-	// Find all users with push tokens who are near the location
-	// Using the push_tokens table and checking user's last known location
+// nearbyTarget is a device of a user near a location.
+type nearbyTarget struct {
+	userID, token, platform string
+}
+
+// nearbyTargets returns the devices of users following an area within
+// radiusMeters of a location, except those in excludeUserIDs. Users
+// following an area within innerRadiusMeters are left out too, so that
+// widening a radius reaches only the ring it newly covers.
+//
+// The server knows where users are only from the areas they follow.
+// Users who follow none could be anywhere, so the first broadcast
+// (innerRadiusMeters 0) goes to them too, as every broadcast did
+// before alerts were targeted.
+func (s *Service) nearbyTargets(ctx context.Context, lat, lon float64, innerRadiusMeters, radiusMeters int, excludeUserIDs []string) ([]nearbyTarget, error) {
 	rows, err := s.db.Pool().Query(ctx, `
 		SELECT DISTINCT pt.user_id, pt.token, pt.platform
 		FROM push_tokens pt
-		WHERE pt.user_id != $4
-	`, lat, lon, radiusMeters, excludeUserID)
+		WHERE pt.user_id::text <> ALL($5)
+		  AND (EXISTS(SELECT 1 FROM subscriptions sub
+		              WHERE sub.user_id = pt.user_id AND sub.is_active AND sub.location IS NOT NULL
+		                AND ST_DWithin(sub.location, ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography, $4))
+		       OR ($3 = 0 AND NOT EXISTS(SELECT 1 FROM subscriptions sub
+		                                 WHERE sub.user_id = pt.user_id AND sub.is_active AND sub.location IS NOT NULL)))
+		  AND ($3 = 0 OR NOT EXISTS(SELECT 1 FROM subscriptions sub
+		                            WHERE sub.user_id = pt.user_id AND sub.is_active AND sub.location IS NOT NULL
+		                              AND ST_DWithin(sub.location, ST_SetSRID(ST_MakePoint($2, $1), 4326)::geography, $3)))
+	`, lat, lon, innerRadiusMeters, radiusMeters, excludeUserIDs)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (nearbyTarget, error) {
+		var t nearbyTarget
+		err := row.Scan(&t.userID, &t.token, &t.platform)
+		return t, err
+	})
+}
 
+// SendToNearbyUsers sends a notification to users following an area
+// between innerRadiusMeters and radiusMeters of a location, except
+// those in excludeUserIDs
+func (s *Service) SendToNearbyUsers(ctx context.Context, lat, lon float64, innerRadiusMeters, radiusMeters int, notification Notification, excludeUserIDs []string) error {
+	targets, err := s.nearbyTargets(ctx, lat, lon, innerRadiusMeters, radiusMeters, excludeUserIDs)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var userID, token, platform string
-		if err := rows.Scan(&userID, &token, &platform); err != nil {
-			continue
-		}
 
+	for _, t := range targets {
 		// Check quiet hours (skip for non-emergency)
-		if notification.Priority != "high" && s.isInQuietHours(ctx, userID) {
+		if notification.Priority != "high" && s.isInQuietHours(ctx, t.userID) {
 			continue
 		}
 
-		switch platform {
+		switch t.platform {
 		case "ios":
-			if err := s.sendAPNs(ctx, token, notification); err != nil {
+			if err := s.sendAPNs(ctx, t.token, notification); err != nil {
 				if err == storage.ErrInvalidToken {
-					s.removeInvalidToken(ctx, userID, token)
+					s.removeInvalidToken(ctx, t.userID, t.token)
 				}
 			}
 		case "android":
-			s.sendFCM(ctx, token, notification)
+			s.sendFCM(ctx, t.token, notification)
 		}
 	}
 
 	return nil
 }
 
-// SendAlertToNearbyUsers sends an SOS alert to nearby users
-func (s *Service) SendAlertToNearbyUsers(ctx context.Context, alertID string, authorID string) error {
+// SendAlertToNearbyUsers sends an SOS alert to nearby users, except
+// those in exclude, who were already sent it
+func (s *Service) SendAlertToNearbyUsers(ctx context.Context, alertID string, authorID string, exclude ...string) error {
+	return s.sendAlertAround(ctx, alertID, authorID, 0, exclude)
+}
+
+// SendAlertToWidenedArea sends an SOS alert whose radius was widened
+// from previousRadiusMeters to the users it newly reaches.
+func (s *Service) SendAlertToWidenedArea(ctx context.Context, alertID string, authorID string, previousRadiusMeters int) error {
+	return s.sendAlertAround(ctx, alertID, authorID, previousRadiusMeters, nil)
+}
+
+// sendAlertAround sends an SOS alert to the users between
+// innerRadiusMeters and its radius, other than its author.
+func (s *Service) sendAlertAround(ctx context.Context, alertID, authorID string, innerRadiusMeters int, exclude []string) error {
 	// Get alert details
 	var title string
 	var severity int
@@ -157,6 +200,28 @@ func (s *Service) SendAlertToNearbyUsers(ctx context.Context, alertID string, au
 		return err
 	}
 
+	notification := alertNotification(alertID, title, severity, "Someone nearby needs help. Tap to respond.")
+	return s.SendToNearbyUsers(ctx, lat, lon, innerRadiusMeters, radiusMeters, notification, append([]string{authorID}, exclude...))
+}
+
+// SendAlertToSkilledUsers sends an SOS alert to userIDs, the people
+// nearby with a skill it asks for, ahead of everyone else. skillLabel
+// names the skills, e.g. "A medic".
+func (s *Service) SendAlertToSkilledUsers(ctx context.Context, alertID string, userIDs []string, skillLabel string) error {
+	var title string
+	var severity int
+	err := s.db.Pool().QueryRow(ctx, "SELECT title, severity FROM alerts WHERE id = $1", alertID).Scan(&title, &severity)
+	if err != nil {
+		return err
+	}
+
+	notification := alertNotification(alertID, title, severity, skillLabel+" is needed nearby. Tap to respond.")
+	return s.SendToUsers(ctx, userIDs, notification)
+}
+
+// alertNotification is the push for an SOS alert, with body saying
+// why the recipient gets it.
+func alertNotification(alertID, title string, severity int, body string) Notification {
 	// Determine priority based on severity
 	priority := "normal"
 	if severity >= 2 {
@@ -176,9 +241,9 @@ func (s *Service) SendAlertToNearbyUsers(ctx context.Context, alertID string, au
 		emoji = "🔔"
 	}
 
-	notification := Notification{
+	return Notification{
 		Title:    emoji + " " + title,
-		Body:     "Someone nearby needs help. Tap to respond.",
+		Body:     body,
 		Priority: priority,
 		Category: "ALERT",
 		ThreadID: "alert-" + alertID,
@@ -187,12 +252,12 @@ func (s *Service) SendAlertToNearbyUsers(ctx context.Context, alertID string, au
 			"alert_id": alertID,
 		},
 	}
-
-	return s.SendToNearbyUsers(ctx, lat, lon, radiusMeters, notification, authorID)
 }
 
-// SendAlertResponseNotification notifies the alert author of a new response
-func (s *Service) SendAlertResponseNotification(ctx context.Context, alertID, responderID string) error {
+// SendAlertResponseNotification notifies the alert author of a new
+// response. responder names the responder by their skill, e.g. "A
+// medic", or is "" to say "Someone".
+func (s *Service) SendAlertResponseNotification(ctx context.Context, alertID, responderID, responder string) error {
 	// Get alert author and title
 	var authorID, title string
 	err := s.db.Pool().QueryRow(ctx,
@@ -215,16 +280,22 @@ func (s *Service) SendAlertResponseNotification(ctx context.Context, alertID, re
 		return err
 	}
 
+	if responder == "" {
+		responder = "Someone"
+	}
 	var body string
 	switch status {
 	case "acknowledged":
-		body = "Someone has acknowledged your alert"
+		body = responder + " has acknowledged your alert"
 	case "en_route":
-		body = "Someone is on their way to help!"
+		body = responder + " is on their way to help!"
 	case "arrived":
 		body = "Help has arrived!"
+		if responder != "Someone" {
+			body = responder + " has arrived!"
+		}
 	default:
-		body = "Someone responded to your alert"
+		body = responder + " responded to your alert"
 	}
 
 	notification := Notification{
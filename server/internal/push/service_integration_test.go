@@ -0,0 +1,61 @@
+//go:build integration
+
+package push
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNearbyTargets(t *testing.T) {
+	db := testutil.NewTestPostgres(t)
+	pool := db.Pool()
+	ctx := context.Background()
+	s := NewService(&config.Config{}, db, nil, nil)
+
+	// follows creates a user with a device who follows the area at lat,
+	// 13.4 (about 111 km per degree of latitude).
+	follows := func(name string, active bool, lats ...float64) string {
+		t.Helper()
+		id := testutil.CreateUser(t, pool, name)
+		_, err := pool.Exec(ctx, `INSERT INTO push_tokens (user_id, token, platform) VALUES ($1, $2, 'ios')`, id, "token-"+name)
+		require.NoError(t, err)
+		for _, lat := range lats {
+			_, err := pool.Exec(ctx, `
+				INSERT INTO subscriptions (user_id, location, radius_meters, is_active)
+				VALUES ($1, ST_SetSRID(ST_MakePoint(13.4, $2), 4326)::geography, 1000, $3)
+			`, id, lat, active)
+			require.NoError(t, err)
+		}
+		return id
+	}
+	author := follows("author", true, 52.5)
+	near := follows("near", true, 52.5)
+	ring := follows("ring", true, 52.53)
+	both := follows("both", true, 52.501, 52.53)
+	follows("far", true, 53.0)
+	inactive := follows("inactive", false, 52.5)
+	unplaced := follows("unplaced", true)
+
+	users := func(inner, outer int) []string {
+		t.Helper()
+		targets, err := s.nearbyTargets(ctx, 52.5, 13.4, inner, outer, []string{author})
+		require.NoError(t, err)
+		ids := make([]string, len(targets))
+		for i, target := range targets {
+			ids[i] = target.userID
+		}
+		return ids
+	}
+
+	assert.ElementsMatch(t, []string{near, both, inactive, unplaced}, users(0, 1000),
+		"users who follow no area get the first broadcast")
+	assert.ElementsMatch(t, []string{ring}, users(1000, 5000),
+		"users already in the inner circle aren't sent it again")
+	assert.ElementsMatch(t, []string{near, ring, both, inactive, unplaced}, users(0, 5000))
+}
@@ -0,0 +1,463 @@
+// Package recurrence implements the subset of RFC 5545 recurrence rules
+// that repeating events use: DAILY, WEEKLY, MONTHLY and YEARLY rules
+// with INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY and WKST.
+//
+// Occurrences keep the wall-clock time of the first start in its
+// location, so a 19:00 meeting stays at 19:00 across DST changes.
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequency is a rule's FREQ.
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+	Yearly  Frequency = "YEARLY"
+)
+
+const (
+	// MaxCount caps COUNT.
+	MaxCount = 1000
+
+	// maxInterval caps INTERVAL.
+	maxInterval = 366
+
+	// maxPeriods bounds how many periods (days, weeks, months, years)
+	// an expansion walks before giving up.
+	maxPeriods = 20000
+)
+
+// WeekdayNum is a BYDAY entry: a weekday with an optional ordinal.
+// In a MONTHLY rule 1FR is the first Friday and -1SU the last Sunday;
+// N is 0 for every such weekday.
+type WeekdayNum struct {
+	N   int
+	Day time.Weekday
+}
+
+// Rule is a parsed RRULE. The zero Interval means 1; a zero Count and
+// Until mean the rule never ends.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	WeekStart  time.Weekday
+}
+
+var dayCodes = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// WeeklyOn returns FREQ=WEEKLY on the given days.
+func WeeklyOn(days ...time.Weekday) Rule {
+	r := Rule{Freq: Weekly, WeekStart: time.Monday}
+	for _, d := range days {
+		r.ByDay = append(r.ByDay, WeekdayNum{Day: d})
+	}
+	return r
+}
+
+// ParseWeekday reads a weekday written as a name ("Friday"), a
+// three-letter abbreviation ("fri") or an RFC 5545 code ("FR").
+func ParseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) < 2 {
+		return 0, false
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if s == name || s == name[:3] || s == strings.ToLower(dayCodes[d]) {
+			return d, true
+		}
+	}
+	return 0, false
+}
+
+// Parse reads an RRULE value, with or without the "RRULE:" prefix.
+// Rule parts outside the supported subset are an error rather than
+// being ignored, so an accepted rule always means what it says.
+func Parse(s string) (Rule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) > 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	if s == "" {
+		return Rule{}, errors.New("empty recurrence rule")
+	}
+
+	r := Rule{WeekStart: time.Monday}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if !ok || value == "" {
+			return Rule{}, fmt.Errorf("malformed rule part %q", part)
+		}
+		if seen[key] {
+			return Rule{}, fmt.Errorf("%s given twice", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			r.Freq = Frequency(value)
+			switch r.Freq {
+			case Daily, Weekly, Monthly, Yearly:
+			default:
+				err = fmt.Errorf("unsupported frequency %s", value)
+			}
+		case "INTERVAL":
+			r.Interval, err = parseBounded(value, 1, maxInterval)
+		case "COUNT":
+			r.Count, err = parseBounded(value, 1, MaxCount)
+		case "UNTIL":
+			r.Until, err = parseUntil(value)
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				wd, perr := parseWeekdayNum(d)
+				if perr != nil {
+					err = perr
+					break
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				n, perr := strconv.Atoi(d)
+				if perr != nil || n == 0 || n < -31 || n > 31 {
+					err = fmt.Errorf("invalid BYMONTHDAY %q", d)
+					break
+				}
+				r.ByMonthDay = append(r.ByMonthDay, n)
+			}
+		case "WKST":
+			if d := dayIndex(value); d >= 0 {
+				r.WeekStart = time.Weekday(d)
+			} else {
+				err = fmt.Errorf("invalid WKST %q", value)
+			}
+		default:
+			err = fmt.Errorf("unsupported rule part %s", key)
+		}
+		if err != nil {
+			return Rule{}, err
+		}
+	}
+
+	return r, r.validate()
+}
+
+func (r Rule) validate() error {
+	if r.Freq == "" {
+		return errors.New("FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return errors.New("COUNT and UNTIL can't both be set")
+	}
+	if len(r.ByDay) > 0 && len(r.ByMonthDay) > 0 {
+		return errors.New("BYDAY and BYMONTHDAY can't be combined")
+	}
+	if len(r.ByMonthDay) > 0 && r.Freq != Monthly {
+		return errors.New("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	if len(r.ByDay) > 0 && r.Freq == Yearly {
+		return errors.New("BYDAY is not supported with FREQ=YEARLY")
+	}
+	for _, d := range r.ByDay {
+		if d.N != 0 && r.Freq != Monthly {
+			return errors.New("numbered BYDAY entries are only supported with FREQ=MONTHLY")
+		}
+	}
+	return nil
+}
+
+func parseBounded(s string, min, max int) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min || n > max {
+		return 0, fmt.Errorf("%q must be a number from %d to %d", s, min, max)
+	}
+	return n, nil
+}
+
+// parseUntil reads a DATE or UTC DATE-TIME. A DATE ends at the last
+// second of that day in UTC.
+func parseUntil(s string) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102T150405", s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse("20060102", s); err == nil {
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q", s)
+}
+
+func parseWeekdayNum(s string) (WeekdayNum, error) {
+	if len(s) < 2 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	d := dayIndex(s[len(s)-2:])
+	if d < 0 {
+		return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+	}
+	wd := WeekdayNum{Day: time.Weekday(d)}
+	if prefix := s[:len(s)-2]; prefix != "" {
+		n, err := strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -5 || n > 5 {
+			return WeekdayNum{}, fmt.Errorf("invalid BYDAY %q", s)
+		}
+		wd.N = n
+	}
+	return wd, nil
+}
+
+func dayIndex(code string) int {
+	for i, c := range dayCodes {
+		if c == code {
+			return i
+		}
+	}
+	return -1
+}
+
+// String renders the rule in canonical form, without the "RRULE:"
+// prefix. UNTIL is always a UTC DATE-TIME.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = dayCodes[d.Day]
+			if d.N != 0 {
+				days[i] = strconv.Itoa(d.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+dayCodes[r.WeekStart])
+	}
+	return strings.Join(parts, ";")
+}
+
+// Iterate calls yield with each occurrence starting at or after
+// dtstart, in order, until yield returns false or the rule ends.
+// dtstart itself is only an occurrence if the rule generates it.
+func (r Rule) Iterate(dtstart time.Time, yield func(time.Time) bool) {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	loc := dtstart.Location()
+	hour, min, sec := dtstart.Clock()
+	year, month, day := dtstart.Date()
+
+	n := 0
+	// emit reports whether to keep going.
+	emit := func(y int, m time.Month, d int) bool {
+		t := time.Date(y, m, d, hour, min, sec, 0, loc)
+		if t.Before(dtstart) {
+			return true
+		}
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false
+		}
+		n++
+		if !yield(t) {
+			return false
+		}
+		return r.Count == 0 || n < r.Count
+	}
+	// Day arithmetic happens at noon UTC so it never trips over DST.
+	civil := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+	}
+
+	for p := 0; p < maxPeriods; p++ {
+		switch r.Freq {
+		case Daily:
+			d := civil(year, month, day+p*interval)
+			if len(r.ByDay) > 0 && !r.onDay(d.Weekday()) {
+				continue
+			}
+			if !emit(d.Date()) {
+				return
+			}
+		case Weekly:
+			offset := (int(dtstart.Weekday()) - int(r.WeekStart) + 7) % 7
+			weekStart := civil(year, month, day-offset+7*p*interval)
+			for _, wd := range r.weekDays(dtstart.Weekday()) {
+				d := weekStart.AddDate(0, 0, (int(wd)-int(r.WeekStart)+7)%7)
+				if !emit(d.Date()) {
+					return
+				}
+			}
+		case Monthly:
+			first := civil(year, month+time.Month(p*interval), 1)
+			for _, d := range r.monthDays(first, day) {
+				if !emit(first.Year(), first.Month(), d) {
+					return
+				}
+			}
+		case Yearly:
+			y := year + p*interval
+			if month == time.February && day == 29 && civil(y, 2, 29).Month() != time.February {
+				continue
+			}
+			if !emit(y, month, day) {
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (r Rule) onDay(d time.Weekday) bool {
+	for _, wd := range r.ByDay {
+		if wd.Day == d {
+			return true
+		}
+	}
+	return false
+}
+
+// weekDays returns a WEEKLY rule's days in week order from WeekStart.
+func (r Rule) weekDays(fallback time.Weekday) []time.Weekday {
+	if len(r.ByDay) == 0 {
+		return []time.Weekday{fallback}
+	}
+	var days []time.Weekday
+	for i := 0; i < 7; i++ {
+		d := time.Weekday((int(r.WeekStart) + i) % 7)
+		if r.onDay(d) {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+// monthDays returns a MONTHLY rule's days in the month starting at
+// first, ascending. Days the month doesn't have are skipped, as RFC
+// 5545 requires (the 31st doesn't fall back to the 30th).
+func (r Rule) monthDays(first time.Time, fallback int) []int {
+	daysIn := first.AddDate(0, 1, -1).Day()
+	set := make(map[int]bool)
+	add := func(d int) {
+		if d >= 1 && d <= daysIn {
+			set[d] = true
+		}
+	}
+
+	switch {
+	case len(r.ByMonthDay) > 0:
+		for _, d := range r.ByMonthDay {
+			if d < 0 {
+				d = daysIn + d + 1
+			}
+			add(d)
+		}
+	case len(r.ByDay) > 0:
+		for _, wd := range r.ByDay {
+			firstOfDay := 1 + (int(wd.Day)-int(first.Weekday())+7)%7
+			switch {
+			case wd.N == 0:
+				for d := firstOfDay; d <= daysIn; d += 7 {
+					add(d)
+				}
+			case wd.N > 0:
+				add(firstOfDay + 7*(wd.N-1))
+			default:
+				last := firstOfDay + 7*((daysIn-firstOfDay)/7)
+				add(last + 7*(wd.N+1))
+			}
+		}
+	default:
+		add(fallback)
+	}
+
+	days := make([]int, 0, len(set))
+	for d := range set {
+		days = append(days, d)
+	}
+	sort.Ints(days)
+	return days
+}
+
+// Between returns up to max occurrences starting in [from, to).
+func (r Rule) Between(dtstart, from, to time.Time, max int) []time.Time {
+	var out []time.Time
+	r.Iterate(dtstart, func(t time.Time) bool {
+		if !t.Before(to) || len(out) >= max {
+			return false
+		}
+		if !t.Before(from) {
+			out = append(out, t)
+		}
+		return true
+	})
+	return out
+}
+
+// Next returns the first occurrence at or after t.
+func (r Rule) Next(dtstart, t time.Time) (time.Time, bool) {
+	var next time.Time
+	r.Iterate(dtstart, func(o time.Time) bool {
+		if o.Before(t) {
+			return true
+		}
+		next = o
+		return false
+	})
+	return next, !next.IsZero()
+}
+
+// Includes reports whether t is an occurrence.
+func (r Rule) Includes(dtstart, t time.Time) bool {
+	next, ok := r.Next(dtstart, t)
+	return ok && next.Equal(t)
+}
+
+// Last returns the final occurrence of a rule with COUNT or UNTIL.
+// ok is false for a rule that never ends or generates nothing.
+func (r Rule) Last(dtstart time.Time) (last time.Time, ok bool) {
+	if r.Count == 0 && r.Until.IsZero() {
+		return time.Time{}, false
+	}
+	r.Iterate(dtstart, func(t time.Time) bool {
+		last = t
+		return true
+	})
+	return last, !last.IsZero()
+}
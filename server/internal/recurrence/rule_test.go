@@ -0,0 +1,131 @@
+package recurrence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustParse(t *testing.T, s string) Rule {
+	t.Helper()
+	r, err := Parse(s)
+	require.NoError(t, err)
+	return r
+}
+
+func dates(ts []time.Time) []string {
+	out := make([]string, len(ts))
+	for i, t := range ts {
+		out[i] = t.Format("2006-01-02 15:04 Mon")
+	}
+	return out
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	for in, want := range map[string]string{
+		"RRULE:FREQ=WEEKLY;BYDAY=FR":                     "FREQ=WEEKLY;BYDAY=FR",
+		"freq=monthly;byday=-1su;interval=2":             "FREQ=MONTHLY;INTERVAL=2;BYDAY=-1SU",
+		"FREQ=DAILY;COUNT=5":                             "FREQ=DAILY;COUNT=5",
+		"FREQ=WEEKLY;UNTIL=20261231;BYDAY=MO,WE;WKST=SU": "FREQ=WEEKLY;UNTIL=20261231T235959Z;BYDAY=MO,WE;WKST=SU",
+		"FREQ=MONTHLY;BYMONTHDAY=1,-1":                   "FREQ=MONTHLY;BYMONTHDAY=1,-1",
+	} {
+		assert.Equal(t, want, mustParse(t, in).String(), in)
+	}
+}
+
+func TestParse_Rejects(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"BYDAY=FR",
+		"FREQ=HOURLY",
+		"FREQ=WEEKLY;BYSETPOS=1",
+		"FREQ=DAILY;COUNT=3;UNTIL=20260101",
+		"FREQ=WEEKLY;BYDAY=1FR",
+		"FREQ=WEEKLY;BYMONTHDAY=3",
+		"FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=100000",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=WEEKLY;BYDAY=XX",
+	} {
+		_, err := Parse(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestIterate_WeeklyKeepsWallClockAcrossDST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	// Friday 2026-03-20 19:00; clocks go forward on the 29th.
+	start := time.Date(2026, 3, 20, 19, 0, 0, 0, berlin)
+	got := mustParse(t, "FREQ=WEEKLY;BYDAY=FR;COUNT=3").Between(start, start, start.AddDate(1, 0, 0), 10)
+	assert.Equal(t, []string{"2026-03-20 19:00 Fri", "2026-03-27 19:00 Fri", "2026-04-03 19:00 Fri"}, dates(got))
+	assert.Equal(t, 7*24*time.Hour-time.Hour, got[2].Sub(got[1]), "the week across the change is an hour short")
+}
+
+func TestIterate_WeeklyMultipleDaysAndInterval(t *testing.T) {
+	// Wednesday start; Monday/Wednesday every other week.
+	start := time.Date(2026, 4, 15, 18, 30, 0, 0, time.UTC)
+	got := mustParse(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE").Between(start, start, start.AddDate(0, 1, 0), 10)
+	assert.Equal(t, []string{
+		"2026-04-15 18:30 Wed",
+		"2026-04-27 18:30 Mon",
+		"2026-04-29 18:30 Wed",
+		"2026-05-11 18:30 Mon",
+		"2026-05-13 18:30 Wed",
+	}, dates(got))
+}
+
+func TestIterate_Monthly(t *testing.T) {
+	start := time.Date(2026, 1, 31, 10, 0, 0, 0, time.UTC)
+	got := mustParse(t, "FREQ=MONTHLY;COUNT=3").Between(start, start, start.AddDate(2, 0, 0), 10)
+	assert.Equal(t, []string{"2026-01-31 10:00 Sat", "2026-03-31 10:00 Tue", "2026-05-31 10:00 Sun"}, dates(got),
+		"months without a 31st are skipped")
+
+	start = time.Date(2026, 1, 25, 12, 0, 0, 0, time.UTC)
+	got = mustParse(t, "FREQ=MONTHLY;BYDAY=-1SU").Between(start, start, time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), 10)
+	assert.Equal(t, []string{"2026-01-25 12:00 Sun", "2026-02-22 12:00 Sun", "2026-03-29 12:00 Sun"}, dates(got))
+
+	start = time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+	got = mustParse(t, "FREQ=MONTHLY;BYDAY=1FR;COUNT=2").Between(start, start, start.AddDate(1, 0, 0), 10)
+	assert.Equal(t, []string{"2026-01-02 09:00 Fri", "2026-02-06 09:00 Fri"}, dates(got))
+}
+
+func TestIterate_UntilAndYearly(t *testing.T) {
+	start := time.Date(2026, 4, 1, 8, 0, 0, 0, time.UTC)
+	r := mustParse(t, "FREQ=DAILY;UNTIL=20260403T080000Z")
+	last, ok := r.Last(start)
+	require.True(t, ok)
+	assert.Equal(t, "2026-04-03 08:00 Fri", last.Format("2006-01-02 15:04 Mon"), "UNTIL is inclusive")
+
+	_, ok = mustParse(t, "FREQ=DAILY").Last(start)
+	assert.False(t, ok, "open-ended rules have no last occurrence")
+
+	leap := time.Date(2028, 2, 29, 12, 0, 0, 0, time.UTC)
+	got := mustParse(t, "FREQ=YEARLY;COUNT=2").Between(leap, leap, leap.AddDate(10, 0, 0), 10)
+	assert.Equal(t, []string{"2028-02-29 12:00 Tue", "2032-02-29 12:00 Sun"}, dates(got))
+}
+
+func TestNextAndIncludes(t *testing.T) {
+	r := WeeklyOn(time.Saturday)
+	// A Wednesday anchor isn't itself an occurrence.
+	anchor := time.Date(2026, 4, 15, 14, 0, 0, 0, time.UTC)
+	next, ok := r.Next(anchor, anchor)
+	require.True(t, ok)
+	assert.Equal(t, 18, next.Day())
+	assert.False(t, r.Includes(anchor, anchor))
+	assert.True(t, r.Includes(anchor, next))
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=SA", r.String())
+}
+
+func TestParseWeekday(t *testing.T) {
+	for _, s := range []string{"Friday", "fri", "FR", " friday "} {
+		d, ok := ParseWeekday(s)
+		assert.True(t, ok, s)
+		assert.Equal(t, time.Friday, d, s)
+	}
+	_, ok := ParseWeekday("someday")
+	assert.False(t, ok)
+}
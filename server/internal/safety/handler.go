@@ -0,0 +1,428 @@
+package safety
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/push"
+	"github.com/kuurier/server/internal/storage"
+	"github.com/kuurier/server/internal/websocket"
+)
+
+// Handler handles safety timer endpoints
+type Handler struct {
+	cfg  *config.Config
+	db   *storage.Postgres
+	push *push.Service
+	hub  *websocket.Hub
+}
+
+// NewHandler creates a new safety timers handler
+func NewHandler(cfg *config.Config, db *storage.Postgres, pushService *push.Service, hub *websocket.Hub) *Handler {
+	return &Handler{cfg: cfg, db: db, push: pushService, hub: hub}
+}
+
+// TimerContact is someone a safety timer alerts. EncryptedNote is
+// encrypted by the client for them, and posted to their DM if the
+// timer fires.
+type TimerContact struct {
+	UserID        string `json:"user_id" binding:"required,uuid"`
+	EncryptedNote []byte `json:"encrypted_note" binding:"max=4096"`
+}
+
+// StartTimerRequest is the request body for setting a safety timer
+type StartTimerRequest struct {
+	Deadline  int64          `json:"deadline" binding:"required"` // Unix timestamp
+	Contacts  []TimerContact `json:"contacts" binding:"required,min=1,dive"`
+	Latitude  *float64       `json:"latitude" binding:"omitempty,min=-90,max=90"`
+	Longitude *float64       `json:"longitude" binding:"omitempty,min=-180,max=180"`
+}
+
+// GetTimer returns the current user's safety timer
+// GET /me/safety-timer
+func (h *Handler) GetTimer(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ctx := c.Request.Context()
+
+	var timerID, status string
+	var deadline, createdAt time.Time
+	var firedAt, locatedAt *time.Time
+	var lat, lon *float64
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT id, deadline, status, created_at, fired_at,
+		       ST_Y(last_location::geometry), ST_X(last_location::geometry), last_location_at
+		FROM safety_timers WHERE user_id = $1
+	`, userID).Scan(&timerID, &deadline, &status, &createdAt, &firedAt, &lat, &lon, &locatedAt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no safety timer set"})
+		return
+	}
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT c.contact_id, u.display_name
+		FROM safety_timer_contacts c
+		JOIN users u ON u.id = c.contact_id
+		WHERE c.timer_id = $1
+		ORDER BY u.display_name NULLS LAST, c.contact_id
+	`, timerID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch safety timer"})
+		return
+	}
+	contacts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (gin.H, error) {
+		var contactID string
+		var displayName *string
+		if err := row.Scan(&contactID, &displayName); err != nil {
+			return nil, err
+		}
+		return gin.H{"user_id": contactID, "display_name": displayName}, nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch safety timer"})
+		return
+	}
+
+	timer := gin.H{
+		"id":         timerID,
+		"deadline":   deadline,
+		"status":     status,
+		"created_at": createdAt,
+		"contacts":   contacts,
+	}
+	if firedAt != nil {
+		timer["fired_at"] = *firedAt
+	}
+	if lat != nil && lon != nil && locatedAt != nil {
+		timer["last_location"] = gin.H{"latitude": *lat, "longitude": *lon, "shared_at": *locatedAt}
+	}
+
+	c.JSON(http.StatusOK, timer)
+}
+
+// StartTimer sets a safety timer for the current user. Contacts must
+// be people they vouched for or who vouched for them, or have a DM
+// with. A location is optional, and can be shared or updated later.
+// POST /me/safety-timer
+func (h *Handler) StartTimer(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ctx := c.Request.Context()
+
+	var req StartTimerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	deadline := time.Unix(req.Deadline, 0).UTC()
+	if err := checkDeadline(deadline, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "latitude and longitude must be set together"})
+		return
+	}
+
+	contactIDs := make([]string, 0, len(req.Contacts))
+	for _, contact := range req.Contacts {
+		if contact.UserID == userID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cannot pick yourself as a contact"})
+			return
+		}
+		if slices.Contains(contactIDs, contact.UserID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "duplicate contact: " + contact.UserID})
+			return
+		}
+		contactIDs = append(contactIDs, contact.UserID)
+	}
+	if len(contactIDs) > maxContacts {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "too many contacts"})
+		return
+	}
+
+	// Vouches either way or a DM, and no block either way
+	var eligible int
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT COUNT(*) FROM users u
+		WHERE u.id::text = ANY($2)
+		  AND (EXISTS(SELECT 1 FROM vouches v
+		              WHERE (v.voucher_id = $1 AND v.vouchee_id = u.id) OR (v.voucher_id = u.id AND v.vouchee_id = $1))
+		       OR EXISTS(SELECT 1 FROM dm_channels d
+		                 WHERE d.user1_id = LEAST($1::uuid, u.id) AND d.user2_id = GREATEST($1::uuid, u.id)))
+		  AND NOT EXISTS(SELECT 1 FROM user_blocks ub
+		                 WHERE (ub.blocker_id = $1 AND ub.blocked_id = u.id) OR (ub.blocker_id = u.id AND ub.blocked_id = $1))
+	`, userID, contactIDs).Scan(&eligible)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set safety timer"})
+		return
+	}
+	if eligible != len(contactIDs) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "contacts must be people you vouch with or have a DM with"})
+		return
+	}
+
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set safety timer"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var timerID string
+	var lon, lat, locatedAt interface{}
+	if req.Latitude != nil {
+		lon, lat, locatedAt = *req.Longitude, *req.Latitude, time.Now().UTC()
+	}
+	err = tx.QueryRow(ctx, `
+		INSERT INTO safety_timers (user_id, deadline, last_location, last_location_at)
+		VALUES ($1, $2, ST_SetSRID(ST_MakePoint($3, $4), 4326)::geography, $5)
+		ON CONFLICT (user_id) DO NOTHING
+		RETURNING id
+	`, userID, deadline, lon, lat, locatedAt).Scan(&timerID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusConflict, gin.H{"error": "you already have a safety timer; extend or cancel it"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set safety timer"})
+		return
+	}
+	for _, contact := range req.Contacts {
+		_, err = tx.Exec(ctx, `
+			INSERT INTO safety_timer_contacts (timer_id, contact_id, encrypted_note) VALUES ($1, $2, $3)
+		`, timerID, contact.UserID, contact.EncryptedNote)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set safety timer"})
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set safety timer"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": timerID, "deadline": deadline, "status": "active"})
+}
+
+// ExtendTimerRequest is the request body for extending a safety timer
+type ExtendTimerRequest struct {
+	Deadline int64 `json:"deadline" binding:"required"` // Unix timestamp
+}
+
+// ExtendTimer moves the current user's safety timer to a later
+// deadline. A timer that already went off can't be extended; the user
+// checks in instead.
+// POST /me/safety-timer/extend
+func (h *Handler) ExtendTimer(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ctx := c.Request.Context()
+
+	var req ExtendTimerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	deadline := time.Unix(req.Deadline, 0).UTC()
+	if err := checkDeadline(deadline, time.Now()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to extend safety timer"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var status string
+	var current time.Time
+	err = tx.QueryRow(ctx, "SELECT status, deadline FROM safety_timers WHERE user_id = $1 FOR UPDATE", userID).
+		Scan(&status, &current)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no safety timer set"})
+		return
+	}
+	if status != "active" {
+		c.JSON(http.StatusConflict, gin.H{"error": "safety timer already went off; check in instead"})
+		return
+	}
+	if !deadline.After(current) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "new deadline must be later than the current one"})
+		return
+	}
+
+	if _, err := tx.Exec(ctx, "UPDATE safety_timers SET deadline = $2 WHERE user_id = $1", userID, deadline); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to extend safety timer"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to extend safety timer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deadline": deadline, "status": status})
+}
+
+// TimerLocationRequest is the request body for sharing a location
+// with a safety timer
+type TimerLocationRequest struct {
+	Latitude  *float64 `json:"latitude" binding:"required,min=-90,max=90"`
+	Longitude *float64 `json:"longitude" binding:"required,min=-180,max=180"`
+}
+
+// SetTimerLocation shares the current user's location with their
+// active safety timer, replacing any shared before. Contacts only see
+// it if the timer goes off.
+// PUT /me/safety-timer/location
+func (h *Handler) SetTimerLocation(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req TimerLocationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tag, err := h.db.Pool().Exec(c.Request.Context(), `
+		UPDATE safety_timers
+		SET last_location = ST_SetSRID(ST_MakePoint($2, $3), 4326)::geography, last_location_at = NOW()
+		WHERE user_id = $1 AND status = 'active'
+	`, userID, *req.Longitude, *req.Latitude)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to share location"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no active safety timer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "location shared"})
+}
+
+// ClearTimerLocation stops sharing a location with the current user's
+// active safety timer.
+// DELETE /me/safety-timer/location
+func (h *Handler) ClearTimerLocation(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	tag, err := h.db.Pool().Exec(c.Request.Context(), `
+		UPDATE safety_timers SET last_location = NULL, last_location_at = NULL
+		WHERE user_id = $1 AND status = 'active'
+	`, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to clear location"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no active safety timer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "location cleared"})
+}
+
+// CancelTimer checks the current user in, deleting their safety timer.
+// If it already went off, their contacts are told they're safe.
+// DELETE /me/safety-timer
+func (h *Handler) CancelTimer(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ctx := c.Request.Context()
+
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel safety timer"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	var timerID, status string
+	var displayName *string
+	err = tx.QueryRow(ctx, `
+		SELECT t.id, t.status, u.display_name
+		FROM safety_timers t JOIN users u ON u.id = t.user_id
+		WHERE t.user_id = $1
+		FOR UPDATE OF t
+	`, userID).Scan(&timerID, &status, &displayName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no safety timer set"})
+		return
+	}
+
+	var contactIDs []string
+	var posted []postedMessage
+	name := contactName(displayName)
+	if status == "fired" {
+		contacts, err := timerContacts(ctx, tx, timerID, userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel safety timer"})
+			return
+		}
+		for _, contact := range contacts {
+			m, err := postDM(ctx, tx, userID, contact.userID, []byte(safeMessage(name)), "system")
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel safety timer"})
+				return
+			}
+			contactIDs = append(contactIDs, contact.userID)
+			posted = append(posted, m)
+		}
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM safety_timers WHERE id = $1", timerID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel safety timer"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to cancel safety timer"})
+		return
+	}
+
+	if len(contactIDs) > 0 {
+		h.broadcastMessages(userID, posted)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			notify(ctx, h.push, contactIDs, timerID, userID, "✅ Checked in safe", safeMessage(name), nil)
+		}()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "checked in"})
+}
+
+// broadcastMessages sends messages posted by senderID to their DMs'
+// connected members.
+func (h *Handler) broadcastMessages(senderID string, posted []postedMessage) {
+	if h.hub == nil {
+		return
+	}
+	for _, m := range posted {
+		data, err := json.Marshal(map[string]interface{}{
+			"id":           m.id,
+			"channel_id":   m.channelID,
+			"sender_id":    senderID,
+			"ciphertext":   m.ciphertext,
+			"message_type": m.messageType,
+			"created_at":   m.createdAt,
+		})
+		if err != nil {
+			log.Printf("safety: marshal message %s: %v", m.id, err)
+			continue
+		}
+		h.hub.BroadcastToChannel(m.channelID, &websocket.Message{
+			Type:      websocket.TypeMessageNew,
+			ChannelID: m.channelID,
+			UserID:    senderID,
+			Payload:   data,
+			Timestamp: time.Now().UTC(),
+		})
+	}
+}
@@ -0,0 +1,284 @@
+// Package safety implements personal safety timers: check-in deadlines
+// that alert the user's chosen contacts if they lapse.
+package safety
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/kuurier/server/internal/push"
+	"github.com/kuurier/server/internal/storage"
+)
+
+const (
+	// minTimerLead and maxTimerHorizon bound how far ahead a deadline
+	// can be set: far enough that the worker fires it on time, near
+	// enough to be about a specific action.
+	minTimerLead    = time.Minute
+	maxTimerHorizon = 48 * time.Hour
+
+	// maxContacts caps how many people one timer alerts.
+	maxContacts = 10
+
+	// firedRetention is how long a fired timer waits for its user to
+	// check in before it is deleted.
+	firedRetention = 7 * 24 * time.Hour
+)
+
+// sharedLocation is the last location a user chose to share with
+// their timer.
+type sharedLocation struct {
+	lat, lon float64
+	at       time.Time
+}
+
+// checkDeadline validates a timer deadline set at now.
+func checkDeadline(deadline, now time.Time) error {
+	if deadline.Before(now.Add(minTimerLead)) {
+		return errors.New("deadline must be at least a minute from now")
+	}
+	if deadline.After(now.Add(maxTimerHorizon)) {
+		return errors.New("deadline must be within 48 hours")
+	}
+	return nil
+}
+
+// formatTime renders t for a message to contacts, e.g.
+// "Fri 1 May 21:00 UTC". Contacts' clients don't get a timezone, so
+// it is always UTC.
+func formatTime(t time.Time) string {
+	return t.UTC().Format("Mon 2 Jan 15:04 MST")
+}
+
+// lapsedMessage is the system message contacts get in their DM with
+// name when name's timer for deadline lapses. It never carries the
+// location: messages are kept, and only ciphertext belongs in them.
+func lapsedMessage(name string, deadline time.Time) string {
+	return fmt.Sprintf("Missed safety check-in: %s set a safety timer for %s and hasn't checked in.", name, formatTime(deadline))
+}
+
+// locationData is loc as push notification data for contacts' clients
+// to show, or nil if no location was shared.
+func locationData(loc *sharedLocation) map[string]string {
+	if loc == nil {
+		return nil
+	}
+	return map[string]string{
+		"latitude":    strconv.FormatFloat(loc.lat, 'f', 6, 64),
+		"longitude":   strconv.FormatFloat(loc.lon, 'f', 6, 64),
+		"location_at": loc.at.UTC().Format(time.RFC3339),
+	}
+}
+
+// safeMessage is the system message contacts get once name checks in
+// after their timer lapsed.
+func safeMessage(name string) string {
+	return name + " has checked in and is safe."
+}
+
+// contactName is how contacts are told who a timer is for.
+func contactName(displayName *string) string {
+	if displayName != nil && *displayName != "" {
+		return *displayName
+	}
+	return "Your contact"
+}
+
+// postedMessage is a DM message posted for a timer.
+type postedMessage struct {
+	id, channelID string
+	createdAt     time.Time
+	ciphertext    []byte
+	messageType   string
+}
+
+// postDM posts a message from senderID in their DM with contactID,
+// creating the DM if needed. System messages are server-generated
+// notices, stored as plain UTF-8 rather than ciphertext.
+func postDM(ctx context.Context, tx pgx.Tx, senderID, contactID string, ciphertext []byte, messageType string) (postedMessage, error) {
+	m := postedMessage{id: uuid.New().String(), ciphertext: ciphertext, messageType: messageType}
+	if err := tx.QueryRow(ctx, `SELECT get_or_create_dm_channel($1, $2)`, senderID, contactID).Scan(&m.channelID); err != nil {
+		return m, err
+	}
+	if err := tx.QueryRow(ctx, `
+		INSERT INTO messages (id, channel_id, sender_id, ciphertext, message_type, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING created_at
+	`, m.id, m.channelID, senderID, ciphertext, messageType).Scan(&m.createdAt); err != nil {
+		return m, err
+	}
+	_, err := tx.Exec(ctx, `UPDATE channels SET updated_at = NOW() WHERE id = $1`, m.channelID)
+	return m, err
+}
+
+// timerContact is someone a timer alerts, with the note for them.
+type timerContact struct {
+	userID string
+	note   []byte
+}
+
+// timerContacts returns timerID's contacts, other than any who blocked
+// or were blocked by userID since the timer was set.
+func timerContacts(ctx context.Context, tx pgx.Tx, timerID, userID string) ([]timerContact, error) {
+	rows, err := tx.Query(ctx, `
+		SELECT c.contact_id, c.encrypted_note
+		FROM safety_timer_contacts c
+		WHERE c.timer_id = $1
+		  AND NOT EXISTS(SELECT 1 FROM user_blocks ub
+		                 WHERE (ub.blocker_id = $2 AND ub.blocked_id = c.contact_id)
+		                    OR (ub.blocker_id = c.contact_id AND ub.blocked_id = $2))
+	`, timerID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (timerContact, error) {
+		var c timerContact
+		err := row.Scan(&c.userID, &c.note)
+		return c, err
+	})
+}
+
+// notify pushes a safety timer notification to userIDs, with extra
+// data if any. It is always high priority: quiet hours shouldn't hold
+// back news about someone's safety.
+func notify(ctx context.Context, pushService *push.Service, userIDs []string, timerID, userID, title, body string, extra map[string]string) {
+	if pushService == nil || len(userIDs) == 0 {
+		return
+	}
+	data := map[string]string{
+		"type":     "safety_timer",
+		"timer_id": timerID,
+		"user_id":  userID,
+	}
+	for k, v := range extra {
+		data[k] = v
+	}
+	pushService.SendToUsers(ctx, userIDs, push.Notification{
+		Title:    title,
+		Body:     body,
+		Priority: "high",
+		Category: "SAFETY",
+		ThreadID: "safety-" + timerID,
+		Data:     data,
+	})
+}
+
+// Watcher is the worker job that fires lapsed safety timers, and
+// deletes fired ones whose user never checked in.
+type Watcher struct {
+	db   *storage.Postgres
+	push *push.Service
+}
+
+// NewWatcher returns a Watcher.
+func NewWatcher(db *storage.Postgres, pushService *push.Service) *Watcher {
+	return &Watcher{db: db, push: pushService}
+}
+
+// RunOnce fires every lapsed timer and sweeps stale fired ones.
+func (j *Watcher) RunOnce(ctx context.Context) error {
+	rows, err := j.db.Pool().Query(ctx, `
+		SELECT id FROM safety_timers WHERE status = 'active' AND deadline <= NOW()
+	`)
+	if err != nil {
+		return fmt.Errorf("list lapsed safety timers: %w", err)
+	}
+	due, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return fmt.Errorf("list lapsed safety timers: %w", err)
+	}
+	for _, timerID := range due {
+		if err := j.fire(ctx, timerID); err != nil {
+			log.Printf("safety: fire timer %s: %v", timerID, err)
+		}
+	}
+
+	tag, err := j.db.Pool().Exec(ctx, `
+		DELETE FROM safety_timers WHERE status = 'fired' AND fired_at < $1
+	`, time.Now().Add(-firedRetention))
+	if err != nil {
+		return fmt.Errorf("sweep fired safety timers: %w", err)
+	}
+	if n := tag.RowsAffected(); n > 0 {
+		log.Printf("safety: deleted %d fired timers", n)
+	}
+	return nil
+}
+
+// fire alerts timerID's contacts: a system message in each of their
+// DMs with its user, followed by the note the user left them, and a
+// push with the last location the user shared. The messages are posted
+// in the same transaction that marks the timer fired, so contacts are
+// told exactly once.
+func (j *Watcher) fire(ctx context.Context, timerID string) error {
+	tx, err := j.db.Pool().Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// The user may have extended or cancelled it since it was listed
+	var userID string
+	var displayName *string
+	var deadline time.Time
+	var lat, lon *float64
+	var locatedAt *time.Time
+	err = tx.QueryRow(ctx, `
+		UPDATE safety_timers t SET status = 'fired', fired_at = NOW()
+		FROM users u
+		WHERE t.id = $1 AND t.status = 'active' AND t.deadline <= NOW() AND u.id = t.user_id
+		RETURNING t.user_id, u.display_name, t.deadline,
+		          ST_Y(t.last_location::geometry), ST_X(t.last_location::geometry), t.last_location_at
+	`, timerID).Scan(&userID, &displayName, &deadline, &lat, &lon, &locatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var loc *sharedLocation
+	if lat != nil && lon != nil && locatedAt != nil {
+		loc = &sharedLocation{lat: *lat, lon: *lon, at: *locatedAt}
+	}
+
+	contacts, err := timerContacts(ctx, tx, timerID, userID)
+	if err != nil {
+		return err
+	}
+	name := contactName(displayName)
+	body := []byte(lapsedMessage(name, deadline))
+	contactIDs := make([]string, len(contacts))
+	for i, c := range contacts {
+		contactIDs[i] = c.userID
+		if _, err := postDM(ctx, tx, userID, c.userID, body, "system"); err != nil {
+			return err
+		}
+		if len(c.note) > 0 {
+			if _, err := postDM(ctx, tx, userID, c.userID, c.note, "text"); err != nil {
+				return err
+			}
+		}
+	}
+	// Delivered; the notes live in the DMs now
+	if _, err := tx.Exec(ctx, `UPDATE safety_timer_contacts SET encrypted_note = NULL WHERE timer_id = $1`, timerID); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return err
+	}
+
+	alert := name + " hasn't checked in by their safety timer. Check your messages."
+	if loc != nil {
+		alert = name + " hasn't checked in by their safety timer. Open to see the last location they shared."
+	}
+	notify(ctx, j.push, contactIDs, timerID, userID, "⏰ Missed safety check-in", alert, locationData(loc))
+	notify(ctx, j.push, []string{userID}, timerID, userID, "Your safety timer went off",
+		"Your contacts have been alerted. Check in to let them know you're safe.", nil)
+	return nil
+}
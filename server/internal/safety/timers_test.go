@@ -0,0 +1,48 @@
+package safety
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckDeadline(t *testing.T) {
+	now := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, checkDeadline(now.Add(3*time.Hour), now))
+	assert.NoError(t, checkDeadline(now.Add(maxTimerHorizon), now))
+	assert.Error(t, checkDeadline(now, now), "too soon to fire on time")
+	assert.Error(t, checkDeadline(now.Add(-time.Hour), now))
+	assert.Error(t, checkDeadline(now.Add(maxTimerHorizon+time.Minute), now))
+}
+
+func TestLapsedMessage(t *testing.T) {
+	deadline := time.Date(2026, 5, 1, 21, 0, 0, 0, time.UTC)
+
+	assert.Equal(t,
+		"Missed safety check-in: Sam set a safety timer for Fri 1 May 21:00 UTC and hasn't checked in.",
+		lapsedMessage("Sam", deadline))
+
+	// Deadlines set in another zone are still told in UTC
+	inBerlin := deadline.In(time.FixedZone("CEST", 2*60*60))
+	assert.Contains(t, lapsedMessage("Sam", inBerlin), "21:00 UTC")
+}
+
+func TestLocationData(t *testing.T) {
+	assert.Nil(t, locationData(nil))
+
+	at := time.Date(2026, 5, 1, 22, 20, 0, 0, time.FixedZone("CEST", 2*60*60))
+	assert.Equal(t, map[string]string{
+		"latitude":    "52.370200",
+		"longitude":   "4.895100",
+		"location_at": "2026-05-01T20:20:00Z",
+	}, locationData(&sharedLocation{lat: 52.3702, lon: 4.8951, at: at}))
+}
+
+func TestContactName(t *testing.T) {
+	name, empty := "Sam", ""
+	assert.Equal(t, "Sam", contactName(&name))
+	assert.Equal(t, "Your contact", contactName(&empty))
+	assert.Equal(t, "Your contact", contactName(nil))
+}
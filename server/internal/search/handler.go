@@ -0,0 +1,498 @@
+// Package search implements full-text search over posts, events,
+// public organizations and topics.
+//
+// Text matching uses the search_vector columns from migration 018.
+// Each row is stemmed in its own language; a query is stemmed in the
+// searcher's language (?lang=) and OR'd with an unstemmed copy, so
+// exact words match across languages.
+//
+// Search never shows more than the regular read paths would: flagged
+// and expired posts are excluded, only public organizations are
+// listed, and an event's exact location is only returned (or used for
+// the bounding-box filter) when GET /events/:id would reveal it.
+package search
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kuurier/server/internal/api/types"
+	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/feed"
+	"github.com/kuurier/server/internal/storage"
+)
+
+const (
+	minQueryLength = 2
+	maxQueryLength = 200
+
+	// maxPostCandidates bounds how many text matches are re-ranked
+	// with confidenceScore, and so how deep post results can page.
+	maxPostCandidates = 200
+
+	// Post score weights: text relevance first, credibility second.
+	textWeight       = 0.7
+	confidenceWeight = 0.3
+)
+
+// Result types, as accepted by ?types=.
+const (
+	typePosts         = "posts"
+	typeEvents        = "events"
+	typeOrganizations = "organizations"
+	typeTopics        = "topics"
+)
+
+var allTypes = []string{typePosts, typeEvents, typeOrganizations, typeTopics}
+
+// tsQuery is the query both stemmed in the searcher's language ($2)
+// and unstemmed, over the raw search string ($1). Every statement
+// below passes those two arguments first.
+const tsQuery = `(websearch_to_tsquery(text_search_config($2), $1) || websearch_to_tsquery('simple', $1))`
+
+// headlineOptions keeps excerpts short enough for a result row.
+const headlineOptions = `'StartSel=<b>, StopSel=</b>, MaxWords=25, MinWords=8, MaxFragments=2'`
+
+// Handler handles search endpoints
+type Handler struct {
+	cfg *config.Config
+	db  *storage.Postgres
+}
+
+// NewHandler creates a new search handler
+func NewHandler(cfg *config.Config, db *storage.Postgres) *Handler {
+	return &Handler{cfg: cfg, db: db}
+}
+
+// searchParams is a parsed GET /search request.
+type searchParams struct {
+	query      string
+	lang       string
+	types      map[string]bool
+	topicID    string
+	sourceType string
+	from, to   *time.Time
+	bbox       *boundingBox
+	limit      int
+	offset     int
+}
+
+type boundingBox struct {
+	minLat, maxLat, minLon, maxLon float64
+}
+
+// Filters that rule out result types without the matching field.
+func (p searchParams) hasLocationFilter() bool { return p.bbox != nil }
+func (p searchParams) hasTopicFilter() bool    { return p.topicID != "" }
+func (p searchParams) hasDateFilter() bool     { return p.from != nil || p.to != nil }
+
+func parseSearchParams(c *gin.Context) (searchParams, error) {
+	p := searchParams{
+		query:      strings.TrimSpace(c.Query("q")),
+		lang:       strings.ToLower(c.Query("lang")),
+		topicID:    c.Query("topic_id"),
+		sourceType: c.Query("source_type"),
+		types:      map[string]bool{},
+	}
+
+	n := utf8.RuneCountInString(p.query)
+	if n < minQueryLength {
+		return p, errors.New("search query must be at least 2 characters")
+	}
+	if n > maxQueryLength {
+		return p, errors.New("search query is too long")
+	}
+
+	if raw := c.Query("types"); raw != "" {
+		for _, t := range strings.Split(raw, ",") {
+			t = strings.TrimSpace(t)
+			switch t {
+			case typePosts, typeEvents, typeOrganizations, typeTopics:
+				p.types[t] = true
+			default:
+				return p, errors.New("unknown result type: " + t)
+			}
+		}
+	} else {
+		for _, t := range allTypes {
+			p.types[t] = true
+		}
+	}
+
+	switch p.sourceType {
+	case "", "firsthand", "aggregated", "mainstream":
+	default:
+		return p, errors.New("invalid source_type")
+	}
+
+	for _, f := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &p.from}, {"to", &p.to}} {
+		if raw := c.Query(f.name); raw != "" {
+			ts, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil {
+				return p, errors.New(f.name + " must be a unix timestamp")
+			}
+			t := time.Unix(ts, 0)
+			*f.dst = &t
+		}
+	}
+	if p.from != nil && p.to != nil && p.to.Before(*p.from) {
+		return p, errors.New("to must not be before from")
+	}
+
+	bboxKeys := []string{"min_lat", "max_lat", "min_lon", "max_lon"}
+	var bbox [4]float64
+	given := 0
+	for i, key := range bboxKeys {
+		if raw := c.Query(key); raw != "" {
+			v, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return p, errors.New(key + " must be a number")
+			}
+			bbox[i] = v
+			given++
+		}
+	}
+	switch given {
+	case 0:
+	case len(bboxKeys):
+		b := boundingBox{minLat: bbox[0], maxLat: bbox[1], minLon: bbox[2], maxLon: bbox[3]}
+		if b.minLat < -90 || b.maxLat > 90 || b.minLat > b.maxLat ||
+			b.minLon < -180 || b.maxLon > 180 || b.minLon > b.maxLon {
+			return p, errors.New("invalid bounding box")
+		}
+		p.bbox = &b
+	default:
+		return p, errors.New("bounding box needs min_lat, max_lat, min_lon and max_lon")
+	}
+
+	p.limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+	p.offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if p.limit < 1 {
+		p.limit = 1
+	}
+	if p.limit > 50 {
+		p.limit = 50
+	}
+	if p.offset < 0 {
+		p.offset = 0
+	}
+	return p, nil
+}
+
+// Search runs a full-text search.
+// GET /search?q=<query>&types=posts,events&lang=de
+//
+// Optional filters: topic_id, source_type, from/to (unix seconds) and
+// a bounding box (min_lat, max_lat, min_lon, max_lon). A filter a result type can't satisfy leaves that type empty: only
+// posts have a source_type, organizations and topics have no location,
+// and so on.
+func (h *Handler) Search(c *gin.Context) {
+	params, err := parseSearchParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ctx := c.Request.Context()
+	userID := c.GetString("user_id")
+
+	resp := types.SearchResponse{
+		Query:         params.query,
+		Posts:         []types.SearchPost{},
+		Events:        []types.SearchEvent{},
+		Organizations: []types.SearchOrganization{},
+		Topics:        []types.SearchTopic{},
+		Limit:         params.limit,
+		Offset:        params.offset,
+	}
+
+	if params.types[typePosts] {
+		if resp.Posts, err = h.searchPosts(ctx, params); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "search failed"})
+			return
+		}
+	}
+	if params.types[typeEvents] && params.sourceType == "" {
+		if resp.Events, err = h.searchEvents(ctx, userID, params); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "search failed"})
+			return
+		}
+	}
+	if params.types[typeOrganizations] && params.sourceType == "" &&
+		!params.hasTopicFilter() && !params.hasLocationFilter() {
+		if resp.Organizations, err = h.searchOrganizations(ctx, params); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "search failed"})
+			return
+		}
+	}
+	if params.types[typeTopics] && params.sourceType == "" &&
+		!params.hasTopicFilter() && !params.hasLocationFilter() && !params.hasDateFilter() {
+		if resp.Topics, err = h.searchTopics(ctx, params); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "search failed"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// filterBuilder appends numbered placeholders after the two tsQuery
+// arguments.
+type filterBuilder struct {
+	where strings.Builder
+	args  []interface{}
+}
+
+func newFilterBuilder(p searchParams) *filterBuilder {
+	return &filterBuilder{args: []interface{}{p.query, p.lang}}
+}
+
+// add appends a condition in which every "?" is the same new argument.
+func (f *filterBuilder) add(cond string, arg interface{}) {
+	f.args = append(f.args, arg)
+	f.where.WriteString(" AND " + strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(f.args))))
+}
+
+// addBBox appends an envelope test on column, which must be geography.
+func (f *filterBuilder) addBBox(column string, b *boundingBox) {
+	n := len(f.args)
+	f.args = append(f.args, b.minLon, b.minLat, b.maxLon, b.maxLat)
+	f.where.WriteString(" AND " + column + " && ST_MakeEnvelope(" +
+		"$" + strconv.Itoa(n+1) + ", $" + strconv.Itoa(n+2) + ", $" + strconv.Itoa(n+3) + ", $" + strconv.Itoa(n+4) +
+		", 4326)::geography")
+}
+
+// next returns the placeholder for one more argument.
+func (f *filterBuilder) next(arg interface{}) string {
+	f.args = append(f.args, arg)
+	return "$" + strconv.Itoa(len(f.args))
+}
+
+type postHit struct {
+	post              types.SearchPost
+	textRank          float64
+	verificationScore int
+	authorTrustScore  int
+	corroborations    int
+}
+
+func (h *Handler) searchPosts(ctx context.Context, p searchParams) ([]types.SearchPost, error) {
+	f := newFilterBuilder(p)
+	if p.topicID != "" {
+		f.add("EXISTS (SELECT 1 FROM post_topics pt JOIN topic_lineage tl ON tl.topic_id = pt.topic_id WHERE pt.post_id = p.id AND tl.ancestor_id = ?)", p.topicID)
+	}
+	if p.sourceType != "" {
+		f.add("p.source_type = ?", p.sourceType)
+	}
+	if p.from != nil {
+		f.add("p.created_at >= ?", *p.from)
+	}
+	if p.to != nil {
+		f.add("p.created_at <= ?", *p.to)
+	}
+	if p.bbox != nil {
+		f.addBBox("p.location", p.bbox)
+	}
+
+	// Fetch the best text matches, then re-rank in Go with the feed's
+	// confidence score (same approach as the live feed path).
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT p.id, p.author_id, p.content, p.source_type,
+		       ST_Y(p.location::geometry) AS lat,
+		       ST_X(p.location::geometry) AS lon,
+		       p.location_name, p.urgency, p.created_at, p.verification_score,
+		       COALESCE(u.trust_score, 0) AS trust_score,
+		       (SELECT COUNT(DISTINCT r.author_id) FROM post_replies r
+		        WHERE r.post_id = p.id AND r.corroborates AND NOT r.is_flagged) AS corroborations,
+		       ts_rank_cd(p.search_vector, `+tsQuery+`, 32) AS rank,
+		       ts_headline(text_search_config(p.language), p.content, `+tsQuery+`, `+headlineOptions+`)
+		FROM posts p
+		LEFT JOIN users u ON u.id = p.author_id
+		WHERE p.search_vector @@ `+tsQuery+`
+		  AND p.is_flagged = false
+		  AND (p.expires_at IS NULL OR p.expires_at > NOW())`+f.where.String()+`
+		ORDER BY rank DESC, p.created_at DESC
+		LIMIT `+f.next(maxPostCandidates),
+		f.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var hits []postHit
+	for rows.Next() {
+		var hit postHit
+		var lat, lon *float64
+		if err := rows.Scan(&hit.post.ID, &hit.post.AuthorID, &hit.post.Content, &hit.post.SourceType,
+			&lat, &lon, &hit.post.LocationName, &hit.post.Urgency, &hit.post.CreatedAt,
+			&hit.verificationScore, &hit.authorTrustScore, &hit.corroborations, &hit.textRank, &hit.post.Headline); err != nil {
+			return nil, err
+		}
+		if lat != nil && lon != nil {
+			hit.post.Location = &types.LatLng{Latitude: *lat, Longitude: *lon}
+		}
+		hits = append(hits, hit)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return rankPosts(hits, p.offset, p.limit), nil
+}
+
+// rankPosts scores hits by text relevance and confidence, best first,
+// and returns the requested page.
+func rankPosts(hits []postHit, offset, limit int) []types.SearchPost {
+	for i := range hits {
+		hit := &hits[i]
+		hit.post.Score = textWeight*hit.textRank +
+			confidenceWeight*feed.ConfidenceScore(hit.authorTrustScore, hit.verificationScore, hit.corroborations, hit.post.SourceType)
+	}
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].post.Score > hits[j].post.Score })
+
+	posts := []types.SearchPost{}
+	for i := offset; i < len(hits) && i < offset+limit; i++ {
+		posts = append(posts, hits[i].post)
+	}
+	return posts
+}
+
+func (h *Handler) searchEvents(ctx context.Context, userID string, p searchParams) ([]types.SearchEvent, error) {
+	f := newFilterBuilder(p)
+	user := f.next(userID)
+
+	// Mirrors events.shouldRevealLocation, with everyone who runs the
+	// event seeing it as the organizer does.
+	revealed := `(e.location_visibility = 'public'
+		OR e.organizer_id = ` + user + `
+		OR EXISTS (SELECT 1 FROM event_co_organizers co WHERE co.event_id = e.id AND co.user_id = ` + user + ` AND co.status = 'accepted')
+		OR EXISTS (SELECT 1 FROM organization_members m WHERE m.org_id = e.org_id AND m.user_id = ` + user + ` AND m.role IN ('admin', 'moderator'))
+		OR (e.location_visibility = 'timed' AND e.location_reveal_at <= NOW())
+		OR EXISTS (SELECT 1 FROM event_rsvps r WHERE r.event_id = e.id AND r.user_id = ` + user + ` AND r.approval = 'approved'))`
+
+	if p.topicID != "" {
+		f.add("EXISTS (SELECT 1 FROM event_topics et JOIN topic_lineage tl ON tl.topic_id = et.topic_id WHERE et.event_id = e.id AND tl.ancestor_id = ?)", p.topicID)
+	}
+	if p.from != nil {
+		f.add("e.starts_at >= ?", *p.from)
+	}
+	if p.to != nil {
+		f.add("e.starts_at <= ?", *p.to)
+	}
+	if p.bbox != nil {
+		// Filtering on a hidden location would leak it one bounding
+		// box at a time, so only revealed events can match.
+		f.where.WriteString(" AND " + revealed)
+		f.addBBox("e.location", p.bbox)
+	}
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT e.id, e.title, e.event_type, e.starts_at, e.ends_at,
+		       e.location_visibility, `+revealed+` AS revealed,
+		       ST_Y(e.location::geometry) AS lat,
+		       ST_X(e.location::geometry) AS lon,
+		       e.location_name, e.location_area,
+		       ts_rank_cd(e.search_vector, `+tsQuery+`, 32) AS rank,
+		       ts_headline(text_search_config(e.language), e.title || ' ' || COALESCE(e.description, ''),
+		                   `+tsQuery+`, `+headlineOptions+`)
+		FROM events e
+		WHERE e.search_vector @@ `+tsQuery+`
+		  AND e.is_cancelled = false`+f.where.String()+`
+		ORDER BY rank DESC, e.starts_at DESC
+		LIMIT `+f.next(p.limit)+` OFFSET `+f.next(p.offset),
+		f.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []types.SearchEvent{}
+	for rows.Next() {
+		var e types.SearchEvent
+		var lat, lon float64
+		var locationName, locationArea *string
+		if err := rows.Scan(&e.ID, &e.Title, &e.EventType, &e.StartsAt, &e.EndsAt,
+			&e.LocationVisibility, &e.LocationRevealed, &lat, &lon,
+			&locationName, &locationArea, &e.Score, &e.Headline); err != nil {
+			return nil, err
+		}
+		if e.LocationRevealed {
+			e.Location = &types.LatLng{Latitude: lat, Longitude: lon}
+			e.LocationName = locationName
+		} else {
+			e.LocationArea = locationArea
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+func (h *Handler) searchOrganizations(ctx context.Context, p searchParams) ([]types.SearchOrganization, error) {
+	f := newFilterBuilder(p)
+	if p.from != nil {
+		f.add("o.created_at >= ?", *p.from)
+	}
+	if p.to != nil {
+		f.add("o.created_at <= ?", *p.to)
+	}
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT o.id, o.name, o.description, o.avatar_url,
+		       (SELECT COUNT(*) FROM organization_members WHERE org_id = o.id) AS member_count,
+		       ts_rank_cd(o.search_vector, `+tsQuery+`, 32) AS rank
+		FROM organizations o
+		WHERE o.is_public = true
+		  AND o.search_vector @@ `+tsQuery+f.where.String()+`
+		ORDER BY rank DESC, member_count DESC
+		LIMIT `+f.next(p.limit)+` OFFSET `+f.next(p.offset),
+		f.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []types.SearchOrganization{}
+	for rows.Next() {
+		var o types.SearchOrganization
+		if err := rows.Scan(&o.ID, &o.Name, &o.Description, &o.AvatarURL, &o.MemberCount, &o.Score); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+func (h *Handler) searchTopics(ctx context.Context, p searchParams) ([]types.SearchTopic, error) {
+	// A handful of rows; no index needed.
+	f := newFilterBuilder(p)
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT id, slug, name, icon, ts_rank_cd(v, `+tsQuery+`, 32) AS rank
+		FROM topics, to_tsvector('simple', name || ' ' || replace(slug, '-', ' ')) AS v
+		WHERE v @@ `+tsQuery+` AND archived_at IS NULL
+		ORDER BY rank DESC, name
+		LIMIT `+f.next(p.limit)+` OFFSET `+f.next(p.offset),
+		f.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	topics := []types.SearchTopic{}
+	for rows.Next() {
+		var t types.SearchTopic
+		if err := rows.Scan(&t.ID, &t.Slug, &t.Name, &t.Icon, &t.Score); err != nil {
+			return nil, err
+		}
+		topics = append(topics, t)
+	}
+	return topics, rows.Err()
+}
@@ -0,0 +1,93 @@
+package search
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kuurier/server/internal/api/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func parse(t *testing.T, rawQuery string) (searchParams, error) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/search?"+rawQuery, nil)
+	return parseSearchParams(c)
+}
+
+func TestParseSearchParams_Defaults(t *testing.T) {
+	p, err := parse(t, "q=eviction+defense")
+	require.NoError(t, err)
+
+	assert.Equal(t, "eviction defense", p.query)
+	assert.Equal(t, 20, p.limit)
+	assert.Equal(t, 0, p.offset)
+	for _, typ := range allTypes {
+		assert.True(t, p.types[typ], "%s should be searched by default", typ)
+	}
+	assert.Nil(t, p.bbox)
+	assert.False(t, p.hasDateFilter())
+}
+
+func TestParseSearchParams_Filters(t *testing.T) {
+	p, err := parse(t, "q=strike&types=posts,events&lang=DE&source_type=firsthand"+
+		"&from=1700000000&to=1700086400&min_lat=52.3&max_lat=52.4&min_lon=4.8&max_lon=5.0&limit=500")
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]bool{"posts": true, "events": true}, p.types)
+	assert.Equal(t, "de", p.lang)
+	assert.Equal(t, "firsthand", p.sourceType)
+	require.NotNil(t, p.from)
+	require.NotNil(t, p.to)
+	assert.Equal(t, int64(1700086400), p.to.Unix())
+	assert.Equal(t, &boundingBox{minLat: 52.3, maxLat: 52.4, minLon: 4.8, maxLon: 5.0}, p.bbox)
+	assert.Equal(t, 50, p.limit, "limit is capped")
+}
+
+func TestParseSearchParams_Rejects(t *testing.T) {
+	for name, q := range map[string]string{
+		"missing query":      "",
+		"short query":        "q=a",
+		"unknown type":       "q=abc&types=posts,users",
+		"bad source_type":    "q=abc&source_type=rumour",
+		"bad timestamp":      "q=abc&from=yesterday",
+		"inverted range":     "q=abc&from=200&to=100",
+		"partial bbox":       "q=abc&min_lat=1&max_lat=2",
+		"inverted bbox":      "q=abc&min_lat=2&max_lat=1&min_lon=0&max_lon=1",
+		"out of range bbox":  "q=abc&min_lat=-91&max_lat=1&min_lon=0&max_lon=1",
+		"non-numeric bounds": "q=abc&min_lat=x&max_lat=1&min_lon=0&max_lon=1",
+	} {
+		_, err := parse(t, q)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestRankPosts_CombinesTextAndConfidence(t *testing.T) {
+	hits := []postHit{
+		// Slightly better text match from an unknown source with negative verification.
+		{post: types.SearchPost{ID: "weak", SourceType: "aggregated"}, textRank: 0.55, verificationScore: -5},
+		// Slightly worse text match from a trusted firsthand reporter.
+		{post: types.SearchPost{ID: "trusted", SourceType: "firsthand"}, textRank: 0.5, authorTrustScore: 100, verificationScore: 10},
+		{post: types.SearchPost{ID: "unrelated", SourceType: "mainstream"}, textRank: 0.01},
+	}
+
+	posts := rankPosts(hits, 0, 10)
+	require.Len(t, posts, 3)
+	assert.Equal(t, "trusted", posts[0].ID)
+	assert.Equal(t, "weak", posts[1].ID)
+	assert.Greater(t, posts[0].Score, posts[1].Score)
+
+	page := rankPosts(hits, 1, 1)
+	require.Len(t, page, 1)
+	assert.Equal(t, "weak", page[0].ID)
+
+	assert.Empty(t, rankPosts(hits, 5, 10))
+}
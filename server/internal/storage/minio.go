@@ -17,8 +17,20 @@ type MinIO struct {
 	publicURL  string
 }
 
-// NewMinIO creates a new MinIO client
+// NewMinIO creates a new MinIO client for a publicly readable bucket,
+// creating it if needed
 func NewMinIO(endpoint, accessKey, secretKey, bucketName string, useSSL bool) (*MinIO, error) {
+	return newMinIO(endpoint, accessKey, secretKey, bucketName, useSSL, true)
+}
+
+// NewPrivateMinIO creates a new MinIO client for a bucket that must not
+// be readable without credentials, creating it without a policy if
+// needed. An existing bucket with an access policy is refused.
+func NewPrivateMinIO(endpoint, accessKey, secretKey, bucketName string, useSSL bool) (*MinIO, error) {
+	return newMinIO(endpoint, accessKey, secretKey, bucketName, useSSL, false)
+}
+
+func newMinIO(endpoint, accessKey, secretKey, bucketName string, useSSL, public bool) (*MinIO, error) {
 	client, err := minio.New(endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
 		Secure: useSSL,
@@ -42,6 +54,23 @@ func NewMinIO(endpoint, accessKey, secretKey, bucketName string, useSSL bool) (*
 		return nil, fmt.Errorf("failed to check bucket: %w", err)
 	}
 
+	if !public {
+		if !exists {
+			if err := client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{}); err != nil {
+				return nil, fmt.Errorf("failed to create bucket: %w", err)
+			}
+			return m, nil
+		}
+		policy, err := client.GetBucketPolicy(ctx, bucketName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check bucket policy: %w", err)
+		}
+		if policy != "" {
+			return nil, fmt.Errorf("bucket %s has an access policy; it must be private", bucketName)
+		}
+		return m, nil
+	}
+
 	if !exists {
 		err = client.MakeBucket(ctx, bucketName, minio.MakeBucketOptions{})
 		if err != nil {
@@ -82,6 +111,20 @@ func (m *MinIO) UploadFile(ctx context.Context, objectName string, reader io.Rea
 	return fmt.Sprintf("%s/%s", m.publicURL, objectName), nil
 }
 
+// GetFile opens a file in MinIO for reading, returning its size
+func (m *MinIO) GetFile(ctx context.Context, objectName string) (io.ReadCloser, int64, error) {
+	obj, err := m.client.GetObject(ctx, m.bucketName, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, 0, err
+	}
+	return obj, info.Size, nil
+}
+
 // DeleteFile deletes a file from MinIO
 func (m *MinIO) DeleteFile(ctx context.Context, objectName string) error {
 	return m.client.RemoveObject(ctx, m.bucketName, objectName, minio.RemoveObjectOptions{})
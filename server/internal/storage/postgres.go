@@ -64,6 +64,12 @@ func NewPostgres(cfg *config.Config) (*Postgres, error) {
 	return &Postgres{pool: pool, cfg: cfg}, nil
 }
 
+// NewPostgresFromPool wraps an existing connection pool, e.g. one a
+// test opened on a throwaway database.
+func NewPostgresFromPool(pool *pgxpool.Pool, cfg *config.Config) *Postgres {
+	return &Postgres{pool: pool, cfg: cfg}
+}
+
 // Pool returns the underlying connection pool
 func (p *Postgres) Pool() *pgxpool.Pool {
 	return p.pool
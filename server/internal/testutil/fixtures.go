@@ -0,0 +1,37 @@
+package testutil
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/storage"
+)
+
+// NewTestPostgres is NewTestDB wrapped for the handlers and jobs that
+// take a *storage.Postgres.
+func NewTestPostgres(t *testing.T) *storage.Postgres {
+	t.Helper()
+	return storage.NewPostgresFromPool(NewTestDB(t), &config.Config{DBAcquireTimeout: 5})
+}
+
+// CreateUser inserts a user with a random public key and the given
+// display name ("" for none), and returns its ID.
+func CreateUser(t *testing.T, pool *pgxpool.Pool, displayName string) string {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("generate public key: %v", err)
+	}
+	var id string
+	err := pool.QueryRow(context.Background(), `
+		INSERT INTO users (public_key, display_name) VALUES ($1, NULLIF($2, ''))
+		RETURNING id
+	`, key, displayName).Scan(&id)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return id
+}
@@ -0,0 +1,44 @@
+package testutil
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kuurier/server/internal/storage"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// NewTestRedis starts a Redis container and returns a client for it.
+// The container is stopped automatically via t.Cleanup.
+func NewTestRedis(t *testing.T) *storage.Redis {
+	t.Helper()
+	ctx := context.Background()
+
+	container, err := testcontainers.Run(ctx, "redis:7-alpine",
+		testcontainers.WithExposedPorts("6379/tcp"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("Ready to accept connections").
+				WithStartupTimeout(30*time.Second)),
+	)
+	if err != nil {
+		t.Fatalf("start redis container: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Logf("terminate container: %v", err)
+		}
+	})
+
+	endpoint, err := container.PortEndpoint(ctx, "6379/tcp", "redis")
+	if err != nil {
+		t.Fatalf("redis endpoint: %v", err)
+	}
+	client, err := storage.NewRedis(endpoint)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
@@ -0,0 +1,415 @@
+// Package topics serves the topic list and lets admins manage it.
+//
+// Topics form a shallow tree (see Tree): a subscription to a topic
+// matches content tagged with any topic nested under it, both in the
+// feed ranker and in SQL through the topic_lineage view. Each topic
+// can have a name per language; GET /topics?lang=de falls back to the
+// default name where there's no translation. Trusted users can also
+// propose topics, which are created once enough others endorse them
+// (see proposals.go).
+package topics
+
+import (
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/kuurier/server/internal/config"
+	"github.com/kuurier/server/internal/storage"
+)
+
+// slugPattern is lower-case words separated by single hyphens, like
+// the seeded slugs ("police-reform").
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// langPattern is an ISO 639-1 code, as accepted for posts and events.
+var langPattern = regexp.MustCompile(`^[a-z]{2}$`)
+
+// Handler handles topic endpoints
+type Handler struct {
+	cfg *config.Config
+	db  *storage.Postgres
+}
+
+// NewHandler creates a new topics handler
+func NewHandler(cfg *config.Config, db *storage.Postgres) *Handler {
+	return &Handler{cfg: cfg, db: db}
+}
+
+// TopicRequest creates or updates a topic. On update, omitted fields
+// are left unchanged; an empty parent_id moves the topic to the top
+// level.
+type TopicRequest struct {
+	Slug        *string `json:"slug" binding:"omitempty,max=50"`
+	Name        *string `json:"name" binding:"omitempty,min=2,max=100"`
+	Icon        *string `json:"icon" binding:"omitempty,max=50"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+	ParentID    *string `json:"parent_id"`
+	Archived    *bool   `json:"archived"`
+}
+
+// TopicNameRequest sets a topic's name in one language
+type TopicNameRequest struct {
+	Name string `json:"name" binding:"required,min=2,max=100"`
+}
+
+// langParam returns the ?lang= query parameter if it's a valid
+// language code, or "".
+func langParam(c *gin.Context) string {
+	lang := strings.ToLower(c.Query("lang"))
+	if !langPattern.MatchString(lang) {
+		return ""
+	}
+	return lang
+}
+
+// ListTopics returns all active topics, with names in ?lang= where a
+// translation exists
+func (h *Handler) ListTopics(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT t.id, t.slug, COALESCE(tn.name, t.name) AS name, t.icon, t.parent_id, t.description
+		FROM topics t
+		LEFT JOIN topic_names tn ON tn.topic_id = t.id AND tn.lang = $1
+		WHERE t.archived_at IS NULL
+		ORDER BY name
+	`, langParam(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch topics"})
+		return
+	}
+	defer rows.Close()
+
+	var topics []gin.H
+	for rows.Next() {
+		var id, slug, name string
+		var icon, parentID, description *string
+		if err := rows.Scan(&id, &slug, &name, &icon, &parentID, &description); err == nil {
+			topic := gin.H{"id": id, "slug": slug, "name": name}
+			if icon != nil {
+				topic["icon"] = *icon
+			}
+			if parentID != nil {
+				topic["parent_id"] = *parentID
+			}
+			if description != nil {
+				topic["description"] = *description
+			}
+			topics = append(topics, topic)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"topics": topics})
+}
+
+// GetTopic returns a topic with its children and all of its names.
+// Archived topics are returned too, so old tags still resolve.
+func (h *Handler) GetTopic(c *gin.Context) {
+	topicID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var slug, name string
+	var icon, parentID, description *string
+	var archivedAt *time.Time
+	var createdAt time.Time
+	err := h.db.Pool().QueryRow(ctx, `
+		SELECT slug, name, icon, parent_id, description, archived_at, created_at
+		FROM topics WHERE id = $1
+	`, topicID).Scan(&slug, &name, &icon, &parentID, &description, &archivedAt, &createdAt)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "topic not found"})
+		return
+	}
+
+	names := gin.H{}
+	rows, err := h.db.Pool().Query(ctx, `SELECT lang, name FROM topic_names WHERE topic_id = $1`, topicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch topic"})
+		return
+	}
+	for rows.Next() {
+		var lang, localized string
+		if err := rows.Scan(&lang, &localized); err == nil {
+			names[lang] = localized
+		}
+	}
+	rows.Close()
+
+	children := []gin.H{}
+	rows, err = h.db.Pool().Query(ctx, `
+		SELECT id, slug, name FROM topics
+		WHERE parent_id = $1 AND archived_at IS NULL
+		ORDER BY name
+	`, topicID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch topic"})
+		return
+	}
+	for rows.Next() {
+		var childID, childSlug, childName string
+		if err := rows.Scan(&childID, &childSlug, &childName); err == nil {
+			children = append(children, gin.H{"id": childID, "slug": childSlug, "name": childName})
+		}
+	}
+	rows.Close()
+
+	topic := gin.H{
+		"id":         topicID,
+		"slug":       slug,
+		"name":       name,
+		"names":      names,
+		"children":   children,
+		"created_at": createdAt,
+		"archived":   archivedAt != nil,
+	}
+	if icon != nil {
+		topic["icon"] = *icon
+	}
+	if parentID != nil {
+		topic["parent_id"] = *parentID
+	}
+	if description != nil {
+		topic["description"] = *description
+	}
+
+	c.JSON(http.StatusOK, topic)
+}
+
+// CreateTopic creates a topic (admin only)
+func (h *Handler) CreateTopic(c *gin.Context) {
+	if !h.checkAdmin(c) {
+		return
+	}
+	ctx := c.Request.Context()
+
+	var req TopicRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Slug == nil || req.Name == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug and name are required"})
+		return
+	}
+	if !h.validSlug(c, *req.Slug, "") {
+		return
+	}
+	parentID, ok := h.validParent(c, "", req.ParentID)
+	if !ok {
+		return
+	}
+
+	var id string
+	err := h.db.Pool().QueryRow(ctx, `
+		INSERT INTO topics (slug, name, icon, description, parent_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, *req.Slug, *req.Name, req.Icon, req.Description, parentID).Scan(&id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create topic"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"id": id, "slug": *req.Slug, "message": "topic created"})
+}
+
+// UpdateTopic renames, moves, archives or restores a topic (admin only)
+func (h *Handler) UpdateTopic(c *gin.Context) {
+	if !h.checkAdmin(c) {
+		return
+	}
+	topicID := c.Param("id")
+	ctx := c.Request.Context()
+
+	var req TopicRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var exists bool
+	if err := h.db.Pool().QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM topics WHERE id = $1)`, topicID).Scan(&exists); err != nil || !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "topic not found"})
+		return
+	}
+	if req.Slug != nil && !h.validSlug(c, *req.Slug, topicID) {
+		return
+	}
+	var parentID *string
+	if req.ParentID != nil {
+		var ok bool
+		if parentID, ok = h.validParent(c, topicID, req.ParentID); !ok {
+			return
+		}
+	}
+
+	_, err := h.db.Pool().Exec(ctx, `
+		UPDATE topics SET
+			slug = COALESCE($2, slug),
+			name = COALESCE($3, name),
+			icon = COALESCE($4, icon),
+			description = COALESCE($5, description),
+			parent_id = CASE WHEN $6::boolean THEN $7::uuid ELSE parent_id END,
+			archived_at = CASE
+				WHEN $8::boolean IS NULL THEN archived_at
+				WHEN $8 THEN COALESCE(archived_at, NOW())
+				ELSE NULL
+			END
+		WHERE id = $1
+	`, topicID, req.Slug, req.Name, req.Icon, req.Description, req.ParentID != nil, parentID, req.Archived)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update topic"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "topic updated"})
+}
+
+// ArchiveTopic hides a topic from the topic list and new posts and
+// events (admin only). Existing tags and subscriptions are kept so
+// the topic can be restored with PUT {"archived": false}.
+func (h *Handler) ArchiveTopic(c *gin.Context) {
+	if !h.checkAdmin(c) {
+		return
+	}
+	topicID := c.Param("id")
+
+	result, err := h.db.Pool().Exec(c.Request.Context(),
+		"UPDATE topics SET archived_at = NOW() WHERE id = $1 AND archived_at IS NULL",
+		topicID,
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to archive topic"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "topic not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "topic archived"})
+}
+
+// SetTopicName sets a topic's name in one language (admin only)
+func (h *Handler) SetTopicName(c *gin.Context) {
+	if !h.checkAdmin(c) {
+		return
+	}
+	topicID := c.Param("id")
+	lang := strings.ToLower(c.Param("lang"))
+	ctx := c.Request.Context()
+
+	if !langPattern.MatchString(lang) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "lang must be a two-letter language code"})
+		return
+	}
+	var req TopicNameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var exists bool
+	if err := h.db.Pool().QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM topics WHERE id = $1)`, topicID).Scan(&exists); err != nil || !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "topic not found"})
+		return
+	}
+
+	if _, err := h.db.Pool().Exec(ctx, `
+		INSERT INTO topic_names (topic_id, lang, name) VALUES ($1, $2, $3)
+		ON CONFLICT (topic_id, lang) DO UPDATE SET name = EXCLUDED.name
+	`, topicID, lang, req.Name); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to set topic name"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "topic name set"})
+}
+
+// DeleteTopicName removes a topic's name in one language (admin only)
+func (h *Handler) DeleteTopicName(c *gin.Context) {
+	if !h.checkAdmin(c) {
+		return
+	}
+
+	result, err := h.db.Pool().Exec(c.Request.Context(),
+		"DELETE FROM topic_names WHERE topic_id = $1 AND lang = $2",
+		c.Param("id"), strings.ToLower(c.Param("lang")),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete topic name"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "topic name not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "topic name removed"})
+}
+
+func (h *Handler) checkAdmin(c *gin.Context) bool {
+	userID := c.GetString("user_id")
+	var isAdmin bool
+	h.db.Pool().QueryRow(c.Request.Context(), "SELECT COALESCE(is_admin, false) FROM users WHERE id = $1", userID).Scan(&isAdmin)
+	if !isAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+		return false
+	}
+	return true
+}
+
+// validSlug checks a slug's format and that no other topic (than
+// topicID) or pending proposal uses it, writing the error response
+// if not.
+func (h *Handler) validSlug(c *gin.Context, slug, topicID string) bool {
+	if !slugPattern.MatchString(slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug must be lower-case letters and digits separated by hyphens"})
+		return false
+	}
+	var taken bool
+	err := h.db.Pool().QueryRow(c.Request.Context(), `
+		SELECT EXISTS(SELECT 1 FROM topics WHERE slug = $1 AND id::text <> $2)
+		    OR EXISTS(SELECT 1 FROM topic_proposals WHERE slug = $1 AND status = 'pending')
+	`, slug, topicID).Scan(&taken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to check slug"})
+		return false
+	}
+	if taken {
+		c.JSON(http.StatusConflict, gin.H{"error": "slug already in use"})
+		return false
+	}
+	return true
+}
+
+// validParent checks that topicID (empty for a new topic) can be
+// nested under *parentID, writing the error response if not. It
+// returns the parent to store: nil for no parent.
+func (h *Handler) validParent(c *gin.Context, topicID string, parentID *string) (*string, bool) {
+	if parentID == nil || *parentID == "" {
+		return nil, true
+	}
+	if _, err := uuid.Parse(*parentID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid parent_id"})
+		return nil, false
+	}
+	tree, err := Load(c.Request.Context(), h.db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load topics"})
+		return nil, false
+	}
+	if err := tree.checkParent(topicID, *parentID); err != nil {
+		status := http.StatusBadRequest
+		if err == errParentNotFound {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return parentID, true
+}
@@ -0,0 +1,411 @@
+package topics
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// proposeMinTrust is the trust needed to propose a topic: twice
+	// the bar for posting, since a topic is shared by everyone.
+	proposeMinTrust = 50
+
+	// endorseMinTrust is the trust needed to endorse a proposal. Same
+	// bar as vouching for a new user.
+	endorseMinTrust = 30
+
+	// endorsementsNeeded is how many users other than the proposer
+	// must endorse a proposal before the topic is created.
+	endorsementsNeeded = 5
+
+	// proposalTTL is how long a proposal can gather endorsements.
+	proposalTTL = 30 * 24 * time.Hour
+
+	// maxPendingProposals caps open proposals per user.
+	maxPendingProposals = 3
+)
+
+// ProposeTopicRequest proposes a new topic
+type ProposeTopicRequest struct {
+	Slug        string  `json:"slug" binding:"required,max=50"`
+	Name        string  `json:"name" binding:"required,min=2,max=100"`
+	Icon        *string `json:"icon" binding:"omitempty,max=50"`
+	Description *string `json:"description" binding:"omitempty,max=500"`
+	ParentID    *string `json:"parent_id"`
+}
+
+// RejectProposalRequest is an admin's reason for rejecting a proposal
+type RejectProposalRequest struct {
+	Reason string `json:"reason" binding:"max=500"`
+}
+
+// topicProposal is the part of a proposal needed to create its topic.
+type topicProposal struct {
+	id          string
+	slug        string
+	name        string
+	icon        *string
+	description *string
+	parentID    *string
+}
+
+// expireProposals closes pending proposals older than proposalTTL so
+// their slugs can be proposed again.
+func (h *Handler) expireProposals(ctx context.Context) {
+	if _, err := h.db.Pool().Exec(ctx, `
+		UPDATE topic_proposals SET status = 'expired', decided_at = NOW()
+		WHERE status = 'pending' AND created_at < $1
+	`, time.Now().Add(-proposalTTL)); err != nil {
+		log.Printf("topics: expire proposals: %v", err)
+	}
+}
+
+// ProposeTopic proposes a topic for endorsement
+func (h *Handler) ProposeTopic(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ctx := c.Request.Context()
+
+	if c.GetFloat64("trust_score") < proposeMinTrust {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient trust score to propose topics"})
+		return
+	}
+
+	var req ProposeTopicRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.expireProposals(ctx)
+	if !h.validSlug(c, req.Slug, "") {
+		return
+	}
+	parentID, ok := h.validParent(c, "", req.ParentID)
+	if !ok {
+		return
+	}
+
+	var pending int
+	if err := h.db.Pool().QueryRow(ctx, `
+		SELECT COUNT(*) FROM topic_proposals WHERE proposer_id = $1 AND status = 'pending'
+	`, userID).Scan(&pending); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create proposal"})
+		return
+	}
+	if pending >= maxPendingProposals {
+		c.JSON(http.StatusConflict, gin.H{"error": "too many open proposals"})
+		return
+	}
+
+	var id string
+	err := h.db.Pool().QueryRow(ctx, `
+		INSERT INTO topic_proposals (proposer_id, slug, name, icon, description, parent_id)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`, userID, req.Slug, req.Name, req.Icon, req.Description, parentID).Scan(&id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create proposal"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"id":                  id,
+		"status":              "pending",
+		"endorsements_needed": endorsementsNeeded,
+		"expires_at":          time.Now().Add(proposalTTL),
+	})
+}
+
+// ListProposals returns topic proposals, pending by default
+// (?status=pending|accepted|rejected|expired). Endorsers and
+// proposers aren't revealed.
+func (h *Handler) ListProposals(c *gin.Context) {
+	userID := c.GetString("user_id")
+	ctx := c.Request.Context()
+
+	status := c.DefaultQuery("status", "pending")
+	switch status {
+	case "pending", "accepted", "rejected", "expired":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, accepted, rejected or expired"})
+		return
+	}
+
+	h.expireProposals(ctx)
+	rows, err := h.db.Pool().Query(ctx, `
+		SELECT p.id, p.slug, p.name, p.icon, p.description, p.parent_id, p.topic_id, p.reason,
+		       p.created_at, p.decided_at,
+		       (SELECT COUNT(*) FROM topic_proposal_endorsements e WHERE e.proposal_id = p.id),
+		       EXISTS(SELECT 1 FROM topic_proposal_endorsements e WHERE e.proposal_id = p.id AND e.user_id = $1),
+		       p.proposer_id IS NOT DISTINCT FROM $1::uuid
+		FROM topic_proposals p
+		WHERE p.status = $2
+		ORDER BY p.created_at DESC
+		LIMIT 100
+	`, userID, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch proposals"})
+		return
+	}
+	defer rows.Close()
+
+	proposals := []gin.H{}
+	for rows.Next() {
+		var id, slug, name string
+		var icon, description, parentID, topicID, reason *string
+		var createdAt time.Time
+		var decidedAt *time.Time
+		var endorsements int
+		var endorsed, mine bool
+		if err := rows.Scan(&id, &slug, &name, &icon, &description, &parentID, &topicID, &reason,
+			&createdAt, &decidedAt, &endorsements, &endorsed, &mine); err != nil {
+			continue
+		}
+		proposal := gin.H{
+			"id":                  id,
+			"slug":                slug,
+			"name":                name,
+			"status":              status,
+			"endorsements":        endorsements,
+			"endorsements_needed": endorsementsNeeded,
+			"endorsed":            endorsed,
+			"proposed_by_me":      mine,
+			"created_at":          createdAt,
+		}
+		if status == "pending" {
+			proposal["expires_at"] = createdAt.Add(proposalTTL)
+		}
+		if icon != nil {
+			proposal["icon"] = *icon
+		}
+		if description != nil {
+			proposal["description"] = *description
+		}
+		if parentID != nil {
+			proposal["parent_id"] = *parentID
+		}
+		if topicID != nil {
+			proposal["topic_id"] = *topicID
+		}
+		if reason != nil {
+			proposal["reason"] = *reason
+		}
+		if decidedAt != nil {
+			proposal["decided_at"] = *decidedAt
+		}
+		proposals = append(proposals, proposal)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"proposals": proposals})
+}
+
+// EndorseProposal endorses a pending proposal. The endorsement that
+// reaches endorsementsNeeded creates the topic.
+func (h *Handler) EndorseProposal(c *gin.Context) {
+	userID := c.GetString("user_id")
+	proposalID := c.Param("id")
+	ctx := c.Request.Context()
+
+	if c.GetFloat64("trust_score") < endorseMinTrust {
+		c.JSON(http.StatusForbidden, gin.H{"error": "insufficient trust score to endorse topics"})
+		return
+	}
+
+	h.expireProposals(ctx)
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to endorse proposal"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	p, proposerID, ok := h.lockPendingProposal(c, tx, proposalID)
+	if !ok {
+		return
+	}
+	if proposerID != nil && *proposerID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "cannot endorse your own proposal"})
+		return
+	}
+
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO topic_proposal_endorsements (proposal_id, user_id) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, proposalID, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to endorse proposal"})
+		return
+	}
+	var endorsements int
+	if err := tx.QueryRow(ctx, `
+		SELECT COUNT(*) FROM topic_proposal_endorsements WHERE proposal_id = $1
+	`, proposalID).Scan(&endorsements); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to endorse proposal"})
+		return
+	}
+
+	response := gin.H{
+		"status":              "pending",
+		"endorsements":        endorsements,
+		"endorsements_needed": endorsementsNeeded,
+	}
+	if endorsements >= endorsementsNeeded {
+		topicID, err := h.acceptProposal(ctx, tx, p)
+		if err != nil {
+			log.Printf("topics: accept proposal %s: %v", proposalID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to endorse proposal"})
+			return
+		}
+		response["status"] = "accepted"
+		response["topic_id"] = topicID
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to endorse proposal"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// WithdrawEndorsement removes the user's endorsement of a pending
+// proposal
+func (h *Handler) WithdrawEndorsement(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	result, err := h.db.Pool().Exec(c.Request.Context(), `
+		DELETE FROM topic_proposal_endorsements e
+		USING topic_proposals p
+		WHERE e.proposal_id = p.id AND p.status = 'pending'
+		  AND e.proposal_id = $1 AND e.user_id = $2
+	`, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to withdraw endorsement"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "endorsement not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "endorsement withdrawn"})
+}
+
+// ApproveProposal creates a proposal's topic without waiting for
+// endorsements (admin only)
+func (h *Handler) ApproveProposal(c *gin.Context) {
+	if !h.checkAdmin(c) {
+		return
+	}
+	proposalID := c.Param("id")
+	ctx := c.Request.Context()
+
+	tx, err := h.db.Pool().Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to approve proposal"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	p, _, ok := h.lockPendingProposal(c, tx, proposalID)
+	if !ok {
+		return
+	}
+	topicID, err := h.acceptProposal(ctx, tx, p)
+	if err != nil {
+		log.Printf("topics: accept proposal %s: %v", proposalID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to approve proposal"})
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to approve proposal"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "accepted", "topic_id": topicID})
+}
+
+// RejectProposal closes a pending proposal (admin only)
+func (h *Handler) RejectProposal(c *gin.Context) {
+	if !h.checkAdmin(c) {
+		return
+	}
+
+	var req RejectProposalRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.db.Pool().Exec(c.Request.Context(), `
+		UPDATE topic_proposals SET status = 'rejected', reason = NULLIF($2, ''), decided_at = NOW()
+		WHERE id = $1 AND status = 'pending'
+	`, c.Param("id"), req.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reject proposal"})
+		return
+	}
+	if result.RowsAffected() == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "pending proposal not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "rejected"})
+}
+
+// lockPendingProposal loads and locks a pending proposal, writing a
+// 404 if there is none.
+func (h *Handler) lockPendingProposal(c *gin.Context, tx pgx.Tx, proposalID string) (topicProposal, *string, bool) {
+	p := topicProposal{id: proposalID}
+	var proposerID *string
+	err := tx.QueryRow(c.Request.Context(), `
+		SELECT slug, name, icon, description, parent_id, proposer_id
+		FROM topic_proposals
+		WHERE id = $1 AND status = 'pending'
+		FOR UPDATE
+	`, proposalID).Scan(&p.slug, &p.name, &p.icon, &p.description, &p.parentID, &proposerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "pending proposal not found"})
+		return p, nil, false
+	}
+	return p, proposerID, true
+}
+
+// acceptProposal creates p's topic and marks p accepted. If a topic
+// with the slug was created in the meantime the proposal is linked to
+// it instead. A parent that's no longer valid is dropped rather than
+// failing the endorsement.
+func (h *Handler) acceptProposal(ctx context.Context, tx pgx.Tx, p topicProposal) (string, error) {
+	var topicID string
+	err := tx.QueryRow(ctx, `SELECT id FROM topics WHERE slug = $1`, p.slug).Scan(&topicID)
+	if err == pgx.ErrNoRows {
+		parentID := p.parentID
+		if parentID != nil {
+			tree, err := Load(ctx, h.db)
+			if err != nil {
+				return "", err
+			}
+			if tree.checkParent("", *parentID) != nil {
+				parentID = nil
+			}
+		}
+		err = tx.QueryRow(ctx, `
+			INSERT INTO topics (slug, name, icon, description, parent_id)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, p.slug, p.name, p.icon, p.description, parentID).Scan(&topicID)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE topic_proposals SET status = 'accepted', topic_id = $2, decided_at = NOW()
+		WHERE id = $1
+	`, p.id, topicID)
+	return topicID, err
+}
@@ -0,0 +1,144 @@
+package topics
+
+import (
+	"context"
+	"errors"
+
+	"github.com/kuurier/server/internal/storage"
+)
+
+// maxDepth is how many levels the topic tree may have: a root topic,
+// a campaign under it and, at most, a local chapter under that.
+const maxDepth = 3
+
+var (
+	errParentNotFound = errors.New("parent topic not found")
+	errParentCycle    = errors.New("a topic cannot be nested under itself or its descendants")
+	errTooDeep        = errors.New("topics can only be nested three levels deep")
+)
+
+// Tree is the topic hierarchy, archived topics included (posts keep
+// their tags when a topic is archived). A nil *Tree is an empty tree.
+type Tree struct {
+	names    map[string]string
+	parents  map[string]string
+	children map[string][]string
+}
+
+// Load reads the whole topic tree. There are at most a few hundred
+// topics, so callers load it per request.
+func Load(ctx context.Context, db *storage.Postgres) (*Tree, error) {
+	rows, err := db.Pool().Query(ctx, `SELECT id, name, COALESCE(parent_id::text, '') FROM topics`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	t := &Tree{}
+	for rows.Next() {
+		var id, name, parentID string
+		if err := rows.Scan(&id, &name, &parentID); err != nil {
+			return nil, err
+		}
+		t.add(id, name, parentID)
+	}
+	return t, rows.Err()
+}
+
+func (t *Tree) add(id, name, parentID string) {
+	if t.names == nil {
+		t.names = make(map[string]string)
+		t.parents = make(map[string]string)
+		t.children = make(map[string][]string)
+	}
+	t.names[id] = name
+	if parentID != "" {
+		t.parents[id] = parentID
+		t.children[parentID] = append(t.children[parentID], id)
+	}
+}
+
+// Names maps topic IDs to their default names.
+func (t *Tree) Names() map[string]string {
+	if t == nil {
+		return nil
+	}
+	return t.names
+}
+
+// WithDescendants returns id followed by every topic nested under it.
+// Subscribing to a topic matches content tagged with any of them.
+func (t *Tree) WithDescendants(id string) []string {
+	ids := []string{id}
+	if t == nil {
+		return ids
+	}
+	seen := map[string]bool{id: true}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range t.children[ids[i]] {
+			if !seen[child] {
+				seen[child] = true
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids
+}
+
+// depth is the number of levels from the root down to id, so a root
+// topic has depth 1.
+func (t *Tree) depth(id string) int {
+	d := 1
+	seen := map[string]bool{id: true}
+	for p, ok := t.parents[id]; ok && !seen[p]; p, ok = t.parents[p] {
+		seen[p] = true
+		d++
+	}
+	return d
+}
+
+// height is the number of levels in the subtree rooted at id, so a
+// leaf has height 1.
+func (t *Tree) height(id string) int {
+	levels := 0
+	frontier := []string{id}
+	seen := map[string]bool{id: true}
+	for len(frontier) > 0 {
+		levels++
+		var next []string
+		for _, n := range frontier {
+			for _, child := range t.children[n] {
+				if !seen[child] {
+					seen[child] = true
+					next = append(next, child)
+				}
+			}
+		}
+		frontier = next
+	}
+	return levels
+}
+
+// checkParent reports whether topic id (empty for a new topic) may be
+// nested under parentID without creating a cycle or exceeding
+// maxDepth.
+func (t *Tree) checkParent(id, parentID string) error {
+	if _, ok := t.names[parentID]; !ok {
+		return errParentNotFound
+	}
+	if id != "" {
+		for _, d := range t.WithDescendants(id) {
+			if d == parentID {
+				return errParentCycle
+			}
+		}
+	}
+	h := 1
+	if id != "" {
+		h = t.height(id)
+	}
+	if t.depth(parentID)+h > maxDepth {
+		return errTooDeep
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package topics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// climate > pipelines > line-5, plus an unrelated housing root.
+func testTree() *Tree {
+	t := &Tree{}
+	t.add("climate", "Climate Action", "")
+	t.add("pipelines", "Pipelines", "climate")
+	t.add("line-5", "Line 5", "pipelines")
+	t.add("coal", "Coal", "climate")
+	t.add("housing", "Housing Justice", "")
+	return t
+}
+
+func TestWithDescendants(t *testing.T) {
+	tree := testTree()
+	assert.ElementsMatch(t, []string{"climate", "pipelines", "line-5", "coal"}, tree.WithDescendants("climate"))
+	assert.Equal(t, []string{"line-5"}, tree.WithDescendants("line-5"))
+
+	var empty *Tree
+	assert.Equal(t, []string{"climate"}, empty.WithDescendants("climate"), "nil tree matches the topic itself")
+	assert.Nil(t, empty.Names())
+}
+
+func TestCheckParent(t *testing.T) {
+	tree := testTree()
+
+	assert.NoError(t, tree.checkParent("", "pipelines"), "new topic at the third level")
+	assert.NoError(t, tree.checkParent("housing", "climate"), "leaf root moves to the second level")
+
+	assert.Equal(t, errParentNotFound, tree.checkParent("", "missing"))
+	assert.Equal(t, errParentCycle, tree.checkParent("climate", "climate"))
+	assert.Equal(t, errParentCycle, tree.checkParent("climate", "line-5"))
+	assert.Equal(t, errTooDeep, tree.checkParent("", "line-5"))
+	assert.Equal(t, errTooDeep, tree.checkParent("pipelines", "coal"), "pipelines brings line-5 along")
+}
@@ -66,6 +66,10 @@ const (
 	TypeChannelUpdated = "channel.updated"
 	TypePresenceOnline = "presence.online"
 	TypePresenceOffline = "presence.offline"
+	TypeEventUpdated   = "event.updated"
+	TypeEventAnnouncement = "event.announcement"
+	TypeAlertLocation     = "alert.location"
+	TypeAlertLocationEnd  = "alert.location_ended"
 	TypeError          = "error"
 	TypePong           = "pong"
 )